@@ -297,7 +297,7 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 // SendTransaction updates the pending block to include the given transaction.
 // It panics if the transaction is invalid.
 func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), tx)
+	sender, err := types.Sender(types.NewTypedTxSigner(big.NewInt(1)), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
@@ -349,3 +349,5 @@ func (m callmsg) Gas() *big.Int        { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
 func (m callmsg) Data() []byte         { return m.CallMsg.Data }
 func (m callmsg) TxType() uint64       { return m.CallMsg.TxType }
+
+func (m callmsg) AccessList() types.AccessList { return m.CallMsg.AccessList }
@@ -48,7 +48,7 @@ type Key struct {
 	// to simplify lookups we also store the address
 	Address common.Address
 	// we only store privkey as pubkey/address can be derived from it
-	// privkey in this struct is always in plaintext
+	// privkey in this struct is always in plaintext, nil in a view-only key
 	PrivateKey *ecdsa.PrivateKey
 	// add a second privkey for privary
 	PrivateKey2 *ecdsa.PrivateKey
@@ -95,6 +95,7 @@ type encryptedKeyJSONV3 struct {
 	Id       string     `json:"id"`
 	Version  int        `json:"version"`
 	WAddress string     `json:"waddress"`
+	ViewOnly bool       `json:"viewonly,omitempty"`
 }
 
 type encryptedKeyJSONV1 struct {
@@ -166,6 +167,64 @@ func newKeyFromECDSA(sk1, sk2 *ecdsa.PrivateKey) *Key {
 	return key
 }
 
+// newViewOnlyKey creates a view-only key from the public key A and the
+// private view key b of an account.
+func newViewOnlyKey(pub *ecdsa.PublicKey, sk2 *ecdsa.PrivateKey) *Key {
+	return &Key{
+		Id:          uuid.NewRandom(),
+		Address:     crypto.PubkeyToAddress(*pub),
+		PrivateKey2: sk2,
+		WAddress:    *GenerateWaddressFromPK(pub, &sk2.PublicKey),
+	}
+}
+
+// IsViewOnly reports whether the key only holds the view key of its
+// account, it finds the OTAs of the account but can't spend them.
+func (k *Key) IsViewOnly() bool {
+	return k.PrivateKey == nil && k.PrivateKey2 != nil
+}
+
+// publicKey returns the public key A of the account.
+func (k *Key) publicKey() (*ecdsa.PublicKey, error) {
+	if k.PrivateKey != nil {
+		return &k.PrivateKey.PublicKey, nil
+	}
+	pub, _, err := GeneratePKPairFromWAddress(k.WAddress[:])
+	return pub, err
+}
+
+// viewKeyLength is the length of an encoded view key, the compressed public
+// key A followed by the private view key b.
+const viewKeyLength = 33 + 32
+
+// encodeViewKey encodes the view key of a key.
+func encodeViewKey(k *Key) ([]byte, error) {
+	if k.PrivateKey2 == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	pub, err := k.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	return append(ECDSAPKCompression(pub), math.PaddedBigBytes(k.PrivateKey2.D, 32)...), nil
+}
+
+// decodeViewKey decodes a view key into a view-only key.
+func decodeViewKey(viewKey []byte) (*Key, error) {
+	if len(viewKey) != viewKeyLength {
+		return nil, ErrInvalidViewKey
+	}
+	pub, err := btcec.ParsePubKey(viewKey[:33], btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	sk2, err := crypto.ToECDSA(viewKey[33:])
+	if err != nil {
+		return nil, err
+	}
+	return newViewOnlyKey((*ecdsa.PublicKey)(pub), sk2), nil
+}
+
 // updateWaddress adds WAddress field to the Key struct
 func updateWaddress(k *Key) {
 	k.WAddress = *GenerateWaddressFromPK(&k.PrivateKey.PublicKey, &k.PrivateKey2.PublicKey)
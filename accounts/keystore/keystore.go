@@ -48,6 +48,8 @@ var (
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
 	ErrInvalidKmsInfo = errors.New("invalid AWS KMS info")
+	ErrOTANotOwned    = errors.New("OTA doesn't belong to the account")
+	ErrViewOnly       = errors.New("view-only account can't sign")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -267,6 +269,9 @@ func (ks *KeyStore) SignHash(a accounts.Account, hash []byte) ([]byte, error) {
 	if !found {
 		return nil, ErrLocked
 	}
+	if unlockedKey.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
 	// Sign the hash using plain ECDSA operations
 	return crypto.Sign(hash, unlockedKey.PrivateKey)
 }
@@ -281,9 +286,12 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	if !found {
 		return nil, ErrLocked
 	}
+	if unlockedKey.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
 	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
+		return types.SignTx(tx, types.NewTypedTxSigner(chainID), unlockedKey.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
 }
@@ -296,6 +304,9 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	if !found {
 		return nil, ErrLocked
 	}
+	if unlockedKey.IsViewOnly() {
+		return computeOTAPubKeys(unlockedKey.Key, AX, AY, BX, BY)
+	}
 
 	pub1, priv1, priv2, err := crypto.GenerteOTAPrivateKey(unlockedKey.PrivateKey, unlockedKey.PrivateKey2, AX, AY, BX, BY)
 
@@ -307,6 +318,81 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	return []string{pub1X, pub1Y, priv1D, priv2D}, err
 }
 
+// computeOTAPubKeys is ComputeOTAPPKeys for a view-only key, it checks the
+// OTA belongs to the account but leaves out the private keys.
+func computeOTAPubKeys(key *Key, AX, AY, BX, BY string) ([]string, error) {
+	var coords [4]*big.Int
+	for i, str := range []string{AX, AY, BX, BY} {
+		b, err := hexutil.Decode(str)
+		if err != nil {
+			return nil, err
+		}
+		coords[i] = new(big.Int).SetBytes(b)
+	}
+	A1 := &ecdsa.PublicKey{Curve: crypto.S256(), X: coords[0], Y: coords[1]}
+	R := &ecdsa.PublicKey{Curve: crypto.S256(), X: coords[2], Y: coords[3]}
+
+	pub, err := key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !crypto.CompareA1(key.PrivateKey2.D.Bytes(), pub, R, A1) {
+		return nil, ErrOTANotOwned
+	}
+
+	pub1X := hexutil.Encode(common.LeftPadBytes(A1.X.Bytes(), 32))
+	pub1Y := hexutil.Encode(common.LeftPadBytes(A1.Y.Bytes(), 32))
+	return []string{pub1X, pub1Y, "", ""}, nil
+}
+
+// OTAViewKey returns the public key A and the private view key b of an
+// unlocked account, they are enough to find the OTAs sent to the account.
+func (ks *KeyStore) OTAViewKey(a accounts.Account) (*ecdsa.PublicKey, *ecdsa.PrivateKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, nil, ErrLocked
+	}
+	pub, err := unlockedKey.publicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, unlockedKey.PrivateKey2, nil
+}
+
+// OTAKeyImage returns the key image of an OTA of an unlocked account, the
+// OTA is spent once its image is stored.
+func (ks *KeyStore) OTAKeyImage(a accounts.Account, otaWanAddr []byte) ([]byte, error) {
+	A1, R, err := GeneratePKPairFromWAddress(otaWanAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+	if unlockedKey.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
+
+	priv, _, err := crypto.GenerateOneTimePrivateKey2528(unlockedKey.PrivateKey, unlockedKey.PrivateKey2, A1, R)
+	if err != nil {
+		return nil, err
+	}
+	priv.PublicKey.Curve = crypto.S256()
+	priv.PublicKey.X, priv.PublicKey.Y = crypto.S256().ScalarBaseMult(priv.D.Bytes())
+	if priv.PublicKey.X.Cmp(A1.X) != 0 || priv.PublicKey.Y.Cmp(A1.Y) != 0 {
+		return nil, ErrOTANotOwned
+	}
+	return crypto.FromECDSAPub(crypto.KeyImage(priv)), nil
+}
+
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
@@ -315,6 +401,9 @@ func (ks *KeyStore) SignHashWithPassphrase(a accounts.Account, passphrase string
 	if err != nil {
 		return nil, err
 	}
+	if key.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
 	defer zeroKey(key.PrivateKey)
 	return crypto.Sign(hash, key.PrivateKey)
 }
@@ -326,11 +415,14 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	if err != nil {
 		return nil, err
 	}
+	if key.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
 	defer zeroKey(key.PrivateKey)
 
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
 	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
+		return types.SignTx(tx, types.NewTypedTxSigner(chainID), key.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
 }
@@ -528,6 +620,31 @@ func (ks *KeyStore) Import(keyJSON []byte, passphrase, newPassphrase string) (ac
 // 	return crypto.FromECDSA(key.PrivateKey), crypto.FromECDSA(key.PrivateKey2), err
 // }
 
+// ExportViewKey returns the view key of an account, the public key A and the
+// private view key b. It finds the OTAs of the account but can't spend them.
+// Note b also derives the PoS key3 of the account.
+func (ks *KeyStore) ExportViewKey(a accounts.Account, passphrase string) ([]byte, error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+	return encodeViewKey(key)
+}
+
+// ImportViewKey stores a view key as a view-only account, encrypting it with
+// the passphrase.
+func (ks *KeyStore) ImportViewKey(viewKey []byte, passphrase string) (accounts.Account, error) {
+	key, err := decodeViewKey(viewKey)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	if ks.cache.hasAddress(key.Address) {
+		return accounts.Account{}, fmt.Errorf("account already exists")
+	}
+	return ks.importKey(key, passphrase)
+}
+
 // ImportECDSA stores the given key into the key directory, encrypting it with the passphrase.
 func (ks *KeyStore) ImportECDSA(priv1, priv2 *ecdsa.PrivateKey, passphrase string) (accounts.Account, error) {
 	key := newKeyFromECDSA(priv1, priv2)
@@ -560,7 +677,10 @@ func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string)
 		}
 		key.PrivateKey2 = sk2
 	}
-	updateWaddress(key)
+	// The wan address of a view-only key holds its public key
+	if !key.IsViewOnly() {
+		updateWaddress(key)
+	}
 	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
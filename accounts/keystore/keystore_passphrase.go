@@ -80,6 +80,7 @@ var (
 	ErrWAddressInvalid       = errors.New("invalid wanchain address")
 	ErrInvalidAccountKey     = errors.New("invalid account key")
 	ErrInvalidPrivateKey     = errors.New("invalid private key")
+	ErrInvalidViewKey        = errors.New("invalid view key")
 )
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -174,9 +175,13 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		return nil, ErrInvalidAccountKey
 	}
 
-	cryptoStruct, err := EncryptOnePrivateKey(key.PrivateKey, auth, scryptN, scryptP)
-	if err != nil {
-		return nil, err
+	// A view-only key has no spend key to encrypt
+	cryptoStruct := new(cryptoJSON)
+	if !key.IsViewOnly() {
+		var err error
+		if cryptoStruct, err = EncryptOnePrivateKey(key.PrivateKey, auth, scryptN, scryptP); err != nil {
+			return nil, err
+		}
 	}
 
 	cryptoStruct2, err := EncryptOnePrivateKey(key.PrivateKey2, auth, scryptN, scryptP)
@@ -191,6 +196,7 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		key.Id.String(),
 		version,
 		hex.EncodeToString(key.WAddress[:]),
+		key.IsViewOnly(),
 	}
 	return json.Marshal(encryptedKeyJSONV3)
 }
@@ -256,6 +262,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		keyBytes, keyBytes2, keyId []byte
 		err                        error
 		waddressStr                *string
+		viewOnly                   bool
 	)
 	if version, ok := m["version"].(string); ok && version == "1" {
 		k := new(encryptedKeyJSONV1)
@@ -285,11 +292,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		}
 
 		waddressStr = &k.WAddress
-	}
-
-	key, err := crypto.ToECDSA(keyBytes)
-	if err != nil || key == nil {
-		return nil, ErrInvalidPrivateKey
+		viewOnly = k.ViewOnly
 	}
 
 	key2, err := crypto.ToECDSA(keyBytes2)
@@ -305,6 +308,24 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	var waddress common.WAddress
 	copy(waddress[:], waddressRaw)
 
+	if viewOnly {
+		pub, _, err := GeneratePKPairFromWAddress(waddressRaw)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{
+			Id:          uuid.UUID(keyId),
+			Address:     crypto.PubkeyToAddress(*pub),
+			PrivateKey2: key2,
+			WAddress:    waddress,
+		}, nil
+	}
+
+	key, err := crypto.ToECDSA(keyBytes)
+	if err != nil || key == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
 	return &Key{
 		Id:          uuid.UUID(keyId),
 		Address:     crypto.PubkeyToAddress(key.PublicKey),
@@ -321,6 +342,12 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 
 	keyId = uuid.Parse(keyProtected.Id)
 
+	// A view-only key only holds the second key
+	if keyProtected.ViewOnly {
+		plainText2, err := decryptKeyV3Item(keyProtected.Crypto2, auth)
+		return nil, plainText2, keyId, err
+	}
+
 	plainText, err := decryptKeyV3Item(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, nil, err
@@ -18,6 +18,7 @@
 package keystore
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -30,6 +31,7 @@ import (
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/event"
 )
@@ -534,3 +536,120 @@ func TestComputeOTAPPKeys(t *testing.T) {
 		t.Errorf("invalid ota pk. pk lenght:%d", len(pk))
 	}
 }
+
+func TestViewOnlyKey(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+	viewDir, viewKs := tmpKeyStore(t, true)
+	defer os.RemoveAll(viewDir)
+
+	a, err := ks.NewAccount("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ks.NewAccount("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewKey, err := ks.ExportViewKey(a, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := viewKs.ImportViewKey(viewKey, "auditor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Address != a.Address {
+		t.Fatalf("view-only account address mismatch: have %x, want %x", v.Address, a.Address)
+	}
+	if _, err := viewKs.ImportViewKey(viewKey, "auditor"); err == nil {
+		t.Fatal("view key imported twice")
+	}
+	waddr, _ := ks.GetWanAddress(a)
+	viewWaddr, _ := viewKs.GetWanAddress(v)
+	if waddr != viewWaddr {
+		t.Fatalf("wan address mismatch: have %x, want %x", viewWaddr, waddr)
+	}
+
+	// The view key survives a passphrase update and an export.
+	if err := viewKs.Update(v, "auditor", "auditor2"); err != nil {
+		t.Fatal(err)
+	}
+	reexported, err := viewKs.ExportViewKey(v, "auditor2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reexported, viewKey) {
+		t.Fatalf("view key mismatch: have %x, want %x", reexported, viewKey)
+	}
+
+	// A view-only account never signs.
+	if err := ks.Unlock(a, "owner"); err != nil {
+		t.Fatal(err)
+	}
+	if err := viewKs.Unlock(v, "auditor2"); err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256([]byte("refund"))
+	if _, err := viewKs.SignHash(v, hash); err != ErrViewOnly {
+		t.Errorf("SignHash error mismatch: have %v, want %v", err, ErrViewOnly)
+	}
+	if _, err := viewKs.SignHashWithPassphrase(v, "auditor2", hash); err != ErrViewOnly {
+		t.Errorf("SignHashWithPassphrase error mismatch: have %v, want %v", err, ErrViewOnly)
+	}
+	if _, err := viewKs.SignTx(v, new(types.Transaction), nil); err != ErrViewOnly {
+		t.Errorf("SignTx error mismatch: have %v, want %v", err, ErrViewOnly)
+	}
+	if _, err := viewKs.Wallets()[0].(*keystoreWallet).GetUnlockedKey(v.Address); err != ErrViewOnly {
+		t.Errorf("GetUnlockedKey error mismatch: have %v, want %v", err, ErrViewOnly)
+	}
+
+	// It finds the OTAs of the account, without their private keys.
+	pub, view, err := ks.OTAViewKey(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewPub, viewView, err := viewKs.OTAViewKey(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.X.Cmp(viewPub.X) != 0 || pub.Y.Cmp(viewPub.Y) != 0 || view.D.Cmp(viewView.D) != 0 {
+		t.Fatal("view key mismatch")
+	}
+
+	ota, err := genOTA(hexutil.Encode(waddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := viewKs.OTAKeyImage(v, hexutil.MustDecode(ota)); err != ErrViewOnly {
+		t.Errorf("OTAKeyImage error mismatch: have %v, want %v", err, ErrViewOnly)
+	}
+	raw, _ := WaddrToUncompressedRawBytes(hexutil.MustDecode(ota))
+	coords := []string{
+		hexutil.Encode(raw[:32]), hexutil.Encode(raw[32:64]),
+		hexutil.Encode(raw[64:96]), hexutil.Encode(raw[96:]),
+	}
+	keys, err := ks.ComputeOTAPPKeys(a, coords[0], coords[1], coords[2], coords[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewKeys, err := viewKs.ComputeOTAPPKeys(v, coords[0], coords[1], coords[2], coords[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viewKeys[0] != keys[0] || viewKeys[1] != keys[1] || viewKeys[2] != "" || viewKeys[3] != "" {
+		t.Errorf("OTA keys mismatch: have %v, want public keys of %v", viewKeys, keys)
+	}
+
+	otherWaddr, _ := ks.GetWanAddress(other)
+	otherOTA, err := genOTA(hexutil.Encode(otherWaddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ = WaddrToUncompressedRawBytes(hexutil.MustDecode(otherOTA))
+	_, err = viewKs.ComputeOTAPPKeys(v, hexutil.Encode(raw[:32]), hexutil.Encode(raw[32:64]), hexutil.Encode(raw[64:96]), hexutil.Encode(raw[96:]))
+	if err != ErrOTANotOwned {
+		t.Errorf("ComputeOTAPPKeys error mismatch: have %v, want %v", err, ErrOTANotOwned)
+	}
+}
@@ -18,6 +18,7 @@
 package keystore
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"math/big"
 
@@ -126,6 +127,34 @@ func (w *keystoreWallet) ComputeOTAPPKeys(account accounts.Account, AX, AY, BX,
 	return w.keystore.ComputeOTAPPKeys(account, AX, AY, BX, BY)
 }
 
+// OTAViewKey returns the public key and the private view key of the account,
+// if it's unlocked.
+func (w *keystoreWallet) OTAViewKey(account accounts.Account) (*ecdsa.PublicKey, *ecdsa.PrivateKey, error) {
+	// Make sure the requested account is contained within
+	if account.Address != w.account.Address {
+		return nil, nil, accounts.ErrUnknownAccount
+	}
+	if account.URL != (accounts.URL{}) && account.URL != w.account.URL {
+		return nil, nil, accounts.ErrUnknownAccount
+	}
+
+	return w.keystore.OTAViewKey(account)
+}
+
+// OTAKeyImage returns the key image of an OTA of the account, if it's
+// unlocked.
+func (w *keystoreWallet) OTAKeyImage(account accounts.Account, otaWanAddr []byte) ([]byte, error) {
+	// Make sure the requested account is contained within
+	if account.Address != w.account.Address {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if account.URL != (accounts.URL{}) && account.URL != w.account.URL {
+		return nil, accounts.ErrUnknownAccount
+	}
+
+	return w.keystore.OTAKeyImage(account, otaWanAddr)
+}
+
 // SignHashWithPassphrase implements accounts.Wallet, attempting to sign the
 // given hash with the given account using passphrase as extra authentication.
 func (w *keystoreWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
@@ -172,6 +201,9 @@ func (w *keystoreWallet) GetUnlockedKey(address common.Address) (*Key, error) {
 	if !ok {
 		return nil, errors.New("can not found a unlock key of: " + address.Hex())
 	}
+	if value.IsViewOnly() {
+		return nil, ErrViewOnly
+	}
 
 	return value.Key, nil
 }
@@ -116,6 +116,18 @@ func (am *Manager) update() {
 	}
 }
 
+// AddBackend registers a backend created after the account manager, its
+// wallets are tracked like the ones of the initial backends.
+func (am *Manager) AddBackend(backend Backend) {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	kind := reflect.TypeOf(backend)
+	am.backends[kind] = append(am.backends[kind], backend)
+	am.updaters = append(am.updaters, backend.Subscribe(am.updates))
+	am.wallets = merge(am.wallets, backend.Wallets()...)
+}
+
 // Backends retrieves the backend(s) with the given type from the account manager.
 func (am *Manager) Backends(kind reflect.Type) []Backend {
 	return am.backends[kind]
@@ -522,6 +522,10 @@ func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID
 	if !ok {
 		return nil, accounts.ErrUnknownAccount
 	}
+	// The devices only sign the legacy transaction encoding
+	if tx.Type() != types.LegacyTxType {
+		return nil, accounts.ErrNotSupported
+	}
 	// All infos gathered and metadata checks out, request signing
 	<-w.commsLock
 	defer func() { w.commsLock <- struct{}{} }()
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -48,6 +49,8 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -65,6 +68,8 @@ It expects the genesis file as argument.`,
 		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -83,6 +88,8 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -100,6 +107,8 @@ if already existing.`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
@@ -117,6 +126,8 @@ The first argument must be the directory containing the blockchain to download f
 		ArgsUsage: " ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -130,6 +141,8 @@ Remove blockchain and state databases`,
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -161,7 +174,15 @@ func initGenesis(ctx *cli.Context) error {
 	// Open an initialise both full and light databases
 	stack := makeFullNode(ctx)
 	for _, name := range []string{"chaindata", "lightchaindata"} {
-		chaindb, err := stack.OpenDatabase(name, 0, 0)
+		var (
+			chaindb ethdb.Database
+			err     error
+		)
+		if name == "chaindata" {
+			chaindb, err = stack.OpenDatabaseWithFreezer(name, 0, 0, ctx.GlobalString(utils.AncientFlag.Name))
+		} else {
+			chaindb, err = stack.OpenDatabase(name, 0, 0)
+		}
 		if err != nil {
 			utils.Fatalf("Failed to open database: %v", err)
 		}
@@ -214,8 +235,12 @@ func importChain(ctx *cli.Context) error {
 
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
-	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
+	// Output pre-compaction stats mostly to see the import trashing, only
+	// leveldb keeps any
+	db, ok := chainDb.(*ethdb.LDBDatabase)
+	if !ok {
+		return nil
+	}
 
 	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
@@ -299,8 +324,18 @@ func copyDb(ctx *cli.Context) error {
 	syncmode := *utils.GlobalTextMarshaler(ctx, utils.SyncModeFlag.Name).(*downloader.SyncMode)
 	dl := downloader.New(syncmode, chainDb, new(event.TypeMux), chain, nil, nil)
 
-	// Create a source peer to satisfy downloader requests from
-	db, err := ethdb.NewLDBDatabase(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	// Create a source peer to satisfy downloader requests from, along with the
+	// blocks it froze if any
+	var (
+		src = ctx.Args().First()
+		db  ethdb.Database
+		err error
+	)
+	if ancient := filepath.Join(src, "ancient"); common.FileExist(ancient) {
+		db, err = ethdb.NewDatabaseWithFreezer("", src, ctx.GlobalInt(utils.CacheFlag.Name), 256, ancient)
+	} else {
+		db, err = ethdb.NewDatabase("", src, ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	}
 	if err != nil {
 		return err
 	}
@@ -325,9 +360,13 @@ func copyDb(ctx *cli.Context) error {
 	fmt.Printf("Database copy done in %v\n", time.Since(start))
 
 	// Compact the entire database to remove any sync overhead
+	ldb, ok := chainDb.(*ethdb.LDBDatabase)
+	if !ok {
+		return nil
+	}
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = ldb.LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -336,13 +375,24 @@ func copyDb(ctx *cli.Context) error {
 }
 
 func removeDB(ctx *cli.Context) error {
-	stack, _ := makeConfigNode(ctx)
+	stack, cfg := makeConfigNode(ctx)
 
-	for _, name := range []string{"chaindata", "lightchaindata"} {
+	dbdirs := map[string]string{
+		"chaindata":      stack.ResolvePath("chaindata"),
+		"lightchaindata": stack.ResolvePath("lightchaindata"),
+	}
+	// The ancient freezer goes with the chain database, unless kept apart
+	if cfg.Eth.DatabaseFreezer != "" {
+		dbdirs["ancient"] = stack.ResolvePath(cfg.Eth.DatabaseFreezer)
+	}
+	for _, name := range []string{"chaindata", "lightchaindata", "ancient"} {
+		dbdir, ok := dbdirs[name]
+		if !ok {
+			continue
+		}
 		// Ensure the database exists in the first place
 		logger := log.New("database", name)
 
-		dbdir := stack.ResolvePath(name)
 		if !common.FileExist(dbdir) {
 			logger.Info("Database doesn't exist, skipping", "path", dbdir)
 			continue
@@ -37,8 +37,6 @@ import (
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
 	//"github.com/wanchain/go-wanchain/pos/posconfig"
-
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
 )
 
@@ -142,8 +140,6 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 
-	//Init wanpos private db
-	posdb.DbInitAll(cfg.Node.DataDir)
 	posconfig.Init(&cfg.Node, cfg.Eth.NetworkId)
 
 	return stack, cfg
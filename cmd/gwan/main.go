@@ -65,6 +65,8 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
 		utils.EthashCacheDirFlag,
@@ -82,20 +84,25 @@ var (
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolPrivacySlotsFlag,
 		utils.TxPoolLifetimeFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.NoStakingFlag,
+		utils.IncentiveIndexFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
+		utils.GCModeFlag,
+		utils.AncientThresholdFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.EtherbaseFlag,
+		utils.PosSignerFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
 		utils.MiningEnabledFlag,
@@ -193,6 +200,12 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See poscmd.go:
+		posCommand,
+		// See possignercmd.go:
+		posSignerCommand,
+		// See protectioncmd.go:
+		protectionCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -0,0 +1,423 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/light"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	posFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.CacheFlag,
+		utils.TestnetFlag,
+		utils.PlutoFlag,
+		utils.PlutoDevFlag,
+		utils.DevInternalFlag,
+	}
+
+	posCommand = cli.Command{
+		Name:     "pos",
+		Usage:    "Inspect the PoS data of the local chain",
+		Category: "POS COMMANDS",
+		Description: `
+
+Print the PoS data of an epoch as json, read from the chain database of the
+datadir. The database is opened read-only, so the commands can't run while
+gwan is using the datadir with the leveldb engine.
+
+Leader groups are read from the PoS tables of the chain database when the node
+selected them itself, and are recomputed from the state of the epoch's target
+block otherwise. The state of older blocks is only available on archive nodes.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "epochleaders",
+				Usage:     "Print the epoch leaders of an epoch",
+				Action:    utils.MigrateFlags(posEpochLeaders),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the epoch leader group of an epoch, white list leaders included, in the
+order used by the slot leader selection.`,
+			},
+			{
+				Name:      "rbproposers",
+				Usage:     "Print the random beacon proposers of an epoch",
+				Action:    utils.MigrateFlags(posRBProposers),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the random beacon proposer group of an epoch.`,
+			},
+			{
+				Name:      "slotleaders",
+				Usage:     "Print the slot leaders of the blocks of an epoch",
+				Action:    utils.MigrateFlags(posSlotLeaders),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the slot leader schedule of an epoch as realised by the canonical chain:
+the slot of every block of the epoch and the leader proven in its header.`,
+			},
+			{
+				Name:      "random",
+				Usage:     "Print the random beacon value of an epoch",
+				Action:    utils.MigrateFlags(posRandom),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the random beacon value of an epoch, as returned by GetR on the state of
+the chain head.`,
+			},
+			{
+				Name:      "stakers",
+				Usage:     "Print the staker set at the end of an epoch",
+				Action:    utils.MigrateFlags(posStakers),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the stakers registered in the state of the last block of an epoch, or of
+the chain head if the epoch is in progress.`,
+			},
+			{
+				Name:      "incentives",
+				Usage:     "Print the incentive payouts of an epoch",
+				Action:    utils.MigrateFlags(posIncentives),
+				ArgsUsage: "<epochID>",
+				Flags:     posFlags,
+				Description: `
+Print the incentive paid for an epoch to its validators and their delegators.`,
+			},
+		},
+	}
+)
+
+// posLeader is the json form of a member of a leader group.
+type posLeader struct {
+	Index     int            `json:"index"`
+	PubSec256 hexutil.Bytes  `json:"pubSec256"`
+	PubBn256  hexutil.Bytes  `json:"pubBn256,omitempty"`
+	Address   common.Address `json:"address"`
+}
+
+type posLeaderGroup struct {
+	EpochID     uint64      `json:"epochId"`
+	BlockNumber uint64      `json:"targetBlockNumber"`
+	Source      string      `json:"source"` // "db" or "state"
+	Leaders     []posLeader `json:"leaders"`
+}
+
+type posSlot struct {
+	SlotID   uint64         `json:"slotId"`
+	Number   uint64         `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Coinbase common.Address `json:"coinbase"`
+	Leader   hexutil.Bytes  `json:"leader"`
+	Address  common.Address `json:"leaderAddress"`
+}
+
+type posSlotSchedule struct {
+	EpochID uint64    `json:"epochId"`
+	Slots   []posSlot `json:"slots"`
+}
+
+type posRandomValue struct {
+	EpochID     uint64       `json:"epochId"`
+	BlockNumber uint64       `json:"blockNumber"`
+	R           *hexutil.Big `json:"r"`
+}
+
+type posStakerSet struct {
+	EpochID     uint64               `json:"epochId"`
+	BlockNumber uint64               `json:"blockNumber"`
+	Stakers     []*posapi.StakerJson `json:"stakers"`
+}
+
+type posIncentivePayout struct {
+	EpochID  uint64                 `json:"epochId"`
+	Total    *math.HexOrDecimal256  `json:"total"`
+	Remain   *math.HexOrDecimal256  `json:"remain"`
+	Payments []posapi.ValidatorInfo `json:"payments"`
+}
+
+// posInspector reads the PoS data of a read-only chain database.
+type posInspector struct {
+	db           ethdb.Database
+	ctx          *posctx.Context
+	head         *types.Header
+	firstEpochId uint64
+}
+
+// openPosInspector opens the chain database of the datadir read-only and
+// returns it with the epoch argument of the command.
+func openPosInspector(ctx *cli.Context) (*posInspector, uint64) {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an epoch ID argument.")
+	}
+	epochID, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid epoch ID: %v", err)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabaseReadOnly(ctx, stack)
+	for _, name := range []string{posconfig.RbLocalDB, posconfig.EpLocalDB, posconfig.StakerLocalDB, posconfig.IncentiveLocalDB} {
+		if ethdb.DatabaseEngine(stack.ResolvePath(name)) != "" {
+			log.Warn("PoS database isn't migrated to the chain database yet, start gwan once to migrate it", "name", name)
+		}
+	}
+
+	hash := core.GetHeadBlockHash(db)
+	head := core.GetHeader(db, hash, core.GetBlockNumber(db, hash))
+	if head == nil {
+		utils.Fatalf("No chain head in the database")
+	}
+	// without a datadir the context reads the tables of db and migrates nothing
+	pctx := posctx.New("")
+	if err := pctx.SetChainDb(db); err != nil {
+		utils.Fatalf("Could not open the PoS tables: %v", err)
+	}
+	return &posInspector{db: db, ctx: pctx, head: head, firstEpochId: light.FirstEpochId(db)}, epochID
+}
+
+func (p *posInspector) close() {
+	p.ctx.Close()
+	p.db.Close()
+}
+
+// header returns the canonical header of a number, nil if there is none.
+func (p *posInspector) header(number uint64) *types.Header {
+	hash := core.GetCanonicalHash(p.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return core.GetHeader(p.db, hash, number)
+}
+
+// epochLastHeader returns the last canonical header of an epoch, the chain
+// head if the epoch is in progress.
+func (p *posInspector) epochLastHeader(epochID uint64) *types.Header {
+	if header := posUtil.SearchEpochLastHeader(p.head.Number.Uint64(), epochID, p.header); header != nil {
+		return header
+	}
+	if headEpoch, _ := posUtil.CalEpochSlotID(p.head.Time.Uint64()); headEpoch == epochID {
+		return p.head
+	}
+	utils.Fatalf("Epoch %d has no block in the chain", epochID)
+	return nil
+}
+
+// targetHeader returns the block the leader groups of an epoch are selected on,
+// the genesis block for the epochs selected before the chain started.
+func (p *posInspector) targetHeader(epochID uint64) *types.Header {
+	if header := light.EpochTargetHeader(p.db, epochID); header != nil {
+		return header
+	}
+	genesis := p.header(0)
+	if genesisEpoch, _ := posUtil.CalEpochSlotID(genesis.Time.Uint64()); epochID < genesisEpoch+2 {
+		return genesis
+	}
+	utils.Fatalf("The target block of epoch %d isn't in the chain", epochID)
+	return nil
+}
+
+func (p *posInspector) state(header *types.Header) *state.StateDB {
+	statedb, err := state.New(header.Root, state.NewDatabase(p.db))
+	if err != nil {
+		utils.Fatalf("Could not open the state of block %d: %v", header.Number, err)
+	}
+	return statedb
+}
+
+func newPosLeader(index int, pubSec256, pubBn256 []byte) posLeader {
+	leader := posLeader{Index: index, PubSec256: pubSec256, PubBn256: pubBn256}
+	if pub := crypto.ToECDSAPub(pubSec256); pub != nil {
+		leader.Address = crypto.PubkeyToAddress(*pub)
+	}
+	return leader
+}
+
+func printPosJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		utils.Fatalf("Could not encode the result: %v", err)
+	}
+	fmt.Println(string(out))
+}
+
+func posEpochLeaders(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	target := p.targetHeader(epochID)
+	statedb := p.state(target)
+
+	group := posLeaderGroup{EpochID: epochID, BlockNumber: target.Number.Uint64(), Source: "db"}
+	pks := p.ctx.Db(posconfig.EpLocalDB).GetEpochLeaderGroup(epochID)
+	if len(pks) > 0 {
+		info := vm.GetEpochWLInfo(statedb, epochID)
+		if len(pks) == posconfig.EpochLeaderCount-int(info.WlCount.Uint64()) {
+			pks = append(pks, posconfig.EpochLeadersHold[info.WlIndex.Uint64():info.WlIndex.Uint64()+info.WlCount.Uint64()]...)
+		}
+	} else {
+		var err error
+		if pks, err = epochLeader.EpochLeadersFromState(statedb, epochID, p.firstEpochId); err != nil {
+			utils.Fatalf("Could not select the epoch leaders: %v", err)
+		}
+		group.Source = "state"
+	}
+	for i, pk := range pks {
+		group.Leaders = append(group.Leaders, newPosLeader(i, pk, nil))
+	}
+	printPosJSON(group)
+	return nil
+}
+
+func posRBProposers(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	target := p.targetHeader(epochID)
+	group := posLeaderGroup{EpochID: epochID, BlockNumber: target.Number.Uint64(), Source: "db"}
+	if stored := p.ctx.Db(posconfig.RbLocalDB).GetStorageByteArray(epochID); len(stored) > 0 {
+		for i, data := range stored {
+			var proposer posdb.Proposer
+			if err := rlp.DecodeBytes(data, &proposer); err != nil {
+				utils.Fatalf("Invalid random beacon proposer %d: %v", i, err)
+			}
+			group.Leaders = append(group.Leaders, newPosLeader(i, proposer.PubSec256, proposer.PubBn256))
+		}
+	} else {
+		proposers, err := epochLeader.RBProposersFromState(p.state(target), epochID, p.firstEpochId)
+		if err != nil {
+			utils.Fatalf("Could not select the random beacon proposers: %v", err)
+		}
+		for i, proposer := range proposers {
+			group.Leaders = append(group.Leaders, newPosLeader(i, proposer.PubSec256, proposer.PubBn256))
+		}
+		group.Source = "state"
+	}
+	printPosJSON(group)
+	return nil
+}
+
+func posSlotLeaders(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	schedule := posSlotSchedule{EpochID: epochID, Slots: []posSlot{}}
+	first, head := posUtil.FirstPosBlockNumber(), p.head.Number.Uint64()
+	if first == 0 {
+		first = 1 // the genesis block has no leader
+	}
+	if head < first {
+		printPosJSON(schedule)
+		return nil
+	}
+	epochOf := func(header *types.Header) uint64 {
+		epoch, _ := posUtil.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		return epoch
+	}
+	n := first + uint64(sort.Search(int(head-first+1), func(i int) bool {
+		header := p.header(first + uint64(i))
+		return header == nil || epochOf(header) >= epochID
+	}))
+	for ; n <= head; n++ {
+		header := p.header(n)
+		if header == nil || epochOf(header) != epochID {
+			break
+		}
+		pub, err := pluto.SlotLeader(header)
+		if err != nil {
+			utils.Fatalf("Invalid slot proof in block %d: %v", n, err)
+		}
+		_, slotID := posUtil.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		schedule.Slots = append(schedule.Slots, posSlot{
+			SlotID:   slotID,
+			Number:   n,
+			Hash:     header.Hash(),
+			Coinbase: header.Coinbase,
+			Leader:   crypto.FromECDSAPub(pub),
+			Address:  crypto.PubkeyToAddress(*pub),
+		})
+	}
+	printPosJSON(schedule)
+	return nil
+}
+
+func posRandom(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	printPosJSON(posRandomValue{
+		EpochID:     epochID,
+		BlockNumber: p.head.Number.Uint64(),
+		R:           (*hexutil.Big)(vm.GetR(p.state(p.head), epochID, p.firstEpochId)),
+	})
+	return nil
+}
+
+func posStakers(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	header := p.epochLastHeader(epochID)
+	stakers := vm.GetStakersSnap(p.state(header))
+	sort.Slice(stakers, func(i, j int) bool {
+		return bytes.Compare(stakers[i].Address[:], stakers[j].Address[:]) < 0
+	})
+
+	set := posStakerSet{EpochID: epochID, BlockNumber: header.Number.Uint64(), Stakers: []*posapi.StakerJson{}}
+	for i := range stakers {
+		set.Stakers = append(set.Stakers, posapi.ToStakerJson(&stakers[i]))
+	}
+	printPosJSON(set)
+	return nil
+}
+
+func posIncentives(ctx *cli.Context) error {
+	p, epochID := openPosInspector(ctx)
+	defer p.close()
+
+	payments, err := incentive.GetEpochPayDetail(p.ctx, epochID)
+	if err != nil {
+		utils.Fatalf("No incentive paid for epoch %d: %v", epochID, err)
+	}
+	payout := posIncentivePayout{EpochID: epochID, Payments: posapi.ToValidatorInfos(payments)}
+	if total, err := incentive.GetEpochIncentive(p.ctx, epochID); err == nil {
+		payout.Total = (*math.HexOrDecimal256)(total)
+	}
+	if remain, err := incentive.GetEpochRemain(p.ctx, epochID); err == nil {
+		payout.Remain = (*math.HexOrDecimal256)(remain)
+	}
+	printPosJSON(payout)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// plutoGenesisEpoch is the epoch of the timestamp of genesis_pluto.json.
+const plutoGenesisEpoch = 0x59f83144 / (posconfig.SlotTime * posconfig.SlotCount)
+
+func TestPosCommands(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	genesis := filepath.Join("..", "..", "genesis_example", "genesis_pluto.json")
+	runGeth(t, "--datadir", datadir, "--pluto", "init", genesis).WaitExit()
+
+	epoch := strconv.Itoa(plutoGenesisEpoch)
+	geth := runGeth(t, "--datadir", datadir, "--pluto", "pos", "stakers", epoch)
+	geth.ExpectRegexp(`"epochId": ` + epoch + `,\s+"blockNumber": 0,\s+"stakers": \[\s+{\s+"address": "0x[0-9a-f]{40}"`)
+	geth.WaitExit()
+
+	geth = runGeth(t, "--datadir", datadir, "--pluto", "pos", "epochleaders", epoch)
+	geth.ExpectRegexp(`"source": "state",\s+"leaders": \[\s+{\s+"index": 0,`)
+	geth.WaitExit()
+
+	geth = runGeth(t, "--datadir", datadir, "--pluto", "pos", "slotleaders", epoch)
+	geth.ExpectRegexp(`"slots": \[\]`)
+	geth.WaitExit()
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+	"github.com/wanchain/go-wanchain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var posSignerCommand = cli.Command{
+	Name:      "possigner",
+	Usage:     "Serve the keys of a validator to its gwan node",
+	Action:    utils.MigrateFlags(posSigner),
+	ArgsUsage: "[<ipcpath>]",
+	Category:  "POS COMMANDS",
+	Flags: []cli.Flag{
+		utils.DataDirFlag,
+		utils.KeyStoreDirFlag,
+		utils.UnlockedAccountFlag,
+		utils.PasswordFileFlag,
+		utils.TestnetFlag,
+		utils.PlutoFlag,
+		utils.PlutoDevFlag,
+		utils.DevInternalFlag,
+	},
+	Description: `
+Unlock the validator account given with --unlock and serve its keys on an IPC
+endpoint, <datadir>/gwan/possigner.ipc by default. The keys never leave the
+signer process: it signs the blocks and the PoS transactions of the validator,
+and computes its slot leader proofs, secret message arrays and random beacon
+signature shares.
+
+Start the validator node with --pos.signer <ipcpath> instead of unlocking the
+account in the node.`,
+}
+
+func posSigner(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	address := ctx.GlobalString(utils.UnlockedAccountFlag.Name)
+	if address == "" || strings.Contains(address, ",") {
+		utils.Fatalf("One validator account must be given with --%s", utils.UnlockedAccountFlag.Name)
+	}
+	account, _ := unlockAccount(ctx, ks, strings.TrimSpace(address), 0, utils.MakePasswordList(ctx))
+	wallet, err := stack.AccountManager().Find(account)
+	if err != nil {
+		utils.Fatalf("Could not find the validator account: %v", err)
+	}
+	signer, err := possigner.FromWallet(wallet, account.Address)
+	if err != nil {
+		utils.Fatalf("Could not load the validator keys: %v", err)
+	}
+	if signer.Bn256PublicKey() == nil {
+		log.Warn("Validator account has no bn256 key, it can't propose random beacons")
+	}
+
+	srv, err := possigner.NewServer(signer)
+	if err != nil {
+		utils.Fatalf("Could not create the signer API: %v", err)
+	}
+	endpoint := ctx.Args().First()
+	if endpoint == "" {
+		endpoint = stack.ResolvePath("possigner.ipc")
+	}
+	listener, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		utils.Fatalf("Could not open the IPC endpoint: %v", err)
+	}
+	go srv.ServeListener(listener)
+	log.Info("Validator signer started", "address", account.Address, "endpoint", endpoint)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	<-sigc
+	log.Info("Validator signer stopping")
+
+	listener.Close()
+	srv.Stop()
+	return nil
+}
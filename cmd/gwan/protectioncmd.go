@@ -0,0 +1,132 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/signprotect"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	protectionFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.DBEngineFlag,
+		utils.TestnetFlag,
+		utils.PlutoFlag,
+		utils.PlutoDevFlag,
+		utils.DevInternalFlag,
+	}
+
+	protectionCommand = cli.Command{
+		Name:     "protection",
+		Usage:    "Manage the double-sign protection database of the validators",
+		Category: "POS COMMANDS",
+		Description: `
+
+The double-sign protection database records the slots a validator sealed a
+block for and the random beacon payloads it sent, so that it never signs two
+conflicting messages for them. Move it with the validator keys when migrating
+a validator to another node, the commands can't run while gwan is using the
+datadir.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the records of the protection database",
+				Action:    utils.MigrateFlags(protectionExport),
+				ArgsUsage: "<file>",
+				Flags:     protectionFlags,
+				Description: `
+Write the records of the protection database of the datadir to a JSON
+interchange file.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import records into the protection database",
+				Action:    utils.MigrateFlags(protectionImport),
+				ArgsUsage: "<file>",
+				Flags:     protectionFlags,
+				Description: `
+Merge the records of a JSON interchange file into the protection database of
+the datadir. A record conflicting with a local one blocks its slot or random
+beacon stage for good.`,
+			},
+		},
+	}
+)
+
+// openProtection opens the protection database of the datadir and returns it
+// with the hash of the genesis block of the chain.
+func openProtection(ctx *cli.Context) (*signprotect.DB, common.Hash) {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a file argument.")
+	}
+	stack, cfg := makeConfigNode(ctx)
+
+	// The genesis of an initialised chain, or else of the selected network
+	var genesis common.Hash
+	if db, err := stack.OpenDatabaseReadOnly("chaindata", 0, 0, ctx.GlobalString(utils.AncientFlag.Name)); err == nil {
+		genesis = core.GetCanonicalHash(db, 0)
+		db.Close()
+	}
+	if genesis == (common.Hash{}) {
+		spec := utils.MakeGenesis(ctx)
+		if spec == nil {
+			spec = core.DefaultGenesisBlock()
+		}
+		block, _ := spec.ToBlock()
+		genesis = block.Hash()
+	}
+
+	db, err := signprotect.Open(cfg.Node.DBEngine, stack.ResolvePath(posconfig.ProtectionDB))
+	if err != nil {
+		utils.Fatalf("Could not open the protection database: %v", err)
+	}
+	return db, genesis
+}
+
+func protectionExport(ctx *cli.Context) error {
+	db, genesis := openProtection(ctx)
+	defer db.Close()
+
+	data, err := db.Export(genesis)
+	if err != nil {
+		utils.Fatalf("Could not export the protection database: %v", err)
+	}
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		utils.Fatalf("Could not encode the records: %v", err)
+	}
+	if err := ioutil.WriteFile(ctx.Args().First(), out, 0600); err != nil {
+		utils.Fatalf("Could not write the records: %v", err)
+	}
+	log.Info("Exported the protection database", "validators", len(data.Data), "file", ctx.Args().First())
+	return nil
+}
+
+func protectionImport(ctx *cli.Context) error {
+	db, genesis := openProtection(ctx)
+	defer db.Close()
+
+	in, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not read the records: %v", err)
+	}
+	var data signprotect.Interchange
+	if err := json.Unmarshal(in, &data); err != nil {
+		utils.Fatalf("Could not decode the records: %v", err)
+	}
+	if err := db.Import(&data, genesis); err != nil {
+		utils.Fatalf("Could not import the records: %v", err)
+	}
+	log.Info("Imported into the protection database", "validators", len(data.Data), "file", ctx.Args().First())
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/pos/signprotect"
+)
+
+func readInterchange(t *testing.T, file string) *signprotect.Interchange {
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := new(signprotect.Interchange)
+	if err := json.Unmarshal(in, data); err != nil {
+		t.Fatalf("invalid interchange file: %v", err)
+	}
+	return data
+}
+
+func writeInterchange(t *testing.T, file string, data *signprotect.Interchange) {
+	out, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProtectionCommands(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	genesis := filepath.Join("..", "..", "genesis_example", "genesis_pluto.json")
+	runGeth(t, "--datadir", datadir, "--pluto", "init", genesis).WaitExit()
+
+	empty := filepath.Join(datadir, "empty.json")
+	runGeth(t, "--datadir", datadir, "--pluto", "protection", "export", empty).WaitExit()
+	data := readInterchange(t, empty)
+	if data.Metadata.GenesisHash == (common.Hash{}) || len(data.Data) != 0 {
+		t.Fatalf("export of an empty database mismatch: %+v", data)
+	}
+
+	data.Data = []signprotect.ValidatorRecords{{
+		Address:      common.Address{1},
+		SignedBlocks: []signprotect.SignedBlock{{Epoch: 18000, Slot: 5, SigningRoot: common.Hash{2}}},
+		SignedRB:     []signprotect.SignedRB{{Epoch: 18000, ProposerID: 3, Kind: "sigShare", PayloadHash: common.Hash{4}}},
+	}}
+	records := filepath.Join(datadir, "records.json")
+	writeInterchange(t, records, data)
+	runGeth(t, "--datadir", datadir, "--pluto", "protection", "import", records).WaitExit()
+
+	exported := filepath.Join(datadir, "exported.json")
+	runGeth(t, "--datadir", datadir, "--pluto", "protection", "export", exported).WaitExit()
+	if have := readInterchange(t, exported); !reflect.DeepEqual(have, data) {
+		t.Errorf("exported records mismatch:\nhave %+v\nwant %+v", have, data)
+	}
+
+	data.Metadata.GenesisHash = common.Hash{1}
+	writeInterchange(t, records, data)
+	geth := runGeth(t, "--datadir", datadir, "--pluto", "protection", "import", records)
+	geth.ExpectRegexp(`Fatal: Could not import the records: interchange data is for another chain`)
+	geth.WaitExit()
+}
@@ -68,6 +68,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
@@ -78,6 +80,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.PlutoDevFlag,
 			utils.DevModeFlag,
 			utils.SyncModeFlag,
+			utils.IncentiveIndexFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
@@ -108,6 +111,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolPrivacySlotsFlag,
 			utils.TxPoolLifetimeFlag,
 		},
 	},
@@ -115,6 +119,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "PERFORMANCE TUNING",
 		Flags: []cli.Flag{
 			utils.CacheFlag,
+			utils.GCModeFlag,
+			utils.AncientThresholdFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -169,6 +175,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MiningEnabledFlag,
 			utils.MinerThreadsFlag,
 			utils.EtherbaseFlag,
+			utils.PosSignerFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
@@ -117,6 +117,14 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for the ancient blocks (default = inside chaindata)",
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: `Key-value engine of the databases ("leveldb", "bolt"), default = the existing one or leveldb`,
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -198,6 +206,10 @@ var (
 		Name:  "noStaking",
 		Usage: "Disable staking",
 	}
+	IncentiveIndexFlag = cli.BoolFlag{
+		Name:  "incentiveindex",
+		Usage: "Index the incentives paid per address (pos_getIncentiveHistory)",
+	}
 
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
@@ -288,6 +300,11 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: eth.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolPrivacySlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.privacyslots",
+		Usage: "Maximum number of privacy transaction slots for all accounts",
+		Value: eth.DefaultConfig.TxPool.PrivacySlots,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
@@ -299,6 +316,16 @@ var (
 		Usage: "Megabytes of memory allocated to internal caching (min 16MB / database forced)",
 		Value: 256,
 	}
+	GCModeFlag = cli.StringFlag{
+		Name:  "gcmode",
+		Usage: `Blockchain garbage collection mode ("full", "archive"), "full" only keeps the recent and the epoch boundary states`,
+		Value: "archive",
+	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks kept out of the ancient freezer (0 = don't freeze)",
+		Value: eth.DefaultConfig.AncientThreshold,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -324,6 +351,10 @@ var (
 		Usage: "Public address for block mining rewards (default = first account created)",
 		Value: "0",
 	}
+	PosSignerFlag = cli.StringFlag{
+		Name:  "pos.signer",
+		Usage: "IPC path or URL of the remote signer holding the validator keys (default = etherbase keystore keys)",
+	}
 	GasPriceFlag = BigFlag{
 		Name:  "gasprice",
 		Usage: "Minimal gas price to accept for mining a transactions",
@@ -801,7 +832,7 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 
 	accounts := ks.Accounts()
-	if (cfg.Etherbase == common.Address{}) {
+	if (cfg.Etherbase == common.Address{}) && cfg.PosSigner == "" {
 		if len(accounts) > 0 {
 			cfg.Etherbase = accounts[0].Address
 		} else {
@@ -895,6 +926,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "pluto")
 	}
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		engine := ctx.GlobalString(DBEngineFlag.Name)
+		if engine != ethdb.EngineLevelDB && engine != ethdb.EngineBolt {
+			Fatalf("--%s must be either '%s' or '%s'", DBEngineFlag.Name, ethdb.EngineLevelDB, ethdb.EngineBolt)
+		}
+		cfg.DBEngine = engine
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
@@ -943,6 +981,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrivacySlotsFlag.Name) {
+		cfg.PrivacySlots = ctx.GlobalUint64(TxPoolPrivacySlotsFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
@@ -997,6 +1038,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	checkExclusive(ctx, DevModeFlag, TestnetFlag, DevInternalFlag, PlutoFlag, PlutoDevFlag)
 	checkExclusive(ctx, FastSyncFlag, LightModeFlag, SyncModeFlag)
 
+	if ctx.GlobalIsSet(PosSignerFlag.Name) {
+		cfg.PosSigner = ctx.GlobalString(PosSignerFlag.Name)
+	}
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
@@ -1014,6 +1058,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(NoStakingFlag.Name) {
 		params.SetNoStaking()
 	}
+	if ctx.GlobalIsSet(IncentiveIndexFlag.Name) {
+		cfg.IncentiveIndex = ctx.GlobalBool(IncentiveIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
@@ -1029,6 +1076,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	}
+	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.AncientThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
@@ -1158,11 +1217,26 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name)
 		handles = makeDatabaseHandles()
 	)
-	name := "chaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
 	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name))
+	}
+	if err != nil {
+		Fatalf("Could not open database: %v", err)
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	return chainDb
+}
+
+// MakeChainDatabaseReadOnly opens the existing full node chain database of
+// the datadir for reading only.
+func MakeChainDatabaseReadOnly(ctx *cli.Context, stack *node.Node) ethdb.Database {
+	cache := ctx.GlobalInt(CacheFlag.Name)
+	chainDb, err := stack.OpenDatabaseReadOnly("chaindata", cache, makeDatabaseHandles(), ctx.GlobalString(AncientFlag.Name))
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1210,7 +1284,16 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		}
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
-	chain, err = core.NewBlockChain(chainDb, config, engine, vmcfg, nil)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	}
+	cache := &core.CacheConfig{
+		Disabled:         ctx.GlobalString(GCModeFlag.Name) == "archive",
+		TrieNodeLimit:    eth.DefaultConfig.TrieCache,
+		TrieTimeLimit:    eth.DefaultConfig.TrieTimeout,
+		AncientThreshold: ctx.GlobalUint64(AncientThresholdFlag.Name),
+	}
+	chain, err = core.NewBlockChainWithCache(chainDb, cache, config, engine, vmcfg, nil)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
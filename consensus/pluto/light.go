@@ -0,0 +1,268 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package pluto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
+)
+
+const (
+	inmemoryProofContexts = 16               // Number of recent epoch proof contexts to keep in memory
+	lightRetrievalTimeout = 30 * time.Second // Timeout of the on demand retrievals of one header
+)
+
+var errSlotMismatch = errors.New("epochId or slotid do not match")
+
+// LightPosBackend provides the PoS data a light client needs to verify slot
+// leader proofs, usually by on demand retrieval from full nodes.
+type LightPosBackend interface {
+	// EpochLeaders returns the epoch leader group of an epoch.
+	EpochLeaders(ctx context.Context, epochID uint64) ([][]byte, error)
+	// Random returns the random beacon value of an epoch as seen by header.
+	Random(ctx context.Context, header *types.Header, epochID uint64) (*big.Int, error)
+	// State returns the state of header.
+	State(ctx context.Context, header *types.Header) (*state.StateDB, error)
+}
+
+// LightPluto is the Pluto engine of light clients. Headers are verified with
+// the data of a LightPosBackend instead of the local PoS databases, which a
+// light client doesn't maintain.
+type LightPluto struct {
+	*Pluto
+
+	backend  LightPosBackend
+	contexts *lru.ARCCache // proof contexts of recent epochs, keyed by epoch and parent
+}
+
+// NewLight creates a Pluto engine verifying headers with the data of backend.
+func NewLight(config *params.PlutoConfig, db ethdb.Database, backend LightPosBackend, posCtx *posctx.Context) *LightPluto {
+	contexts, _ := lru.NewARC(inmemoryProofContexts)
+	return &LightPluto{
+		Pluto:    New(config, db, posCtx),
+		backend:  backend,
+		contexts: contexts,
+	}
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (c *LightPluto) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return c.verifyHeader(chain, header, nil)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers.
+func (c *LightPluto) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := c.verifyHeader(chain, header, headers[:i])
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// VerifySeal checks the signature and the slot leader proof of a header.
+func (c *LightPluto) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return c.verifySeal(chain, header, nil)
+}
+
+func (c *LightPluto) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	// Don't waste time checking blocks from the future
+	if header.Time.Cmp(new(big.Int).SetUint64(c.posCtx.Now())) > 0 {
+		return consensus.ErrFutureBlock
+	}
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	// The genesis block is the always valid dead-end
+	if header.Number.Uint64() == 0 {
+		return nil
+	}
+	return c.verifySeal(chain, header, parents)
+}
+
+func (c *LightPluto) verifySeal(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	parent := lightParent(chain, header, parents)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+
+	epidTime, slIdTime := posUtil.CalEpochSlotID(header.Time.Uint64())
+	epochID, slotID := posUtil.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	if epidTime != epochID || slIdTime != slotID || header.Difficulty.Cmp(new(big.Int).SetUint64(math.MaxUint64)) > 0 {
+		return errSlotMismatch
+	}
+	if len(header.Extra) > 512 || len(header.Extra) <= extraSeal {
+		return errUnauthorized
+	}
+
+	proof, proofMeg, err := slotleader.DecodeSlotProof(epochID, header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil || len(proofMeg) != slotleader.LenProofMeg {
+		return errUnauthorized
+	}
+	signer, err := ecrecover(header, c.signatures)
+	if err != nil {
+		return errUnauthorized
+	}
+	if signer != crypto.PubkeyToAddress(*proofMeg[0]) {
+		log.Debug("Pk signer verify failed in light verifySeal", "number", number, "signer", signer.Hex())
+		return errUnauthorized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lightRetrievalTimeout)
+	defer cancel()
+
+	proofCtx, err := c.proofContext(ctx, chain, parent, epochID)
+	if err != nil {
+		return err
+	}
+	if !slotleader.VerifySlotProofWithContext(proofCtx, slotID, proof, proofMeg) {
+		log.Debug("Light slot proof verify failed", "number", number, "epochID", epochID, "slotID", slotID)
+		return errUnauthorized
+	}
+	return nil
+}
+
+// proofContext gathers the data the slot proofs of epochID are checked against
+// from the state of parent, following SLS.VerifySlotProof.
+func (c *LightPluto) proofContext(ctx context.Context, chain consensus.ChainReader, parent *types.Header, epochID uint64) (*slotleader.SlotProofContext, error) {
+	if epochID <= lightFirstEpochId(chain)+2 {
+		return c.genesisContext(ctx, chain)
+	}
+	// stage two data depends on the parent, so do the cached contexts
+	key := [2]interface{}{epochID, parent.Hash()}
+	if cached, ok := c.contexts.Get(key); ok {
+		return cached.(*slotleader.SlotProofContext), nil
+	}
+
+	leaders, err := c.backend.EpochLeaders(ctx, epochID-1)
+	if err != nil {
+		return nil, err
+	}
+	if len(leaders) == 0 {
+		return c.genesisContext(ctx, chain)
+	}
+	statedb, err := c.backend.State(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	valid, alphaPKi, err := slotleader.StageTwoFromState(statedb, epochID-1)
+	if err != nil || !hasValidIndex(valid) {
+		// no stage two tx on the chain
+		return c.genesisContext(ctx, chain)
+	}
+	r, err := c.backend.Random(ctx, parent, epochID)
+	if err != nil {
+		return nil, err
+	}
+
+	pks := make([]*ecdsa.PublicKey, len(leaders))
+	for i, leader := range leaders {
+		pks[i] = crypto.ToECDSAPub(leader)
+	}
+	proofCtx := &slotleader.SlotProofContext{
+		EpochID:      epochID,
+		EpochLeaders: pks,
+		Random:       r.Bytes(),
+		ValidIndexes: valid,
+		AlphaPKi:     alphaPKi,
+	}
+	c.contexts.Add(key, proofCtx)
+	return proofCtx, nil
+}
+
+// genesisContext returns the context built from the default epoch leaders of
+// the white list in the genesis state.
+func (c *LightPluto) genesisContext(ctx context.Context, chain consensus.ChainReader) (*slotleader.SlotProofContext, error) {
+	if cached, ok := c.contexts.Get(uint64(0)); ok {
+		return cached.(*slotleader.SlotProofContext), nil
+	}
+
+	var whiteList []string
+	if c.posCtx.SelfTestMode() {
+		whiteList = posconfig.WhiteListOrig[:]
+	} else {
+		genesis := chain.GetHeaderByNumber(0)
+		if genesis == nil {
+			return nil, errUnknownBlock
+		}
+		statedb, err := c.backend.State(ctx, genesis)
+		if err != nil {
+			return nil, err
+		}
+		info := vm.GetEpochWLInfo(statedb, 0)
+		whiteList = posconfig.WhiteList[info.WlIndex.Uint64() : info.WlIndex.Uint64()+info.WlCount.Uint64()]
+	}
+
+	proofCtx := slotleader.NewGenesisProofContext(slotleader.DefaultEpochLeadersPK(whiteList))
+	c.contexts.Add(uint64(0), proofCtx)
+	return proofCtx, nil
+}
+
+// lightFirstEpochId returns the epoch of the first pos block of chain, zero
+// if the chain hasn't reached pos.
+func lightFirstEpochId(chain consensus.ChainReader) uint64 {
+	header := chain.GetHeaderByNumber(posUtil.FirstPosBlockNumber())
+	if header == nil {
+		return 0
+	}
+	epochID, _ := posUtil.CalEpSlbyTd(header.Difficulty.Uint64())
+	return epochID
+}
+
+func lightParent(chain consensus.ChainReader, header *types.Header, parents []*types.Header) *types.Header {
+	number := header.Number.Uint64()
+
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return nil
+	}
+	return parent
+}
+
+func hasValidIndex(valid [posconfig.EpochLeaderCount]bool) bool {
+	for _, v := range valid {
+		if v {
+			return true
+		}
+	}
+	return false
+}
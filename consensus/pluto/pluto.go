@@ -18,6 +18,7 @@
 package pluto
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"math"
@@ -31,7 +32,6 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/wanchain/go-wanchain/accounts"
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus"
@@ -47,10 +47,13 @@ import (
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
+	"sync/atomic"
 )
 
 const (
@@ -76,7 +79,6 @@ var (
 
 	diffInTurn      = big.NewInt(2) // Block difficulty for in-turn signatures
 	diffNoTurn      = big.NewInt(1) // Block difficulty for out-of-turn signatures
-	lastEpochSlotId = uint64(0)
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -213,6 +215,20 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 	return signer, nil
 }
 
+// SlotLeader returns the public key of the slot leader of a pos header, as
+// proven in its extra data. The seal of the header isn't verified.
+func SlotLeader(header *types.Header) (*ecdsa.PublicKey, error) {
+	if len(header.Extra) <= extraSeal {
+		return nil, errMissingSignature
+	}
+	epochID, _ := posUtil.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	_, proofMeg, err := slotleader.DecodeSlotProof(epochID, header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil {
+		return nil, err
+	}
+	return proofMeg[0], nil
+}
+
 // Pluto is the proof-of-authority consensus engine proposed to support the
 // Ethereum testnet following the Ropsten attacks.
 type Pluto struct {
@@ -228,12 +244,16 @@ type Pluto struct {
 	signFn SignerFn       // Signer function to authorize hashes with
 	lock   sync.RWMutex   // Protects the signer fields
 
-	key *keystore.Key // Unlocked key
+	posSigner possigner.Signer // Signer of the slot leader proofs
+
+	posCtx *posctx.Context // PoS context of the node
+
+	lastEpochSlotId uint64 // epoch and slot of the last sealed block, accessed atomically
 }
 
 // New creates a Pluto proof-of-authority consensus engine with the initial
-// signers set to the ones provided by the user.
-func New(config *params.PlutoConfig, db ethdb.Database) *Pluto {
+// signers set to the ones provided by the user, running the PoS services of posCtx.
+func New(config *params.PlutoConfig, db ethdb.Database, posCtx *posctx.Context) *Pluto {
 	// Set any missing consensus parameters to their defaults
 	conf := *config
 	if conf.Epoch == 0 {
@@ -243,13 +263,23 @@ func New(config *params.PlutoConfig, db ethdb.Database) *Pluto {
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
 
-	return &Pluto{
+	c := &Pluto{
 		config:     &conf,
 		db:         db,
 		recents:    recents,
 		signatures: signatures,
 		proposals:  make(map[common.Address]bool),
+		posCtx:     posCtx,
+	}
+	if posCtx != nil {
+		posCtx.SetSealVerifier(c)
 	}
+	return c
+}
+
+// PosContext returns the PoS context of the node.
+func (c *Pluto) PosContext() *posctx.Context {
+	return c.posCtx
 }
 
 // Author implements consensus.Engine, returning the Ethereum address recovered
@@ -296,7 +326,7 @@ func (c *Pluto) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	//number := header.Number.Uint64()
 
 	// Don't waste time checking blocks from the future
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(new(big.Int).SetUint64(c.posCtx.Now())) > 0 {
 		return consensus.ErrFutureBlock
 	}
 
@@ -542,6 +572,21 @@ func (c *Pluto) VerifySeal(chain consensus.ChainReader, header *types.Header) er
 	return c.verifySeal(chain, header, nil, true)
 }
 
+// RecoverSealer implements util.SealVerifier, checking the seal of a header
+// that may not be part of the chain, as in the evidence of a slot leader
+// equivocation. The slot leader proof isn't verified, it needs the local pos
+// data of the epoch.
+func (c *Pluto) RecoverSealer(header *types.Header) (*ecdsa.PublicKey, common.Hash, error) {
+	if err := c.verifySeal(nil, header, nil, false); err != nil {
+		return nil, common.Hash{}, err
+	}
+	pk, err := SlotLeader(header)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	return pk, sigHash(header), nil
+}
+
 func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []*types.Header) error {
 	// Verifying the genesis block is not supported
 	number := header.Number.Uint64()
@@ -551,7 +596,7 @@ func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []
 
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(c.posCtx)
 
 	proof, proofMeg, err := s.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
 
@@ -593,7 +638,7 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 		return errors.New("epochId or slotid do not match")
 	}
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(c.posCtx)
 
 	if len(header.Extra) > 512 { // proof,proofmsg,sign
 		log.SyslogErr("Header extra info length is too long")
@@ -761,18 +806,35 @@ func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, minin
 // rewards given, and returns the final block.
 func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
-	if posconfig.FirstEpochId != 0 && epochID > posconfig.FirstEpochId+2 && epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
+	firstEpochId := c.posCtx.FirstEpochId()
+	if firstEpochId != 0 && epochID > firstEpochId+2 && epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
 		log.Debug("--------Incentive Start--------", "number", header.Number.String(), "epochID", epochID)
 		snap := state.Snapshot()
-		if !incentive.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs) {
+		if !incentive.Run(c.posCtx, chain, header.ParentHash, state, epochID-posconfig.IncentiveDelayEpochs) {
 			log.SyslogAlert("********Incentive Failed********", "number", header.Number.String(), "epochID", epochID)
 			state.RevertToSnapshot(snap)
 		} else {
 			log.Debug("--------Incentive Finish--------", "number", header.Number.String(), "epochID", epochID)
 		}
 
+		// The jailing logs are part of the receipt of the last transaction, so
+		// the jailing waits for a block with transactions. The block hash of
+		// the logs is only known here for complete headers, the miner sets it
+		// once the block is sealed.
+		if chain.Config().IsPosJail(header.Number) && len(receipts) > 0 {
+			last := receipts[len(receipts)-1]
+			state.Prepare(last.TxHash, header.Hash(), len(receipts)-1)
+			snap = state.Snapshot()
+			if !incentive.Jail(c.posCtx, chain.Config().JailConfig(), state, header.Number, epochID-posconfig.IncentiveDelayEpochs) {
+				log.SyslogErr("Jail failed.", "number", header.Number.String(), "epochID", epochID)
+				state.RevertToSnapshot(snap)
+			}
+			last.Logs = state.GetLogs(last.TxHash)
+			last.Bloom = types.CreateBloom(types.Receipts{last})
+		}
+
 		snap = state.Snapshot()
-		if !epochLeader.StakeOutRun(state, epochID) {
+		if !epochLeader.StakeOutRun(c.posCtx, state, epochID) {
 			log.SyslogErr("Stake Out failed.")
 			state.RevertToSnapshot(snap)
 		}
@@ -795,15 +857,15 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
-// Authorize injects a private key into the consensus engine to mint new blocks
-// with.
-func (c *Pluto) Authorize(signer common.Address, signFn SignerFn, key *keystore.Key) {
+// Authorize injects the validator signer into the consensus engine to mint new
+// blocks with.
+func (c *Pluto) Authorize(signer common.Address, signFn SignerFn, posSigner possigner.Signer) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	c.signer = signer
 	c.signFn = signFn
-	c.key = key
+	c.posSigner = posSigner
 }
 
 // Seal implements consensus.Engine, attempting to create a sealed block using
@@ -822,8 +884,11 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	c.lock.RLock()
-	signer, signFn, key := c.signer, c.signFn, c.key
+	signer, signFn, posSigner := c.signer, c.signFn, c.posSigner
 	c.lock.RUnlock()
+	if posSigner == nil {
+		return nil, errUnauthorized
+	}
 
 	// Bail out if we're unauthorized to sign a block
 	// snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
@@ -838,11 +903,11 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	epochId, slotId := util.CalEpochSlotID(header.Time.Uint64())
 	epochSlotId += slotId << 8
 	epochSlotId += epochId << 32
-	if epochSlotId <= lastEpochSlotId {
+	if epochSlotId <= atomic.LoadUint64(&c.lastEpochSlotId) {
 		return nil, nil
 	}
-	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&c.key.PrivateKey.PublicKey))
-	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
+	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(posSigner.PublicKey()))
+	leaderPub, err := slotleader.GetSlotLeaderSelection(c.posCtx).GetSlotLeader(epochId, slotId)
 	if err != nil {
 		return nil, err
 	}
@@ -854,7 +919,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	log.Info("Generate a new block", "number", number, "epochID", epochId, "slotId", slotId, "curTime", time.Now(),
 		"header.Time", header.Time)
 
-	//leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
+	//leaderPub, err := slotleader.GetSlotLeaderSelection(c.posCtx).GetSlotLeader(epochId, slotId)
 	//if err != nil {
 	//	return nil, err
 	//}
@@ -870,8 +935,8 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	header.Difficulty.SetUint64(epochSlotId)
 	header.Coinbase = signer
 
-	s := slotleader.GetSlotLeaderSelection()
-	buf, err := s.PackSlotProof(epochId, slotId, key.PrivateKey)
+	s := slotleader.GetSlotLeaderSelection(c.posCtx)
+	buf, err := s.PackSlotProof(epochId, slotId, posSigner)
 	if err != nil {
 		log.Warn("PackSlotProof failed in Seal", "epochID", epochId, "slotID", slotId, "error", err.Error())
 		return nil, err
@@ -883,6 +948,16 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	copy(header.Extra[:len(buf)], buf)
 	header.Difficulty.SetUint64(epochSlotId)
 
+	// Never seal two blocks for a slot, even across restarts
+	protection, err := c.posCtx.Protection()
+	if err != nil {
+		return nil, err
+	}
+	if err := protection.SignBlock(signer, epochId, slotId, sigHash(header)); err != nil {
+		log.Error("Refusing to seal a second block for the slot", "epochID", epochId, "slotID", slotId, "err", err)
+		return nil, err
+	}
+
 	sighash, err := signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
 	if err != nil {
 		return nil, err
@@ -891,7 +966,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 
 	log.Debug("signature", "hex", hex.EncodeToString(sighash))
 	log.Debug("sigHash(header)", "Bytes", hex.EncodeToString(sigHash(header).Bytes()))
-	log.Debug("Packed slotleader proof info success", "epochID", epochId, "slotID", slotId, "len", len(header.Extra), "pk", hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey)))
+	log.Debug("Packed slotleader proof info success", "epochID", epochId, "slotID", slotId, "len", len(header.Extra), "pk", hex.EncodeToString(crypto.FromECDSAPub(posSigner.PublicKey())))
 
 	err = c.verifySeal(nil, header, nil, false)
 	if err != nil {
@@ -904,7 +979,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 		log.Warn("Seal error", "error", err.Error())
 		return nil, err
 	}
-	lastEpochSlotId = epochSlotId
+	atomic.StoreUint64(&c.lastEpochSlotId, epochSlotId)
 	return block.WithSeal(header), nil
 }
 
@@ -375,7 +375,7 @@ func TestVoting(t *testing.T) {
 		// Pass all the headers through pluto and ensure tallying succeeds
 		head := headers[len(headers)-1]
 
-		snap, err := New(&params.PlutoConfig{Epoch: tt.epoch}, db).snapshot(&testerChainReader{db: db}, head.Number.Uint64(), head.Hash(), headers)
+		snap, err := New(&params.PlutoConfig{Epoch: tt.epoch}, db, nil).snapshot(&testerChainReader{db: db}, head.Number.Uint64(), head.Hash(), headers)
 		if err != nil {
 			t.Errorf("test %d: failed to create voting snapshot: %v", i, err)
 			continue
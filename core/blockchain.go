@@ -21,7 +21,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"io"
 	"math/big"
 	mrand "math/rand"
@@ -44,9 +43,11 @@ import (
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/trie"
+	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
 var (
@@ -64,6 +65,7 @@ const (
 	//maxTimeFutureBlocks = 30
 	maxTimeFutureBlocks = 3
 	badBlockLimit       = 10
+	triesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
@@ -71,6 +73,15 @@ const (
 	INITRESTARTING = 0
 )
 
+// CacheConfig contains the configuration values for the trie caching/pruning
+// that's resident in a blockchain.
+type CacheConfig struct {
+	Disabled         bool          // Whether to disable trie write caching (archive node)
+	TrieNodeLimit    int           // Memory limit (MB) at which to flush the current in-memory trie to disk
+	TrieTimeLimit    time.Duration // Time limit after which to flush the current in-memory trie to disk
+	AncientThreshold uint64        // Number of recent blocks kept out of the freezer (0 = don't freeze)
+}
+
 // BlockChain represents the canonical chain given a database with a genesis
 // block. The Blockchain manages chain imports, reverts, chain reorganisations.
 //
@@ -86,7 +97,8 @@ const (
 // included in the canonical one where as GetBlockByNumber always represents the
 // canonical chain.
 type BlockChain struct {
-	config *params.ChainConfig // chain & network configuration
+	config      *params.ChainConfig // chain & network configuration
+	cacheConfig *CacheConfig        // Cache configuration for pruning
 
 	hc            *HeaderChain
 	chainDb       ethdb.Database
@@ -108,6 +120,8 @@ type BlockChain struct {
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	triegc       *prque.Prque   // Priority queue mapping block numbers to tries to gc
+	gcproc       time.Duration  // Accumulates canonical block processing for trie dumping
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -122,12 +136,16 @@ type BlockChain struct {
 	engine    consensus.Engine
 	posEngine consensus.Engine
 	agents    []consensus.EngineSwitcher
-	processor Processor // block processor interface
-	validator Validator // block and state validator interface
+	posCtx    *posctx.Context // PoS context of the node
+	ownPosCtx bool            // whether posCtx was created by the chain
+	processor Processor       // block processor interface
+	validator Validator       // block and state validator interface
 	vmConfig  vm.Config
 
 	badBlocks *lru.Cache // Bad block cache
 
+	stableBlockFn func() uint64 // Highest irreversible block, caps the freezer
+
 	CurrentEpochId int64
 
 	slotValidator Validator
@@ -143,11 +161,26 @@ type BlockChain struct {
 
 }
 
+// posContextProvider is implemented by the consensus engines running pos.
+type posContextProvider interface {
+	PosContext() *posctx.Context
+}
+
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default Ethereum Validator and
 // Processor.
 //func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine consensus.Engine, vmConfig vm.Config, posEngine consensus.Engine) (*BlockChain, error) {
 func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine consensus.Engine, vmConfig vm.Config, posEngines ...consensus.Engine) (*BlockChain, error) {
+	return NewBlockChainWithCache(chainDb, nil, config, engine, vmConfig, posEngines...)
+}
+
+// NewBlockChainWithCache returns a block chain like NewBlockChain, pruning
+// its states as configured by cacheConfig. A nil cacheConfig keeps all the
+// states on disk, like an archive node.
+func NewBlockChainWithCache(chainDb ethdb.Database, cacheConfig *CacheConfig, config *params.ChainConfig, engine consensus.Engine, vmConfig vm.Config, posEngines ...consensus.Engine) (*BlockChain, error) {
+	if cacheConfig == nil {
+		cacheConfig = &CacheConfig{Disabled: true}
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -156,8 +189,10 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 
 	bc := &BlockChain{
 		config:       config,
+		cacheConfig:  cacheConfig,
 		chainDb:      chainDb,
 		stateCache:   state.NewDatabase(chainDb),
+		triegc:       prque.New(),
 		quit:         make(chan struct{}),
 		bodyCache:    bodyCache,
 		bodyRLPCache: bodyRLPCache,
@@ -184,6 +219,16 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	} else {
 		bc.posEngine = nil
 	}
+	if p, ok := bc.posEngine.(posContextProvider); ok {
+		bc.posCtx = p.PosContext()
+	} else if p, ok := engine.(posContextProvider); ok {
+		bc.posCtx = p.PosContext()
+	} else {
+		bc.posCtx, bc.ownPosCtx = posctx.New(""), true
+		if err := bc.posCtx.SetChainDb(chainDb); err != nil {
+			return nil, err
+		}
+	}
 
 	bc.SetValidator(NewBlockValidator(config, bc, engine))
 	bc.SetProcessor(NewStateProcessor(config, bc, engine))
@@ -241,6 +286,13 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	//record the restarting slot point
 	bc.checkCQStartSlot = epid*posconfig.SlotCount + slid
 
+	// Move the old blocks to the freezer if the database has one
+	if ancients, ok := chainDb.(ethdb.AncientStore); ok && cacheConfig.AncientThreshold > 0 {
+		if _, err := ancients.Ancients(); err == nil {
+			bc.wg.Add(1)
+			go bc.freeze(ancients)
+		}
+	}
 	go bc.update()
 	return bc, nil
 }
@@ -283,9 +335,12 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		// Dangling block without a state associated, like the head of a
+		// pruning node not stopped properly
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			return err
+		}
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
@@ -319,6 +374,31 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// repair rolls back the head block until one with its state is found, which
+// is needed when the recent states cached in memory weren't written to disk.
+// The head header and fast block are left intact, the PoS data derived from
+// the blocks rolled back is dropped with the head block update.
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			batch := bc.chainDb.NewBatch()
+			if err := bc.posCtx.Rewind(batch, bc.posEpochOf((*head).Header())); err != nil {
+				return err
+			}
+			if err := WriteHeadBlockHash(batch, (*head).Hash()); err != nil {
+				return err
+			}
+			return batch.Write()
+		}
+		parent := bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if parent == nil {
+			return fmt.Errorf("missing block %d [%x] with state", (*head).NumberU64()-1, (*head).ParentHash())
+		}
+		*head = parent
+	}
+}
+
 // SetHead rewinds the local chain to a new head. In the case of headers, everything
 // above the new head will be deleted and the new one set. In the case of blocks
 // though, the head may be further rewound if block bodies are missing (non-archive
@@ -336,6 +416,15 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	// Drop the frozen blocks above the new head too
+	if ancients, ok := bc.chainDb.(ethdb.AncientStore); ok {
+		if frozen, err := ancients.Ancients(); err == nil && frozen > currentHeader.Number.Uint64()+1 {
+			if err := ancients.TruncateAncients(currentHeader.Number.Uint64() + 1); err != nil {
+				log.Error("Failed to truncate ancient blocks", "err", err)
+			}
+		}
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -363,12 +452,20 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if bc.currentFastBlock == nil {
 		bc.currentFastBlock = bc.genesisBlock
 	}
-	if err := WriteHeadBlockHash(bc.chainDb, bc.currentBlock.Hash()); err != nil {
+	// Drop the PoS data of the rewound blocks with the head update
+	batch := bc.chainDb.NewBatch()
+	if err := bc.posCtx.Rewind(batch, bc.posEpochOf(bc.currentBlock.Header())); err != nil {
+		return err
+	}
+	if err := WriteHeadBlockHash(batch, bc.currentBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head full block", "err", err)
 	}
-	if err := WriteHeadFastBlockHash(bc.chainDb, bc.currentFastBlock.Hash()); err != nil {
+	if err := WriteHeadFastBlockHash(batch, bc.currentFastBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head fast block", "err", err)
 	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to reset head blocks", "err", err)
+	}
 	return bc.loadLastState()
 }
 
@@ -471,7 +568,27 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	statedb, err := state.New(root, bc.stateCache)
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetEpochSource(bc.headEpochId)
+	return statedb, nil
+}
+
+// PosContext returns the PoS context of the node running the chain, nil for
+// a nil chain as used by the chain makers.
+func (bc *BlockChain) PosContext() *posctx.Context {
+	if bc == nil {
+		return nil
+	}
+	return bc.posCtx
+}
+
+// headEpochId returns the epoch of the current head once pos is running.
+func (bc *BlockChain) headEpochId() uint64 {
+	epochId, _ := bc.posCtx.CurrentBlkEpochSlotID()
+	return epochId
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -497,7 +614,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 		log.Crit("Failed to write genesis block", "err", err)
 	}
 	bc.genesisBlock = genesis
-	bc.insert(bc.genesisBlock)
+	bc.insert(bc.chainDb, bc.genesisBlock)
 	bc.currentBlock = bc.genesisBlock
 	bc.hc.SetGenesis(bc.genesisBlock.Header())
 	bc.hc.SetCurrentHeader(bc.genesisBlock.Header())
@@ -541,15 +658,15 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 // or if they are on a different side chain.
 //
 // Note, this function assumes that the `mu` mutex is held!
-func (bc *BlockChain) insert(block *types.Block) {
+func (bc *BlockChain) insert(db ethdb.Putter, block *types.Block) {
 	// If the block is on a side chain or an unknown one, force other heads onto it too
 	updateHeads := GetCanonicalHash(bc.chainDb, block.NumberU64()) != block.Hash()
 
 	// Add the block to the canonical chain number scheme and mark as the head
-	if err := WriteCanonicalHash(bc.chainDb, block.Hash(), block.NumberU64()); err != nil {
+	if err := WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
 		log.Crit("Failed to insert block number", "err", err)
 	}
-	if err := WriteHeadBlockHash(bc.chainDb, block.Hash()); err != nil {
+	if err := WriteHeadBlockHash(db, block.Hash()); err != nil {
 		log.Crit("Failed to insert head block hash", "err", err)
 	}
 	bc.currentBlock = block
@@ -558,7 +675,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 	if updateHeads {
 		bc.hc.SetCurrentHeader(block.Header())
 
-		if err := WriteHeadFastBlockHash(bc.chainDb, block.Hash()); err != nil {
+		if err := WriteHeadFastBlockHash(db, block.Hash()); err != nil {
 			log.Crit("Failed to insert head fast block hash", "err", err)
 		}
 		bc.currentFastBlock = block
@@ -609,7 +726,7 @@ func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 		return true
 	}
 	ok, _ := bc.chainDb.Has(blockBodyKey(hash, number))
-	return ok
+	return ok || isFrozen(bc.chainDb, hash, number)
 }
 
 // HasBlockAndState checks if a block and associated state trie is fully present
@@ -695,6 +812,35 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+
+	// Write the recent states cached in memory to disk, so that the chain
+	// doesn't have to be repaired on restart:
+	//  - HEAD:     So we don't need to reprocess any blocks in the general case
+	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
+	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
+	if !bc.cacheConfig.Disabled {
+		triedb := bc.stateCache.TrieDB()
+
+		for _, offset := range []uint64{0, 1, triesInMemory - 1} {
+			if number := bc.CurrentBlock().NumberU64(); number > offset {
+				recent := bc.GetBlockByNumber(number - offset)
+
+				log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
+				if err := triedb.Commit(recent.Root(), true); err != nil {
+					log.Error("Failed to commit recent state trie", "err", err)
+				}
+			}
+		}
+		for !bc.triegc.Empty() {
+			triedb.Dereference(bc.triegc.PopItem().(common.Hash))
+		}
+		if size := triedb.Size(); size != 0 {
+			log.Error("Dangling trie nodes after full cleanup", "size", size)
+		}
+	}
+	if bc.ownPosCtx {
+		bc.posCtx.Close()
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -963,14 +1109,14 @@ func (bc *BlockChain) ChainQuality(epochid uint64, slotid uint64) (uint64, error
 	blkSlots := blkEpid*posconfig.SlotCount + blkSlid
 	expSlots := epochid*posconfig.SlotCount + slotid
 
-	if expSlots >= (blkSlots+posconfig.SlotSecurityParam) || (epochid == posconfig.FirstEpochId && slotid == 0) {
+	if expSlots >= (blkSlots+posconfig.SlotSecurityParam) || (epochid == bc.posCtx.FirstEpochId() && slotid == 0) {
 		return 0, errors.New("wrong epoid or slotid")
 	}
 
 	//lastBlock := bc.epochGene.rbLeaderSelector.GetEpochLastBlkNumber(epochid)
 	checkSlots := uint64(0)
 
-	lastBlock := posUtil.GetEpochBlock(blkEpid)
+	lastBlock := bc.posCtx.EpochBlock(blkEpid)
 	for i := lastBlock; i > 0; i-- {
 
 		curBlkHeader := bc.GetHeaderByNumber(i)
@@ -1033,7 +1179,7 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 
 
 
-	if bc.config.IsPosActive && epid > posconfig.FirstEpochId+1  {
+	if bc.config.IsPosActive && epid > bc.posCtx.FirstEpochId()+1  {
 
 		//res, _ := bc.ChainRestartStatus()
 
@@ -1081,7 +1227,7 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 		return NonStatTy, err
 	}
 
-	if _, err := state.CommitTo(batch, true /*bc.config.IsEIP158(block.Number())*/); err != nil {
+	if err := bc.writeState(batch, block, state); err != nil {
 		return NonStatTy, err
 	}
 	if err := WriteBlockReceipts(batch, block.Hash(), block.NumberU64(), receipts); err != nil {
@@ -1103,7 +1249,7 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 		(bc.config.IsPosActive && (bc.currentBlock.NumberU64() == 0 || block.NumberU64() > bc.currentBlock.NumberU64())) {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != bc.currentBlock.Hash() {
-			if err := bc.reorg(bc.currentBlock, block); err != nil {
+			if err := bc.reorg(bc.currentBlock, block, batch, state); err != nil {
 				return NonStatTy, err
 			}
 		}
@@ -1121,6 +1267,10 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 		//if incoming block humber is smaller than or equal local block number,then keep current
 		status = SideStatTy
 	}
+	// The PoS data of the block is written with it
+	if err := bc.posCtx.CommitBlock(batch, state); err != nil {
+		return NonStatTy, err
+	}
 	if err := batch.Write(); err != nil {
 		return NonStatTy, err
 	}
@@ -1128,9 +1278,9 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	// Set new head.
 	if status == CanonStatTy {
 
-		bc.insert(block)
+		bc.insert(bc.chainDb, block)
 		if bc.config.IsPosActive {
-			posUtil.UpdateEpochBlock(block)
+			bc.posCtx.UpdateEpochBlock(block)
 			
 			flatSlotId := epid*posconfig.SlotCount + slotId
 			bc.cqCache.Add(flatSlotId, block.Number().Uint64())
@@ -1154,6 +1304,85 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	return status, nil
 }
 
+// writeState commits the state of a block. An archive node writes it with
+// the block, else it is cached in memory and garbage collected once older
+// than triesInMemory blocks, unless flushed to disk in the meantime.
+//
+// PoS reads the state at the last block of an epoch two epochs later, so a
+// pruning node writes it to disk when a block of the next epoch arrives.
+func (bc *BlockChain) writeState(batch ethdb.Batch, block *types.Block, state *state.StateDB) error {
+	if bc.cacheConfig.Disabled {
+		_, err := state.CommitTo(batch, true /*bc.config.IsEIP158(block.Number())*/)
+		return err
+	}
+	triedb := bc.stateCache.TrieDB()
+	root, err := state.CommitTo(triedb, true /*bc.config.IsEIP158(block.Number())*/)
+	if err != nil {
+		return err
+	}
+	triedb.Reference(root, common.Hash{})
+	bc.triegc.Push(root, -float32(block.NumberU64()))
+
+	if parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1); parent != nil && isEpochLastBlock(parent, block.Header()) {
+		if err := triedb.Commit(parent.Root, false); err != nil {
+			return err
+		}
+	}
+	current := block.NumberU64()
+	if current <= triesInMemory {
+		return nil
+	}
+	// Flush the oldest state kept in memory if the cache is full or wasn't
+	// flushed for too long
+	chosen := current - triesInMemory
+	limit := common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
+	if triedb.Size() > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+		if header := bc.GetHeaderByNumber(chosen); header == nil {
+			log.Warn("Reorg in progress, trie commit postponed", "number", chosen)
+		} else {
+			if err := triedb.Commit(header.Root, true); err != nil {
+				return err
+			}
+			bc.gcproc = 0
+		}
+	}
+	// Garbage collect the states no longer kept in memory
+	for !bc.triegc.Empty() {
+		root, number := bc.triegc.Pop()
+		if uint64(-number) > chosen {
+			bc.triegc.Push(root, number)
+			break
+		}
+		triedb.Dereference(root.(common.Hash))
+	}
+	return nil
+}
+
+// isEpochLastBlock reports whether parent is the last block of an epoch, its
+// child being the first PoS block or in a later epoch.
+func isEpochLastBlock(parent, child *types.Header) bool {
+	if !posUtil.IsPosBlock(child.Number.Uint64()) {
+		return false
+	}
+	if !posUtil.IsPosBlock(parent.Number.Uint64()) {
+		return true
+	}
+	parentEpoch, _ := posUtil.GetEpochSlotIDFromDifficulty(parent.Difficulty)
+	childEpoch, _ := posUtil.GetEpochSlotIDFromDifficulty(child.Difficulty)
+	return childEpoch > parentEpoch
+}
+
+// StateCache returns the database the states are read from.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
+// TrieNode retrieves a state trie node or contract code by hash, from memory
+// if it's cached, else from disk.
+func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
+	return bc.stateCache.TrieDB().Get(hash[:])
+}
+
 func (bc *BlockChain) isCurrentLastPPowBlock() bool {
 	num := bc.currentBlock.Number()
 	num = num.Add(num, big.NewInt(1))
@@ -1399,7 +1628,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 		if block.NumberU64() == posconfig.Pow2PosUpgradeBlockNumber {
 			epochId, _ := posUtil.CalEpSlbyTd(block.Difficulty().Uint64())
-			posconfig.FirstEpochId = epochId
+			bc.posCtx.SetFirstEpochId(epochId)
 		}
 
 		var parent *types.Block
@@ -1412,6 +1641,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
+		state.SetEpochSource(bc.headEpochId)
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
 		if err != nil {
@@ -1433,6 +1663,8 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			}
 		}
 
+		proctime := time.Since(bstart)
+
 		// Write the block to the chain and get the status.
 		status, err := bc.WriteBlockAndState(block, receipts, state)
 		if err != nil {
@@ -1440,6 +1672,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		}
 		switch status {
 		case CanonStatTy:
+			// Only count canonical blocks for GC processing time
+			bc.gcproc += proctime
+
 			log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()),
 				"txs", len(block.Transactions()), "gas", block.GasUsed(), "elapsed", common.PrettyDuration(time.Since(bstart)))
 
@@ -1519,7 +1754,7 @@ func countTransactions(chain []*types.Block) (c int) {
 	return c
 }
 
-func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
+func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block, batch ethdb.Batch, statedb *state.StateDB) error {
 	var (
 		newChain    types.Blocks
 		oldChain    types.Blocks
@@ -1606,15 +1841,24 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		//return fmt.Errorf("Impossible reorg, please file an issue")
 	}
 
+	// The PoS data derived from the old chain past the split is derived again,
+	// it is dropped with the switch of the canonical chain
+	commonEpochId := bc.posEpochOf(commonBlock.Header())
+	if len(oldChain) > 0 {
+		if err := bc.posCtx.RewindBranch(batch, commonEpochId); err != nil {
+			return err
+		}
+	}
+
 	var addedTxs types.Transactions
 	// insert blocks. Order does not matter. Last block will be written in ImportChain itself which creates the new head properly
 	newChainLen := len(newChain)
 	epochId, slotid := posUtil.CalEpSlbyTd(newChain[newChainLen-1].Header().Difficulty.Uint64())
-	bc.updateReOrg(epochId, slotid, uint64(len(oldChain)))
+	bc.updateReOrg(statedb, epochId, slotid, uint64(len(oldChain)), len(oldChain) > 0 && epochId > commonEpochId)
 	go bc.reorgFeed.Send(ReorgEvent{epochId, slotid, uint64(len(oldChain))})
 
 	//if reorg length is bigger than k,do not let reorg happen
-	if posconfig.FirstEpochId != 0 && uint(newChainLen) > posconfig.Cfg().K {
+	if bc.posCtx.FirstEpochId() != 0 && uint(newChainLen) > posconfig.Cfg().K {
 		log.Error("Impossible reorg because reorg length is bigger than K setting", "reorg length", newChainLen, "old chain rollback lenght", len(oldChain))
 		return ErrSecurityViolated
 
@@ -1625,7 +1869,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	for _, block := range newChain {
 
 		// insert the block in the canonical way, re-writing history
-		bc.insert(block)
+		bc.insert(batch, block)
 
 		log.Debug("blockchain reorg","new chain", block.Number().String(), common.ToHex(block.Hash().Bytes()), common.ToHex(block.ParentHash().Bytes()))
 		// write lookup entries for hash based transaction/receipt searches
@@ -1883,6 +2127,15 @@ func (bc *BlockChain) SlotValidator() Validator {
 	return bc.slotValidator
 }
 
+// posEpochOf returns the epoch of a pos block, 0 for the blocks before pos.
+func (bc *BlockChain) posEpochOf(header *types.Header) uint64 {
+	if bc.config.PosFirstBlock == nil || !bc.config.IsPosBlockNumber(header.Number) {
+		return 0
+	}
+	epochID, _ := posUtil.CalEpSlbyTd(header.Difficulty.Uint64())
+	return epochID
+}
+
 // if current block number +1 is >= pos first block
 func (bc *BlockChain) IsInPosStage() bool {
 	currentBlockNumber := bc.currentBlock.Number()
@@ -1985,17 +2238,18 @@ func (bc *BlockChain) checkRestarting(chain types.Blocks) ([]uint, error) {
 	return idxs, nil
 
 }
-func (bc *BlockChain) updateReOrg(epochId uint64, slotid uint64, length uint64) {
 
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
-	if reOrgDb == nil {
-		reOrgDb = posdb.NewDb(posconfig.ReorgLocalDB)
-	}
+// updateReOrg records a reorg to the chain of the block whose state is
+// statedb, the record is written with the block. The records of the epoch
+// are counted again if rewound, dropped with the old chain.
+func (bc *BlockChain) updateReOrg(statedb *state.StateDB, epochId uint64, slotid uint64, length uint64, rewound bool) {
+
+	reOrgDb := bc.posCtx.BlockDb(posconfig.ReorgLocalDB, statedb)
 
 	numberBytes, _ := reOrgDb.Get(epochId, "reorgNumber")
 
 	num := uint64(0)
-	if numberBytes != nil {
+	if numberBytes != nil && !rewound {
 		num = binary.BigEndian.Uint64(numberBytes) + 1
 	}
 
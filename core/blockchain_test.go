@@ -186,7 +186,7 @@ func TestLastBlock(t *testing.T) {
 	defer bchain.Stop()
 
 	block := chainEnv.makeBlockChain(bchain.CurrentBlock(), 1, 0)[0]
-	bchain.insert(block)
+	bchain.insert(bchain.chainDb, block)
 	if block.Hash() != GetHeadBlockHash(bchain.chainDb) {
 		t.Errorf("Write/Get HeadBlockHash failed")
 	}
@@ -1072,6 +1072,59 @@ func TestCanonicalBlockRetrieval(t *testing.T) {
 }
 
 
+// Tests that a pruning chain only keeps the recent states, and flushes the
+// head state to disk when stopped.
+func TestTrieGarbageCollection(t *testing.T) {
+	var (
+		gendb, _ = ethdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	gspec := DefaultPPOWTestingGenesisBlock()
+	gspec.Alloc = GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}
+	genesis := gspec.MustCommit(gendb)
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+	engine := ethash.NewFaker(gendb)
+	genChain, _ := NewBlockChain(gendb, gspec.Config, engine, vm.Config{})
+	defer genChain.Stop()
+
+	blocks, _ := NewChainEnv(params.TestChainConfig, gspec, engine, genChain, gendb).GenerateChain(genesis, 2*triesInMemory, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), bigTxGas, nil, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: time.Hour}
+	chain, err := NewBlockChainWithCache(db, cacheConfig, gspec.Config, ethash.NewFaker(db), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to process block %d: %v", n, err)
+	}
+	for i, block := range blocks {
+		recent := i >= len(blocks)-triesInMemory
+		if have := chain.HasBlockAndState(block.Hash()); have != recent {
+			t.Fatalf("block %d: state availability mismatch: have %v, want %v", block.NumberU64(), have, recent)
+		}
+	}
+	chain.Stop()
+
+	// The head state is on disk once the chain is stopped.
+	chain, err = NewBlockChainWithCache(db, cacheConfig, gspec.Config, ethash.NewFaker(db), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if head := chain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head mismatch after restart: have #%d, want #%d", head.NumberU64(), blocks[len(blocks)-1].NumberU64())
+	}
+}
+
 //func TestEIP155Transition(t *testing.T) {
 //	// Configure and generate a sample block chain
 //	var (
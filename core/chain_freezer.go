@@ -0,0 +1,161 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+const (
+	// freezerRecheckInterval is the time between two runs of the freezer
+	// once it caught up with the chain.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks frozen in one run.
+	freezerBatchLimit = 30000
+)
+
+// SetStableBlockFn sets the function returning the highest block that can't be
+// reorganised anymore. Blocks above it are never frozen.
+func (bc *BlockChain) SetStableBlockFn(fn func() uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.stableBlockFn = fn
+}
+
+// freeze moves the blocks older than the ancient threshold from the key-value
+// store to the freezer until the chain is stopped.
+func (bc *BlockChain) freeze(ancients ethdb.AncientStore) {
+	defer bc.wg.Done()
+
+	for {
+		frozen, err := bc.freezeBlocks(ancients)
+		if err != nil {
+			log.Error("Failed to freeze blocks", "err", err)
+		}
+		// Keep going right away while there is a backlog to freeze
+		if err == nil && frozen == freezerBatchLimit {
+			select {
+			case <-bc.quit:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-bc.quit:
+			return
+		case <-time.After(freezerRecheckInterval):
+		}
+	}
+}
+
+// freezeBlocks moves the next batch of canonical blocks to freeze from the
+// key-value store to the freezer, returning the number of blocks moved. The
+// chain lock is only held for a block at a time while appending, so that block
+// imports go on while a batch is frozen.
+func (bc *BlockChain) freezeBlocks(ancients ethdb.AncientStore) (int, error) {
+	bc.chainmu.RLock()
+	head := bc.CurrentBlock().NumberU64()
+	bc.chainmu.RUnlock()
+
+	if head < bc.cacheConfig.AncientThreshold {
+		return 0, nil
+	}
+	limit := head - bc.cacheConfig.AncientThreshold
+
+	bc.mu.RLock()
+	stableBlockFn := bc.stableBlockFn
+	bc.mu.RUnlock()
+	if stableBlockFn != nil {
+		if stable := stableBlockFn(); stable < limit {
+			limit = stable
+		}
+	}
+	first, err := ancients.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	if first > limit {
+		return 0, nil
+	}
+	if limit-first >= freezerBatchLimit {
+		limit = first + freezerBatchLimit - 1
+	}
+	var (
+		start  = time.Now()
+		hashes = make([]common.Hash, 0, limit-first+1)
+	)
+	for number := first; number <= limit; number++ {
+		var hash common.Hash
+		if hash, err = bc.freezeBlock(ancients, number); err != nil {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+	// Only drop the blocks from the key-value store once they are on disk
+	if len(hashes) == 0 {
+		return 0, err
+	}
+	if serr := ancients.Sync(); serr != nil {
+		return 0, serr
+	}
+	// Drop the blocks in a batch under the chain lock, skipping those that a
+	// reorg made non-canonical since they were appended
+	bc.chainmu.Lock()
+	batch := bc.chainDb.NewBatch()
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if GetCanonicalHash(bc.chainDb, number) != hash {
+			log.Warn("Canonical block changed while freezing", "number", number, "hash", hash)
+			continue
+		}
+		DeleteCanonicalHash(batch, number)
+		batch.Delete(headerKey(hash, number))
+		DeleteBody(batch, hash, number)
+		DeleteTd(batch, hash, number)
+		DeleteBlockReceipts(batch, hash, number)
+	}
+	werr := batch.Write()
+	bc.chainmu.Unlock()
+	if werr != nil {
+		return 0, werr
+	}
+	log.Info("Froze ancient blocks", "count", len(hashes), "number", first+uint64(len(hashes))-1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(hashes), err
+}
+
+// freezeBlock appends the canonical block of a number to the freezer,
+// returning its hash.
+func (bc *BlockChain) freezeBlock(ancients ethdb.AncientStore, number uint64) (common.Hash, error) {
+	bc.chainmu.RLock()
+	defer bc.chainmu.RUnlock()
+
+	hash := GetCanonicalHash(bc.chainDb, number)
+	if hash == (common.Hash{}) {
+		return hash, fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+	}
+	header := GetHeaderRLP(bc.chainDb, hash, number)
+	if len(header) == 0 {
+		return hash, fmt.Errorf("block header missing, can't freeze block %d", number)
+	}
+	body := GetBodyRLP(bc.chainDb, hash, number)
+	if len(body) == 0 {
+		return hash, fmt.Errorf("block body missing, can't freeze block %d", number)
+	}
+	receipts := getReceiptsRLP(bc.chainDb, hash, number)
+	if len(receipts) == 0 {
+		return hash, fmt.Errorf("block receipts missing, can't freeze block %d", number)
+	}
+	td := getTdRLP(bc.chainDb, hash, number)
+	if len(td) == 0 {
+		return hash, fmt.Errorf("total difficulty missing, can't freeze block %d", number)
+	}
+	return hash, ancients.AppendAncient(number, hash[:], header, body, receipts, td)
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestChainFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain-freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabaseWithFreezer(dir, 0, 0, filepath.Join(dir, "ancient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = DefaultPPOWTestingGenesisBlock()
+	)
+	gspec.Alloc = GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}
+	genesis := gspec.MustCommit(db)
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+	engine := ethash.NewFaker(db)
+
+	// The freezer doesn't run on its own without an ancient threshold
+	chain, err := NewBlockChainWithCache(db, &CacheConfig{Disabled: true}, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	env := NewChainEnv(params.TestChainConfig, gspec, engine, chain, db)
+
+	blocks, receipts := env.GenerateChain(genesis, 64, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), bigTxGas, nil, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	chain.cacheConfig.AncientThreshold = 16
+
+	// Blocks that aren't stable yet are left alone
+	chain.SetStableBlockFn(func() uint64 { return 20 })
+	if n, err := chain.freezeBlocks(db); err != nil || n != 21 {
+		t.Fatalf("capped freeze mismatch: have %d (%v), want %d", n, err, 21)
+	}
+	chain.SetStableBlockFn(nil)
+	if n, err := chain.freezeBlocks(db); err != nil || n != 28 {
+		t.Fatalf("freeze mismatch: have %d (%v), want %d", n, err, 28)
+	}
+	if n, err := chain.freezeBlocks(db); err != nil || n != 0 {
+		t.Fatalf("repeated freeze mismatch: have %d (%v), want 0", n, err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 49 {
+		t.Fatalf("frozen blocks mismatch: have %d, want %d", frozen, 49)
+	}
+	// The frozen blocks are gone from leveldb, but still readable as before
+	for i, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+
+		if has, _ := db.Has(blockBodyKey(hash, number)); has != (number > 48) {
+			t.Fatalf("block %d: body in leveldb mismatch: have %v", number, has)
+		}
+		if have := GetCanonicalHash(db, number); have != hash {
+			t.Fatalf("block %d: canonical hash mismatch: have %x, want %x", number, have, hash)
+		}
+		if have := GetBlock(db, hash, number); have == nil || have.Hash() != hash || len(have.Transactions()) != 1 {
+			t.Fatalf("block %d: block mismatch: have %v", number, have)
+		}
+		if have := GetBlockReceipts(db, hash, number); len(have) != len(receipts[i]) {
+			t.Fatalf("block %d: receipts mismatch: have %d, want %d", number, len(have), len(receipts[i]))
+		}
+		if GetTd(db, hash, number) == nil {
+			t.Fatalf("block %d: total difficulty missing", number)
+		}
+		if !chain.HasBlock(hash, number) || !chain.HasHeader(hash, number) {
+			t.Fatalf("block %d: block not reported as present", number)
+		}
+		if tx, blockHash, _, _ := GetTransaction(db, block.Transactions()[0].Hash()); tx == nil || blockHash != hash {
+			t.Fatalf("block %d: transaction lookup failed", number)
+		}
+	}
+	// Rewinding the chain below the frozen blocks drops them from the freezer
+	if err := chain.SetHead(10); err != nil {
+		t.Fatal(err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 11 {
+		t.Fatalf("frozen blocks after rewind mismatch: have %d, want %d", frozen, 11)
+	}
+	if hash := GetCanonicalHash(db, 20); hash != (common.Hash{}) {
+		t.Fatalf("rewound block still canonical: %x", hash)
+	}
+}
@@ -92,6 +92,9 @@ func encodeBlockNumber(number uint64) []byte {
 // GetCanonicalHash retrieves a hash assigned to a canonical block number.
 func GetCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
+	if len(data) == 0 {
+		data = getAncient(db, ethdb.AncientHashes, number)
+	}
 	if len(data) == 0 {
 		return common.Hash{}
 	}
@@ -150,6 +153,9 @@ func GetHeadFastBlockHash(db DatabaseReader) common.Hash {
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(hash, number))
+	if len(data) == 0 {
+		data = getAncientByHash(db, ethdb.AncientHeaders, hash, number)
+	}
 	return data
 }
 
@@ -171,6 +177,9 @@ func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	if len(data) == 0 {
+		data = getAncientByHash(db, ethdb.AncientBodies, hash, number)
+	}
 	return data
 }
 
@@ -200,7 +209,7 @@ func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body {
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	data := getTdRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -212,6 +221,49 @@ func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
 	return td
 }
 
+// getTdRLP retrieves a block's total difficulty in RLP encoding.
+func getTdRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	if len(data) == 0 {
+		data = getAncientByHash(db, ethdb.AncientTds, hash, number)
+	}
+	return data
+}
+
+// getReceiptsRLP retrieves the receipts of a block in their RLP storage encoding.
+func getReceiptsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		data = getAncientByHash(db, ethdb.AncientReceipts, hash, number)
+	}
+	return data
+}
+
+// getAncient retrieves an item of a block moved to the freezer, nil if the
+// database has no freezer or the block isn't frozen.
+func getAncient(db DatabaseReader, kind string, number uint64) []byte {
+	ancients, ok := db.(ethdb.AncientReader)
+	if !ok {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// getAncientByHash retrieves an item of a frozen block, nil unless the block
+// frozen at that number has the given hash.
+func getAncientByHash(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	if !isFrozen(db, hash, number) {
+		return nil
+	}
+	return getAncient(db, kind, number)
+}
+
+// isFrozen reports whether a block was moved to the freezer.
+func isFrozen(db DatabaseReader, hash common.Hash, number uint64) bool {
+	return bytes.Equal(getAncient(db, ethdb.AncientHashes, number), hash[:])
+}
+
 // GetBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -235,7 +287,7 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data := getReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
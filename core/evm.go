@@ -23,6 +23,9 @@ import (
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
 )
 
 // ChainContext supports retrieving headers and consensus parameters from the
@@ -45,19 +48,73 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		beneficiary = *author
 	}
 	return vm.Context{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Origin:      msg.From(),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).Set(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		GasLimit:    new(big.Int).Set(header.GasLimit),
-		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		CanTransfer:  CanTransfer,
+		Transfer:     Transfer,
+		GetHash:      GetHashFn(header, chain),
+		Origin:       msg.From(),
+		Coinbase:     beneficiary,
+		BlockNumber:  new(big.Int).Set(header.Number),
+		Time:         new(big.Int).Set(header.Time),
+		Difficulty:   new(big.Int).Set(header.Difficulty),
+		GasLimit:     new(big.Int).Set(header.GasLimit),
+		GasPrice:     new(big.Int).Set(msg.GasPrice()),
+		PosContext:   chainPosContext(chain),
+		FirstEpochId: firstEpochId(chain, header),
+		SealVerifier: sealVerifier(chain),
 	}
 }
 
+// chainPosContext returns the PoS context of chain, nil if it has none.
+func chainPosContext(chain ChainContext) *posctx.Context {
+	if p, ok := chain.(posContextProvider); ok {
+		return p.PosContext()
+	}
+	return nil
+}
+
+// posChainReader is implemented by the chains, light ones included, that can
+// find the first pos block without a pos context.
+type posChainReader interface {
+	Config() *params.ChainConfig
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// firstEpochId returns the epoch of the first pos block of chain as seen from
+// header, 0 if header comes before it. The chains without a pos context read
+// it from the first pos header.
+func firstEpochId(chain ChainContext, header *types.Header) uint64 {
+	if p, ok := chain.(posContextProvider); ok {
+		return p.PosContext().FirstEpochId()
+	}
+	reader, ok := chain.(posChainReader)
+	if !ok {
+		return 0
+	}
+	first := reader.Config().PosFirstBlock
+	if first == nil || header.Number.Cmp(first) < 0 {
+		return 0
+	}
+	posHeader := header
+	if header.Number.Cmp(first) != 0 {
+		posHeader = reader.GetHeaderByNumber(first.Uint64())
+	}
+	if posHeader == nil {
+		return 0
+	}
+	epochId, _ := posUtil.CalEpSlbyTd(posHeader.Difficulty.Uint64())
+	return epochId
+}
+
+// sealVerifier returns the verifier of the pos seals of chain. The chains
+// without a pos context use their consensus engine if it's a verifier.
+func sealVerifier(chain ChainContext) posUtil.SealVerifier {
+	if p, ok := chain.(posContextProvider); ok {
+		return p.PosContext().SealVerifier()
+	}
+	verifier, _ := chain.Engine().(posUtil.SealVerifier)
+	return verifier
+}
+
 // GetHashFn returns a GetHashFunc which retrieves header hashes by number
 func GetHashFn(ref *types.Header, chain ChainContext) func(n uint64) common.Hash {
 	return func(n uint64) common.Hash {
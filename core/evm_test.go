@@ -0,0 +1,67 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// headerChain is a chain context without a pos context, like the light chain.
+type headerChain struct {
+	config  *params.ChainConfig
+	headers map[uint64]*types.Header
+}
+
+func (c *headerChain) Engine() consensus.Engine                    { return ethash.NewFaker(nil) }
+func (c *headerChain) Config() *params.ChainConfig                 { return c.config }
+func (c *headerChain) GetHeader(common.Hash, uint64) *types.Header { return nil }
+func (c *headerChain) GetHeaderByNumber(n uint64) *types.Header    { return c.headers[n] }
+
+func posHeader(number, epochID, slotID uint64) *types.Header {
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8),
+		Time:       big.NewInt(0),
+		GasLimit:   big.NewInt(0),
+	}
+}
+
+// Tests that the chains without a pos context read the first pos epoch from
+// the first pos header.
+func TestEVMContextFirstEpochId(t *testing.T) {
+	config := *params.TestChainConfig
+	config.PosFirstBlock = big.NewInt(10)
+	chain := &headerChain{config: &config, headers: map[uint64]*types.Header{}}
+	msg := types.NewMessage(common.Address{}, nil, 0, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, false)
+	author := common.Address{}
+
+	tests := []struct {
+		header *types.Header
+		stored bool
+		want   uint64
+	}{
+		{posHeader(9, 0, 0), false, 0},
+		{posHeader(10, 5, 3), false, 5},
+		{posHeader(12, 6, 1), false, 0},
+		{posHeader(12, 6, 1), true, 5},
+	}
+	for i, tt := range tests {
+		if tt.stored {
+			chain.headers[10] = posHeader(10, 5, 3)
+		}
+		ctx := NewEVMContext(msg, tt.header, chain, &author)
+		if ctx.FirstEpochId != tt.want {
+			t.Errorf("test %d: first epoch mismatch: have %d, want %d", i, ctx.FirstEpochId, tt.want)
+		}
+		if ctx.SealVerifier != nil {
+			t.Errorf("test %d: seal verifier of a non pos engine", i)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// txRingSignData returns the ring signature carried by a privacy tx or a
+// wancoin refund, false if the tx has none.
+func txRingSignData(signer types.Signer, tx *types.Transaction) (vm.RingSignData, bool) {
+	isPrivacy := types.IsPrivacyTransaction(tx.Txtype())
+	if !isPrivacy && tx.To() == nil {
+		return vm.RingSignData{}, false
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return vm.RingSignData{}, false
+	}
+	if !isPrivacy {
+		return vm.RefundRingSignData(tx.To(), from, tx.Data())
+	}
+
+	in := tx.Data()
+	if len(in) < 4 {
+		return vm.RingSignData{}, false
+	}
+	var TxDataWithRing struct {
+		RingSignedData string
+		CxtCallParams  []byte
+	}
+	if err := utilAbi.Unpack(&TxDataWithRing, "combine", in[4:]); err != nil {
+		return vm.RingSignData{}, false
+	}
+	return vm.RingSignData{HashInput: from.Bytes(), RingSigned: TxDataWithRing.RingSignedData}, true
+}
+
+// preverifyRingSigns verifies the ring signatures of txs in one batch, so
+// that validating or running the txs one by one finds them verified.
+func preverifyRingSigns(signer types.Signer, txs types.Transactions) {
+	var data []vm.RingSignData
+	for _, tx := range txs {
+		if d, ok := txRingSignData(signer, tx); ok {
+			data = append(data, d)
+		}
+	}
+	if len(data) > 1 {
+		vm.PreverifyRingSigns(data)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/trie"
 )
 
@@ -50,6 +51,9 @@ type Database interface {
 	ContractCodeSize(addrHash, codeHash common.Hash) (int, error)
 	// CopyTrie returns an independent copy of the given trie.
 	CopyTrie(Trie) Trie
+	// TrieDB returns the node cache the tries are read from, nil if the
+	// tries aren't backed by a local database.
+	TrieDB() *trie.NodeDatabase
 }
 
 // Trie is a Ethereum Merkle Trie.
@@ -61,17 +65,31 @@ type Trie interface {
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
+	Prove(key []byte) []rlp.RawValue
 }
 
 // NewDatabase creates a backing store for state. The returned database is safe for
 // concurrent use and retains cached trie nodes in memory.
+//
+// The tries are read through a node cache, which a state can be committed
+// to instead of the disk database so that it can be garbage collected.
 func NewDatabase(db ethdb.Database) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
-	return &cachingDB{db: db, codeSizeCache: csc}
+	return &cachingDB{db: trie.NewNodeDatabase(db, storageRoot), codeSizeCache: csc}
+}
+
+// storageRoot returns the storage trie root of an account leaf of the state
+// trie, which it keeps alive in a node cache.
+func storageRoot(leaf []byte) []common.Hash {
+	var account Account
+	if err := rlp.DecodeBytes(leaf, &account); err != nil || account.Root == emptyTrieRoot {
+		return nil
+	}
+	return []common.Hash{account.Root}
 }
 
 type cachingDB struct {
-	db            ethdb.Database
+	db            *trie.NodeDatabase
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
@@ -128,6 +146,11 @@ func (db *cachingDB) ContractCode(addrHash, codeHash common.Hash) ([]byte, error
 	return code, err
 }
 
+// TrieDB returns the node cache of the tries.
+func (db *cachingDB) TrieDB() *trie.NodeDatabase {
+	return db.db
+}
+
 func (db *cachingDB) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
 	if cached, ok := db.codeSizeCache.Get(codeHash); ok {
 		return cached.(int), nil
@@ -94,6 +94,7 @@ type stateObject struct {
 	trie Trie // storage trie, which becomes non-nil on first access
 	code Code // contract bytecode, which gets set when code is loaded
 
+	originStorage Storage // Storage entries as of the start of the current transaction
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
 
@@ -141,6 +142,7 @@ func newObject(db *StateDB, address common.Address, data Account, onDirty func(a
 		address:                address,
 		addrHash:               crypto.Keccak256Hash(address[:]),
 		data:                   data,
+		originStorage:          make(Storage),
 		cachedStorage:          make(Storage),
 		dirtyStorage:           make(Storage),
 		cachedStorageByteArray: make(StorageByteArray),
@@ -200,6 +202,20 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
+	value = self.GetCommittedState(db, key)
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	return value
+}
+
+// GetCommittedState returns a value in account storage as of the start of
+// the current transaction.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	value, exists := self.originStorage[key]
+	if exists {
+		return value
+	}
 	// Load from DB in case it is missing.
 	enc, err := self.getTrie(db).TryGet(key[:])
 	if err != nil {
@@ -213,9 +229,7 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
+	self.originStorage[key] = value
 	return value
 }
 
@@ -277,6 +291,7 @@ func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
+		self.originStorage[key] = value
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
 			continue
@@ -378,6 +393,7 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 		stateObject.trie = db.db.CopyTrie(self.trie)
 	}
 	stateObject.code = self.code
+	stateObject.originStorage = self.originStorage.Copy()
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
 	stateObject.dirtyStorageByteArray = self.dirtyStorageByteArray.Copy()
@@ -19,9 +19,9 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"math/big"
 	"sort"
 	"sync"
@@ -38,6 +38,11 @@ const (
 	OTA_ADDR_LEN = 128
 )
 
+var (
+	errProofMissingNode = errors.New("missing trie node for proof")
+	errNoStorageTrie    = errors.New("storage trie for requested address does not exist")
+)
+
 type revision struct {
 	id           int
 	journalIndex int
@@ -79,9 +84,19 @@ type StateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// epochSource returns the epoch of the current chain head, used to pick
+	// the storage iteration rules of the running fork.
+	epochSource func() uint64
+
 	lock sync.Mutex
 }
 
+// SetEpochSource sets the function returning the epoch of the current chain
+// head. Without it the state follows the rules before the mercury fork.
+func (self *StateDB) SetEpochSource(source func() uint64) {
+	self.epochSource = source
+}
+
 // Create a new state from a given trie
 func New(root common.Hash, db Database) (*StateDB, error) {
 	tr, err := db.OpenTrie(root)
@@ -173,6 +188,15 @@ func (self *StateDB) AddRefund(gas *big.Int) {
 	self.refund.Add(self.refund, gas)
 }
 
+// SubRefund removes gas from the refund counter, which must not go below zero.
+func (self *StateDB) SubRefund(gas *big.Int) {
+	if gas.Cmp(self.refund) > 0 {
+		panic("refund counter below zero")
+	}
+	self.journal = append(self.journal, refundChange{prev: new(big.Int).Set(self.refund)})
+	self.refund.Sub(self.refund, gas)
+}
+
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
@@ -243,6 +267,16 @@ func (self *StateDB) GetState(a common.Address, b common.Hash) common.Hash {
 	return common.Hash{}
 }
 
+// GetCommittedState returns a value in account storage as of the start of the
+// current transaction.
+func (self *StateDB) GetCommittedState(a common.Address, b common.Hash) common.Hash {
+	stateObject := self.getStateObject(a)
+	if stateObject != nil {
+		return stateObject.GetCommittedState(self.db, b)
+	}
+	return common.Hash{}
+}
+
 func (self *StateDB) GetStateByteArray(a common.Address, b common.Hash) []byte {
 	stateObject := self.getStateObject(a)
 	if stateObject != nil {
@@ -262,6 +296,33 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the Merkle proof of an account in the state trie.
+func (self *StateDB) GetProof(a common.Address) ([]rlp.RawValue, error) {
+	proof := self.trie.Prove(a[:])
+	if proof == nil {
+		return nil, errProofMissingNode
+	}
+	return proof, nil
+}
+
+// GetStorageProof returns the Merkle proof of a storage slot in the storage
+// trie of an account, and the value of the slot as stored in the trie.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([]rlp.RawValue, []byte, error) {
+	tr := self.StorageTrie(a)
+	if tr == nil {
+		return nil, nil, errNoStorageTrie
+	}
+	value, err := tr.TryGet(key[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := tr.Prove(key[:])
+	if proof == nil {
+		return nil, nil, errProofMissingNode
+	}
+	return proof, value, nil
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -469,7 +530,10 @@ func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common
 // cb is callback function. cb return true indicating like to continue, return false indicating stop
 func (db *StateDB) ForEachStorageByteArray(addr common.Address, cb func(key common.Hash, value []byte) bool) {
 
-	epochid,_ := util.GetCurrentBlkEpochSlotID()
+	epochid := uint64(0)
+	if db.epochSource != nil {
+		epochid = db.epochSource()
+	}
 
 	if epochid < posconfig.Cfg().MercuryEpochId {
 		db.ForEachStorageByteArrayBeforeFork(addr,cb)
@@ -544,6 +608,7 @@ func (self *StateDB) Copy() *StateDB {
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
 		preimages:         make(map[common.Hash][]byte),
+		epochSource:       self.epochSource,
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.stateObjectsDirty {
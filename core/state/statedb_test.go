@@ -32,7 +32,10 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -393,3 +396,58 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+func TestProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		addr    = common.BytesToAddress([]byte{0x01})
+		slot    = common.BytesToHash([]byte{0x02})
+		byteKey = common.BytesToHash([]byte{0x03})
+	)
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetState(addr, slot, common.BytesToHash([]byte{0x04}))
+	state.SetStateByteArray(addr, byteKey, []byte("staker"))
+	root, _ := state.CommitTo(db, false)
+	state, _ = New(root, NewDatabase(db))
+
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := trie.VerifyProof(root, crypto.Keccak256(addr[:]), proof)
+	if err != nil {
+		t.Fatalf("account proof: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("proven balance mismatch: have %v, want 42", account.Balance)
+	}
+
+	for key, want := range map[common.Hash][]byte{slot: {0x04}, byteKey: []byte("staker")} {
+		proof, value, err := state.GetStorageProof(addr, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), proof)
+		if err != nil {
+			t.Fatalf("storage proof of %x: %v", key, err)
+		}
+		if !bytes.Equal(have, value) {
+			t.Fatalf("proven value of %x mismatch: have %x, want %x", key, have, value)
+		}
+		if key == slot {
+			want, _ = rlp.EncodeToBytes(want)
+		}
+		if !bytes.Equal(value, want) {
+			t.Fatalf("value of %x mismatch: have %x, want %x", key, value, want)
+		}
+	}
+	if _, _, err := state.GetStorageProof(common.BytesToAddress([]byte{0x05}), slot); err != errNoStorageTrie {
+		t.Fatalf("missing account error mismatch: have %v, want %v", err, errNoStorageTrie)
+	}
+}
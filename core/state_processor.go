@@ -78,6 +78,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	//if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 	//	misc.ApplyDAOHardFork(statedb)
 	//}
+	// Verify the ring signatures of the block in one batch
+	preverifyRingSigns(types.MakeSigner(p.config, header.Number), block.Transactions())
+
 	// Iterate over and process the individual transactions
 	log.Debug("***process", "block", block.Number().Uint64())
 	for i, tx := range block.Transactions() {
@@ -87,11 +90,15 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 			return nil, nil, nil, err
 		}
 		receipts = append(receipts, receipt)
-		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
 
+	// Finalize may log to the receipts, as the pos jailing does
+	for _, receipt := range receipts {
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+
 	return receipts, allLogs, totalUsedGas, nil
 }
 
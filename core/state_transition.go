@@ -38,6 +38,7 @@ import (
 var (
 	Big0                         = big.NewInt(0)
 	errInsufficientBalanceForGas = errors.New("insufficient balance to pay for gas")
+	errStampSpent                = errors.New("stamp has been spended")
 )
 
 /*
@@ -84,6 +85,7 @@ type Message interface {
 	Data() []byte
 
 	TxType() uint64
+	AccessList() types.AccessList
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message
@@ -122,6 +124,13 @@ func IntrinsicGas(data []byte, to *common.Address, homestead bool) *big.Int {
 	return igas
 }
 
+// AccessListGas computes the gas paid for the access list of a typed
+// transaction on top of its intrinsic gas.
+func AccessListGas(accessList types.AccessList) *big.Int {
+	gas := new(big.Int).SetUint64(uint64(len(accessList)) * params.TxAccessListAddressGas)
+	return gas.Add(gas, new(big.Int).SetUint64(uint64(accessList.StorageKeys())*params.TxAccessListStorageKeyGas))
+}
+
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
 	return &StateTransition{
@@ -248,6 +257,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 	// Pay intrinsic gas
 	// TODO convert to uint64
 	intrinsicGas := IntrinsicGas(st.data, msg.To(), true /*homestead*/)
+	intrinsicGas.Add(intrinsicGas, AccessListGas(msg.AccessList()))
 	//log.Trace("get intrinsic gas", "gas", intrinsicGas.String())
 	if intrinsicGas.BitLen() > 64 {
 		return nil, nil, nil, false, vm.ErrOutOfGas
@@ -427,37 +437,55 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 
 func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int,
 	intrGas *big.Int, txValue *big.Int, gasLimit *big.Int) error {
+	info, err := verifyPrivacyTx(stateDB, hashInput, in, gasPrice, intrGas, txValue)
+	if err != nil {
+		return err
+	}
+
+	return checkPrivacyTxStamp(stateDB, crypto.FromECDSAPub(info.KeyImage), info.StampTotalGas, gasLimit)
+}
+
+// verifyPrivacyTx checks a privacy tx but for the spending of its stamp. The
+// ring signature and the stamp don't change once on chain, so the result holds
+// until the stamp is spent.
+func verifyPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int,
+	intrGas *big.Int, txValue *big.Int) (*PrivacyTxInfo, error) {
 	if intrGas == nil || intrGas.BitLen() > 64 {
-		return vm.ErrOutOfGas
+		return nil, vm.ErrOutOfGas
 	}
 
 	if txValue.Sign() != 0 {
-		return vm.ErrInvalidPrivacyValue
+		return nil, vm.ErrInvalidPrivacyValue
 	}
 
 	if gasPrice == nil || gasPrice.Cmp(common.Big0) <= 0 {
-		return vm.ErrInvalidGasPrice
+		return nil, vm.ErrInvalidGasPrice
 	}
 
 	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if info.StampTotalGas > gasLimit.Uint64() {
+	if info.GasLeftSubRingSign < intrGas.Uint64() {
+		return nil, vm.ErrOutOfGas
+	}
+
+	return info, nil
+}
+
+// checkPrivacyTxStamp checks that the stamp of a verified privacy tx, given by
+// its key image, isn't spent and the gas it pays for fits in a block.
+func checkPrivacyTxStamp(stateDB vm.StateDB, keyImage []byte, stampGas uint64, gasLimit *big.Int) error {
+	if stampGas > gasLimit.Uint64() {
 		return ErrGasLimit
 	}
 
-	kix := crypto.FromECDSAPub(info.KeyImage)
-	exist, _, err := vm.CheckOTAImageExist(stateDB, kix)
+	exist, _, err := vm.CheckOTAImageExist(stateDB, keyImage)
 	if err != nil {
 		return err
 	} else if exist {
-		return errors.New("stamp has been spended")
-	}
-
-	if info.GasLeftSubRingSign < intrGas.Uint64() {
-		return vm.ErrOutOfGas
+		return errStampSpent
 	}
 
 	return nil
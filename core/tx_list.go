@@ -27,6 +27,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 // nonceHeap is a heap.Interface implementation over 64bit unsigned integers for
@@ -252,11 +253,13 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
 func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
-	// If there's an older better transaction, abort
+	// If there's an older better transaction, abort. Both the fee cap and the
+	// tip cap have to be bumped, they are the gas price of legacy transactions.
 	old := l.txs.Get(tx.Nonce())
 	if old != nil {
-		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-		if threshold.Cmp(tx.GasPrice()) >= 0 {
+		feeThreshold := new(big.Int).Div(new(big.Int).Mul(old.GasFeeCap(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		tipThreshold := new(big.Int).Div(new(big.Int).Mul(old.GasTipCap(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		if feeThreshold.Cmp(tx.GasFeeCap()) >= 0 || tipThreshold.Cmp(tx.GasTipCap()) >= 0 {
 			return false, nil
 		}
 	}
@@ -316,8 +319,9 @@ func (l *txList) Filter(costLimit, gasLimit *big.Int) (types.Transactions, types
 	return removed, invalids
 }
 
-// InvalidPrivacyTx remove invalidate privacy transactions
-func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int) types.Transactions {
+// InvalidPrivacyTx remove invalidate privacy transactions, the ring signatures
+// verified by the lane aren't verified again
+func (l *txList) InvalidPrivacyTx(lane *privacyLane, stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPrivacyTransaction(tx.Txtype()) {
 			return false
@@ -329,7 +333,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 		}
 
 		intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
-		err = ValidPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit)
+		_, err = lane.verify(stateDB, from, tx, intrGas, gasLimit)
 
 		return err != nil
 	})
@@ -352,7 +356,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 }
 
 // InvalidPosTx remove invalidate pos transactions
-func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.Transactions {
+func (l *txList) InvalidPosRBTx(posCtx *posctx.Context, stateDB vm.StateDB, signer types.Signer) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPosTransaction(tx.Txtype()) || (*tx.To()) != vm.GetRBAddress() {
 			return false
@@ -363,7 +367,7 @@ func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.T
 			return true
 		}
 
-		err = vm.ValidPosRBTx(stateDB, from, tx.Data())
+		err = vm.ValidPosRBTx(posCtx, stateDB, from, tx.Data())
 		return err != nil
 	})
 
@@ -34,6 +34,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
@@ -86,6 +87,22 @@ var (
 
 	// ErrStakingTx is returned if pos_staking_contract tx called in noStaking mode
 	ErrStakingTx = errors.New("pos staking in staking mode")
+
+	// ErrPrivacyStampConflict is returned if a privacy transaction spends the same
+	// stamp as a pooled one, without the price bump required to replace it.
+	ErrPrivacyStampConflict = errors.New("stamp spent by pooled transaction")
+
+	// ErrPrivacyPoolFull is returned if the privacy transaction lane is full of
+	// transactions paying at least as much as a new one.
+	ErrPrivacyPoolFull = errors.New("privacy transaction pool full")
+
+	// ErrTipAboveFeeCap is returned if a dynamic-fee transaction has a tip cap
+	// higher than its fee cap.
+	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
+
+	// ErrFeeCapVeryHigh is returned if the fee cap or the tip cap of a
+	// transaction doesn't fit in 256 bits.
+	ErrFeeCapVeryHigh = errors.New("max fee per gas higher than 2^256-1")
 )
 
 var (
@@ -106,6 +123,11 @@ var (
 	queuedRateLimitCounter = metrics.NewCounter("txpool/queued/ratelimit") // Dropped due to rate limiting
 	queuedNofundsCounter   = metrics.NewCounter("txpool/queued/nofunds")   // Dropped due to out-of-funds
 
+	// Metrics for the privacy lane
+	privacyReplaceCounter   = metrics.NewCounter("txpool/privacy/replace")
+	privacyConflictCounter  = metrics.NewCounter("txpool/privacy/conflict")  // Dropped due to a pooled tx spending the stamp
+	privacyRateLimitCounter = metrics.NewCounter("txpool/privacy/ratelimit") // Dropped due to the lane limit
+
 	// General tx metrics
 	invalidTxCounter     = metrics.NewCounter("txpool/invalid")
 	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
@@ -117,6 +139,7 @@ type blockChain interface {
 	CurrentBlock() *types.Block
 	GetBlock(hash common.Hash, number uint64) *types.Block
 	StateAt(root common.Hash) (*state.StateDB, error)
+	PosContext() *posctx.Context
 
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
 }
@@ -134,6 +157,7 @@ type TxPoolConfig struct {
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
+	PrivacySlots uint64 // Maximum number of privacy transaction slots, counted in the global ones too
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 }
@@ -151,6 +175,7 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalSlots:  10240,
 	AccountQueue: 64 * 128,
 	GlobalQueue:  10240,
+	PrivacySlots: 1024,
 
 	Lifetime: 3 * time.Hour,
 }
@@ -171,6 +196,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.PrivacySlots < 1 {
+		log.Warn("Sanitizing invalid txpool privacy slots", "provided", conf.PrivacySlots, "updated", DefaultTxPoolConfig.PrivacySlots)
+		conf.PrivacySlots = DefaultTxPoolConfig.PrivacySlots
+	}
 	return conf
 }
 
@@ -205,10 +234,12 @@ type TxPool struct {
 	beats   map[common.Address]time.Time       // Last heartbeat from each known account
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
+	privacy *privacyLane                       // Privacy transactions by stamp spent
 
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
+	typedTx   bool // Fork accepting typed transactions enabled for the next block
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -222,11 +253,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.NewEIP155Signer(chainconfig.ChainId),
+		signer:      types.NewTypedTxSigner(chainconfig.ChainId),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
 		all:         make(map[common.Hash]*types.Transaction),
+		privacy:     newPrivacyLane(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
@@ -410,6 +442,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.typedTx = pool.chainconfig.IsTypedTx(new(big.Int).Add(newHead.Number, big.NewInt(1)))
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -521,6 +554,19 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// PrivacyContent retrieves the privacy transactions of the pool, pending or
+// queued, by the hex encoded key image of the stamp they spend.
+func (pool *TxPool) PrivacyContent() map[string]*types.Transaction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	content := make(map[string]*types.Transaction, pool.privacy.Len())
+	for hash, info := range pool.privacy.txs {
+		content[common.ToHex(info.keyImage)] = pool.all[hash]
+	}
+	return content
+}
+
 // Pending retrieves all currently processable transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -553,9 +599,21 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
-func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (*common.Address, error) {
+//
+// The verification of a privacy transaction is returned along with the sender.
+func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (*common.Address, *privacyTxInfo, error) {
 	if !types.IsValidTransactionType(tx.Txtype()) {
-		return nil, ErrInvalidTxType
+		return nil, nil, ErrInvalidTxType
+	}
+	// Typed transactions are only accepted once the fork is enabled
+	if tx.Type() != types.LegacyTxType && !pool.typedTx {
+		return nil, nil, types.ErrTxTypeNotSupported
+	}
+	if tx.GasFeeCap().BitLen() > 256 || tx.GasTipCap().BitLen() > 256 {
+		return nil, nil, ErrFeeCapVeryHigh
+	}
+	if tx.GasTipCap().Cmp(tx.GasFeeCap()) > 0 {
+		return nil, nil, ErrTipAboveFeeCap
 	}
 
 	// type must match to des address
@@ -563,66 +621,68 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (*common.Addre
 	isPosType := types.IsPosTransaction(tx.Txtype())
 	isPosAddr := vm.IsPosPrecompiledAddr(tx.To())
 	if isPosType != isPosAddr {
-		return nil, ErrInvalidTxType
+		return nil, nil, ErrInvalidTxType
 	}
 
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 320*1024 {
-		return nil, ErrOversizedData
+		return nil, nil, ErrOversizedData
 	}
 
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
-		return nil, ErrNegativeValue
+		return nil, nil, ErrNegativeValue
 	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if pool.currentMaxGas.Cmp(tx.Gas()) < 0 {
-		return nil, ErrGasLimit
+		return nil, nil, ErrGasLimit
 	}
 	// Make sure the transaction is signed properly
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
-		return nil, ErrInvalidSender
+		return nil, nil, ErrInvalidSender
 	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
-		return nil, ErrUnderpriced
+		return nil, nil, ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
-		return nil, ErrNonceTooLow
+		return nil, nil, ErrNonceTooLow
 	}
 	// Transactor should have enough funds to cover the costs
 	// cost == V + GP * GL
 	if (isNormalType || isPosType) && pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
-		return nil, ErrInsufficientFunds
+		return nil, nil, ErrInsufficientFunds
 	}
 
+	var privacy *privacyTxInfo
 	intrGas := IntrinsicGas(tx.Data(), tx.To(), pool.homestead)
+	intrGas.Add(intrGas, AccessListGas(tx.AccessList()))
 	if isNormalType || isPosType {
 		if tx.Gas().Cmp(intrGas) < 0 {
-			return nil, ErrIntrinsicGas
+			return nil, nil, ErrIntrinsicGas
 		}
 
 	} else {
-		err := ValidPrivacyTx(pool.currentState, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), pool.currentMaxGas)
+		privacy, err = pool.privacy.verify(pool.currentState, from, tx, intrGas, pool.currentMaxGas)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	// Check precompile contracts transactions validation
 	if tx.To() != nil {
 		if p := vm.PrecompiledContractsByzantium[*tx.To()]; p != nil {
-			if err = p.ValidTx(pool.currentState, pool.signer, tx); err != nil {
-				return nil, err
+			if err = p.ValidTx(pool.chain.PosContext(), pool.currentState, pool.signer, tx); err != nil {
+				return nil, nil, err
 			}
 		}
 	}
 
-	return &from, nil
+	return &from, privacy, nil
 }
 
 // add validates a transaction and inserts it into the non-executable queue for
@@ -642,13 +702,21 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	}
 
 	var senderFrom *common.Address
+	var privacy *privacyTxInfo
 	var err error
 	// If the transaction fails basic validation, discard it
-	if senderFrom, err = pool.validateTx(tx, local); err != nil {
+	if senderFrom, privacy, err = pool.validateTx(tx, local); err != nil {
 		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	// Check the room in the privacy lane, keeping a single transaction per stamp
+	var evict []common.Hash
+	if privacy != nil {
+		if evict, err = pool.checkPrivacyTx(tx, privacy); err != nil {
+			return false, err
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -677,12 +745,15 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 		// New transaction is better, replace old one
 		if old != nil {
-			delete(pool.all, old.Hash())
-			pool.priced.Removed()
+			pool.forgetTx(old.Hash())
 			pendingReplaceCounter.Inc(1)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
+		if privacy != nil {
+			pool.privacy.add(hash, privacy)
+			pool.evictPrivacyTxs(evict)
+		}
 		pool.journalTx(from, tx)
 
 		//log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -693,6 +764,10 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if privacy != nil {
+		pool.privacy.add(hash, privacy)
+		pool.evictPrivacyTxs(evict)
+	}
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
@@ -703,6 +778,55 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	return replace, nil
 }
 
+// checkPrivacyTx checks whether a new privacy transaction fits into the privacy
+// lane, returning the pooled transactions to evict to make room for it. A pooled
+// transaction spending the same stamp is replaced if the new one pays the required
+// price bump, and if the lane is full its cheapest non-local transaction is evicted
+// if the new one pays more. The pool itself is left untouched.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkPrivacyTx(tx *types.Transaction, info *privacyTxInfo) ([]common.Hash, error) {
+	if hash, ok := pool.privacy.conflict(info.keyImage); ok {
+		old := pool.all[hash]
+		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump))), big.NewInt(100))
+		if tx.GasPrice().Cmp(threshold) < 0 {
+			privacyConflictCounter.Inc(1)
+			return nil, ErrPrivacyStampConflict
+		}
+		privacyReplaceCounter.Inc(1)
+		return []common.Hash{hash}, nil
+	}
+	if uint64(pool.privacy.Len()) < pool.config.PrivacySlots {
+		return nil, nil
+	}
+	var cheapest *types.Transaction
+	for hash := range pool.privacy.txs {
+		pooled := pool.all[hash]
+		if pool.locals.containsTx(pooled) {
+			continue
+		}
+		if cheapest == nil || pooled.GasPrice().Cmp(cheapest.GasPrice()) < 0 {
+			cheapest = pooled
+		}
+	}
+	if cheapest == nil || cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+		privacyRateLimitCounter.Inc(1)
+		return nil, ErrPrivacyPoolFull
+	}
+	return []common.Hash{cheapest.Hash()}, nil
+}
+
+// evictPrivacyTxs removes the privacy transactions a newly inserted one made
+// room for, once it is pooled.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evictPrivacyTxs(hashes []common.Hash) {
+	for _, hash := range hashes {
+		log.Trace("Evicting privacy transaction", "hash", hash)
+		pool.removeTx(hash)
+	}
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -720,8 +844,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
-		delete(pool.all, old.Hash())
-		pool.priced.Removed()
+		pool.forgetTx(old.Hash())
 		queuedReplaceCounter.Inc(1)
 	}
 	pool.all[hash] = tx
@@ -754,16 +877,14 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	inserted, old := list.Add(tx, pool.config.PriceBump)
 	if !inserted {
 		// An older transaction was better, discard this
-		delete(pool.all, hash)
-		pool.priced.Removed()
+		pool.forgetTx(hash)
 
 		pendingDiscardCounter.Inc(1)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
-		delete(pool.all, old.Hash())
-		pool.priced.Removed()
+		pool.forgetTx(old.Hash())
 
 		pendingReplaceCounter.Inc(1)
 	}
@@ -832,6 +953,9 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
+	// Verify the ring signatures of the batch before taking the lock
+	preverifyRingSigns(pool.signer, txs)
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -871,6 +995,14 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all[hash]
 }
 
+// forgetTx drops a transaction from the lookup set, the price index and the
+// privacy lane, leaving the account lists alone.
+func (pool *TxPool) forgetTx(hash common.Hash) {
+	delete(pool.all, hash)
+	pool.priced.Removed()
+	pool.privacy.remove(hash)
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash) {
@@ -882,8 +1014,7 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	addr, _ := types.Sender(pool.signer, tx) // already validated during insertion
 
 	// Remove it from the list of known transactions
-	delete(pool.all, hash)
-	pool.priced.Removed()
+	pool.forgetTx(hash)
 
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
@@ -935,8 +1066,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -944,29 +1074,26 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			if types.IsNormalTransaction(tx.Txtype()) || types.IsPosTransaction(tx.Txtype()) {
 				hash := tx.Hash()
 				log.Trace("Removed unpayable queued transaction", "hash", hash)
-				delete(pool.all, hash)
-				pool.priced.Removed()
+				pool.forgetTx(hash)
 				queuedNofundsCounter.Inc(1)
 			}
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.privacy, pool.currentState, pool.signer, pool.currentMaxGas)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			queuedNofundsCounter.Inc(1)
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.chain.PosContext(), pool.currentState, pool.signer)
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 
@@ -975,8 +1102,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range invalidPosEL {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos EL transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 
@@ -990,8 +1116,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		if !pool.locals.contains(addr) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
-				delete(pool.all, hash)
-				pool.priced.Removed()
+				pool.forgetTx(hash)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -1035,8 +1160,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						for _, tx := range list.Cap(list.Len() - 1) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
-							delete(pool.all, hash)
-							pool.priced.Removed()
+							pool.forgetTx(hash)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1057,8 +1181,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 					for _, tx := range list.Cap(list.Len() - 1) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
-						delete(pool.all, hash)
-						pool.priced.Removed()
+						pool.forgetTx(hash)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1128,8 +1251,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range list.Forward(nonce) {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -1137,8 +1259,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			if types.IsNormalTransaction(tx.Txtype()) || types.IsPosTransaction(tx.Txtype()) {
 				hash := tx.Hash()
 				log.Trace("Removed unpayable pending transaction", "hash", hash)
-				delete(pool.all, hash)
-				pool.priced.Removed()
+				pool.forgetTx(hash)
 				pendingNofundsCounter.Inc(1)
 			}
 		}
@@ -1149,22 +1270,20 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.privacy, pool.currentState, pool.signer, pool.currentMaxGas)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.chain.PosContext(), pool.currentState, pool.signer)
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 
@@ -1173,8 +1292,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range invalidPosEL {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos EL transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.forgetTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 
@@ -37,6 +37,7 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 // testTxPoolConfig is a transaction pool configuration without stateful disk
@@ -68,6 +69,10 @@ func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
 	return bc.statedb, nil
 }
 
+func (bc *testBlockChain) PosContext() *posctx.Context {
+	return nil
+}
+
 func (bc *testBlockChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription {
 	return bc.chainHeadFeed.Subscribe(ch)
 }
@@ -122,9 +127,31 @@ func validateTxPoolInternals(pool *TxPool) error {
 			return fmt.Errorf("pending nonce mismatch: have %v, want %v", nonce, last+1)
 		}
 	}
+	// Ensure the privacy lane only tracks pooled transactions, one per stamp
+	if images := len(pool.privacy.images); images != pool.privacy.Len() {
+		return fmt.Errorf("privacy key image count %d != %d privacy transactions", images, pool.privacy.Len())
+	}
+	for hash := range pool.privacy.txs {
+		if pool.all[hash] == nil {
+			return fmt.Errorf("privacy transaction %x not pooled", hash)
+		}
+	}
 	return nil
 }
 
+// privacyTransaction creates a privacy transaction spending the stamp of the
+// given key image, marked as verified so that it needs no ring signature.
+func privacyTransaction(pool *TxPool, gasprice *big.Int, keyImage []byte) *types.Transaction {
+	key, _ := crypto.GenerateKey()
+	return privacyTransactionWithKey(pool, 0, gasprice, keyImage, key)
+}
+
+func privacyTransactionWithKey(pool *TxPool, nonce uint64, gasprice *big.Int, keyImage []byte, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewOTATransaction(nonce, common.Address{}, big.NewInt(0), big.NewInt(100000), gasprice, nil), types.HomesteadSigner{}, key)
+	pool.privacy.verified.Add(tx.Hash(), &privacyTxInfo{keyImage: keyImage, stampGas: 100000})
+	return tx
+}
+
 func deriveSender(tx *types.Transaction) (common.Address, error) {
 	return types.Sender(types.HomesteadSigner{}, tx)
 }
@@ -1521,7 +1548,9 @@ func (CTStateDB) GetCode(common.Address) []byte
 func (CTStateDB) SetCode(common.Address, []byte)                                         {}
 func (CTStateDB) GetCodeSize(common.Address) int                                         { return 0 }
 func (CTStateDB) AddRefund(*big.Int)                                                     {}
+func (CTStateDB) SubRefund(*big.Int)                                                     {}
 func (CTStateDB) GetRefund() *big.Int                                                    { return nil }
+func (CTStateDB) GetCommittedState(common.Address, common.Hash) common.Hash              { return common.Hash{} }
 func (CTStateDB) GetState(common.Address, common.Hash) common.Hash                       { return common.Hash{} }
 func (CTStateDB) SetState(common.Address, common.Hash, common.Hash)                      {}
 func (CTStateDB) Suicide(common.Address) bool                                            { return false }
@@ -1631,3 +1660,174 @@ func TestStampVerifyFailWrongSender(t *testing.T) {
 	}
 
 }
+
+// Tests that the privacy lane keeps a single transaction per stamp, and evicts
+// the cheapest transactions when full.
+func TestTransactionPrivacyLane(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PrivacySlots = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	cheap := privacyTransaction(pool, big.NewInt(1), []byte("image1"))
+	pricey := privacyTransaction(pool, big.NewInt(10), []byte("image2"))
+	if err := pool.AddRemotes(types.Transactions{cheap, pricey}); err != nil {
+		t.Fatalf("failed to add privacy transactions: %v", err)
+	}
+	if pool.privacy.Len() != 2 {
+		t.Fatalf("privacy transaction count mismatch: have %d, want 2", pool.privacy.Len())
+	}
+	// Spending a pooled stamp needs a price bump
+	if err := pool.AddRemote(privacyTransaction(pool, big.NewInt(10), []byte("image2"))); err != ErrPrivacyStampConflict {
+		t.Fatalf("conflicting transaction error mismatch: have %v, want %v", err, ErrPrivacyStampConflict)
+	}
+	replacement := privacyTransaction(pool, big.NewInt(11), []byte("image2"))
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to replace privacy transaction: %v", err)
+	}
+	if pool.Get(pricey.Hash()) != nil {
+		t.Fatalf("replaced privacy transaction still pooled")
+	}
+	// A full lane only accepts transactions paying more than its cheapest one
+	if err := pool.AddRemote(privacyTransaction(pool, big.NewInt(1), []byte("image3"))); err != ErrPrivacyPoolFull {
+		t.Fatalf("underpriced transaction error mismatch: have %v, want %v", err, ErrPrivacyPoolFull)
+	}
+	better := privacyTransaction(pool, big.NewInt(2), []byte("image3"))
+	if err := pool.AddRemote(better); err != nil {
+		t.Fatalf("failed to add better paying privacy transaction: %v", err)
+	}
+	if pool.Get(cheap.Hash()) != nil {
+		t.Fatalf("cheapest privacy transaction not evicted")
+	}
+	content := pool.PrivacyContent()
+	if len(content) != 2 || content[common.ToHex([]byte("image2"))] != replacement || content[common.ToHex([]byte("image3"))] != better {
+		t.Fatalf("privacy content mismatch: %v", content)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Spending a stamp on chain drops its transaction on reset
+	vm.AddOTAImage(statedb, []byte("image3"), []byte{1})
+	pool.lockedReset(nil, nil)
+
+	if pool.Get(better.Hash()) != nil {
+		t.Fatalf("privacy transaction with spent stamp still pooled")
+	}
+	if pool.Get(replacement.Hash()) == nil {
+		t.Fatalf("valid privacy transaction dropped")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that a privacy transaction rejected by the pool doesn't evict anything
+// from the privacy lane.
+func TestTransactionPrivacyLaneRejected(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PrivacySlots = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	cheap := privacyTransaction(pool, big.NewInt(1), []byte("image1"))
+	pricey := privacyTransactionWithKey(pool, 0, big.NewInt(10), []byte("image2"), key)
+	if err := pool.AddRemotes(types.Transactions{cheap, pricey}); err != nil {
+		t.Fatalf("failed to add privacy transactions: %v", err)
+	}
+	// An underpriced nonce replacement must leave the cheapest transaction pooled
+	if err := pool.AddRemote(privacyTransactionWithKey(pool, 0, big.NewInt(5), []byte("image3"), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if pool.Get(cheap.Hash()) == nil {
+		t.Fatalf("cheapest privacy transaction evicted by a rejected one")
+	}
+	// An underpriced nonce replacement must leave the spender of its stamp pooled
+	other, _ := crypto.GenerateKey()
+	spender := privacyTransactionWithKey(pool, 0, big.NewInt(2), []byte("image3"), other)
+	if err := pool.AddRemote(spender); err != nil {
+		t.Fatalf("failed to add better paying privacy transaction: %v", err)
+	}
+	if err := pool.AddRemote(privacyTransactionWithKey(pool, 0, big.NewInt(5), []byte("image3"), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if pool.privacy.Len() != 2 {
+		t.Fatalf("privacy transaction count mismatch: have %d, want 2", pool.privacy.Len())
+	}
+	if pool.Get(spender.Hash()) == nil || pool.Get(pricey.Hash()) == nil {
+		t.Fatalf("pooled transaction dropped by a rejected replacement")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+func dynamicFeeTransaction(nonce uint64, gaslimit, tip, feeCap *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:    params.TestChainConfig.ChainId,
+		Nonce:      nonce,
+		GasTipCap:  tip,
+		GasFeeCap:  feeCap,
+		Gas:        gaslimit,
+		To:         &common.Address{},
+		Value:      big.NewInt(100),
+		AccessList: types.AccessList{{Address: common.Address{1}}},
+	}), types.NewTypedTxSigner(params.TestChainConfig.ChainId), key)
+	return tx
+}
+
+// Tests that typed transactions are only pooled once their fork is enabled,
+// and that replacing a dynamic-fee transaction bumps both of its caps.
+func TestTransactionTypedTx(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	gas := big.NewInt(30000)
+	if err := pool.AddRemote(dynamicFeeTransaction(0, gas, big.NewInt(1), big.NewInt(1), key)); err != types.ErrTxTypeNotSupported {
+		t.Fatalf("pre-fork error mismatch: have %v, want %v", err, types.ErrTxTypeNotSupported)
+	}
+
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+	pool.chainconfig = &config
+	pool.lockedReset(nil, nil)
+
+	if err := pool.AddRemote(dynamicFeeTransaction(0, big.NewInt(21000), big.NewInt(1), big.NewInt(1), key)); err != ErrIntrinsicGas {
+		t.Fatalf("access list gas error mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	if err := pool.AddRemote(dynamicFeeTransaction(0, gas, big.NewInt(2), big.NewInt(1), key)); err != ErrTipAboveFeeCap {
+		t.Fatalf("tip cap error mismatch: have %v, want %v", err, ErrTipAboveFeeCap)
+	}
+	if err := pool.AddRemote(dynamicFeeTransaction(0, gas, big.NewInt(1), big.NewInt(10), key)); err != nil {
+		t.Fatalf("failed to add dynamic-fee transaction: %v", err)
+	}
+	// Bumping the fee cap alone doesn't replace the pooled transaction
+	if err := pool.AddRemote(dynamicFeeTransaction(0, gas, big.NewInt(1), big.NewInt(20), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	replacement := dynamicFeeTransaction(0, gas, big.NewInt(2), big.NewInt(20), key)
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to replace dynamic-fee transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 || pool.Get(replacement.Hash()) == nil {
+		t.Fatalf("replacement not pending")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"math/big"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+const verifiedPrivacyTxCacheSize = 4096
+
+// privacyTxInfo is the part of the verification of a privacy transaction that
+// holds until its stamp is spent.
+type privacyTxInfo struct {
+	keyImage []byte // Key image of the stamp OTA spent by the transaction
+	stampGas uint64 // Gas paid for by the stamp
+}
+
+// privacyLane tracks the privacy transactions of the pool apart from the
+// others. It indexes them by the key image of the stamp they spend, so that
+// only one transaction spending a stamp is pooled, and caches their verified
+// ring signatures, so that they aren't verified again on every reset.
+type privacyLane struct {
+	txs      map[common.Hash]*privacyTxInfo // Pooled privacy transactions
+	images   map[string]common.Hash         // Pooled transaction spending each key image
+	verified *lru.Cache                     // Verified privacy transactions, pooled or not
+}
+
+// newPrivacyLane creates an empty privacy transaction lane.
+func newPrivacyLane() *privacyLane {
+	verified, _ := lru.New(verifiedPrivacyTxCacheSize)
+	return &privacyLane{
+		txs:      make(map[common.Hash]*privacyTxInfo),
+		images:   make(map[string]common.Hash),
+		verified: verified,
+	}
+}
+
+// Len returns the number of pooled privacy transactions.
+func (l *privacyLane) Len() int {
+	return len(l.txs)
+}
+
+// verify checks the validity of a privacy transaction against a state, reusing
+// the verification of its ring signature if it has already been done.
+func (l *privacyLane) verify(stateDB vm.StateDB, from common.Address, tx *types.Transaction, intrGas *big.Int, gasLimit *big.Int) (*privacyTxInfo, error) {
+	hash := tx.Hash()
+	if cached, ok := l.verified.Get(hash); ok {
+		info := cached.(*privacyTxInfo)
+		if err := checkPrivacyTxStamp(stateDB, info.keyImage, info.stampGas, gasLimit); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+	verified, err := verifyPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value())
+	if err != nil {
+		return nil, err
+	}
+	info := &privacyTxInfo{
+		keyImage: crypto.FromECDSAPub(verified.KeyImage),
+		stampGas: verified.StampTotalGas,
+	}
+	l.verified.Add(hash, info)
+
+	if err := checkPrivacyTxStamp(stateDB, info.keyImage, info.stampGas, gasLimit); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// conflict returns the pooled transaction spending the same stamp as a key
+// image, if any.
+func (l *privacyLane) conflict(keyImage []byte) (common.Hash, bool) {
+	hash, ok := l.images[string(keyImage)]
+	return hash, ok
+}
+
+// add inserts a pooled privacy transaction into the lane.
+func (l *privacyLane) add(hash common.Hash, info *privacyTxInfo) {
+	l.txs[hash] = info
+	l.images[string(info.keyImage)] = hash
+}
+
+// remove deletes a transaction from the lane, if it's tracked there.
+func (l *privacyLane) remove(hash common.Hash) {
+	info, ok := l.txs[hash]
+	if !ok {
+		return
+	}
+	delete(l.txs, hash)
+	if l.images[string(info.keyImage)] == hash {
+		delete(l.images, string(info.keyImage))
+	}
+}
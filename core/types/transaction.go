@@ -19,6 +19,7 @@ package types
 
 import (
 	"container/heap"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -34,8 +35,20 @@ import (
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
 
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
-	errNoSigner   = errors.New("missing signing methods")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	errNoSigner           = errors.New("missing signing methods")
+	errEmptyTypedTx       = errors.New("empty typed transaction bytes")
+)
+
+// Envelope types of the transactions. A typed transaction is encoded as its
+// type byte followed by the RLP encoding of its fields, the legacy ones as
+// before. The Wanchain privacy and PoS transactions are legacy ones, typed
+// transactions are normal ones.
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01
+	DynamicFeeTxType = 0x02
 )
 
 // deriveSigner makes a *best* guess about which signer to use.
@@ -48,13 +61,40 @@ func deriveSigner(V *big.Int) Signer {
 }
 
 type Transaction struct {
-	data txdata
+	inner TxData
 	// caches
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
 }
 
+// TxData is the underlying data of a transaction, implemented by the legacy
+// transactions and each typed transaction.
+type TxData interface {
+	txType() byte
+	copy() TxData
+
+	chainID() *big.Int
+	txtype() uint64
+	accessList() AccessList
+	data() []byte
+	gas() *big.Int
+	gasPrice() *big.Int
+	gasTipCap() *big.Int
+	gasFeeCap() *big.Int
+	value() *big.Int
+	nonce() uint64
+	to() *common.Address
+
+	rawSignatureValues() (v, r, s *big.Int)
+	setSignatureValues(v, r, s *big.Int)
+}
+
+// NewTx creates a new transaction from its underlying data, which is copied.
+func NewTx(inner TxData) *Transaction {
+	return &Transaction{inner: inner.copy()}
+}
+
 type txdata struct {
 	Txtype uint64 `json:"Txtype"    gencodec:"required"`
 
@@ -87,6 +127,40 @@ type txdataMarshaling struct {
 	S            *hexutil.Big
 }
 
+func (d *txdata) txType() byte { return LegacyTxType }
+
+func (d *txdata) copy() TxData {
+	cpy := &txdata{
+		Txtype:       d.Txtype,
+		AccountNonce: d.AccountNonce,
+		Recipient:    copyAddressPtr(d.Recipient),
+		Payload:      common.CopyBytes(d.Payload),
+		Price:        copyBig(d.Price),
+		GasLimit:     copyBig(d.GasLimit),
+		Amount:       copyBig(d.Amount),
+		V:            copyBig(d.V),
+		R:            copyBig(d.R),
+		S:            copyBig(d.S),
+	}
+	return cpy
+}
+
+func (d *txdata) chainID() *big.Int      { return deriveChainId(d.V) }
+func (d *txdata) txtype() uint64         { return d.Txtype }
+func (d *txdata) accessList() AccessList { return nil }
+func (d *txdata) data() []byte           { return d.Payload }
+func (d *txdata) gas() *big.Int          { return d.GasLimit }
+func (d *txdata) gasPrice() *big.Int     { return d.Price }
+func (d *txdata) gasTipCap() *big.Int    { return d.Price }
+func (d *txdata) gasFeeCap() *big.Int    { return d.Price }
+func (d *txdata) value() *big.Int        { return d.Amount }
+func (d *txdata) nonce() uint64          { return d.AccountNonce }
+func (d *txdata) to() *common.Address    { return d.Recipient }
+
+func (d *txdata) rawSignatureValues() (v, r, s *big.Int) { return d.V, d.R, d.S }
+
+func (d *txdata) setSignatureValues(v, r, s *big.Int) { d.V, d.R, d.S = v, r, s }
+
 func NewTransaction(nonce uint64, to common.Address, amount, gasLimit, gasPrice *big.Int, data []byte) *Transaction {
 	return newTransaction(nonce, &to, amount, gasLimit, gasPrice, data)
 }
@@ -121,17 +195,35 @@ func newTransaction(nonce uint64, to *common.Address, amount, gasLimit, gasPrice
 		d.Price.Set(gasPrice)
 	}
 
-	return &Transaction{data: d}
+	return &Transaction{inner: &d}
+}
+
+// txData returns the underlying data of the transaction, the zero value being
+// an empty legacy transaction.
+func (tx *Transaction) txData() TxData {
+	if tx.inner == nil {
+		return new(txdata)
+	}
+	return tx.inner
+}
+
+// Type returns the envelope type of the transaction.
+func (tx *Transaction) Type() uint8 {
+	return tx.txData().txType()
 }
 
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
-	return deriveChainId(tx.data.V)
+	return tx.txData().chainID()
 }
 
 // Protected returns whether the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
-	return isProtectedV(tx.data.V)
+	if tx.Type() != LegacyTxType {
+		return true
+	}
+	v, _, _ := tx.txData().rawSignatureValues()
+	return isProtectedV(v)
 }
 
 func isProtectedV(V *big.Int) bool {
@@ -143,31 +235,135 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// DecodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. A typed transaction is encoded as an RLP
+// string holding its binary encoding.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.Type() == LegacyTxType {
+		return rlp.Encode(w, tx.txData())
+	}
+	enc, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case kind == rlp.List:
+		var inner txdata
+		if err := s.Decode(&inner); err != nil {
+			return err
+		}
+		tx.setDecoded(&inner, common.StorageSize(rlp.ListSize(size)))
+		return nil
+	default:
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		inner, err := decodeTyped(b)
+		if err != nil {
+			return err
+		}
+		tx.setDecoded(inner, common.StorageSize(len(b)))
+		return nil
+	}
+}
+
+// MarshalBinary returns the canonical encoding of the transaction, the RLP
+// encoding of the legacy ones and the type byte followed by the RLP encoding
+// of the fields of the typed ones.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.Type() == LegacyTxType {
+		return rlp.EncodeToBytes(tx.txData())
+	}
+	return tx.encodeTyped()
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		var inner txdata
+		if err := rlp.DecodeBytes(b, &inner); err != nil {
+			return err
+		}
+		tx.setDecoded(&inner, common.StorageSize(len(b)))
+		return nil
+	}
+	inner, err := decodeTyped(b)
+	if err != nil {
+		return err
+	}
+	tx.setDecoded(inner, common.StorageSize(len(b)))
+	return nil
+}
+
+// encodeTyped returns the binary encoding of a typed transaction.
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(tx.txData())
+	if err != nil {
+		return nil, err
 	}
+	return append([]byte{tx.Type()}, payload...), nil
+}
+
+// decodeTyped decodes the binary encoding of a typed transaction.
+func decodeTyped(b []byte) (TxData, error) {
+	if len(b) == 0 {
+		return nil, errEmptyTypedTx
+	}
+	var inner TxData
+	switch b[0] {
+	case AccessListTxType:
+		inner = new(AccessListTx)
+	case DynamicFeeTxType:
+		inner = new(DynamicFeeTx)
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
+	if err := rlp.DecodeBytes(b[1:], inner); err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
 
-	return err
+// setDecoded sets the decoded data of the transaction, with its size.
+func (tx *Transaction) setDecoded(inner TxData, size common.StorageSize) {
+	tx.inner = inner
+	tx.size.Store(size)
 }
 
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
-	data := tx.data
-	data.Hash = &hash
-	return data.MarshalJSON()
+	if inner, ok := tx.txData().(*txdata); ok {
+		data := *inner
+		data.Hash = &hash
+		return data.MarshalJSON()
+	}
+	return json.Marshal(newTypedTxJSON(tx.txData(), hash))
 }
 
 // UnmarshalJSON decodes the web3 RPC transaction format.
 func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	var envelope struct {
+		Type *hexutil.Uint64 `json:"type"`
+	}
+	if err := json.Unmarshal(input, &envelope); err != nil {
+		return err
+	}
+	if envelope.Type != nil && *envelope.Type != LegacyTxType {
+		inner, err := decodeTypedTxJSON(input, byte(*envelope.Type))
+		if err != nil {
+			return err
+		}
+		*tx = Transaction{inner: inner}
+		return nil
+	}
 	var dec txdata
 	if err := dec.UnmarshalJSON(input); err != nil {
 		return err
@@ -182,38 +378,61 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
 		return ErrInvalidSig
 	}
-	*tx = Transaction{data: dec}
+	*tx = Transaction{inner: &dec}
 	return nil
 }
 
-func (tx *Transaction) Data() []byte   { return common.CopyBytes(tx.data.Payload) }
-func (tx *Transaction) Txtype() uint64 { return tx.data.Txtype }
-func (tx *Transaction) SetTxtype(txtype uint64)  { tx.data.Txtype = txtype }
+func (tx *Transaction) Data() []byte   { return common.CopyBytes(tx.txData().data()) }
+func (tx *Transaction) Txtype() uint64 { return tx.txData().txtype() }
+func (tx *Transaction) SetTxtype(txtype uint64) {
+	if tx.inner == nil {
+		tx.inner = new(txdata)
+	}
+	if inner, ok := tx.inner.(*txdata); ok {
+		inner.Txtype = txtype
+	}
+}
+
+func (tx *Transaction) Gas() *big.Int    { return new(big.Int).Set(tx.txData().gas()) }
+func (tx *Transaction) Value() *big.Int  { return new(big.Int).Set(tx.txData().value()) }
+func (tx *Transaction) Nonce() uint64    { return tx.txData().nonce() }
+func (tx *Transaction) CheckNonce() bool { return true }
+
+// AccessList returns the access list of the transaction, nil for the legacy
+// transactions.
+func (tx *Transaction) AccessList() AccessList { return tx.txData().accessList() }
+
+// GasTipCap returns the priority fee per gas of the transaction, its gas price
+// if it has no fee fields.
+func (tx *Transaction) GasTipCap() *big.Int { return new(big.Int).Set(tx.txData().gasTipCap()) }
 
-func (tx *Transaction) Gas() *big.Int      { return new(big.Int).Set(tx.data.GasLimit) }
-func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
-func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amount) }
-func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
-func (tx *Transaction) CheckNonce() bool   { return true }
+// GasFeeCap returns the fee cap per gas of the transaction, its gas price if
+// it has no fee fields.
+func (tx *Transaction) GasFeeCap() *big.Int { return new(big.Int).Set(tx.txData().gasFeeCap()) }
+
+// GasPrice returns the price per gas paid by the transaction. There is no base
+// fee, so a dynamic-fee transaction pays its priority fee, capped by its fee
+// cap.
+func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.txData().gasPrice()) }
 
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
-	if tx.data.Recipient == nil {
-		return nil
-	} else {
-		to := *tx.data.Recipient
-		return &to
-	}
+	return copyAddressPtr(tx.txData().to())
 }
 
-// Hash hashes the RLP encoding of tx.
+// Hash hashes the RLP encoding of tx, or the binary encoding of a typed tx.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.Type() == LegacyTxType {
+		v = rlpHash(tx.txData())
+	} else {
+		v = prefixedRlpHash(tx.Type(), tx.txData())
+	}
 	tx.hash.Store(v)
 	return v
 }
@@ -223,7 +442,10 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx.txData())
+	if tx.Type() != LegacyTxType {
+		c++ // type byte
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -235,12 +457,13 @@ func (tx *Transaction) Size() common.StorageSize {
 // XXX Rename message to something less arbitrary?
 func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 	msg := Message{
-		nonce:      tx.data.AccountNonce,
-		price:      new(big.Int).Set(tx.data.Price),
-		gasLimit:   new(big.Int).Set(tx.data.GasLimit),
-		to:         tx.data.Recipient,
-		amount:     tx.data.Amount,
-		data:       tx.data.Payload,
+		nonce:      tx.txData().nonce(),
+		price:      tx.GasPrice(),
+		gasLimit:   tx.Gas(),
+		to:         tx.txData().to(),
+		amount:     tx.txData().value(),
+		data:       tx.txData().data(),
+		accessList: tx.txData().accessList(),
 		checkNonce: true,
 		txType:     tx.Txtype(),
 	}
@@ -257,28 +480,33 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	if err != nil {
 		return nil, err
 	}
-	cpy := &Transaction{data: tx.data}
-	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
-	return cpy, nil
+	cpy := tx.txData().copy()
+	cpy.setSignatureValues(v, r, s)
+	return &Transaction{inner: cpy}, nil
 }
 
-// Cost returns amount + gasprice * gaslimit.
+// Cost returns amount + gasprice * gaslimit, with the fee cap as price for
+// a dynamic-fee transaction.
 func (tx *Transaction) Cost() *big.Int {
-	total := new(big.Int).Mul(tx.data.Price, tx.data.GasLimit)
-	total.Add(total, tx.data.Amount)
+	total := new(big.Int).Mul(tx.txData().gasFeeCap(), tx.txData().gas())
+	total.Add(total, tx.txData().value())
 	return total
 }
 
 func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
-	return tx.data.V, tx.data.R, tx.data.S
+	return tx.txData().rawSignatureValues()
 }
 
 func (tx *Transaction) String() string {
 	var from, to string
-	if tx.data.V != nil {
+	v, r, s := tx.txData().rawSignatureValues()
+	if v != nil {
 		// make a best guess about the signer and use that to derive
 		// the sender.
-		signer := deriveSigner(tx.data.V)
+		signer := deriveSigner(v)
+		if tx.Type() != LegacyTxType {
+			signer = NewTypedTxSigner(tx.ChainId())
+		}
 		if f, err := Sender(signer, tx); err != nil { // derive but don't cache
 			from = "[invalid sender: invalid sig]"
 		} else {
@@ -288,14 +516,15 @@ func (tx *Transaction) String() string {
 		from = "[invalid sender: nil V field]"
 	}
 
-	if tx.data.Recipient == nil {
+	if tx.txData().to() == nil {
 		to = "[contract creation]"
 	} else {
-		to = fmt.Sprintf("%x", tx.data.Recipient[:])
+		to = fmt.Sprintf("%x", tx.txData().to()[:])
 	}
-	enc, _ := rlp.EncodeToBytes(&tx.data)
+	enc, _ := tx.MarshalBinary()
 	return fmt.Sprintf(`
 	TX(%x)
+	Type:     %d
 	Contract: %v
 	From:     %s
 	To:       %s
@@ -310,17 +539,18 @@ func (tx *Transaction) String() string {
 	Hex:      %x
 `,
 		tx.Hash(),
-		tx.data.Recipient == nil,
+		tx.Type(),
+		tx.txData().to() == nil,
 		from,
 		to,
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Amount,
-		tx.data.Payload,
-		tx.data.V,
-		tx.data.R,
-		tx.data.S,
+		tx.txData().nonce(),
+		tx.txData().gasPrice(),
+		tx.txData().gas(),
+		tx.txData().value(),
+		tx.txData().data(),
+		v,
+		r,
+		s,
 		enc,
 	)
 }
@@ -334,9 +564,10 @@ func (s Transactions) Len() int { return len(s) }
 // Swap swaps the i'th and the j'th element in s
 func (s Transactions) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// GetRlp implements Rlpable and returns the i'th element of s in rlp
+// GetRlp implements Rlpable and returns the i'th element of s in rlp, or in
+// its binary encoding for a typed transaction.
 func (s Transactions) GetRlp(i int) []byte {
-	enc, _ := rlp.EncodeToBytes(s[i])
+	enc, _ := s[i].MarshalBinary()
 	return enc
 }
 
@@ -364,7 +595,7 @@ func TxDifference(a, b Transactions) (keep Transactions) {
 type TxByNonce Transactions
 
 func (s TxByNonce) Len() int           { return len(s) }
-func (s TxByNonce) Less(i, j int) bool { return s[i].data.AccountNonce < s[j].data.AccountNonce }
+func (s TxByNonce) Less(i, j int) bool { return s[i].inner.nonce() < s[j].inner.nonce() }
 func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // TxByPrice implements both the sort and the heap interface, making it useful
@@ -373,10 +604,10 @@ type TxByPrice Transactions
 
 func (s TxByPrice) Len() int           { return len(s) }
 func (s TxByPrice) Less(i, j int) bool {
-	if s[j].data.Txtype != POS_TX && s[i].data.Txtype == POS_TX {
+	if s[j].Txtype() != POS_TX && s[i].Txtype() == POS_TX {
 		return true
 	}
-	return s[i].data.Price.Cmp(s[j].data.Price) > 0
+	return s[i].inner.gasPrice().Cmp(s[j].inner.gasPrice()) > 0
 }
 func (s TxByPrice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
@@ -460,11 +691,12 @@ type Message struct {
 	nonce                   uint64
 	amount, price, gasLimit *big.Int
 	data                    []byte
+	accessList              AccessList
 	checkNonce              bool
 	txType                  uint64
 }
 
-func NewMessage(from common.Address, to *common.Address, nonce uint64, amount, gasLimit, price *big.Int, data []byte, checkNonce bool) Message {
+func NewMessage(from common.Address, to *common.Address, nonce uint64, amount, gasLimit, price *big.Int, data []byte, accessList AccessList, checkNonce bool) Message {
 	return Message{
 		from:       from,
 		to:         to,
@@ -473,6 +705,7 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount, g
 		price:      price,
 		gasLimit:   gasLimit,
 		data:       data,
+		accessList: accessList,
 		checkNonce: checkNonce,
 		txType:		NORMAL_TX,
 	}
@@ -487,6 +720,8 @@ func (m Message) Nonce() uint64        { return m.nonce }
 func (m Message) Data() []byte         { return m.data }
 func (m Message) CheckNonce() bool     { return m.checkNonce }
 
+func (m Message) AccessList() AccessList { return m.accessList }
+
 func (m Message) TxType() uint64 { return m.txType }
 
 ////////////////////////////////////for privacy tx ///////////////////////
@@ -524,7 +759,7 @@ func newOTATransaction(nonce uint64, to *common.Address, amount, gasLimit, gasPr
 		d.Price.Set(gasPrice)
 	}
 
-	return &Transaction{data: d}
+	return &Transaction{inner: &d}
 }
 
 const (
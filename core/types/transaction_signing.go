@@ -42,6 +42,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsTypedTx(blockNumber):
+		signer = NewTypedTxSigner(config.ChainId)
 
 	//case config.IsEIP155(blockNumber):
 	//	signer = NewEIP155Signer(config.ChainId)
@@ -108,6 +110,91 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// TypedTxSigner implements Signer for the EIP-2930 access list and EIP-1559
+// dynamic-fee transactions, using the EIP155 rules for the legacy ones.
+type TypedTxSigner struct{ EIP155Signer }
+
+func NewTypedTxSigner(chainId *big.Int) TypedTxSigner {
+	return TypedTxSigner{NewEIP155Signer(chainId)}
+}
+
+func (s TypedTxSigner) Equal(s2 Signer) bool {
+	typed, ok := s2.(TypedTxSigner)
+	return ok && typed.chainId.Cmp(s.chainId) == 0
+}
+
+func (s TypedTxSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.Sender(tx)
+	}
+	if !s.supports(tx) {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	// The signature holds the y parity as V, recoverPlain expects v+27
+	v, r, sig := tx.RawSignatureValues()
+	V := new(big.Int).Add(v, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), r, sig, V, true)
+}
+
+// SignatureValues returns the raw signature values of a transaction. The V of
+// a typed transaction is the y parity, 0 or 1.
+func (s TypedTxSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	}
+	if !s.supports(tx) {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	if chainId := tx.ChainId(); chainId == nil || chainId.Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _, err = HomesteadSigner{}.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return R, S, big.NewInt(int64(sig[64])), nil
+}
+
+// Hash returns the hash to be signed by the sender, the type byte followed by
+// the transaction fields for a typed transaction.
+// It does not uniquely identify the transaction.
+func (s TypedTxSigner) Hash(tx *Transaction) common.Hash {
+	inner := tx.txData()
+	switch tx.Type() {
+	case AccessListTxType:
+		return prefixedRlpHash(tx.Type(), []interface{}{
+			s.chainId,
+			inner.nonce(),
+			inner.gasPrice(),
+			inner.gas(),
+			inner.to(),
+			inner.value(),
+			inner.data(),
+			inner.accessList(),
+		})
+	case DynamicFeeTxType:
+		return prefixedRlpHash(tx.Type(), []interface{}{
+			s.chainId,
+			inner.nonce(),
+			inner.gasTipCap(),
+			inner.gasFeeCap(),
+			inner.gas(),
+			inner.to(),
+			inner.value(),
+			inner.data(),
+			inner.accessList(),
+		})
+	}
+	return s.EIP155Signer.Hash(tx)
+}
+
+func (s TypedTxSigner) supports(tx *Transaction) bool {
+	return tx.Type() == AccessListTxType || tx.Type() == DynamicFeeTxType
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
@@ -131,20 +218,27 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 var big8 = big.NewInt(8)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
 	if tx.ChainId().Cmp(s.chainId) != 0 {
 		return common.Address{}, ErrInvalidChainId
 	}
-	V := new(big.Int).Sub(tx.data.V, s.chainIdMul)
+	v, r, sig := tx.RawSignatureValues()
+	V := new(big.Int).Sub(v, s.chainIdMul)
 	V.Sub(V, big8)
-	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	return recoverPlain(s.Hash(tx), r, sig, V, true)
 }
 
 // WithSignature returns a new transaction with the given signature. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -159,14 +253,15 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
+	inner := tx.txData()
 	return rlpHash([]interface{}{
-		tx.data.Txtype,
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		inner.txtype(),
+		inner.nonce(),
+		inner.gasPrice(),
+		inner.gas(),
+		inner.to(),
+		inner.value(),
+		inner.data(),
 		s.chainId, uint(0), uint(0),
 	})
 }
@@ -187,7 +282,11 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
-	return recoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	v, r, s := tx.RawSignatureValues()
+	return recoverPlain(hs.Hash(tx), r, s, v, true)
 }
 
 type FrontierSigner struct{}
@@ -212,19 +311,24 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
+	inner := tx.txData()
 	return rlpHash([]interface{}{
-		tx.data.Txtype,
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		inner.txtype(),
+		inner.nonce(),
+		inner.gasPrice(),
+		inner.gas(),
+		inner.to(),
+		inner.value(),
+		inner.data(),
 	})
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
-	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	v, r, s := tx.RawSignatureValues()
+	return recoverPlain(fs.Hash(tx), r, s, v, false)
 }
 
 func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (common.Address, error) {
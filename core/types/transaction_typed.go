@@ -0,0 +1,296 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// AccessList is an EIP-2930 access list, the accounts and storage slots a
+// transaction declares it accesses.
+type AccessList []AccessTuple
+
+// AccessTuple is an account of an access list with its storage slots.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// StorageKeys returns the number of storage slots in the access list.
+func (al AccessList) StorageKeys() int {
+	sum := 0
+	for _, tuple := range al {
+		sum += len(tuple.StorageKeys)
+	}
+	return sum
+}
+
+// AccessListTx is the data of an EIP-2930 access list transaction.
+type AccessListTx struct {
+	ChainID    *big.Int        // destination chain ID
+	Nonce      uint64          // nonce of sender account
+	GasPrice   *big.Int        // wei per gas
+	Gas        *big.Int        // gas limit
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int        // wei amount
+	Data       []byte          // contract invocation input data
+	AccessList AccessList      // EIP-2930 access list
+	V, R, S    *big.Int        // signature values
+}
+
+func (tx *AccessListTx) txType() byte { return AccessListTxType }
+
+func (tx *AccessListTx) copy() TxData {
+	return &AccessListTx{
+		ChainID:    copyBig(tx.ChainID),
+		Nonce:      tx.Nonce,
+		GasPrice:   copyBig(tx.GasPrice),
+		Gas:        copyBig(tx.Gas),
+		To:         copyAddressPtr(tx.To),
+		Value:      copyBig(tx.Value),
+		Data:       common.CopyBytes(tx.Data),
+		AccessList: copyAccessList(tx.AccessList),
+		V:          copyBig(tx.V),
+		R:          copyBig(tx.R),
+		S:          copyBig(tx.S),
+	}
+}
+
+func (tx *AccessListTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *AccessListTx) txtype() uint64         { return NORMAL_TX }
+func (tx *AccessListTx) accessList() AccessList { return tx.AccessList }
+func (tx *AccessListTx) data() []byte           { return tx.Data }
+func (tx *AccessListTx) gas() *big.Int          { return tx.Gas }
+func (tx *AccessListTx) gasPrice() *big.Int     { return tx.GasPrice }
+func (tx *AccessListTx) gasTipCap() *big.Int    { return tx.GasPrice }
+func (tx *AccessListTx) gasFeeCap() *big.Int    { return tx.GasPrice }
+func (tx *AccessListTx) value() *big.Int        { return tx.Value }
+func (tx *AccessListTx) nonce() uint64          { return tx.Nonce }
+func (tx *AccessListTx) to() *common.Address    { return tx.To }
+
+func (tx *AccessListTx) rawSignatureValues() (v, r, s *big.Int) { return tx.V, tx.R, tx.S }
+
+func (tx *AccessListTx) setSignatureValues(v, r, s *big.Int) { tx.V, tx.R, tx.S = v, r, s }
+
+// DynamicFeeTx is the data of an EIP-1559 dynamic-fee transaction.
+type DynamicFeeTx struct {
+	ChainID    *big.Int        // destination chain ID
+	Nonce      uint64          // nonce of sender account
+	GasTipCap  *big.Int        // max priority fee per gas
+	GasFeeCap  *big.Int        // max fee per gas
+	Gas        *big.Int        // gas limit
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int        // wei amount
+	Data       []byte          // contract invocation input data
+	AccessList AccessList      // EIP-2930 access list
+	V, R, S    *big.Int        // signature values
+}
+
+func (tx *DynamicFeeTx) txType() byte { return DynamicFeeTxType }
+
+func (tx *DynamicFeeTx) copy() TxData {
+	return &DynamicFeeTx{
+		ChainID:    copyBig(tx.ChainID),
+		Nonce:      tx.Nonce,
+		GasTipCap:  copyBig(tx.GasTipCap),
+		GasFeeCap:  copyBig(tx.GasFeeCap),
+		Gas:        copyBig(tx.Gas),
+		To:         copyAddressPtr(tx.To),
+		Value:      copyBig(tx.Value),
+		Data:       common.CopyBytes(tx.Data),
+		AccessList: copyAccessList(tx.AccessList),
+		V:          copyBig(tx.V),
+		R:          copyBig(tx.R),
+		S:          copyBig(tx.S),
+	}
+}
+
+func (tx *DynamicFeeTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *DynamicFeeTx) txtype() uint64         { return NORMAL_TX }
+func (tx *DynamicFeeTx) accessList() AccessList { return tx.AccessList }
+func (tx *DynamicFeeTx) data() []byte           { return tx.Data }
+func (tx *DynamicFeeTx) gas() *big.Int          { return tx.Gas }
+func (tx *DynamicFeeTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *DynamicFeeTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *DynamicFeeTx) value() *big.Int        { return tx.Value }
+func (tx *DynamicFeeTx) nonce() uint64          { return tx.Nonce }
+func (tx *DynamicFeeTx) to() *common.Address    { return tx.To }
+
+// gasPrice returns the priority fee capped by the fee cap, there is no base
+// fee to add.
+func (tx *DynamicFeeTx) gasPrice() *big.Int {
+	if tx.GasTipCap.Cmp(tx.GasFeeCap) > 0 {
+		return tx.GasFeeCap
+	}
+	return tx.GasTipCap
+}
+
+func (tx *DynamicFeeTx) rawSignatureValues() (v, r, s *big.Int) { return tx.V, tx.R, tx.S }
+
+func (tx *DynamicFeeTx) setSignatureValues(v, r, s *big.Int) { tx.V, tx.R, tx.S = v, r, s }
+
+// typedTxJSON is the JSON encoding of the typed transactions, in the format
+// of the Ethereum RPC.
+type typedTxJSON struct {
+	Type                 hexutil.Uint64  `json:"type"`
+	Txtype               hexutil.Uint64  `json:"Txtype"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+	Nonce                *hexutil.Uint64 `json:"nonce"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	Gas                  *hexutil.Big    `json:"gas"`
+	To                   *common.Address `json:"to"`
+	Value                *hexutil.Big    `json:"value"`
+	Input                *hexutil.Bytes  `json:"input"`
+	AccessList           *AccessList     `json:"accessList"`
+	V                    *hexutil.Big    `json:"v"`
+	R                    *hexutil.Big    `json:"r"`
+	S                    *hexutil.Big    `json:"s"`
+
+	// This is only used when marshaling to JSON.
+	Hash *common.Hash `json:"hash,omitempty"`
+}
+
+func newTypedTxJSON(inner TxData, hash common.Hash) *typedTxJSON {
+	nonce, input, accessList := hexutil.Uint64(inner.nonce()), hexutil.Bytes(inner.data()), inner.accessList()
+	v, r, s := inner.rawSignatureValues()
+	enc := &typedTxJSON{
+		Type:       hexutil.Uint64(inner.txType()),
+		Txtype:     hexutil.Uint64(inner.txtype()),
+		ChainID:    (*hexutil.Big)(inner.chainID()),
+		Nonce:      &nonce,
+		Gas:        (*hexutil.Big)(inner.gas()),
+		To:         inner.to(),
+		Value:      (*hexutil.Big)(inner.value()),
+		Input:      &input,
+		AccessList: &accessList,
+		V:          (*hexutil.Big)(v),
+		R:          (*hexutil.Big)(r),
+		S:          (*hexutil.Big)(s),
+		Hash:       &hash,
+	}
+	if inner.txType() == DynamicFeeTxType {
+		enc.MaxPriorityFeePerGas = (*hexutil.Big)(inner.gasTipCap())
+		enc.MaxFeePerGas = (*hexutil.Big)(inner.gasFeeCap())
+	} else {
+		enc.GasPrice = (*hexutil.Big)(inner.gasPrice())
+	}
+	return enc
+}
+
+// decodeTypedTxJSON decodes the JSON encoding of a typed transaction.
+func decodeTypedTxJSON(input []byte, typ byte) (TxData, error) {
+	var dec typedTxJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return nil, err
+	}
+	if dec.ChainID == nil {
+		return nil, errMissingTxField("chainId")
+	}
+	if dec.Nonce == nil {
+		return nil, errMissingTxField("nonce")
+	}
+	if dec.Gas == nil {
+		return nil, errMissingTxField("gas")
+	}
+	if dec.Value == nil {
+		return nil, errMissingTxField("value")
+	}
+	if dec.Input == nil {
+		return nil, errMissingTxField("input")
+	}
+	if dec.V == nil || dec.R == nil || dec.S == nil {
+		return nil, errMissingTxField("v, r, s")
+	}
+	var accessList AccessList
+	if dec.AccessList != nil {
+		accessList = *dec.AccessList
+	}
+
+	var inner TxData
+	switch typ {
+	case AccessListTxType:
+		if dec.GasPrice == nil {
+			return nil, errMissingTxField("gasPrice")
+		}
+		inner = &AccessListTx{
+			ChainID:    (*big.Int)(dec.ChainID),
+			Nonce:      uint64(*dec.Nonce),
+			GasPrice:   (*big.Int)(dec.GasPrice),
+			Gas:        (*big.Int)(dec.Gas),
+			To:         dec.To,
+			Value:      (*big.Int)(dec.Value),
+			Data:       *dec.Input,
+			AccessList: accessList,
+			V:          (*big.Int)(dec.V),
+			R:          (*big.Int)(dec.R),
+			S:          (*big.Int)(dec.S),
+		}
+	case DynamicFeeTxType:
+		if dec.MaxPriorityFeePerGas == nil {
+			return nil, errMissingTxField("maxPriorityFeePerGas")
+		}
+		if dec.MaxFeePerGas == nil {
+			return nil, errMissingTxField("maxFeePerGas")
+		}
+		inner = &DynamicFeeTx{
+			ChainID:    (*big.Int)(dec.ChainID),
+			Nonce:      uint64(*dec.Nonce),
+			GasTipCap:  (*big.Int)(dec.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(dec.MaxFeePerGas),
+			Gas:        (*big.Int)(dec.Gas),
+			To:         dec.To,
+			Value:      (*big.Int)(dec.Value),
+			Data:       *dec.Input,
+			AccessList: accessList,
+			V:          (*big.Int)(dec.V),
+			R:          (*big.Int)(dec.R),
+			S:          (*big.Int)(dec.S),
+		}
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
+	// The signature of a typed transaction holds the y parity as V
+	v, r, s := inner.rawSignatureValues()
+	if v.BitLen() > 1 || !crypto.ValidateSignatureValues(byte(v.Uint64()), r, s, false) {
+		return nil, ErrInvalidSig
+	}
+	return inner, nil
+}
+
+func errMissingTxField(name string) error {
+	return fmt.Errorf("missing required field '%s' in transaction", name)
+}
+
+func copyBig(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x)
+}
+
+func copyAddressPtr(a *common.Address) *common.Address {
+	if a == nil {
+		return nil
+	}
+	cpy := *a
+	return &cpy
+}
+
+func copyAccessList(al AccessList) AccessList {
+	if al == nil {
+		return nil
+	}
+	cpy := make(AccessList, len(al))
+	for i, tuple := range al {
+		cpy[i] = AccessTuple{Address: tuple.Address, StorageKeys: append([]common.Hash(nil), tuple.StorageKeys...)}
+	}
+	return cpy
+}
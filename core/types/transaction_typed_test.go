@@ -0,0 +1,139 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	typedTxChainId = big.NewInt(3)
+	typedTxTo      = common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+
+	typedTxAccessList = AccessList{{
+		Address:     typedTxTo,
+		StorageKeys: []common.Hash{{1}, {2}},
+	}}
+)
+
+func typedTestTxs() []TxData {
+	return []TxData{
+		&AccessListTx{
+			ChainID:    typedTxChainId,
+			Nonce:      3,
+			GasPrice:   big.NewInt(10),
+			Gas:        big.NewInt(25000),
+			To:         &typedTxTo,
+			Value:      big.NewInt(10),
+			Data:       common.FromHex("5544"),
+			AccessList: typedTxAccessList,
+		},
+		&DynamicFeeTx{
+			ChainID:    typedTxChainId,
+			Nonce:      3,
+			GasTipCap:  big.NewInt(5),
+			GasFeeCap:  big.NewInt(10),
+			Gas:        big.NewInt(25000),
+			Value:      big.NewInt(10),
+			Data:       common.FromHex("5544"),
+			AccessList: typedTxAccessList,
+		},
+	}
+}
+
+func TestTypedTxEncoding(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewTypedTxSigner(typedTxChainId)
+
+	for _, inner := range typedTestTxs() {
+		tx, err := SignTx(NewTx(inner), signer, key)
+		if err != nil {
+			t.Fatalf("type %d: could not sign transaction: %v", inner.txType(), err)
+		}
+		if from, err := Sender(signer, tx); err != nil || from != addr {
+			t.Fatalf("type %d: sender mismatch: have %x (%v), want %x", tx.Type(), from, err, addr)
+		}
+
+		// The binary encoding is the type followed by the RLP of the fields
+		blob, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("type %d: could not encode transaction: %v", tx.Type(), err)
+		}
+		if blob[0] != tx.Type() {
+			t.Fatalf("type %d: encoding prefix mismatch: have %d", tx.Type(), blob[0])
+		}
+		binTx := new(Transaction)
+		if err := binTx.UnmarshalBinary(blob); err != nil {
+			t.Fatalf("type %d: could not decode transaction: %v", tx.Type(), err)
+		}
+		if binTx.Hash() != tx.Hash() {
+			t.Fatalf("type %d: binary round trip hash mismatch", tx.Type())
+		}
+
+		// Within a block the envelope is an RLP string
+		enc, err := rlp.EncodeToBytes(Transactions{tx, emptyTx})
+		if err != nil {
+			t.Fatalf("type %d: could not encode transaction list: %v", tx.Type(), err)
+		}
+		var txs Transactions
+		if err := rlp.DecodeBytes(enc, &txs); err != nil {
+			t.Fatalf("type %d: could not decode transaction list: %v", tx.Type(), err)
+		}
+		if len(txs) != 2 || txs[0].Hash() != tx.Hash() || txs[1].Hash() != emptyTx.Hash() {
+			t.Fatalf("type %d: list round trip mismatch", tx.Type())
+		}
+
+		data, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatalf("type %d: could not marshal transaction: %v", tx.Type(), err)
+		}
+		jsonTx := new(Transaction)
+		if err := json.Unmarshal(data, jsonTx); err != nil {
+			t.Fatalf("type %d: could not unmarshal transaction: %v", tx.Type(), err)
+		}
+		if jsonTx.Hash() != tx.Hash() {
+			t.Fatalf("type %d: JSON round trip hash mismatch", tx.Type())
+		}
+		if from, err := Sender(signer, jsonTx); err != nil || from != addr {
+			t.Fatalf("type %d: sender after JSON round trip mismatch: have %x (%v)", tx.Type(), from, err)
+		}
+	}
+}
+
+func TestTypedTxSigner(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewTypedTxSigner(typedTxChainId)
+
+	// Legacy transactions are signed as with EIP155
+	legacy, err := SignTx(NewTransaction(0, typedTxTo, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(NewEIP155Signer(typedTxChainId), legacy); err != nil || from != addr {
+		t.Fatalf("legacy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	blob, _ := legacy.MarshalBinary()
+	if enc, _ := rlp.EncodeToBytes(legacy); !bytes.Equal(blob, enc) {
+		t.Fatalf("legacy binary encoding differs from RLP")
+	}
+
+	tx, err := SignTx(NewTx(typedTestTxs()[1]), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(NewEIP155Signer(typedTxChainId), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("EIP155 signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if _, err := Sender(NewTypedTxSigner(big.NewInt(4)), tx); err != ErrInvalidChainId {
+		t.Fatalf("chain id error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	if _, err := SignTx(NewTx(typedTestTxs()[0]), NewTypedTxSigner(big.NewInt(4)), key); err != ErrInvalidChainId {
+		t.Fatalf("signing chain id error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"golang.org/x/crypto/ripemd160"
 	"fmt"
 )
@@ -81,7 +82,7 @@ func (c *ecrecover) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), nil
 }
 
-func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ecrecover) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -100,7 +101,7 @@ func (c *sha256hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return h[:], nil
 }
 
-func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *sha256hash) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -120,7 +121,7 @@ func (c *ripemd160hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return common.LeftPadBytes(ripemd.Sum(nil), 32), nil
 }
 
-func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ripemd160hash) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -138,7 +139,7 @@ func (c *dataCopy) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return in, nil
 }
 
-func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *dataCopy) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -247,7 +248,7 @@ func (c *bigModExp) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), nil
 }
 
-func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bigModExp) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -306,7 +307,7 @@ func (c *bn256Add) Run(input []byte, contract *Contract, evm *EVM) ([]byte, erro
 	return res.Marshal(), nil
 }
 
-func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Add) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -328,7 +329,7 @@ func (c *bn256ScalarMul) Run(input []byte, contract *Contract, evm *EVM) ([]byte
 	return res.Marshal(), nil
 }
 
-func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256ScalarMul) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -380,7 +381,7 @@ func (c *bn256Pairing) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return false32Byte, nil
 }
 
-func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Pairing) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -549,7 +550,7 @@ func (c *wanchainStampSC) Run(in []byte, contract *Contract, env *EVM) ([]byte,
 	return nil, errMethodId
 }
 
-func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanchainStampSC) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -637,6 +638,40 @@ func (c *wanchainStampSC) buyStamp(in []byte, contract *Contract, evm *EVM) ([]b
 type wanCoinSC struct {
 }
 
+// UnpackBuyOTA returns the OTA wan address and the value of a wancoin or
+// stamp buy call, false if the call isn't a buy. The call isn't validated.
+func UnpackBuyOTA(to *common.Address, input []byte) (wanAddr []byte, value *big.Int, ok bool) {
+	if to == nil || len(input) < 4 {
+		return nil, nil, false
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], input[:4])
+
+	var BuyStruct struct {
+		OtaAddr string
+		Value   *big.Int
+	}
+	var err error
+	switch {
+	case *to == wanCoinPrecompileAddr && methodIdArr == buyIdArr:
+		err = coinAbi.Unpack(&BuyStruct, "buyCoinNote", input[4:])
+	case *to == wanStampPrecompileAddr && methodIdArr == stBuyId:
+		err = stampAbi.UnpackTmp(&BuyStruct, "buyStamp", input[4:])
+	default:
+		return nil, nil, false
+	}
+	if err != nil || BuyStruct.Value == nil {
+		return nil, nil, false
+	}
+
+	wanAddr, err = hexutil.Decode(BuyStruct.OtaAddr)
+	if err != nil || len(wanAddr) != common.WAddressLength {
+		return nil, nil, false
+	}
+	return wanAddr, BuyStruct.Value, true
+}
+
 func (c *wanCoinSC) RequiredGas(input []byte) uint64 {
 	if len(input) < 4 {
 		return 0
@@ -692,7 +727,7 @@ func (c *wanCoinSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return nil, errMethodId
 }
 
-func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanCoinSC) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -948,7 +983,7 @@ func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string)
 
 	infoTmp.OTABalance = balanceGet
 
-	valid := crypto.VerifyRingSign(hashInput, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random)
+	valid := verifyRingSign(RingSignData{HashInput: hashInput, RingSigned: ringSignedStr}, infoTmp)
 	if !valid {
 		return nil, ErrInvalidRingSigned
 	}
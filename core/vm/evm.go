@@ -25,6 +25,8 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // emptyCodeHash is used by create to ensure deployment is disallowed to already
@@ -76,6 +78,13 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+
+	// PosContext is the PoS context of the node, nil on chains without pos
+	PosContext *posctx.Context
+	// FirstEpochId is the epoch of the first pos block, 0 before it
+	FirstEpochId uint64
+	// SealVerifier verifies the seals of the pos headers, nil without pos
+	SealVerifier util.SealVerifier
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -139,6 +148,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, CALL, caller.Address(), addr, input, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
@@ -205,6 +218,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, CALLCODE, caller.Address(), addr, input, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
@@ -244,6 +261,10 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, DELEGATECALL, caller.Address(), addr, input, gas, nil)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -276,6 +297,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, STATICCALL, caller.Address(), addr, input, gas, new(big.Int))
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -311,9 +336,8 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	return ret, contract.Gas, err
 }
 
-// Create creates a new contract using code as deployment code.
-func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
-
+// create creates a new contract at address using code as deployment code.
+func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, value *big.Int, contractAddr common.Address) ([]byte, common.Address, uint64, error) {
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(params.CallCreateDepth) {
@@ -322,11 +346,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
-	// Ensure there's no existing contract already at the designated address
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	// Ensure there's no existing contract already at the designated address
 	if !evm.StateDB.Empty(contractAddr) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
@@ -349,7 +372,7 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, contractAddr, gas, nil
 	}
-	ret, err = run(evm, snapshot, contract, nil)
+	ret, err := run(evm, snapshot, contract, nil)
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := /*evm.ChainConfig().IsEIP158(evm.BlockNumber) &&*/ len(ret) > params.MaxCodeSize
 	// if the contract creation ran successfully and no errors were returned
@@ -381,6 +404,37 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// Create creates a new contract using code as deployment code.
+func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, CREATE, caller.Address(), contractAddr, code, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
+	return evm.create(caller, code, gas, value, contractAddr)
+}
+
+// Create2 creates a new contract using code as deployment code, at an address
+// derived from the caller, the salt and the code hash instead of the caller
+// nonce (EIP-1014).
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), crypto.Keccak256(code))
+	if tracer := evm.callTracer(); tracer != nil {
+		tracer.CaptureEnter(evm, CREATE2, caller.Address(), contractAddr, code, gas, endowment)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
+	return evm.create(caller, code, gas, endowment, contractAddr)
+}
+
+// callTracer returns the tracer to notify of calls, nil if not tracing them.
+func (evm *EVM) callTracer() CallTracer {
+	if !evm.vmConfig.Debug {
+		return nil
+	}
+	tracer, _ := evm.vmConfig.Tracer.(CallTracer)
+	return tracer
+}
+
 // ChainConfig returns the evmironment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
@@ -89,6 +89,15 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error
 }
 
+// CallTracer is a Tracer also notified when a call or contract creation is
+// entered and exited, including the calls of precompiled contracts, which
+// don't run any opcode.
+type CallTracer interface {
+	Tracer
+	CaptureEnter(env *EVM, typ OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error
+	CaptureExit(output []byte, gasUsed uint64, err error) error
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
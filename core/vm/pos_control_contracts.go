@@ -5,13 +5,13 @@ import (
 	"math/big"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
 	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
@@ -117,7 +117,7 @@ func (p *PosControl) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, errMethodId
 }
 
-func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosControl) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -127,7 +127,7 @@ func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	copy(methodId[:], input[:4])
 
 	if methodId == upgradeWhiteEpochLeaderId {
-		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], uint64(time.Now().Unix()))
+		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], posCtx.Now())
 		if err != nil {
 			return errors.New("upgradeWhiteEpochLeaderParseAndValid verify failed")
 		}
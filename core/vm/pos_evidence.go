@@ -0,0 +1,282 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	errEvidenceNotEnabled = errors.New("equivocationEvidence haven't enabled")
+	errEvidenceSlot       = errors.New("headers are not sealed for the same slot")
+	errEvidenceSame       = errors.New("headers are not conflicting")
+	errEvidenceEpoch      = errors.New("evidence epoch out of range")
+	errEvidenceSigners    = errors.New("headers sealed by different slot leaders")
+	errEvidenceLeader     = errors.New("headers not sealed by an epoch leader")
+	errEvidenceKnown      = errors.New("equivocation already punished")
+	errNoSealVerifier     = errors.New("no pos seal verifier")
+)
+
+// EquivocationInfo is the record of the slot leader equivocations of a
+// validator, proven by the evidence submitted to the staking contract.
+type EquivocationInfo struct {
+	Address common.Address
+	EpochID uint64   // epoch of the last slot the validator equivocated in
+	SlotID  uint64   // last slot the validator equivocated in
+	Count   uint64   // slots the validator equivocated in
+	Burnt   *big.Int // stake burnt over all equivocations
+}
+
+// GetEquivocationInfo returns the equivocation record of a validator, nil if
+// it was never proven to equivocate.
+func GetEquivocationInfo(stateDB StateDB, addr common.Address) (*EquivocationInfo, error) {
+	infoBytes, err := GetInfo(stateDB, StakersEvidenceAddr, GetStakeInKeyHash(addr))
+	if err != nil || len(infoBytes) == 0 {
+		return nil, err
+	}
+	var info EquivocationInfo
+	if err := rlp.DecodeBytes(infoBytes, &info); err != nil {
+		return nil, errors.New("parse equivocation info error")
+	}
+	return &info, nil
+}
+
+// SetEquivocationInfo stores the equivocation record of a validator.
+func SetEquivocationInfo(stateDB StateDB, info *EquivocationInfo) error {
+	infoBytes, err := rlp.EncodeToBytes(info)
+	if err != nil {
+		return err
+	}
+	return StoreInfo(stateDB, StakersEvidenceAddr, GetStakeInKeyHash(info.Address), infoBytes)
+}
+
+func getEquivocationKey(addr common.Address, epochID, slotID uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte("equivocation"), addr[:], convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(slotID))
+}
+
+// IsEquivocationPunished reports whether a validator was punished for an
+// equivocation in a slot.
+func IsEquivocationPunished(stateDB StateDB, addr common.Address, epochID, slotID uint64) bool {
+	return len(stateDB.GetStateByteArray(StakersEvidenceAddr, getEquivocationKey(addr, epochID, slotID))) != 0
+}
+
+func setEquivocationPunished(stateDB StateDB, addr common.Address, epochID, slotID uint64) {
+	stateDB.SetStateByteArray(StakersEvidenceAddr, getEquivocationKey(addr, epochID, slotID), []byte{1})
+}
+
+// EquivocationEvidence punishes the slot leader that sealed the two
+// conflicting headers of the input with the penalties of
+// posconfig.EquivocationPenalty, once per slot. The slot leader proof of the
+// headers isn't verified: any stage two epoch leader of the previous epoch
+// signing two headers of a slot is punished, leading the slot or not.
+func (p *PosStaking) EquivocationEvidence(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	if !evm.ChainConfig().IsPosEvidence(evm.BlockNumber) {
+		return nil, errEvidenceNotEnabled
+	}
+	headers, err := p.equivocationEvidenceParseAndValid(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	epochID, slotID := util.GetEpochSlotIDFromDifficulty(headers[0].Difficulty)
+	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	if epochID > eidNow || eidNow > epochID+posconfig.EquivocationEvidenceEpochs {
+		return nil, errEvidenceEpoch
+	}
+
+	verifier := evm.Context.SealVerifier
+	if verifier == nil {
+		return nil, errNoSealVerifier
+	}
+	sealer, hash1, err := verifier.RecoverSealer(headers[0])
+	if err != nil {
+		return nil, err
+	}
+	sealer2, hash2, err := verifier.RecoverSealer(headers[1])
+	if err != nil {
+		return nil, err
+	}
+	if !util.PkEqual(sealer, sealer2) {
+		return nil, errEvidenceSigners
+	}
+	// Two signatures of the same header aren't an equivocation
+	if hash1 == hash2 {
+		return nil, errEvidenceSame
+	}
+	// The slot leaders of an epoch are elected among the epoch leaders of the
+	// previous one
+	if epochID == 0 || !isStageTwoLeader(evm.StateDB, epochID-1, sealer) {
+		return nil, errEvidenceLeader
+	}
+	signer := crypto.PubkeyToAddress(*sealer)
+	if IsEquivocationPunished(evm.StateDB, signer, epochID, slotID) {
+		return nil, errEvidenceKnown
+	}
+
+	stakerInfo, err := p.getStakeInfo(evm, signer)
+	if err != nil {
+		return nil, err
+	}
+	info, err := GetEquivocationInfo(evm.StateDB, signer)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		info = &EquivocationInfo{Address: signer, Burnt: big.NewInt(0)}
+	}
+
+	penalty := posconfig.EquivocationPenalty
+	if penalty&posconfig.PenaltyFeeRateReset != 0 {
+		if err := p.resetFeeRate(evm, stakerInfo, eidNow); err != nil {
+			return nil, err
+		}
+	}
+	burnt := big.NewInt(0)
+	if penalty&posconfig.PenaltyBurn != 0 {
+		burnt = burnOwnStake(evm, stakerInfo, posconfig.EquivocationBurnPercent)
+	}
+	if penalty&posconfig.PenaltyStakeOut != 0 {
+		forceStakeOut(stakerInfo, eidNow)
+	}
+	if err := p.saveStakeInfo(evm, stakerInfo); err != nil {
+		return nil, err
+	}
+
+	info.EpochID, info.SlotID = epochID, slotID
+	info.Count++
+	info.Burnt.Add(info.Burnt, burnt)
+	if err := SetEquivocationInfo(evm.StateDB, info); err != nil {
+		return nil, err
+	}
+	setEquivocationPunished(evm.StateDB, signer, epochID, slotID)
+
+	log.Warn("Slot leader equivocation punished", "address", signer, "epochID", epochID, "slotID", slotID,
+		"penalty", penalty, "burnt", burnt)
+	return nil, p.equivocationEvidenceLog(contract, evm, signer, epochID, slotID, penalty, burnt)
+}
+
+// isStageTwoLeader reports whether pk is the key of an epoch leader of epochID
+// whose stage two slot leader transaction is recorded in stateDB.
+func isStageTwoLeader(stateDB StateDB, epochID uint64, pk *ecdsa.PublicKey) bool {
+	compressed, err := util.CompressPk(pk)
+	if err != nil {
+		return false
+	}
+	epochIDBuf := convert.Uint64ToBytes(epochID)
+	for i := uint64(0); i < posconfig.EpochLeaderCount; i++ {
+		input := stateDB.GetStateByteArray(slotLeaderPrecompileAddr, GetSlotLeaderStage2KeyHash(epochIDBuf, convert.Uint64ToBytes(i)))
+		if len(input) <= 4 {
+			continue
+		}
+		var data stage2Data
+		if err := rlp.DecodeBytes(input[4:], &data); err != nil {
+			continue
+		}
+		if data.EpochID == epochID && data.SelfIndex == i && bytes.Equal(data.SelfPk, compressed) {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFeeRate drops the fee rate of a validator to the minimum, for good. The
+// fee rate of a validator taking no delegation isn't changed.
+func (p *PosStaking) resetFeeRate(evm *EVM, stakerInfo *StakerInfo, eidNow uint64) error {
+	if stakerInfo.FeeRate == PSMaxFeeRate {
+		return nil
+	}
+	stakerInfo.FeeRate = PSMinFeeRate
+	feeRate := &UpdateFeeRate{
+		ValidatorAddr: stakerInfo.Address,
+		MaxFeeRate:    PSMinFeeRate,
+		FeeRate:       PSMinFeeRate,
+		ChangedEpoch:  eidNow,
+	}
+	return p.saveStakeFeeRate(evm, feeRate, stakerInfo.Address)
+}
+
+// burnOwnStake burns percent of the own stake of a validator, keeping the
+// voting power of the rest, and returns the amount burnt.
+func burnOwnStake(evm *EVM, stakerInfo *StakerInfo, percent uint64) *big.Int {
+	burnt := new(big.Int).Mul(stakerInfo.Amount, new(big.Int).SetUint64(percent))
+	burnt.Div(burnt, big.NewInt(100))
+	if burnt.Sign() == 0 {
+		return burnt
+	}
+	stakeBurnt := new(big.Int).Mul(stakerInfo.StakeAmount, burnt)
+	stakeBurnt.Div(stakeBurnt, stakerInfo.Amount)
+	stakerInfo.StakeAmount.Sub(stakerInfo.StakeAmount, stakeBurnt)
+	stakerInfo.Amount.Sub(stakerInfo.Amount, burnt)
+	evm.StateDB.SubBalance(WanCscPrecompileAddr, burnt)
+	return burnt
+}
+
+// forceStakeOut makes a validator quit, with its partners and delegators,
+// QuitDelay epochs after eidNow at the latest. The builtin validators, whose
+// stake never expires, can't be forced out.
+func forceStakeOut(stakerInfo *StakerInfo, eidNow uint64) {
+	if stakerInfo.LockEpochs == 0 {
+		return
+	}
+	stakerInfo.NextLockEpochs = 0
+	quitEpoch := eidNow + QuitDelay
+	if quitEpoch > stakerInfo.StakingEpoch && stakerInfo.StakingEpoch+stakerInfo.LockEpochs > quitEpoch {
+		stakerInfo.LockEpochs = quitEpoch - stakerInfo.StakingEpoch
+	}
+}
+
+func (p *PosStaking) equivocationEvidenceParseAndValid(payload []byte) ([2]*types.Header, error) {
+	var (
+		param   EquivocationEvidenceParam
+		headers [2]*types.Header
+	)
+	err := cscAbi.UnpackInput(&param, "equivocationEvidence", payload)
+	if err != nil {
+		return headers, err
+	}
+	for i, enc := range [][]byte{param.Header1, param.Header2} {
+		headers[i] = new(types.Header)
+		if err := rlp.DecodeBytes(enc, headers[i]); err != nil {
+			return headers, err
+		}
+		if headers[i].Difficulty == nil || headers[i].Difficulty.BitLen() > 64 {
+			return headers, errEvidenceSlot
+		}
+	}
+	epochID1, slotID1 := util.GetEpochSlotIDFromDifficulty(headers[0].Difficulty)
+	epochID2, slotID2 := util.GetEpochSlotIDFromDifficulty(headers[1].Difficulty)
+	if epochID1 != epochID2 || slotID1 != slotID2 {
+		return headers, errEvidenceSlot
+	}
+	if headers[0].Hash() == headers[1].Hash() {
+		return headers, errEvidenceSame
+	}
+	return headers, nil
+}
+
+func (p *PosStaking) equivocationEvidenceLog(contract *Contract, evm *EVM, validator common.Address, epochID, slotID, penalty uint64, burnt *big.Int) error {
+	// event equivocationEvidence(address indexed sender, address indexed posAddress, uint indexed epochId, uint slotId, uint penalty, uint burnt);
+	params := make([]common.Hash, 3)
+	params[0] = common.BytesToHash(contract.Caller().Bytes())
+	params[1] = validator.Hash()
+	params[2] = common.BigToHash(new(big.Int).SetUint64(epochID))
+
+	data := make([]byte, 0)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(slotID)).Bytes()...)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(penalty)).Bytes()...)
+	data = append(data, common.BigToHash(burnt).Bytes()...)
+	sig := cscAbi.Events["equivocationEvidence"].Id().Bytes()
+	return precompiledScAddLog(contract.Address(), evm, common.BytesToHash(sig), params, data)
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// testSealVerifier accepts any header as sealed by the key of its coinbase,
+// signing its state root.
+type testSealVerifier map[common.Address]*ecdsa.PublicKey
+
+func (v testSealVerifier) RecoverSealer(header *types.Header) (*ecdsa.PublicKey, common.Hash, error) {
+	pk, ok := v[header.Coinbase]
+	if !ok {
+		return nil, common.Hash{}, errors.New("unknown sealer")
+	}
+	return pk, header.Root, nil
+}
+
+// recordStageTwo records the stage two slot leader transaction of an epoch
+// leader, as handleStgTwo does.
+func recordStageTwo(stateDB StateDB, epochID, selfIndex uint64, pk *ecdsa.PublicKey) error {
+	input, err := RlpPackStage2DataForTx(epochID, selfIndex, pk, []*ecdsa.PublicKey{pk}, []*big.Int{big.NewInt(1)}, slotLeaderSCDef)
+	if err != nil {
+		return err
+	}
+	stateDB.SetStateByteArray(slotLeaderPrecompileAddr, GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(selfIndex)), input)
+	return nil
+}
+
+func evidenceHeader(signer common.Address, epochID, slotID uint64, root common.Hash, extra byte) *types.Header {
+	return &types.Header{
+		Coinbase:   signer,
+		Root:       root,
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8),
+		Number:     big.NewInt(100),
+		Time:       big.NewInt(0),
+		Extra:      []byte{extra},
+	}
+}
+
+func submitEvidence(evm *EVM, h1, h2 *types.Header) error {
+	enc1, _ := rlp.EncodeToBytes(h1)
+	enc2, _ := rlp.EncodeToBytes(h2)
+	input, err := PackEquivocationEvidence(&EquivocationEvidenceParam{Header1: enc1, Header2: enc2})
+	if err != nil {
+		return err
+	}
+	contract.CallerAddress = common.Address{0xee}
+	_, err = stakercontract.Run(input, contract, evm)
+	return err
+}
+
+func TestEquivocationEvidence(t *testing.T) {
+	if !reset() {
+		t.Fatal("pos staking db init error")
+	}
+	defer clearDb()
+	if err := doStakeIn(10000); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := crypto.GenerateKey()
+	outsider, _ := crypto.GenerateKey()
+	verifier := testSealVerifier{
+		stakerAddr:        pb,
+		common.Address{1}: &other.PublicKey,
+		common.Address{2}: &outsider.PublicKey,
+	}
+	stakerdb[WanCscPrecompileAddr] = *new(big.Int).Mul(big.NewInt(10000), ether)
+
+	config := *params.TestChainConfig
+	config.PosEvidenceBlock = big.NewInt(0)
+	ctx := Context{PosContext: stakerPosCtx, FirstEpochId: stakerPosCtx.FirstEpochId(), SealVerifier: verifier}
+	evm := NewEVM(ctx, dummyStakerDB{ref: stakerref}, &config, Config{})
+	evm.Time = big.NewInt(time.Now().Unix())
+	evm.BlockNumber = big.NewInt(10)
+	eidNow, slotNow := util.CalEpochSlotID(evm.Time.Uint64())
+	signer := stakerAddr
+	if err := recordStageTwo(evm.StateDB, eidNow-1, 3, pb); err != nil {
+		t.Fatal(err)
+	}
+
+	h1 := evidenceHeader(signer, eidNow, slotNow, common.Hash{1}, 0)
+	h2 := evidenceHeader(signer, eidNow, slotNow, common.Hash{2}, 0)
+
+	stakerevm.Time, stakerevm.BlockNumber = evm.Time, evm.BlockNumber
+	if err := submitEvidence(stakerevm, h1, h2); err != errEvidenceNotEnabled {
+		t.Errorf("evidence before the fork error mismatch: have %v, want %v", err, errEvidenceNotEnabled)
+	}
+	tests := []struct {
+		h1, h2 *types.Header
+		err    error
+	}{
+		{h1, evidenceHeader(signer, eidNow, slotNow+1, common.Hash{2}, 0), errEvidenceSlot},
+		{h1, h1, errEvidenceSame},
+		{h1, evidenceHeader(signer, eidNow, slotNow, common.Hash{1}, 1), errEvidenceSame},
+		{h1, evidenceHeader(common.Address{1}, eidNow, slotNow, common.Hash{2}, 0), errEvidenceSigners},
+		{
+			evidenceHeader(common.Address{2}, eidNow, slotNow, common.Hash{1}, 0),
+			evidenceHeader(common.Address{2}, eidNow, slotNow, common.Hash{2}, 0),
+			errEvidenceLeader,
+		},
+		{evidenceHeader(signer, eidNow+1, 0, common.Hash{1}, 0), evidenceHeader(signer, eidNow+1, 0, common.Hash{2}, 0), errEvidenceEpoch},
+		{
+			evidenceHeader(signer, eidNow-posconfig.EquivocationEvidenceEpochs-1, 0, common.Hash{1}, 0),
+			evidenceHeader(signer, eidNow-posconfig.EquivocationEvidenceEpochs-1, 0, common.Hash{2}, 0),
+			errEvidenceEpoch,
+		},
+	}
+	for i, tt := range tests {
+		if err := submitEvidence(evm, tt.h1, tt.h2); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+
+	// Default penalties: fee rate reset and 10% burn
+	if err := submitEvidence(evm, h1, h2); err != nil {
+		t.Fatalf("failed to punish the equivocation: %v", err)
+	}
+	if err := submitEvidence(evm, h2, h1); err != errEvidenceKnown {
+		t.Errorf("second evidence error mismatch: have %v, want %v", err, errEvidenceKnown)
+	}
+	staker, err := GetStakerInfo(evm.StateDB, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	burnt := new(big.Int).Mul(big.NewInt(1000), ether)
+	if staker.FeeRate != 0 || staker.Amount.Cmp(new(big.Int).Mul(big.NewInt(9000), ether)) != 0 {
+		t.Errorf("staker not punished: fee rate %d, amount %v", staker.FeeRate, staker.Amount)
+	}
+	if balance := evm.StateDB.GetBalance(WanCscPrecompileAddr); balance.Cmp(new(big.Int).Mul(big.NewInt(9000), ether)) != 0 {
+		t.Errorf("stake not burnt: contract balance %v", balance)
+	}
+	if fee, _ := GetStakeFeeRate(evm.StateDB, signer); fee == nil || fee.MaxFeeRate != 0 {
+		t.Errorf("max fee rate not reset: %+v", fee)
+	}
+	info, err := GetEquivocationInfo(evm.StateDB, signer)
+	if err != nil || info == nil {
+		t.Fatalf("no equivocation record: %v", err)
+	}
+	if info.EpochID != eidNow || info.SlotID != slotNow || info.Count != 1 || info.Burnt.Cmp(burnt) != 0 {
+		t.Errorf("equivocation record mismatch: %+v", info)
+	}
+
+	// Forced stake-out
+	defer func(penalty uint64) { posconfig.EquivocationPenalty = penalty }(posconfig.EquivocationPenalty)
+	posconfig.EquivocationPenalty = posconfig.PenaltyStakeOut
+	h1 = evidenceHeader(signer, eidNow, slotNow+1, common.Hash{1}, 0)
+	h2 = evidenceHeader(signer, eidNow, slotNow+1, common.Hash{2}, 0)
+	if err := submitEvidence(evm, h1, h2); err != nil {
+		t.Fatalf("failed to punish the equivocation: %v", err)
+	}
+	staker, _ = GetStakerInfo(evm.StateDB, signer)
+	if staker.StakingEpoch+staker.LockEpochs != eidNow+QuitDelay || staker.NextLockEpochs != 0 {
+		t.Errorf("staker not forced out: staking epoch %d, lock epochs %d, next lock epochs %d",
+			staker.StakingEpoch, staker.LockEpochs, staker.NextLockEpochs)
+	}
+	if info, _ := GetEquivocationInfo(evm.StateDB, signer); info.Count != 2 || info.Burnt.Cmp(burnt) != 0 {
+		t.Errorf("equivocation record mismatch: %+v", info)
+	}
+}
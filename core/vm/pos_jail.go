@@ -0,0 +1,149 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// JailInfo is the jailing record of a validator.
+type JailInfo struct {
+	Address      common.Address
+	JailedEpoch  uint64   // epoch whose inactivity the validator was last jailed for
+	ReleaseEpoch uint64   // first epoch the validator may be selected in again
+	Count        uint64   // times the validator was jailed
+	Burnt        *big.Int // stake burnt over all jailings
+}
+
+// Jailed reports whether the validator can't be selected in an epoch.
+func (j *JailInfo) Jailed(epochID uint64) bool {
+	return j != nil && epochID < j.ReleaseEpoch
+}
+
+// GetJailInfo returns the jailing record of a validator, nil if it was never
+// jailed.
+func GetJailInfo(stateDB StateDB, addr common.Address) (*JailInfo, error) {
+	infoBytes, err := GetInfo(stateDB, StakersJailAddr, GetStakeInKeyHash(addr))
+	if err != nil || len(infoBytes) == 0 {
+		return nil, err
+	}
+	var info JailInfo
+	if err := rlp.DecodeBytes(infoBytes, &info); err != nil {
+		return nil, errors.New("parse jail info error")
+	}
+	return &info, nil
+}
+
+// SetJailInfo stores the jailing record of a validator.
+func SetJailInfo(stateDB StateDB, info *JailInfo) error {
+	infoBytes, err := rlp.EncodeToBytes(info)
+	if err != nil {
+		return err
+	}
+	return StoreInfo(stateDB, StakersJailAddr, GetStakeInKeyHash(info.Address), infoBytes)
+}
+
+// IsJailed reports whether a validator is excluded from the selections of an
+// epoch.
+func IsJailed(stateDB StateDB, addr common.Address, epochID uint64) bool {
+	info, _ := GetJailInfo(stateDB, addr)
+	return info.Jailed(epochID)
+}
+
+func getJailRunKey(epochID uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte("jail_run"), convert.Uint64ToBytes(epochID))
+}
+
+// JailIsFinished reports whether the validators were judged for the
+// inactivity of an epoch.
+func JailIsFinished(stateDB StateDB, epochID uint64) bool {
+	return len(stateDB.GetStateByteArray(StakersJailAddr, getJailRunKey(epochID))) != 0
+}
+
+// JailSetFinished records that the validators were judged for the inactivity
+// of an epoch.
+func JailSetFinished(stateDB StateDB, epochID uint64) {
+	stateDB.SetStateByteArray(StakersJailAddr, getJailRunKey(epochID), []byte{1})
+}
+
+// JailEvent records the jailing of a validator for the inactivity of an
+// epoch.
+type JailEvent struct {
+	Address      common.Address
+	Duties       uint64   // epoch leader and random proposer duties of the epoch
+	Fulfilled    uint64   // duties fulfilled
+	ReleaseEpoch uint64   // first epoch the validator may be selected in again
+	Burnt        *big.Int // stake burnt
+}
+
+func getJailEventsKey(epochID uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte("jail_events"), convert.Uint64ToBytes(epochID))
+}
+
+// GetJailEvents returns the validators jailed for the inactivity of an epoch.
+func GetJailEvents(stateDB StateDB, epochID uint64) ([]JailEvent, error) {
+	eventsBytes, err := GetInfo(stateDB, StakersJailAddr, getJailEventsKey(epochID))
+	if err != nil || len(eventsBytes) == 0 {
+		return nil, err
+	}
+	var events []JailEvent
+	if err := rlp.DecodeBytes(eventsBytes, &events); err != nil {
+		return nil, errors.New("parse jail events error")
+	}
+	return events, nil
+}
+
+// AddJailEvent records the jailing of a validator for the inactivity of an
+// epoch.
+func AddJailEvent(stateDB StateDB, epochID uint64, event *JailEvent) error {
+	events, err := GetJailEvents(stateDB, epochID)
+	if err != nil {
+		return err
+	}
+	eventsBytes, err := rlp.EncodeToBytes(append(events, *event))
+	if err != nil {
+		return err
+	}
+	return StoreInfo(stateDB, StakersJailAddr, getJailEventsKey(epochID), eventsBytes)
+}
+
+// jailEVM returns an evm to emit the logs of the jailings with, which run out
+// of any transaction.
+func jailEVM(stateDB StateDB, blockNumber *big.Int) *EVM {
+	return &EVM{Context: Context{BlockNumber: blockNumber}, StateDB: stateDB}
+}
+
+// JailLog emits the log of the jailing of a validator for the inactivity of
+// an epoch, from the pos staking contract.
+func JailLog(stateDB StateDB, blockNumber *big.Int, epochID uint64, event *JailEvent) error {
+	// event validatorJailed(address indexed posAddress, uint indexed epochId, uint duties, uint fulfilled, uint releaseEpoch, uint burnt);
+	params := make([]common.Hash, 2)
+	params[0] = event.Address.Hash()
+	params[1] = common.BigToHash(new(big.Int).SetUint64(epochID))
+
+	data := make([]byte, 0)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(event.Duties)).Bytes()...)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(event.Fulfilled)).Bytes()...)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(event.ReleaseEpoch)).Bytes()...)
+	data = append(data, common.BigToHash(event.Burnt).Bytes()...)
+	sig := cscAbi.Events["validatorJailed"].Id().Bytes()
+	return precompiledScAddLog(WanCscPrecompileAddr, jailEVM(stateDB, blockNumber), common.BytesToHash(sig), params, data)
+}
+
+// ReleaseLog emits the log of the release of a validator, selectable again
+// from an epoch, from the pos staking contract.
+func ReleaseLog(stateDB StateDB, blockNumber *big.Int, addr common.Address, epochID uint64) error {
+	// event validatorReleased(address indexed posAddress, uint indexed epochId);
+	params := make([]common.Hash, 2)
+	params[0] = addr.Hash()
+	params[1] = common.BigToHash(new(big.Int).SetUint64(epochID))
+
+	sig := cscAbi.Events["validatorReleased"].Id().Bytes()
+	return precompiledScAddLog(WanCscPrecompileAddr, jailEVM(stateDB, blockNumber), common.BytesToHash(sig), params, nil)
+}
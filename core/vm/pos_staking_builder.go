@@ -0,0 +1,92 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"errors"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// PackStakeIn packs the input of a stakeIn call to the staking contract.
+func PackStakeIn(param *StakeInParam) ([]byte, error) {
+	return cscAbi.Pack("stakeIn", param.SecPk, param.Bn256Pk, param.LockEpochs, param.FeeRate)
+}
+
+// PackStakeRegister packs the input of a stakeRegister call to the staking
+// contract.
+func PackStakeRegister(param *StakeRegisterParam) ([]byte, error) {
+	return cscAbi.Pack("stakeRegister", param.SecPk, param.Bn256Pk, param.LockEpochs, param.FeeRate, param.MaxFeeRate)
+}
+
+// PackStakeUpdate packs the input of a stakeUpdate call to the staking
+// contract.
+func PackStakeUpdate(param *StakeUpdateParam) ([]byte, error) {
+	return cscAbi.Pack("stakeUpdate", param.Addr, param.LockEpochs)
+}
+
+// PackStakeAppend packs the input of a stakeAppend call to the staking
+// contract.
+func PackStakeAppend(addr common.Address) ([]byte, error) {
+	return cscAbi.Pack("stakeAppend", addr)
+}
+
+// PackPartnerIn packs the input of a partnerIn call to the staking contract.
+func PackPartnerIn(param *PartnerInParam) ([]byte, error) {
+	return cscAbi.Pack("partnerIn", param.Addr, param.Renewal)
+}
+
+// PackDelegateIn packs the input of a delegateIn call to the staking
+// contract.
+func PackDelegateIn(param *DelegateParam) ([]byte, error) {
+	return cscAbi.Pack("delegateIn", param.DelegateAddress)
+}
+
+// PackDelegateOut packs the input of a delegateOut call to the staking
+// contract.
+func PackDelegateOut(param *DelegateParam) ([]byte, error) {
+	return cscAbi.Pack("delegateOut", param.DelegateAddress)
+}
+
+// PackStakeUpdateFeeRate packs the input of a stakeUpdateFeeRate call to the
+// staking contract.
+func PackStakeUpdateFeeRate(param *UpdateFeeRateParam) ([]byte, error) {
+	return cscAbi.Pack("stakeUpdateFeeRate", param.Addr, param.FeeRate)
+}
+
+// PackEquivocationEvidence packs the input of an equivocationEvidence call to
+// the staking contract.
+func PackEquivocationEvidence(param *EquivocationEvidenceParam) ([]byte, error) {
+	return cscAbi.Pack("equivocationEvidence", param.Header1, param.Header2)
+}
+
+// GetStakerInfo returns the staker info of a validator in a state.
+func GetStakerInfo(stateDB StateDB, addr common.Address) (*StakerInfo, error) {
+	stakerBytes, _ := GetInfo(stateDB, StakersInfoAddr, GetStakeInKeyHash(addr))
+	if stakerBytes == nil {
+		return nil, errors.New("item doesn't exist")
+	}
+	var stakerInfo StakerInfo
+	if err := rlp.DecodeBytes(stakerBytes, &stakerInfo); err != nil {
+		return nil, errors.New("parse staker info error")
+	}
+	return &stakerInfo, nil
+}
+
+// GetStakeFeeRate returns the fee rate changes of a validator in a state, nil
+// if its fee rate never changed.
+func GetStakeFeeRate(stateDB StateDB, addr common.Address) (*UpdateFeeRate, error) {
+	feeBytes, err := GetInfo(stateDB, StakersFeeAddr, GetStakeInKeyHash(addr))
+	if err != nil {
+		return nil, err
+	}
+	if feeBytes == nil {
+		return nil, nil
+	}
+	var feeRate UpdateFeeRate
+	if err := rlp.DecodeBytes(feeBytes, &feeRate); err != nil {
+		return nil, err
+	}
+	return &feeRate, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+func TestPackStakingInputs(t *testing.T) {
+	var (
+		p     = &PosStaking{}
+		addr  = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+		param = StakeInParam{
+			SecPk:      common.FromHex("0x04d7dffe5e06d2c7024d9bb93f675b8242e71901ee66a1bfe3fe5369324c0a75bf6f033dc4af65f5d0fe7072e98788fcfa670919b5bdc046f1ca91f28dff59db70"),
+			Bn256Pk:    common.FromHex("0x150b2b3230d6d6c8d1c133ec42d82f84add5e096c57665ff50ad071f6345cf45191fd8015cea72c4591ab3fd2ade12287c28a092ac0abf9ea19c13eb65fd4910"),
+			LockEpochs: big.NewInt(10),
+			FeeRate:    big.NewInt(100),
+		}
+	)
+
+	input, err := PackStakeIn(&param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.stakeInParseAndValid(input[4:]); err != nil || have.LockEpochs.Cmp(param.LockEpochs) != 0 || have.FeeRate.Cmp(param.FeeRate) != 0 {
+		t.Errorf("stakeIn mismatch: have %+v, %v", have, err)
+	}
+
+	input, err = PackStakeRegister(&StakeRegisterParam{StakeInParam: param, MaxFeeRate: big.NewInt(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.stakeRegisterParseAndValid(input[4:]); err != nil || have.MaxFeeRate.Uint64() != 1000 {
+		t.Errorf("stakeRegister mismatch: have %+v, %v", have, err)
+	}
+
+	input, err = PackStakeUpdate(&StakeUpdateParam{Addr: addr, LockEpochs: big.NewInt(20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.stakeUpdateParseAndValid(input[4:]); err != nil || have.Addr != addr || have.LockEpochs.Uint64() != 20 {
+		t.Errorf("stakeUpdate mismatch: have %+v, %v", have, err)
+	}
+
+	input, err = PackStakeAppend(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.stakeAppendParseAndValid(input[4:]); err != nil || have != addr {
+		t.Errorf("stakeAppend mismatch: have %x, %v", have, err)
+	}
+
+	input, err = PackPartnerIn(&PartnerInParam{Addr: addr, Renewal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.partnerInParseAndValid(input[4:]); err != nil || have.Addr != addr || !have.Renewal {
+		t.Errorf("partnerIn mismatch: have %+v, %v", have, err)
+	}
+
+	input, err = PackDelegateIn(&DelegateParam{DelegateAddress: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.delegateInParseAndValid(input[4:]); err != nil || have != addr {
+		t.Errorf("delegateIn mismatch: have %x, %v", have, err)
+	}
+
+	input, err = PackDelegateOut(&DelegateParam{DelegateAddress: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.delegateOutParseAndValid(input[4:]); err != nil || have != addr {
+		t.Errorf("delegateOut mismatch: have %x, %v", have, err)
+	}
+
+	input, err = PackStakeUpdateFeeRate(&UpdateFeeRateParam{Addr: addr, FeeRate: big.NewInt(200)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := p.updateFeeRateParseAndValid(input[4:]); err != nil || have.Addr != addr || have.FeeRate.Uint64() != 200 {
+		t.Errorf("stakeUpdateFeeRate mismatch: have %+v, %v", have, err)
+	}
+}
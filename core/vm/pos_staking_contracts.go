@@ -6,7 +6,6 @@ import (
 	"github.com/wanchain/go-wanchain/params"
 	"math/big"
 	"strings"
-	"time"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
@@ -17,6 +16,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
@@ -31,6 +31,7 @@ contract stake {
 	function partnerIn(address addr, bool renewal) public payable {}
 	function delegateIn(address delegateAddress) public payable {}
 	function delegateOut(address delegateAddress) public {}
+	function equivocationEvidence(bytes memory header1, bytes memory header2) public {}
 
 	event stakeIn(address indexed sender, address indexed posAddress, uint indexed value, uint256 feeRate, uint256 lockEpoch);
 	event stakeAppend(address indexed sender, address indexed posAddress, uint indexed value);
@@ -39,6 +40,9 @@ contract stake {
 	event delegateOut(address indexed sender, address indexed posAddress);
 	event stakeUpdateFeeRate(address indexed sender, address indexed posAddress, uint indexed feeRate);
 	event partnerIn(address indexed sender, address indexed posAddress, uint indexed value, bool renewal);
+	event equivocationEvidence(address indexed sender, address indexed posAddress, uint indexed epochId, uint slotId, uint penalty, uint burnt);
+	event validatorJailed(address indexed posAddress, uint indexed epochId, uint duties, uint fulfilled, uint releaseEpoch, uint burnt);
+	event validatorReleased(address indexed posAddress, uint indexed epochId);
 }
 
 */
@@ -219,6 +223,24 @@ var (
 		"stateMutability": "nonpayable",
 		"type": "function"
 	},
+	{
+		"constant": false,
+		"inputs": [
+			{
+				"name": "header1",
+				"type": "bytes"
+			},
+			{
+				"name": "header2",
+				"type": "bytes"
+			}
+		],
+		"name": "equivocationEvidence",
+		"outputs": [],
+		"payable": false,
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
 	{
 		"anonymous": false,
 		"inputs": [
@@ -419,6 +441,97 @@ var (
 		],
 		"name": "stakeUpdateFeeRate",
 		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "slotId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "penalty",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "burnt",
+				"type": "uint256"
+			}
+		],
+		"name": "equivocationEvidence",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "duties",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "fulfilled",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "releaseEpoch",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "burnt",
+				"type": "uint256"
+			}
+		],
+		"name": "validatorJailed",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			}
+		],
+		"name": "validatorReleased",
+		"type": "event"
 	}
 ]
 `
@@ -434,6 +547,7 @@ var (
 	delegateInId  [4]byte
 	delegateOutId [4]byte
 	stakeUpdateFeeRateId [4]byte
+	equivocationEvidenceId [4]byte
 
 	maxEpochNum                = big.NewInt(PSMaxEpochNum)
 	minEpochNum                = big.NewInt(PSMinEpochNum)
@@ -477,6 +591,10 @@ type UpdateFeeRateParam struct {
 	Addr common.Address
 	FeeRate *big.Int
 }
+type EquivocationEvidenceParam struct {
+	Header1 []byte // rlp encoded headers sealed for the same slot
+	Header2 []byte
+}
 
 //
 // storage structures
@@ -565,6 +683,7 @@ func init() {
 	copy(delegateInId[:], cscAbi.Methods["delegateIn"].Id())
 	copy(delegateOutId[:], cscAbi.Methods["delegateOut"].Id())
 	copy(stakeUpdateFeeRateId[:], cscAbi.Methods["stakeUpdateFeeRate"].Id())
+	copy(equivocationEvidenceId[:], cscAbi.Methods["equivocationEvidence"].Id())
 }
 
 /////////////////////////////
@@ -609,11 +728,13 @@ func (p *PosStaking) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 		return p.DelegateOut(input[4:], contract, evm)
 	} else if methodId == stakeUpdateFeeRateId {
 		return p.StakeUpdateFeeRate(input[4:], contract, evm)
+	} else if methodId == equivocationEvidenceId {
+		return p.EquivocationEvidence(input[4:], contract, evm)
 	}
 	return nil, errMethodId
 }
 
-func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosStaking) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -626,7 +747,7 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	copy(methodId[:], input[:4])
 
 	if methodId == stakeRegisterId {
-		eidNow, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+		eidNow, _ := util.CalEpochSlotID(posCtx.Now())
 		if eidNow < posconfig.ApolloEpochID {
 			return  errors.New("stakeRegister haven't enabled.")
 		}
@@ -672,7 +793,7 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 		}
 		return nil
 	} else if methodId == stakeUpdateFeeRateId {
-		eidNow, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+		eidNow, _ := util.CalEpochSlotID(posCtx.Now())
 		if eidNow < posconfig.ApolloEpochID {
 			return  errors.New("stakeUpdateFeeRateId haven't enabled.")
 		}
@@ -681,6 +802,12 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 			return errors.New("update fee rate verify failed")
 		}
 		return nil
+	} else if methodId == equivocationEvidenceId {
+		_, err := p.equivocationEvidenceParseAndValid(input[4:])
+		if err != nil {
+			return errors.New("equivocationEvidence verify failed " + err.Error())
+		}
+		return nil
 	}
 
 	return errParameters
@@ -699,35 +826,12 @@ func (p *PosStaking) saveStakeInfo(evm *EVM, stakerInfo *StakerInfo) error {
 	return nil
 }
 func (p *PosStaking) getStakeInfo(evm *EVM, addr common.Address) (*StakerInfo, error) {
-	key := GetStakeInKeyHash(addr)
-	stakerBytes, err := GetInfo(evm.StateDB, StakersInfoAddr, key)
-	if stakerBytes == nil {
-		return nil, errors.New("item doesn't exist")
-	}
-	var stakerInfo StakerInfo
-	err = rlp.DecodeBytes(stakerBytes, &stakerInfo)
-	if err != nil {
-		return nil, errors.New("parse staker info error")
-	}
-	return &stakerInfo, nil
+	return GetStakerInfo(evm.StateDB, addr)
 }
 
 
 func (p *PosStaking) getStakeFeeRate(evm *EVM, address common.Address) (*UpdateFeeRate, error) {
-	key := GetStakeInKeyHash(address)
-	feeBytes, err := GetInfo(evm.StateDB, StakersFeeAddr, key)
-	if err != nil {
-		return nil, err
-	}
-	if feeBytes == nil {
-		return nil, nil
-	}
-	var feeRate UpdateFeeRate
-	err = rlp.DecodeBytes(feeBytes, &feeRate)
-	if err != nil {
-		return nil, err
-	}
-	return &feeRate, nil
+	return GetStakeFeeRate(evm.StateDB, address)
 }
 
 func (p *PosStaking) saveStakeFeeRate(evm *EVM, feeRate *UpdateFeeRate, address common.Address) error {
@@ -837,7 +941,7 @@ func (p *PosStaking) PartnerIn(payload []byte, contract *Contract, evm *EVM) ([]
 			StakingEpoch: eidNow + JoinDelay,
 			LockEpochs:   uint64(realLockEpoch),
 		}
-		if posconfig.FirstEpochId == 0 {
+		if evm.Context.FirstEpochId == 0 {
 			partner.StakingEpoch = 0
 		}
 		partner.StakeAmount = big.NewInt(0).Mul(partner.Amount, big.NewInt(int64(weight)))
@@ -994,7 +1098,7 @@ func (p *PosStaking) doStakeIn(contract *Contract, evm *EVM, info StakeInParam)
 		From:         contract.CallerAddress,
 		StakingEpoch: eidNow + JoinDelay,
 	}
-	if posconfig.FirstEpochId == 0 {
+	if evm.Context.FirstEpochId == 0 {
 		stakerInfo.StakingEpoch = 0
 	}
 	stakerInfo.StakeAmount = big.NewInt(0).Mul(stakerInfo.Amount, big.NewInt(int64(weight)))
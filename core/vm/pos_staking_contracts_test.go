@@ -8,6 +8,7 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"io/ioutil"
@@ -56,7 +57,9 @@ func (StakerStateDB) GetCode(common.Address) []byte
 func (StakerStateDB) SetCode(common.Address, []byte)                                         {}
 func (StakerStateDB) GetCodeSize(common.Address) int                                         { return 0 }
 func (StakerStateDB) AddRefund(*big.Int)                                                     {}
+func (StakerStateDB) SubRefund(*big.Int)                                                     {}
 func (StakerStateDB) GetRefund() *big.Int                                                    { return nil }
+func (StakerStateDB) GetCommittedState(common.Address, common.Hash) common.Hash              { return common.Hash{} }
 func (StakerStateDB) GetState(common.Address, common.Hash) common.Hash                       { return common.Hash{} }
 func (StakerStateDB) SetState(common.Address, common.Hash, common.Hash)                      {}
 func (StakerStateDB) Suicide(common.Address) bool                                            { return false }
@@ -97,11 +100,18 @@ func initDb() bool {
 func reset() bool {
 	clearDb()
 	t := time.Now().Unix()
-	posconfig.FirstEpochId, _ = util.CalEpochSlotID(uint64(t))
+	firstEpochId, _ := util.CalEpochSlotID(uint64(t))
+	setFirstEpochId(firstEpochId)
 	evmtime = 0
 	return initDb()
 }
 
+// setFirstEpochId sets the first pos epoch of the pos context and the evm.
+func setFirstEpochId(epochId uint64) {
+	stakerPosCtx.SetFirstEpochId(epochId)
+	stakerevm.FirstEpochId = epochId
+}
+
 func (StakerStateDB) GetStateByteArray(addr common.Address, hs common.Hash) []byte {
 	//ret, _ := posStakingDB.Get(hs[:])
 
@@ -144,8 +154,9 @@ var (
 
 	stakerAddr = crypto.PubkeyToAddress(*pb)
 
-	stakerref = &dummyStakerRef{}
-	stakerevm = NewEVM(Context{}, dummyStakerDB{ref: stakerref}, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
+	stakerref    = &dummyStakerRef{}
+	stakerPosCtx = posctx.New("")
+	stakerevm    = NewEVM(Context{PosContext: stakerPosCtx}, dummyStakerDB{ref: stakerref}, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
 
 	contract       = &Contract{value: big.NewInt(0).Mul(big.NewInt(10), ether), CallerAddress: stakerAddr}
 	stakercontract = &PosStaking{}
@@ -178,11 +189,11 @@ func TestStakeRegister(t *testing.T)  {
 		t.Fatal("should not stakeIn twice")
 	}
 
-	// if posconfig.FirstEpochId == 0
+	// if FirstEpochId == 0
 	if !reset() {
 		t.Fatal("pos staking db init error")
 	}
-	posconfig.FirstEpochId = 0
+	setFirstEpochId(0)
 	err = doStakeRegister(10000)
 	if err != nil {
 		t.Fatal(err.Error())
@@ -230,11 +241,11 @@ func TestStakeIn(t *testing.T) {
 		t.Fatal("should not stakeIn twice")
 	}
 
-	// if posconfig.FirstEpochId == 0
+	// if FirstEpochId == 0
 	if !reset() {
 		t.Fatal("pos staking db init error")
 	}
-	posconfig.FirstEpochId = 0
+	setFirstEpochId(0)
 	err = doStakeIn(10000)
 	if err != nil {
 		t.Fatal(err.Error())
@@ -407,24 +418,24 @@ func TestPartnerIn(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	setEpochTime(posconfig.FirstEpochId + 11)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 11)
 	err = doPartnerOne(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 1000000)
 	if err == nil {
 		t.Fatal("should be failed if realLockEpoch < 0")
 	}
-	setEpochTime(posconfig.FirstEpochId + 10)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 10)
 	err = doPartnerOne(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 1000000)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 	// realLockEpoch > PSMaxEpochNum
 	// TODO: li hua check
-	setEpochTime(posconfig.FirstEpochId - 90 + 10 - 1)
+	setEpochTime(stakerPosCtx.FirstEpochId() - 90 + 10 - 1)
 	err = doPartnerOne(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 0)
 	if err == nil {
 		t.Fatal("should be failed if realLockEpoch > PSMaxEpochNum")
 	}
-	setEpochTime(posconfig.FirstEpochId- 90 + 10)
+	setEpochTime(stakerPosCtx.FirstEpochId()- 90 + 10)
 	err = doPartnerOne(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 1000000)
 	if err != nil {
 		t.Fatal(err.Error())
@@ -530,24 +541,24 @@ func TestStakeAppend(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	setEpochTime(posconfig.FirstEpochId + 11)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 11)
 	err = doStakeAppend(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 1000000)
 	if err == nil {
 		t.Fatal("should be failed if realLockEpoch < 0")
 	}
-	setEpochTime(posconfig.FirstEpochId + 10)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 10)
 	err = doStakeAppend(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 10)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 	// realLockEpoch > PSMaxEpochNum
 	// TODO: li hua check
-	setEpochTime(posconfig.FirstEpochId - 90 + 10 - 1)
+	setEpochTime(stakerPosCtx.FirstEpochId() - 90 + 10 - 1)
 	err = doStakeAppend(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 20)
 	if err == nil {
 		t.Fatal("should be failed if realLockEpoch > PSMaxEpochNum")
 	}
-	setEpochTime(posconfig.FirstEpochId - 90 + 10)
+	setEpochTime(stakerPosCtx.FirstEpochId() - 90 + 10)
 	err = doStakeAppend(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 20)
 	if err != nil {
 		t.Fatal(err.Error())
@@ -606,13 +617,13 @@ func TestStakeUpdate(t *testing.T) {
 		t.Fatal("should be failed if contract.CallerAddress != stakeInfo.From")
 	}
 	// cannot change at the last 3 epoch
-	setEpochTime(posconfig.FirstEpochId + 2 + 10 - UpdateDelay + 1)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 2 + 10 - UpdateDelay + 1)
 	err = doStakeUpdate(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 0, 0)
 	if err == nil {
 		t.Fatal("should be failed if contract.CallerAddress != stakeInfo.From")
 	}
 	// normal
-	setEpochTime(posconfig.FirstEpochId + 2 + 10 - UpdateDelay)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 2 + 10 - UpdateDelay)
 	err = doStakeUpdate(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 0, 10)
 	if err != nil {
 		t.Fatal(err.Error())
@@ -679,7 +690,7 @@ func TestUpdateFeeRate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	setEpochTime(posconfig.FirstEpochId + 1)
+	setEpochTime(stakerPosCtx.FirstEpochId() + 1)
 	err = doUpdateFeeRate(common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e"), 901)
 	if err == nil || err.Error() != "updateFeeRate called failed 0 <= newFeeRate <= oldFeerate + 100" {
 		t.Fatal("0 <= newFeeRate <= oldFeerate + 100")
@@ -994,7 +1005,7 @@ func doStakeInWithParam(amount int64, feeRate int) error {
 		info.Amount.Cmp(a) != 0  {
 		return errors.New("stakeIn from amount epoch address saved wrong")
 	}
-	if posconfig.FirstEpochId == 0 {
+	if stakerPosCtx.FirstEpochId() == 0 {
 		if info.StakingEpoch != 0 {
 			return errors.New("StakingEpoch saved wrong, should eq 0")
 		}
@@ -1058,7 +1069,7 @@ func doStakeRegisterWithParam(amount int64, feeRate int) error {
 		info.Amount.Cmp(a) != 0  {
 		return errors.New("stakeIn from amount epoch address saved wrong")
 	}
-	if posconfig.FirstEpochId == 0 {
+	if stakerPosCtx.FirstEpochId() == 0 {
 		if info.StakingEpoch != 0 {
 			return errors.New("StakingEpoch saved wrong, should eq 0")
 		}
@@ -0,0 +1,98 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+)
+
+// PrecompileCall is a call to a Wanchain precompiled contract decoded with
+// the ABI of the contract.
+type PrecompileCall struct {
+	Contract string                 `json:"contract"`
+	Method   string                 `json:"method"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// precompileAbi returns the name and the ABI of the Wanchain precompiled
+// contract at an address, nil if it isn't called with ABI encoded input.
+func precompileAbi(addr common.Address) (string, *abi.ABI) {
+	switch addr {
+	case WanCscPrecompileAddr:
+		return "staking", &cscAbi
+	case PosControlPrecompileAddr:
+		return "posControl", &posControlAbi
+	case randomBeaconPrecompileAddr:
+		return "randomBeacon", &rbSCAbi
+	case slotLeaderPrecompileAddr:
+		return "slotLeader", &slotLeaderAbi
+	case wanCoinPrecompileAddr:
+		return "wanCoin", &coinAbi
+	case wanStampPrecompileAddr:
+		return "wanStamp", &stampAbi
+	}
+	return "", nil
+}
+
+// DecodePrecompileCall decodes the input of a call to a Wanchain precompiled
+// contract, returning nil if addr isn't one or the method is unknown. The
+// arguments are left out if they can't be decoded.
+func DecodePrecompileCall(addr common.Address, input []byte) *PrecompileCall {
+	name, contractAbi := precompileAbi(addr)
+	if contractAbi == nil || len(input) < 4 {
+		return nil
+	}
+	for _, method := range contractAbi.Methods {
+		if !bytes.Equal(method.Id(), input[:4]) {
+			continue
+		}
+		call := &PrecompileCall{Contract: name, Method: method.Name}
+		values := unpackInputs(method.Inputs, input[4:])
+		if len(values) != len(method.Inputs) {
+			return call
+		}
+		call.Args = make(map[string]interface{}, len(values))
+		for i, value := range values {
+			call.Args[method.Inputs[i].Name] = formatAbiValue(value)
+		}
+		return call
+	}
+	return nil
+}
+
+// unpackInputs decodes the arguments of a call, nil on malformed input which
+// the decoder may panic on.
+func unpackInputs(inputs abi.Arguments, data []byte) (values []interface{}) {
+	defer func() {
+		if recover() != nil {
+			values = nil
+		}
+	}()
+	values, _ = inputs.UnpackValues(data)
+	return values
+}
+
+// formatAbiValue converts the byte and number values of an ABI argument to
+// their hex JSON encoding.
+func formatAbiValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case common.Address:
+		return v
+	case *big.Int:
+		return (*hexutil.Big)(v)
+	case []byte:
+		return hexutil.Bytes(v)
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Bytes(b)
+	}
+	return value
+}
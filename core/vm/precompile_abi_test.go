@@ -0,0 +1,39 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+)
+
+func TestDecodePrecompileCall(t *testing.T) {
+	input, err := cscAbi.Pack("stakeIn", []byte{1, 2}, []byte{3}, big.NewInt(10), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := DecodePrecompileCall(WanCscPrecompileAddr, input)
+	if call == nil || call.Contract != "staking" || call.Method != "stakeIn" {
+		t.Fatalf("call mismatch: %+v", call)
+	}
+	if secPk, ok := call.Args["secPk"].(hexutil.Bytes); !ok || secPk.String() != "0x0102" {
+		t.Fatalf("secPk mismatch: %v", call.Args["secPk"])
+	}
+	if lockEpochs, ok := call.Args["lockEpochs"].(*hexutil.Big); !ok || lockEpochs.ToInt().Uint64() != 10 {
+		t.Fatalf("lockEpochs mismatch: %v", call.Args["lockEpochs"])
+	}
+
+	// Truncated arguments keep the method name only.
+	if call := DecodePrecompileCall(WanCscPrecompileAddr, input[:40]); call == nil || call.Method != "stakeIn" || call.Args != nil {
+		t.Fatalf("truncated call mismatch: %+v", call)
+	}
+	if call := DecodePrecompileCall(WanCscPrecompileAddr, []byte{1, 2, 3, 4}); call != nil {
+		t.Fatalf("unknown method decoded: %+v", call)
+	}
+	if call := DecodePrecompileCall(common.HexToAddress("0x1234"), input); call != nil {
+		t.Fatalf("call to a contract decoded: %+v", call)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 // Precompiled contracts address or
@@ -30,6 +31,8 @@ var (
 	StakingCommonAddr     = common.BytesToAddress(big.NewInt(401).Bytes())
 	StakersFeeAddr        = common.BytesToAddress(big.NewInt(402).Bytes())
 	StakersMaxFeeAddr     = common.BytesToAddress(big.NewInt(403).Bytes())
+	StakersJailAddr       = common.BytesToAddress(big.NewInt(404).Bytes())
+	StakersEvidenceAddr   = common.BytesToAddress(big.NewInt(405).Bytes())
 	otaBalanceStorageAddr = common.BytesToAddress(big.NewInt(300).Bytes())
 	otaImageStorageAddr   = common.BytesToAddress(big.NewInt(301).Bytes())
 
@@ -78,7 +81,7 @@ var (
 type PrecompiledContract interface {
 	RequiredGas(input []byte) uint64                                // RequiredPrice calculates the contract gas use
 	Run(input []byte, contract *Contract, evm *EVM) ([]byte, error) // Run runs the precompiled contract
-	ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error
+	ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error
 }
 
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
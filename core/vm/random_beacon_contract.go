@@ -11,7 +11,6 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/common"
@@ -19,6 +18,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	posutil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
@@ -264,7 +264,7 @@ func (c *RandomBeaconContract) getEpochId(payload []byte, contract *Contract, ev
 func (c *RandomBeaconContract) getRandomNumberByEpochId(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	epochId := new(big.Int).SetBytes(getData(payload, 0, 32)).Uint64()
 
-	r := GetStateR(evm.StateDB, epochId)
+	r := GetStateR(evm.StateDB, epochId, evm.Context.FirstEpochId)
 
 	if r == nil {
 		r = big.NewInt(0)
@@ -278,7 +278,7 @@ func (c *RandomBeaconContract) getRandomNumberByTimestamp(payload []byte, contra
 
 	epochId,_ := posutil.CalEpochSlotID(timestamp)
 
-	r := GetStateR(evm.StateDB, epochId)
+	r := GetStateR(evm.StateDB, epochId, evm.Context.FirstEpochId)
 
 	if r == nil {
 		r = big.NewInt(0)
@@ -287,8 +287,8 @@ func (c *RandomBeaconContract) getRandomNumberByTimestamp(payload []byte, contra
 	return common.LeftPadBytes(r.Bytes(), 32), nil
 }
 
-func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
-	if posconfig.FirstEpochId == 0 {
+func (c *RandomBeaconContract) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+	if posCtx.FirstEpochId() == 0 {
 		return  errParameters
 	}
 	if stateDB == nil || signer == nil || tx == nil {
@@ -305,12 +305,12 @@ func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx
 		return err
 	}
 
-	return ValidPosRBTx(stateDB, from, payload)
+	return ValidPosRBTx(posCtx, stateDB, from, payload)
 }
-func getRBProposerGroup(eid uint64)([]bn256.G1,error){
-	ep := util.GetEpocherInst()
+func getRBProposerGroup(posCtx *posctx.Context, eid uint64)([]bn256.G1,error){
+	ep := posCtx.Selector()
 	if ep == nil {
-		return nil,  errors.New("GetEpocherInst() == nil")
+		return nil,  errors.New("epoch leader selector is nil")
 	}
 		pks := ep.GetRBProposerG1(eid)
 	if len(pks) == 0 {
@@ -322,19 +322,19 @@ func getRBProposerGroup(eid uint64)([]bn256.G1,error){
 //
 // params or gas check functions
 //
-func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
+func ValidPosRBTx(posCtx *posctx.Context, stateDB StateDB, from common.Address, payload []byte) error {
 	log.Debug("ValidPosRBTx")
 	var methodId [4]byte
 	copy(methodId[:], payload[:4])
 
 	if methodId == dkg1Id {
-		_, err := validDkg1(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg1(posCtx, stateDB, posCtx.Now(), from, payload[4:])
 		return err
 	} else if methodId == dkg2Id {
-		_, err := validDkg2(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg2(posCtx, stateDB, posCtx.Now(), from, payload[4:])
 		return err
 	} else if methodId == sigShareId {
-		_, _, _, err := validSigShare(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, _, _, err := validSigShare(posCtx, stateDB, posCtx.Now(), from, payload[4:])
 		return err
 	} else {
 		return errParameters
@@ -352,7 +352,7 @@ func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
 // 'caller' is the caller of DKG1. It should be set as Contract.CallerAddress
 // when called by precompiled contract. And should be set as tx's sender when
 // called by tx pool.
-func validDkg1(stateDB StateDB, time uint64, caller common.Address,
+func validDkg1(posCtx *posctx.Context, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbDKG1FlatTxPayload, error) {
 
 	var dkg1FlatParam RbDKG1FlatTxPayload
@@ -369,7 +369,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	eid := dkg1Param.EpochId
 	pid := dkg1Param.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(posCtx, eid)
 	if err != nil {
 		return nil, err
 	}
@@ -379,7 +379,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(posCtx, pks, eid, pid, caller) {
 		return nil, logError(errors.New("invalid proposer, proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -415,7 +415,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg1FlatParam, nil
 }
 
-func validDkg2(stateDB StateDB, time uint64, caller common.Address,
+func validDkg2(posCtx *posctx.Context, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbDKG2FlatTxPayload, error) {
 
 	var dkg2FlatParam RbDKG2FlatTxPayload
@@ -432,7 +432,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	eid := dkg2Param.EpochId
 	pid := dkg2Param.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(posCtx, eid)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +443,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(posCtx, pks, eid, pid, caller) {
 		return nil, logError(errors.New("error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -476,7 +476,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg2FlatParam, nil
 }
 
-func validSigShare(stateDB StateDB, time uint64, caller common.Address,
+func validSigShare(posCtx *posctx.Context, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbSIGTxPayload, []bn256.G1, []RbCijDataCollector, error) {
 
 	var sigShareParam RbSIGTxPayload
@@ -488,7 +488,7 @@ func validSigShare(stateDB StateDB, time uint64, caller common.Address,
 	eid := sigShareParam.EpochId
 	pid := sigShareParam.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(posCtx, eid)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -499,12 +499,12 @@ func validSigShare(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(posCtx, pks, eid, pid, caller) {
 		return nil, nil, nil, logError(errors.New(" error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
 	// 3. Verification
-	M, err := getRBMVar(stateDB, eid)
+	M, err := getRBMVar(stateDB, eid, posCtx.FirstEpochId())
 	if err != nil {
 		return nil, nil, nil, logError(buildError("getRBM error", eid, pid))
 	}
@@ -571,24 +571,24 @@ func GetRBRKeyHash(epochId uint64) *common.Hash {
 	return &hash
 }
 
-// get r of one epoch, if not exist return r in epoch 0
-func GetR(db StateDB, epochId uint64) *big.Int {
-	if epochId == posconfig.FirstEpochId {
-		return GetStateR(db, posconfig.FirstEpochId)
+// get r of one epoch, if not exist return r of the first pos epoch
+func GetR(db StateDB, epochId uint64, firstEpochId uint64) *big.Int {
+	if epochId == firstEpochId {
+		return GetStateR(db, firstEpochId, firstEpochId)
 	}
-	r := GetStateR(db, epochId)
+	r := GetStateR(db, epochId, firstEpochId)
 	if r == nil {
-		if epochId > posconfig.FirstEpochId+2 {
+		if epochId > firstEpochId+2 {
 			log.SyslogWarning("***Can not found random r just use the first epoch R", "epochId", epochId)
 		}
-		r = GetStateR(db, posconfig.FirstEpochId)
+		r = GetStateR(db, firstEpochId, firstEpochId)
 	}
 	return r
 }
 
 // get r of one epoch
-func GetStateR(db StateDB, epochId uint64) *big.Int {
-	if epochId == posconfig.FirstEpochId {
+func GetStateR(db StateDB, epochId uint64, firstEpochId uint64) *big.Int {
+	if epochId == firstEpochId {
 		return new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
 	}
 	hash := GetRBRKeyHash(epochId)
@@ -618,9 +618,9 @@ func GetSig(db StateDB, epochId uint64, proposerId uint32) (*RbSIGTxPayload, err
 }
 
 // get M
-func GetRBM(db StateDB, epochId uint64) ([]byte, error) {
+func GetRBM(db StateDB, epochId uint64, firstEpochId uint64) ([]byte, error) {
 	epochIdBigInt := big.NewInt(int64(epochId + 1))
-	preRandom := GetR(db, epochId)
+	preRandom := GetR(db, epochId, firstEpochId)
 
 	buf := epochIdBigInt.Bytes()
 	buf = append(buf, preRandom.Bytes()...)
@@ -932,11 +932,11 @@ func isValidEpochStage(epochId uint64, stage int, time uint64) bool {
 	return true
 }
 
-func isInRandomGroup(pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
+func isInRandomGroup(posCtx *posctx.Context, pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
 	if len(pks) <= int(proposerId) || int(proposerId)<0 {
 		return false
 	}
-	ep := util.GetEpocherInst()
+	ep := posCtx.Selector()
 	if ep == nil {
 		return false
 	}
@@ -991,7 +991,7 @@ var getRBProposerGroupVar = getRBProposerGroup
 // dkg1: happens in 0~2k-1 slots, send the commits to chain
 func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg1")
-	dkg1FlatParam, err := validDkg1(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg1FlatParam, err := validDkg1(evm.Context.PosContext, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -1014,7 +1014,7 @@ func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM
 // dkg2: happens in 5k~7k-1 slots, send the proof, enShare to chain
 func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg2")
-	dkg2FlatParam, err := validDkg2(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg2FlatParam, err := validDkg2(evm.Context.PosContext, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -1037,7 +1037,7 @@ func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM
 // sigShare: sign, happens in 8k~10k-1 slots, generate R if enough signers
 func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("sigShare")
-	sigShareParam, pks, dkgData, err := validSigShare(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	sigShareParam, pks, dkgData, err := validSigShare(evm.Context.PosContext, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -1054,7 +1054,7 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 	sigNum := getSignorsNum(eid, evm) + 1
 	setSignorsNum(eid, sigNum, evm)
 	if uint(sigNum) >= posconfig.Cfg().RBThres {
-		r, err := computeRandom(evm.StateDB, eid, dkgData, pks)
+		r, err := computeRandom(evm.StateDB, eid, dkgData, pks, evm.Context.FirstEpochId)
 		if r != nil && err == nil {
 			hashR := GetRBRKeyHash(eid + 1)
 			evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hashR, r.Bytes())
@@ -1077,8 +1077,8 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 // calc random
 //
 // compute random[epochId+1] by data of epoch[epochId]
-func computeRandom(stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector, pks []bn256.G1) (*big.Int, error) {
-	randomInt := GetStateR(stateDB, epochId+1)
+func computeRandom(stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector, pks []bn256.G1, firstEpochId uint64) (*big.Int, error) {
+	randomInt := GetStateR(stateDB, epochId+1, firstEpochId)
 	if randomInt != nil && randomInt.Cmp(big.NewInt(0)) != 0 {
 		return randomInt, errors.New("random exist already")
 	}
@@ -1130,7 +1130,7 @@ func computeRandom(stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector
 	gPub := rbselection.LagrangePub(c, xAll, int(posconfig.Cfg().PolymDegree))
 
 	// mG
-	mBuf, err := getRBMVar(stateDB, epochId)
+	mBuf, err := getRBMVar(stateDB, epochId, firstEpochId)
 	if err != nil {
 		return nil, logError(err)
 	}
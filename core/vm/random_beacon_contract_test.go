@@ -12,6 +12,7 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"github.com/wanchain/go-wanchain/rlp"
 	"math/big"
@@ -40,7 +41,9 @@ func (CTStateDB) GetCode(common.Address) []byte
 func (CTStateDB) SetCode(common.Address, []byte)                                         {}
 func (CTStateDB) GetCodeSize(common.Address) int                                         { return 0 }
 func (CTStateDB) AddRefund(*big.Int)                                                     {}
+func (CTStateDB) SubRefund(*big.Int)                                                     {}
 func (CTStateDB) GetRefund() *big.Int                                                    { return nil }
+func (CTStateDB) GetCommittedState(common.Address, common.Hash) common.Hash              { return common.Hash{} }
 func (CTStateDB) GetState(common.Address, common.Hash) common.Hash                       { return common.Hash{} }
 func (CTStateDB) SetState(common.Address, common.Hash, common.Hash)                      {}
 func (CTStateDB) Suicide(common.Address) bool                                            { return false }
@@ -207,7 +210,7 @@ func prepareSig(Prikey []big.Int, enshare [][]*bn256.G1) ([]*bn256.G1)  {
 		}
 	}
 
-	M, err := getRBMVar(statedb, rbepochId)
+	M, err := getRBMVar(statedb, rbepochId, 0)
 	if err != nil {
 		fmt.Printf("get rbm error id:%v\n", rbepochId)
 	}
@@ -221,12 +224,12 @@ func prepareSig(Prikey []big.Int, enshare [][]*bn256.G1) ([]*bn256.G1)  {
 	return gSigShare
 }
 
-func getRBProposerGroupMock(epochId uint64) ([]bn256.G1,error){
+func getRBProposerGroupMock(_ *posctx.Context, epochId uint64) ([]bn256.G1,error){
 	return rbgroupdb[epochId],nil
 }
 
 
-func getRBMMock(_ StateDB, epochId uint64) ([]byte, error) {
+func getRBMMock(_ StateDB, epochId uint64, _ uint64) ([]byte, error) {
 	nextEpochId := big.NewInt(int64(epochId + 1))
 
 	preRandom, exisit := rbranddb[epochId]
@@ -249,7 +252,7 @@ func getRBMMock(_ StateDB, epochId uint64) ([]byte, error) {
 func isValidEpochStageMock(_ uint64, _ int, _ uint64) bool {
 	return true
 }
-func isInRandomGroupMock(_ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
+func isInRandomGroupMock(_ *posctx.Context, _ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
 	return true
 }
 
@@ -420,7 +423,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg1(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.Context.PosContext, evm.StateDB, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -442,7 +445,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg2(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.Context.PosContext, evm.StateDB, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -464,7 +467,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(sigShareParam)
 		payload := buildSig(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.Context.PosContext, evm.StateDB, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
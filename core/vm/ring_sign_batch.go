@@ -0,0 +1,96 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+const verifiedRingSignCacheSize = 4096
+
+// verifiedRingSigns holds the hashes of the ring signatures known to be
+// valid. A ring signature only depends on its hash input and encoding, so
+// the result holds for any state.
+var verifiedRingSigns, _ = lru.New(verifiedRingSignCacheSize)
+
+// RingSignData is a ring signature carried by a tx, in the encoding of
+// DecodeRingSignOut, with the hash input it signs.
+type RingSignData struct {
+	HashInput  []byte
+	RingSigned string
+}
+
+func (d *RingSignData) hash() common.Hash {
+	return crypto.Keccak256Hash(d.HashInput, []byte(d.RingSigned))
+}
+
+// RefundRingSignData returns the ring signature of a wancoin refund call
+// made by from, false if the call isn't a refund.
+func RefundRingSignData(to *common.Address, from common.Address, input []byte) (RingSignData, bool) {
+	if to == nil || *to != wanCoinPrecompileAddr || len(input) < 4 {
+		return RingSignData{}, false
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], input[:4])
+	if methodIdArr != refundIdArr {
+		return RingSignData{}, false
+	}
+
+	var RefundStruct struct {
+		RingSignedData string
+		Value          *big.Int
+	}
+	if err := coinAbi.Unpack(&RefundStruct, "refundCoin", input[4:]); err != nil {
+		return RingSignData{}, false
+	}
+	return RingSignData{HashInput: from.Bytes(), RingSigned: RefundStruct.RingSignedData}, true
+}
+
+// PreverifyRingSigns verifies a batch of ring signatures with
+// crypto.VerifyRingSigns, the valid ones aren't verified again when their
+// txs are validated or run.
+func PreverifyRingSigns(data []RingSignData) {
+	var (
+		hashes = make([]common.Hash, 0, len(data))
+		sigs   = make([]*crypto.RingSig, 0, len(data))
+	)
+	for i := range data {
+		hash := data[i].hash()
+		if verifiedRingSigns.Contains(hash) {
+			continue
+		}
+		err, publicKeys, keyImage, w, q := DecodeRingSignOut(data[i].RingSigned)
+		if err != nil {
+			continue
+		}
+		hashes = append(hashes, hash)
+		sigs = append(sigs, &crypto.RingSig{M: data[i].HashInput, PublicKeys: publicKeys, I: keyImage, C: w, R: q})
+	}
+	if len(sigs) == 0 {
+		return
+	}
+
+	for i, valid := range crypto.VerifyRingSigns(sigs) {
+		if valid {
+			verifiedRingSigns.Add(hashes[i], struct{}{})
+		}
+	}
+}
+
+// verifyRingSign verifies the decoded ring signature info of data.
+func verifyRingSign(data RingSignData, info *RingSignInfo) bool {
+	hash := data.hash()
+	if verifiedRingSigns.Contains(hash) {
+		return true
+	}
+	if !crypto.VerifyRingSign(data.HashInput, info.PublicKeys, info.KeyImage, info.W_Random, info.Q_Random) {
+		return false
+	}
+	verifiedRingSigns.Add(hash, struct{}{})
+	return true
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// testRingSignData signs from with a ring of size keys, in the encoding of
+// DecodeRingSignOut.
+func testRingSignData(t *testing.T, from common.Address, size int) RingSignData {
+	signer, _ := crypto.GenerateKey()
+	ring := []*ecdsa.PublicKey{&signer.PublicKey}
+	for i := 1; i < size; i++ {
+		key, _ := crypto.GenerateKey()
+		ring = append(ring, &key.PublicKey)
+	}
+	pks, image, w, q, err := crypto.RingSign(from.Bytes(), signer.D, ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pkStrs, wStrs, qStrs []string
+	for i := range pks {
+		pkStrs = append(pkStrs, common.ToHex(crypto.FromECDSAPub(pks[i])))
+		wStrs = append(wStrs, hexutil.EncodeBig(w[i]))
+		qStrs = append(qStrs, hexutil.EncodeBig(q[i]))
+	}
+	encoded := strings.Join([]string{
+		strings.Join(pkStrs, "&"),
+		common.ToHex(crypto.FromECDSAPub(image)),
+		strings.Join(wStrs, "&"),
+		strings.Join(qStrs, "&"),
+	}, "+")
+	return RingSignData{HashInput: from.Bytes(), RingSigned: encoded}
+}
+
+func TestPreverifyRingSigns(t *testing.T) {
+	from := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	good := []RingSignData{
+		testRingSignData(t, from, 1),
+		testRingSignData(t, from, 3),
+		testRingSignData(t, from, 5),
+	}
+	// Signed by another address.
+	bad := testRingSignData(t, common.HexToAddress("0x2"), 3)
+	bad.HashInput = from.Bytes()
+	malformed := RingSignData{HashInput: from.Bytes(), RingSigned: "0x01+0x02"}
+
+	PreverifyRingSigns(append(good, bad, malformed))
+	for i := range good {
+		if !verifiedRingSigns.Contains(good[i].hash()) {
+			t.Errorf("ring signature %d isn't cached", i)
+		}
+	}
+	if verifiedRingSigns.Contains(bad.hash()) {
+		t.Error("invalid ring signature is cached")
+	}
+	if verifiedRingSigns.Contains(malformed.hash()) {
+		t.Error("malformed ring signature is cached")
+	}
+
+	err, pks, image, w, q := DecodeRingSignOut(bad.RingSigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifyRingSign(bad, &RingSignInfo{PublicKeys: pks, KeyImage: image, W_Random: w, Q_Random: q}) {
+		t.Error("invalid ring signature verified")
+	}
+}
+
+func TestRefundRingSignData(t *testing.T) {
+	from := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	want := testRingSignData(t, from, 2)
+	input, err := coinAbi.Pack("refundCoin", want.RingSigned, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, ok := RefundRingSignData(&wanCoinPrecompileAddr, from, input)
+	if !ok {
+		t.Fatal("refund not recognized")
+	}
+	if data.hash() != want.hash() {
+		t.Errorf("ring signature mismatch: have %+v, want %+v", data, want)
+	}
+
+	if _, ok := RefundRingSignData(&wanStampPrecompileAddr, from, input); ok {
+		t.Error("refund recognized for the stamp contract")
+	}
+	if _, ok := RefundRingSignData(nil, from, input); ok {
+		t.Error("refund recognized for a contract creation")
+	}
+	buy, _ := coinAbi.Pack("buyCoinNote", "0x01", big.NewInt(1))
+	if _, ok := RefundRingSignData(&wanCoinPrecompileAddr, from, buy); ok {
+		t.Error("buy recognized as a refund")
+	}
+}
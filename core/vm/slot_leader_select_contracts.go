@@ -13,7 +13,6 @@ import (
 	"github.com/wanchain/go-wanchain/rlp"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
@@ -25,6 +24,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 const (
@@ -137,7 +137,7 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 
 	if methodId == stgOneIdArr {
 		vldReset := validStg1Reset(evm.StateDB, from, in[:], evm.Time.Uint64())
-		vldService := validStg1Service(from, in[:])
+		vldService := validStg1Service(evm.Context.PosContext, from, in[:])
 
 		if !(vldReset && vldService) {
 			return nil, errors.New("ValidTx stg1")
@@ -145,7 +145,7 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 		return handleStgOne(in[:], contract, evm) //Do not use [4:] because it has do it in function
 	} else if methodId == stgTwoIdArr {
 		vldReset := validStg2Reset(evm.StateDB, from, in[:], evm.Time.Uint64())
-		vldService := validStg2Service(evm.StateDB, from, in[:])
+		vldService := validStg2Service(evm.Context.PosContext, evm.StateDB, from, in[:])
 
 		if !(vldReset && vldService) {
 			return nil, errors.New("ValidTx stg2")
@@ -158,9 +158,9 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	return nil, errMethodId
 }
 
-func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) ValidTx(posCtx *posctx.Context, stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
 
-	if posconfig.FirstEpochId == 0 {
+	if posCtx.FirstEpochId() == 0 {
 		log.SyslogErr("slotLeaderSC:ValidTx", "", ErrPowRcvPosTrans.Error())
 		return ErrPowRcvPosTrans
 	}
@@ -179,8 +179,8 @@ func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.T
 	copy(methodId[:], payload[:4])
 
 	if methodId == stgOneIdArr {
-		vldReset := validStg1Reset(stateDB, from, payload, uint64(time.Now().Unix()))
-		vldService := validStg1Service(from, payload)
+		vldReset := validStg1Reset(stateDB, from, payload, posCtx.Now())
+		vldService := validStg1Service(posCtx, from, payload)
 
 		if vldReset && vldService {
 			return nil
@@ -188,8 +188,8 @@ func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.T
 			return errors.New("ValidTx stg1")
 		}
 	} else if methodId == stgTwoIdArr {
-		vldReset := validStg2Reset(stateDB, from, payload, uint64(time.Now().Unix()))
-		vldService := validStg2Service(stateDB, from, payload)
+		vldReset := validStg2Reset(stateDB, from, payload, posCtx.Now())
+		vldService := validStg2Service(posCtx, stateDB, from, payload)
 
 		if vldReset && vldService {
 			return nil
@@ -273,14 +273,14 @@ func validStg2Reset(stateDB StateDB, from common.Address, payload []byte, time u
 	return true
 }
 
-func validStg1Service(from common.Address, payload []byte) bool {
+func validStg1Service(posCtx *posctx.Context, from common.Address, payload []byte) bool {
 	epochIDBuf, selfIndexBuf, err := RlpGetStage1IDFromTx(payload[:])
 	if err != nil {
 		log.Error("validStg1Service failed")
 		return false
 	}
 
-	if !InEpochLeadersOrNotByAddress(convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
+	if !InEpochLeadersOrNotByAddress(posCtx, convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
 		log.SyslogErr(ErrIllegalSender.Error())
 		return false
 	}
@@ -293,14 +293,14 @@ func validStg1Service(from common.Address, payload []byte) bool {
 	return true
 }
 
-func validStg2Service(stateDB StateDB, from common.Address, payload []byte) bool {
+func validStg2Service(posCtx *posctx.Context, stateDB StateDB, from common.Address, payload []byte) bool {
 	epochID, selfIndex, _, alphaPkis, proofs, err := RlpUnpackStage2DataForTx(payload[:])
 	if err != nil {
 		log.Error("validTxStg2:RlpUnpackStage2DataForTx failed")
 		return false
 	}
 
-	if !InEpochLeadersOrNotByAddress(epochID, selfIndex, from) {
+	if !InEpochLeadersOrNotByAddress(posCtx, epochID, selfIndex, from) {
 		log.SyslogErr("validTxStg2:InEpochLeadersOrNotByAddress failed")
 		return false
 	}
@@ -331,7 +331,7 @@ func validStg2Service(stateDB StateDB, from common.Address, payload []byte) bool
 	}
 	//Dleq
 
-	ep := util.GetEpocherInst()
+	ep := posCtx.Selector()
 	if ep == nil {
 		log.Error(ErrEpochID.Error())
 		return false
@@ -371,7 +371,7 @@ func handleStgOne(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
 		return nil, err
 	}
 
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.Context.PosContext, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgOne save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -397,7 +397,7 @@ func handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.Context.PosContext, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgTwo save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -428,8 +428,8 @@ func GetSlotLeaderScAbiString() string {
 }
 
 // GetSlotScCallTimes can get this precompile contract called times
-func GetSlotScCallTimes(epochID uint64) uint64 {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+func GetSlotScCallTimes(posCtx *posctx.Context, epochID uint64) uint64 {
+	buf, err := posCtx.LocalDb().Get(epochID, scCallTimes)
 	if err != nil {
 		return 0
 	} else {
@@ -480,8 +480,8 @@ func PackStage1Data(input []byte, abiString string) ([]byte, error) {
 	return outBuf, err
 }
 
-func InEpochLeadersOrNotByAddress(epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
-	ep := util.GetEpocherInst()
+func InEpochLeadersOrNotByAddress(posCtx *posctx.Context, epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
+	ep := posCtx.Selector()
 	if ep == nil {
 		return false
 	}
@@ -739,8 +739,12 @@ func getSlotLeaderStageKeyHash(epochID, selfIndex []byte, slotLeaderStage string
 	return crypto.Keccak256Hash(keyBuf.Bytes())
 }
 
-func addSlotScCallTimes(epochID uint64) error {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+func addSlotScCallTimes(posCtx *posctx.Context, epochID uint64) error {
+	if posCtx == nil {
+		return nil
+	}
+	db := posCtx.LocalDb()
+	buf, err := db.Get(epochID, scCallTimes)
 	times := uint64(0)
 	if err != nil {
 		if err.Error() != "leveldb: not found" {
@@ -753,7 +757,7 @@ func addSlotScCallTimes(epochID uint64) error {
 
 	times++
 
-	posdb.GetDb().Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
+	db.Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
 	return nil
 }
 
@@ -10,15 +10,12 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 	"github.com/wanchain/go-wanchain/rlp"
 	"math/big"
-	"os"
-	"path"
-	"path/filepath"
 	"testing"
 	"time"
 )
@@ -200,22 +197,18 @@ func TestIsInValidStage(t *testing.T) {
 }
 
 func TestAddSlotScCallTimes(t *testing.T) {
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	t.Log("Current dir path ", dir)
-	os.RemoveAll(path.Join(dir, "sl_contract_test"))
-	posdb.NewDb(path.Join(dir, "sl_contract_test"))
+	posCtx := posctx.New("")
+	defer posCtx.Close()
 
 	epochID := uint64(0)
 	loopCount := 10
 	for i := 0; i < loopCount; i++ {
-		addSlotScCallTimes(epochID)
+		addSlotScCallTimes(posCtx, epochID)
 	}
 
-	if intByte, _ := posdb.GetDb().Get(epochID, scCallTimes); convert.BytesToUint64(intByte[:]) != uint64(loopCount) {
+	if GetSlotScCallTimes(posCtx, epochID) != uint64(loopCount) {
 		t.Fail()
 	}
-
-	os.RemoveAll(path.Join(dir, "sl_contract_test"))
 }
 
 func TestUpdateSlotLeaderStageIndex(t *testing.T) {
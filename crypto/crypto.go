@@ -83,6 +83,12 @@ func CreateAddress(b common.Address, nonce uint64) common.Address {
 	return common.BytesToAddress(Keccak256(data)[12:])
 }
 
+// CreateAddress2 creates an ethereum address given the address bytes, initial
+// contract code hash and a salt, as CREATE2 does.
+func CreateAddress2(b common.Address, salt [32]byte, inithash []byte) common.Address {
+	return common.BytesToAddress(Keccak256([]byte{0xff}, b.Bytes(), salt[:], inithash)[12:])
+}
+
 // ToECDSA creates a private key with the given D value.
 func ToECDSA(d []byte) (*ecdsa.PrivateKey, error) {
 	return toECDSA(d, true)
@@ -390,12 +396,29 @@ func randFieldElement2528(rand io.Reader) (k *big.Int, err error) {
 
 // calc [x]Hash(P)
 func xScalarHashP(x []byte, pub *ecdsa.PublicKey) (I *ecdsa.PublicKey) {
-	KeyImg := new(ecdsa.PublicKey)
-	I = new(ecdsa.PublicKey)
-	KeyImg.X, KeyImg.Y = S256().ScalarMult(pub.X, pub.Y, Keccak256(FromECDSAPub(pub))) //Hash(P)
-	I.X, I.Y = S256().ScalarMult(KeyImg.X, KeyImg.Y, x)
-	I.Curve = S256()
-	return
+	return xScalarPoint(x, hashPoint(pub))
+}
+
+// KeyImage returns the key image [x]Hash(P) of a private key, a ring
+// signature made with the key carries it.
+func KeyImage(priv *ecdsa.PrivateKey) *ecdsa.PublicKey {
+	return xScalarHashP(priv.D.Bytes(), &priv.PublicKey)
+}
+
+// calc Hash(P)
+func hashPoint(pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	hp := new(ecdsa.PublicKey)
+	hp.X, hp.Y = S256().ScalarMult(pub.X, pub.Y, Keccak256(FromECDSAPub(pub)))
+	hp.Curve = S256()
+	return hp
+}
+
+// calc [x]P
+func xScalarPoint(x []byte, pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	p := new(ecdsa.PublicKey)
+	p.X, p.Y = S256().ScalarMult(pub.X, pub.Y, x)
+	p.Curve = S256()
+	return p
 }
 
 var (
@@ -511,22 +534,11 @@ func RingSign(M []byte, x *big.Int, PublicKeys []*ecdsa.PublicKey) ([]*ecdsa.Pub
 // VerifyRingSign verifies the validity of ring signature
 // Pengbo added, Shi,TeemoGuo revised
 func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int) bool {
-	if M == nil || PublicKeys == nil || I == nil || c == nil || r == nil {
-		return false
-	}
-
-	if len(PublicKeys) == 0 || len(PublicKeys) != len(c) || len(PublicKeys) != len(r) {
+	if !validRingSignParams(M, PublicKeys, I, c, r) {
 		return false
 	}
 
 	n := len(PublicKeys)
-	for i := 0; i < n; i++ {
-		if PublicKeys[i] == nil || PublicKeys[i].X == nil || PublicKeys[i].Y == nil ||
-			c[i] == nil || r[i] == nil {
-			return false
-		}
-	}
-
 	log.Debug("M info", "R", 0, "M", common.ToHex(M))
 	for i := 0; i < n; i++ {
 		log.Debug("publicKeys", "i", i, "publickey", common.ToHex(FromECDSAPub(PublicKeys[i])))
@@ -541,6 +553,36 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		log.Debug("r info", "i", i, "r", common.ToHex(r[i].Bytes()))
 	}
 
+	hashPs := make([]*ecdsa.PublicKey, n)
+	for i := 0; i < n; i++ {
+		hashPs[i] = hashPoint(PublicKeys[i])
+	}
+	return verifyRingSign(M, PublicKeys, I, c, r, hashPs)
+}
+
+// validRingSignParams checks the shape of a ring signature.
+func validRingSignParams(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int) bool {
+	if M == nil || PublicKeys == nil || I == nil || c == nil || r == nil {
+		return false
+	}
+
+	if len(PublicKeys) == 0 || len(PublicKeys) != len(c) || len(PublicKeys) != len(r) {
+		return false
+	}
+
+	for i := 0; i < len(PublicKeys); i++ {
+		if PublicKeys[i] == nil || PublicKeys[i].X == nil || PublicKeys[i].Y == nil ||
+			c[i] == nil || r[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyRingSign verifies a well formed ring signature, hashPs holds Hash(Pi)
+// of the ring members.
+func verifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int, hashPs []*ecdsa.PublicKey) bool {
+	n := len(PublicKeys)
 	SumC := new(big.Int).SetInt64(0)
 	Lpub := new(ecdsa.PublicKey)
 	d := sha3.NewKeccak256()
@@ -563,13 +605,14 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		SumC.Add(SumC, c[i])
 		SumC.Mod(SumC, secp256k1_N)
 		d.Write(FromECDSAPub(Lpub))
-		log.Debug("LPublicKeys", "i", i, "Lpub", common.ToHex(FromECDSAPub(Lpub)))
 	}
 
-	Rpub := new(ecdsa.PublicKey)
 	for i := 0; i < n; i++ {
-		Rpub = xScalarHashP(r[i].Bytes(), PublicKeys[i]) //[qi]HashPi
-		if Rpub == nil || Rpub.X == nil || Rpub.Y == nil {
+		if hashPs[i] == nil || hashPs[i].X == nil || hashPs[i].Y == nil {
+			return false
+		}
+		Rpub := xScalarPoint(r[i].Bytes(), hashPs[i]) //[qi]HashPi
+		if Rpub.X == nil || Rpub.Y == nil {
 			return false
 		}
 
@@ -580,17 +623,12 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		}
 
 		Rpub.X, Rpub.Y = S256().Add(Rpub.X, Rpub.Y, Ppub.X, Ppub.Y) //[qi]HashPi+[wi]I
-		log.Debug("RPublicKeys", "i", i, "Rpub", common.ToHex(FromECDSAPub(Rpub)))
-
 		d.Write(FromECDSAPub(Rpub))
 	}
 
 	hash := new(big.Int).SetBytes(d.Sum(nil)) //hash(m,Li,Ri)
-	log.Debug("hash info", "i", 0, "hash", common.ToHex(hash.Bytes()))
-
 	hash.Mod(hash, secp256k1_N)
-	log.Debug("hash info", "i", 2, "hash", common.ToHex(hash.Bytes()))
-	log.Debug("SumC info", "i", 3, "SumC", common.ToHex(SumC.Bytes()))
+	log.Debug("ring sign hash info", "hash", common.ToHex(hash.Bytes()), "SumC", common.ToHex(SumC.Bytes()))
 
 	return hash.Cmp(SumC) == 0
 }
@@ -0,0 +1,91 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// RingSig is a ring signature of the message M, as made by RingSign. The
+// ring may have any size.
+type RingSig struct {
+	M          []byte
+	PublicKeys []*ecdsa.PublicKey
+	I          *ecdsa.PublicKey // key image
+	C          []*big.Int
+	R          []*big.Int
+}
+
+// VerifyRingSigns verifies a batch of ring signatures, valid[i] is the
+// result of sigs[i]. Hash(P) of a public key used in several rings is only
+// computed once, and the signatures are verified in parallel.
+func VerifyRingSigns(sigs []*RingSig) (valid []bool) {
+	valid = make([]bool, len(sigs))
+
+	// Collect the distinct ring members of the well formed signatures.
+	index := make(map[string]int)
+	members := make([]*ecdsa.PublicKey, 0)
+	wellFormed := make([]bool, len(sigs))
+	for i, sig := range sigs {
+		if sig == nil || !validRingSignParams(sig.M, sig.PublicKeys, sig.I, sig.C, sig.R) {
+			continue
+		}
+		wellFormed[i] = true
+		for _, pub := range sig.PublicKeys {
+			key := string(FromECDSAPub(pub))
+			if _, ok := index[key]; !ok {
+				index[key] = len(members)
+				members = append(members, pub)
+			}
+		}
+	}
+
+	hashPs := make([]*ecdsa.PublicKey, len(members))
+	parallelFor(len(members), func(i int) {
+		hashPs[i] = hashPoint(members[i])
+	})
+
+	parallelFor(len(sigs), func(i int) {
+		if !wellFormed[i] {
+			return
+		}
+		sig := sigs[i]
+		ringHashPs := make([]*ecdsa.PublicKey, len(sig.PublicKeys))
+		for j, pub := range sig.PublicKeys {
+			ringHashPs[j] = hashPs[index[string(FromECDSAPub(pub))]]
+		}
+		valid[i] = verifyRingSign(sig.M, sig.PublicKeys, sig.I, sig.C, sig.R, ringHashPs)
+	})
+	return valid
+}
+
+// parallelFor runs fn(0) to fn(n-1) on all the cpus.
+func parallelFor(n int, fn func(i int)) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		next = int64(-1)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
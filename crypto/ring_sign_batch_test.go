@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+)
+
+// testRingSigs makes count ring signatures of the given ring sizes, the ring
+// members are drawn from a pool of poolSize keys as the OTA mix sets are.
+func testRingSigs(t testing.TB, count int, sizes []int, poolSize int) []*RingSig {
+	pool := make([]*ecdsa.PrivateKey, poolSize)
+	for i := range pool {
+		pool[i], _ = GenerateKey()
+	}
+
+	sigs := make([]*RingSig, count)
+	for i := range sigs {
+		size := sizes[i%len(sizes)]
+		signer := pool[i%poolSize]
+		ring := []*ecdsa.PublicKey{&signer.PublicKey}
+		for j := 1; j < size; j++ {
+			ring = append(ring, &pool[(i+j*7)%poolSize].PublicKey)
+		}
+		msg := Keccak256(big.NewInt(int64(i)).Bytes())
+		pks, image, c, r, err := RingSign(msg, signer.D, ring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs[i] = &RingSig{M: msg, PublicKeys: pks, I: image, C: c, R: r}
+	}
+	return sigs
+}
+
+func TestVerifyRingSigns(t *testing.T) {
+	sigs := testRingSigs(t, 12, []int{1, 2, 3, 8, 16}, 20)
+
+	// Break some of the signatures.
+	sigs[1].M = Keccak256([]byte("other message"))
+	sigs[4].C[0] = new(big.Int).Add(sigs[4].C[0], big.NewInt(1))
+	sigs[7].I = sigs[8].I
+	sigs[9].R = sigs[9].R[1:]
+	sigs = append(sigs, nil)
+
+	valid := VerifyRingSigns(sigs)
+	if len(valid) != len(sigs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(valid), len(sigs))
+	}
+	for i, sig := range sigs {
+		want := sig != nil && VerifyRingSign(sig.M, sig.PublicKeys, sig.I, sig.C, sig.R)
+		if valid[i] != want {
+			t.Errorf("signature %d: have %v, want %v", i, valid[i], want)
+		}
+	}
+	for _, i := range []int{1, 4, 7, 9, 12} {
+		if valid[i] {
+			t.Errorf("broken signature %d is valid", i)
+		}
+	}
+	for _, i := range []int{0, 2, 3, 5, 6, 8, 10, 11} {
+		if !valid[i] {
+			t.Errorf("signature %d is invalid", i)
+		}
+	}
+}
+
+func TestVerifyRingSignsEmpty(t *testing.T) {
+	if valid := VerifyRingSigns(nil); len(valid) != 0 {
+		t.Fatalf("results for an empty batch: %v", valid)
+	}
+}
+
+func benchmarkRingSigs(b *testing.B) []*RingSig {
+	sigs := testRingSigs(b, 32, []int{8}, 64)
+	b.ResetTimer()
+	return sigs
+}
+
+func BenchmarkVerifyRingSign(b *testing.B) {
+	sigs := benchmarkRingSigs(b)
+	for i := 0; i < b.N; i++ {
+		for _, sig := range sigs {
+			if !VerifyRingSign(sig.M, sig.PublicKeys, sig.I, sig.C, sig.R) {
+				b.Fatal("invalid signature")
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyRingSigns(b *testing.B) {
+	sigs := benchmarkRingSigs(b)
+	for i := 0; i < b.N; i++ {
+		for _, valid := range VerifyRingSigns(sigs) {
+			if !valid {
+				b.Fatal("invalid signature")
+			}
+		}
+	}
+}
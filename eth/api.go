@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -46,6 +47,9 @@ import (
 
 const defaultTraceTimeout = 5 * time.Second
 
+// errBlockTracer is returned when a block is traced with a JavaScript tracer.
+var errBlockTracer = errors.New("blocks can only be traced by the struct logger or a native tracer")
+
 // PublicEthereumAPI provides an API to access Ethereum full node-related
 // information.
 type PublicEthereumAPI struct {
@@ -92,6 +96,11 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// ValidatorStatus returns the state of the PoS validator duties of the miner.
+func (api *PublicMinerAPI) ValidatorStatus() miner.ValidatorStatus {
+	return api.e.Miner().ValidatorStatus()
+}
+
 // SubmitWork can be used by external miner to submit their POW solution. It returns an indication if the work was
 // accepted. Note, this is not an indication if the provided work was valid!
 func (api *PublicMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest common.Hash) bool {
@@ -354,36 +363,49 @@ func NewPrivateDebugAPI(config *params.ChainConfig, eth *Ethereum) *PrivateDebug
 type BlockTraceResult struct {
 	Validated  bool                  `json:"validated"`
 	StructLogs []ethapi.StructLogRes `json:"structLogs"`
+	Traces     []interface{}         `json:"traces,omitempty"` // results of a native tracer per transaction
 	Error      string                `json:"error"`
 }
 
-// TraceArgs holds extra parameters to trace functions
+// TraceArgs holds extra parameters to trace functions. Tracer is the code of
+// a JavaScript tracer or the name of a native one, like callTracer.
 type TraceArgs struct {
 	*vm.LogConfig
 	Tracer  *string
 	Timeout *string
 }
 
+// logConfig returns the struct logger configuration of the arguments.
+func (args *TraceArgs) logConfig() *vm.LogConfig {
+	if args == nil {
+		return nil
+	}
+	return args.LogConfig
+}
+
+// nativeTracer returns the name of the native tracer requested, if any.
+func (args *TraceArgs) nativeTracer() (string, bool) {
+	if args == nil || args.Tracer == nil || !ethapi.IsNativeTracer(*args.Tracer) {
+		return "", false
+	}
+	return *args.Tracer, true
+}
+
 // TraceBlock processes the given block'api RLP but does not import the block in to
 // the chain.
-func (api *PrivateDebugAPI) TraceBlock(blockRlp []byte, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlock(blockRlp []byte, config *TraceArgs) BlockTraceResult {
 	var block types.Block
 	err := rlp.Decode(bytes.NewReader(blockRlp), &block)
 	if err != nil {
 		return BlockTraceResult{Error: fmt.Sprintf("could not decode block: %v", err)}
 	}
 
-	validated, logs, err := api.traceBlock(&block, config)
-	return BlockTraceResult{
-		Validated:  validated,
-		StructLogs: ethapi.FormatLogs(logs),
-		Error:      formatError(err),
-	}
+	return api.traceBlock(&block, config)
 }
 
 // TraceBlockFromFile loads the block'api RLP from the given file name and attempts to
 // process it but does not import the block in to the chain.
-func (api *PrivateDebugAPI) TraceBlockFromFile(file string, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockFromFile(file string, config *TraceArgs) BlockTraceResult {
 	blockRlp, err := ioutil.ReadFile(file)
 	if err != nil {
 		return BlockTraceResult{Error: fmt.Sprintf("could not read file: %v", err)}
@@ -392,7 +414,7 @@ func (api *PrivateDebugAPI) TraceBlockFromFile(file string, config *vm.LogConfig
 }
 
 // TraceBlockByNumber processes the block by canonical block number.
-func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *TraceArgs) BlockTraceResult {
 	// Fetch the block that we aim to reprocess
 	var block *types.Block
 	switch blockNr {
@@ -409,61 +431,109 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *
 		return BlockTraceResult{Error: fmt.Sprintf("block #%d not found", blockNr)}
 	}
 
-	validated, logs, err := api.traceBlock(block, config)
-	return BlockTraceResult{
-		Validated:  validated,
-		StructLogs: ethapi.FormatLogs(logs),
-		Error:      formatError(err),
-	}
+	return api.traceBlock(block, config)
 }
 
 // TraceBlockByHash processes the block by hash.
-func (api *PrivateDebugAPI) TraceBlockByHash(hash common.Hash, config *vm.LogConfig) BlockTraceResult {
+func (api *PrivateDebugAPI) TraceBlockByHash(hash common.Hash, config *TraceArgs) BlockTraceResult {
 	// Fetch the block that we aim to reprocess
 	block := api.eth.BlockChain().GetBlockByHash(hash)
 	if block == nil {
 		return BlockTraceResult{Error: fmt.Sprintf("block #%x not found", hash)}
 	}
 
-	validated, logs, err := api.traceBlock(block, config)
+	return api.traceBlock(block, config)
+}
+
+// traceBlock processes the given block but does not save the state, tracing
+// it with the struct logger or the native tracer of the arguments.
+func (api *PrivateDebugAPI) traceBlock(block *types.Block, config *TraceArgs) BlockTraceResult {
+	name, native := config.nativeTracer()
+	if !native {
+		if config != nil && config.Tracer != nil {
+			return BlockTraceResult{Error: errBlockTracer.Error()}
+		}
+		structLogger := vm.NewStructLogger(config.logConfig())
+		validated, err := api.processBlock(block, vm.Config{Debug: true, Tracer: structLogger})
+		return BlockTraceResult{
+			Validated:  validated,
+			StructLogs: ethapi.FormatLogs(structLogger.StructLogs()),
+			Error:      formatError(err),
+		}
+	}
+	// Validate the block untraced, then trace its transactions one by one
+	validated, err := api.processBlock(block, vm.Config{})
+	if err != nil {
+		return BlockTraceResult{Validated: validated, Error: formatError(err)}
+	}
+	traces, err := api.traceBlockTxs(block, name)
 	return BlockTraceResult{
 		Validated:  validated,
-		StructLogs: ethapi.FormatLogs(logs),
+		StructLogs: []ethapi.StructLogRes{},
+		Traces:     traces,
 		Error:      formatError(err),
 	}
 }
 
-// traceBlock processes the given block but does not save the state.
-func (api *PrivateDebugAPI) traceBlock(block *types.Block, logConfig *vm.LogConfig) (bool, []vm.StructLog, error) {
-	// Validate and reprocess the block
+// traceBlockTxs replays the transactions of a block on the state of its
+// parent, each traced by a new native tracer.
+func (api *PrivateDebugAPI) traceBlockTxs(block *types.Block, name string) ([]interface{}, error) {
+	parent := api.eth.BlockChain().GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("block parent %x not found", block.ParentHash())
+	}
+	statedb, err := api.eth.BlockChain().StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
 	var (
-		blockchain = api.eth.BlockChain()
-		validator  = blockchain.Validator()
-		processor  = blockchain.Processor()
+		signer = types.MakeSigner(api.config, block.Number())
+		traces = make([]interface{}, 0, len(block.Transactions()))
 	)
+	for _, tx := range block.Transactions() {
+		msg, _ := tx.AsMessage(signer)
+		context := core.NewEVMContext(msg, block.Header(), api.eth.BlockChain(), nil)
 
-	structLogger := vm.NewStructLogger(logConfig)
+		tracer, _ := ethapi.NewNativeTracer(name, statedb.Copy())
+		vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+			return traces, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
+		}
+		statedb.DeleteSuicides()
 
-	config := vm.Config{
-		Debug:  true,
-		Tracer: structLogger,
+		result, err := tracer.GetResult()
+		if err != nil {
+			return traces, fmt.Errorf("tx %x trace failed: %v", tx.Hash(), err)
+		}
+		traces = append(traces, result)
 	}
+	return traces, nil
+}
+
+// processBlock validates and reprocesses the given block with the given VM
+// configuration.
+func (api *PrivateDebugAPI) processBlock(block *types.Block, config vm.Config) (bool, error) {
+	var (
+		blockchain = api.eth.BlockChain()
+		validator  = blockchain.Validator()
+		processor  = blockchain.Processor()
+	)
 	if err := api.eth.engine.VerifyHeader(blockchain, block.Header(), true); err != nil {
-		return false, structLogger.StructLogs(), err
+		return false, err
 	}
 	statedb, err := blockchain.StateAt(blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1).Root())
 	if err != nil {
-		return false, structLogger.StructLogs(), err
+		return false, err
 	}
 
 	receipts, _, usedGas, err := processor.Process(block, statedb, config)
 	if err != nil {
-		return false, structLogger.StructLogs(), err
+		return false, err
 	}
 	if err := validator.ValidateState(block, blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1), statedb, receipts, usedGas); err != nil {
-		return false, structLogger.StructLogs(), err
+		return false, err
 	}
-	return true, structLogger.StructLogs(), nil
+	return true, nil
 }
 
 // formatError formats a Go error into either an empty string or the data content
@@ -484,8 +554,20 @@ func (t *timeoutError) Error() string {
 // TraceTransaction returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
+	// Retrieve the tx from the chain and the containing block
+	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", txHash)
+	}
+	msg, vmctx, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
+	if err != nil {
+		return nil, err
+	}
+
 	var tracer vm.Tracer
-	if config != nil && config.Tracer != nil {
+	if name, ok := config.nativeTracer(); ok {
+		tracer, _ = ethapi.NewNativeTracer(name, statedb.Copy())
+	} else if config != nil && config.Tracer != nil {
 		timeout := defaultTraceTimeout
 		if config.Timeout != nil {
 			var err error
@@ -506,24 +588,12 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 			tracer.(*ethapi.JavascriptTracer).Stop(&timeoutError{})
 		}()
 		defer cancel()
-	} else if config == nil {
-		tracer = vm.NewStructLogger(nil)
 	} else {
-		tracer = vm.NewStructLogger(config.LogConfig)
-	}
-
-	// Retrieve the tx from the chain and the containing block
-	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
-	if tx == nil {
-		return nil, fmt.Errorf("transaction %x not found", txHash)
-	}
-	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
-	if err != nil {
-		return nil, err
+		tracer = vm.NewStructLogger(config.logConfig())
 	}
 
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
@@ -538,6 +608,8 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 		}, nil
 	case *ethapi.JavascriptTracer:
 		return tracer.GetResult()
+	case ethapi.NativeTracer:
+		return tracer.GetResult()
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
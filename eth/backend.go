@@ -20,7 +20,7 @@ package eth
 import (
 	"errors"
 	"fmt"
-	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/pos/incentiveindex"
 	"github.com/wanchain/go-wanchain/pos/posapi"
 	"math/big"
 	"runtime"
@@ -41,6 +41,7 @@ import (
 	"github.com/wanchain/go-wanchain/eth/downloader"
 	"github.com/wanchain/go-wanchain/eth/filters"
 	"github.com/wanchain/go-wanchain/eth/gasprice"
+	"github.com/wanchain/go-wanchain/eth/otascan"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/internal/ethapi"
@@ -49,6 +50,8 @@ import (
 	"github.com/wanchain/go-wanchain/node"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -75,7 +78,10 @@ type Ethereum struct {
 	lesServer       LesServer
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb ethdb.Database  // Block chain database
+	posCtx  *posctx.Context // PoS context, holding the PoS databases
+
+	posSigner *possigner.Remote // Remote validator signer, nil if the keys are in the keystore
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -84,8 +90,12 @@ type Ethereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	incentiveIndexer *core.ChainIndexer // Incentive indexer, nil unless enabled
+
 	ApiBackend *EthApiBackend
 
+	otaScanner *otascan.Scanner
+
 	miner     *miner.Miner
 	gasPrice  *big.Int
 	etherbase common.Address
@@ -109,7 +119,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
-	chainDb, err := CreateDB(ctx, config, "chaindata")
+	posSigner, err := dialPosSigner(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	chainDb, err := createChainDB(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -119,11 +133,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
-	posEngine := pluto.New(chainConfig.Pluto, chainDb)
+	posCtx := posctx.NewWithEngine(ctx.ResolvePath(""), ctx.DBEngine())
+	if err := posCtx.SetChainDb(chainDb); err != nil {
+		return nil, err
+	}
+	posEngine := pluto.New(chainConfig.Pluto, chainDb, posCtx)
 
 	eth := &Ethereum{
 		config:         config,
 		chainDb:        chainDb,
+		posCtx:         posCtx,
+		posSigner:      posSigner,
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
@@ -157,7 +177,13 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
 
-	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.engine, vmConfig, posEngine)
+	cacheConfig := &core.CacheConfig{
+		Disabled:         config.NoPruning,
+		TrieNodeLimit:    config.TrieCache,
+		TrieTimeLimit:    config.TrieTimeout,
+		AncientThreshold: config.AncientThreshold,
+	}
+	eth.blockchain, err = core.NewBlockChainWithCache(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig, posEngine)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +196,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain.CurrentHeader(), eth.blockchain.SubscribeChainEvent)
+	if config.IncentiveIndex {
+		eth.incentiveIndexer = incentiveindex.New(chainDb, posCtx, incentiveindex.SectionSize)
+		eth.incentiveIndexer.Start(eth.blockchain.CurrentHeader(), eth.blockchain.SubscribeChainEvent)
+	}
 
 	// TODO:ppow2pos
 	//if chainConfig.Pluto != nil {
@@ -184,9 +214,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
-	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
+	if eth.miner, err = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine); err != nil {
+		return nil, err
+	}
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
+	otaDb, err := CreateDB(ctx, config, "otaindex")
+	if err != nil {
+		return nil, err
+	}
+	eth.otaScanner = otascan.New(eth.blockchain, chainDb, eth.accountManager, otaDb)
+
 	eth.ApiBackend = &EthApiBackend{eth, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -196,7 +234,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 
     if inPosStage{
-		miner.PosInit(eth)
+		if _, err := miner.PosInit(eth); err != nil {
+			return nil, err
+		}
 		chainConfig.SetPosActive()
 	}
 	return eth, nil
@@ -231,6 +271,39 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 	return db, nil
 }
 
+// createChainDB creates the chain database of a full node, the old blocks of
+// which are kept in the ancient freezer.
+func createChainDB(ctx *node.ServiceContext, config *Config) (ethdb.Database, error) {
+	db, err := ctx.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer)
+	if err != nil {
+		return nil, err
+	}
+	if db, ok := db.(*ethdb.LDBDatabase); ok {
+		db.Meter("eth/db/chaindata/")
+	}
+	return db, nil
+}
+
+// dialPosSigner connects to the remote validator signer of the config and
+// adds its wallet to the account manager, the etherbase defaults to the
+// validator account.
+func dialPosSigner(ctx *node.ServiceContext, config *Config) (*possigner.Remote, error) {
+	if config.PosSigner == "" {
+		return nil, nil
+	}
+	signer, err := possigner.Dial(config.PosSigner)
+	if err != nil {
+		return nil, fmt.Errorf("validator signer %s unavailable: %v", config.PosSigner, err)
+	}
+	url := accounts.URL{Scheme: "possigner", Path: config.PosSigner}
+	ctx.AccountManager.AddBackend(possigner.NewBackend(signer, url))
+	if (config.Etherbase == common.Address{}) {
+		config.Etherbase = signer.Address()
+	}
+	log.Info("Connected to validator signer", "url", url, "address", signer.Address())
+	return signer, nil
+}
+
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
 func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig *params.ChainConfig, db ethdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
@@ -267,6 +340,10 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend)...)
+	apis = append(apis, otascan.APIs(s.otaScanner)...)
+	if s.incentiveIndexer != nil {
+		apis = append(apis, incentiveindex.APIs(s.incentiveIndexer, s.chainDb, s.posCtx)...)
+	}
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -361,21 +438,17 @@ func (s *Ethereum) StartMining(local bool) error {
 		clique.Authorize(eb, wallet.SignHash)
 	}
 	if pluto, ok := s.engine.(*pluto.Pluto); ok {
+		// The validator waits for a locked key instead
 		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
-		}
-		//------------Get local unlock publicKey
-		type getKey interface {
-			GetUnlockedKey(address common.Address) (*keystore.Key, error)
+		if err == nil {
+			var signer possigner.Signer
+			if signer, err = possigner.FromWallet(wallet, eb); err == nil {
+				pluto.Authorize(eb, wallet.SignHash, signer)
+			}
 		}
-		key, err := wallet.(getKey).GetUnlockedKey(eb)
-		if key == nil || err != nil {
-			panic(err)
+		if err != nil {
+			log.Warn("Etherbase validator signer unavailable, waiting for it", "address", eb, "err", err)
 		}
-		//------------
-		pluto.Authorize(eb, wallet.SignHash, key)
 	}
 
 	if ethash, ok := s.engine.(*ethash.Ethash); ok {
@@ -443,6 +516,8 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	// Start finding the OTAs of the local accounts
+	s.otaScanner.Start()
 	return nil
 }
 
@@ -453,6 +528,10 @@ func (s *Ethereum) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.bloomIndexer.Close()
+	if s.incentiveIndexer != nil {
+		s.incentiveIndexer.Close()
+	}
+	s.otaScanner.Stop()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -462,6 +541,10 @@ func (s *Ethereum) Stop() error {
 	s.miner.Stop()
 	s.eventMux.Stop()
 
+	s.posCtx.Close()
+	if s.posSigner != nil {
+		s.posSigner.Close()
+	}
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
@@ -471,5 +554,7 @@ func (s *Ethereum) Stop() error {
 func (s *Ethereum) SwitchEngine(engine consensus.Engine){
 	s.engine = engine
 
-	miner.PosInit(s)
+	if _, err := miner.PosInit(s); err != nil {
+		log.Error("Failed to init pos", "err", err)
+	}
 }
@@ -23,6 +23,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
@@ -42,6 +43,9 @@ var DefaultConfig = Config{
 	NetworkId:            1,
 	LightPeers:           20,
 	DatabaseCache:        128,
+	TrieCache:            256,
+	TrieTimeout:          5 * time.Minute,
+	AncientThreshold:     90000,
 	//GasPrice:             big.NewInt(0).Mul(big.NewInt(18 * params.Shannon),params.WanGasTimesFactor),
 	GasPrice:             big.NewInt(1 * params.Shannon),
 	TxPool: core.DefaultTxPoolConfig,
@@ -84,12 +88,18 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	TrieCache          int
+	TrieTimeout        time.Duration
+	NoPruning          bool   // Whether to keep the states of all blocks (archive node)
+	DatabaseFreezer    string // Directory of the ancient freezer (default = inside the chain database)
+	AncientThreshold   uint64 // Number of recent blocks kept out of the freezer (0 = don't freeze)
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
 	MinerThreads int            `toml:",omitempty"`
 	ExtraData    []byte         `toml:",omitempty"`
 	GasPrice     *big.Int
+	PosSigner    string `toml:",omitempty"` // Endpoint of the remote validator signer (default = keystore keys)
 
 	// Ethash options
 	EthashCacheDir       string
@@ -108,6 +118,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables the index of the incentives paid per address
+	IncentiveIndex bool
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowFake   bool   `toml:"-"`
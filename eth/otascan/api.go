@@ -0,0 +1,126 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otascan
+
+import (
+	"context"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// APIs returns the RPC services of an OTA scanner. They link the accounts to
+// their OTAs, so they are only served to the local clients.
+func APIs(s *Scanner) []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "personal",
+			Version:   "1.0",
+			Service:   &PrivateOTAAPI{s},
+			Public:    false,
+		},
+	}
+}
+
+// OTAInfo is an OTA of a local account, as returned by the RPC.
+type OTAInfo struct {
+	Account     common.Address `json:"account"`
+	WanAddr     hexutil.Bytes  `json:"otaAddr"`
+	Value       *hexutil.Big   `json:"value"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	KeyImage    hexutil.Bytes  `json:"keyImage"`
+	Balance     *hexutil.Big   `json:"balance"`
+	Spent       *bool          `json:"spent"` // nil if the key image is unknown
+}
+
+// newOTAInfo returns the info of an OTA in a state.
+func newOTAInfo(statedb *state.StateDB, ota *OTA) *OTAInfo {
+	info := &OTAInfo{
+		Account:     ota.Account,
+		WanAddr:     ota.WanAddr,
+		Value:       (*hexutil.Big)(ota.Value),
+		BlockNumber: hexutil.Uint64(ota.BlockNumber),
+		BlockHash:   ota.BlockHash,
+		TxHash:      ota.TxHash,
+	}
+	if ax, err := vm.GetAXFromWanAddr(ota.WanAddr); err == nil {
+		if _, balance, err := vm.GetOTAInfoFromAX(statedb, ax); err == nil && balance != nil {
+			info.Balance = (*hexutil.Big)(balance)
+		}
+	}
+	if len(ota.KeyImage) != 0 {
+		info.KeyImage = ota.KeyImage
+		if spent, _, err := vm.CheckOTAImageExist(statedb, ota.KeyImage); err == nil {
+			info.Spent = &spent
+		}
+	}
+	return info
+}
+
+// PrivateOTAAPI lists and notifies the OTAs of the local accounts.
+type PrivateOTAAPI struct {
+	s *Scanner
+}
+
+// ListOTAs returns the OTAs of an account found so far, with their balance
+// and spent status at the head. The account must have been unlocked for
+// its OTAs to be found.
+func (api *PrivateOTAAPI) ListOTAs(account common.Address) ([]*OTAInfo, error) {
+	statedb, err := api.s.chain.State()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*OTAInfo, 0)
+	for _, ota := range api.s.OTAs(account) {
+		if len(ota.KeyImage) == 0 {
+			api.s.fillKeyImage(ota)
+		}
+		infos = append(infos, newOTAInfo(statedb, ota))
+	}
+	return infos, nil
+}
+
+// SubscribeOTAs creates a subscription firing for each OTA found for an
+// account, e.g. personal_subscribe("subscribeOTAs", account).
+func (api *PrivateOTAAPI) SubscribeOTAs(ctx context.Context, account common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		otas := make(chan *OTA, 16)
+		otasSub := api.s.SubscribeOTAs(otas)
+		defer otasSub.Unsubscribe()
+
+		for {
+			select {
+			case ota := <-otas:
+				if ota.Account != account {
+					continue
+				}
+				statedb, err := api.s.chain.State()
+				if err != nil {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, newOTAInfo(statedb, ota))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-otasSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
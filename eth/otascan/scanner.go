@@ -0,0 +1,393 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package otascan finds the OTAs sent to the local accounts.
+package otascan
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+const (
+	// scanInterval is how often the accounts are checked for new view keys
+	// when no block arrives.
+	scanInterval = 10 * time.Second
+
+	// maxScanBlocks is the number of blocks scanned before checking for
+	// shutdown and new heads.
+	maxScanBlocks = 1024
+)
+
+var (
+	cursorPrefix = []byte("otascan-c") // cursorPrefix + account -> rlp(cursor)
+	otasPrefix   = []byte("otascan-o") // otasPrefix + account -> rlp([]*OTA)
+)
+
+// otaWallet is a wallet able to find and spend OTAs.
+type otaWallet interface {
+	OTAViewKey(account accounts.Account) (*ecdsa.PublicKey, *ecdsa.PrivateKey, error)
+	OTAKeyImage(account accounts.Account, otaWanAddr []byte) ([]byte, error)
+}
+
+// OTA is an OTA sent to a local account.
+type OTA struct {
+	Account     common.Address
+	WanAddr     []byte
+	Value       *big.Int
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	KeyImage    []byte // empty until the spend key is unlocked
+}
+
+// cursor is the last block scanned for an account.
+type cursor struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// viewKey is the view key of an account being scanned.
+type viewKey struct {
+	account accounts.Account
+	wallet  otaWallet
+	pub     *ecdsa.PublicKey
+	view    *ecdsa.PrivateKey
+	cursor  cursor
+}
+
+// Scanner scans the blocks for the wancoin and stamp OTAs of the accounts
+// whose view key is available, and keeps the matches in an index.
+type Scanner struct {
+	chain   *core.BlockChain
+	chainDb ethdb.Database
+	am      *accounts.Manager
+
+	db ethdb.Database // index of the matches
+	mu sync.Mutex     // protects the index
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an OTA scanner keeping its index in db.
+func New(chain *core.BlockChain, chainDb ethdb.Database, am *accounts.Manager, db ethdb.Database) *Scanner {
+	return &Scanner{
+		chain:   chain,
+		chainDb: chainDb,
+		am:      am,
+		db:      db,
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start starts scanning in the background.
+func (s *Scanner) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop stops scanning and closes the index.
+func (s *Scanner) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+	s.scope.Close()
+	s.db.Close()
+}
+
+// SubscribeOTAs subscribes to the OTAs found.
+func (s *Scanner) SubscribeOTAs(ch chan<- *OTA) event.Subscription {
+	return s.scope.Track(s.feed.Subscribe(ch))
+}
+
+func (s *Scanner) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.scan() {
+			continue
+		}
+		select {
+		case <-heads:
+		case <-ticker.C:
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// scan scans the blocks up to the head for the accounts whose view key is
+// available, it returns true if blocks are left to scan.
+func (s *Scanner) scan() bool {
+	keys := s.viewKeys()
+	if len(keys) == 0 {
+		return false
+	}
+
+	from := keys[0].cursor.Number + 1
+	for _, key := range keys[1:] {
+		if key.cursor.Number+1 < from {
+			from = key.cursor.Number + 1
+		}
+	}
+	head := s.chain.CurrentBlock().NumberU64()
+	to := head
+	if to >= from+maxScanBlocks {
+		to = from + maxScanBlocks - 1
+	}
+
+	for number := from; number <= to; number++ {
+		select {
+		case <-s.quit:
+			return false
+		default:
+		}
+		block := s.chain.GetBlockByNumber(number)
+		if block == nil {
+			return false
+		}
+
+		var scanning []*viewKey
+		for _, key := range keys {
+			if key.cursor.Number < number {
+				scanning = append(scanning, key)
+			}
+		}
+		s.scanBlock(block, scanning)
+		for _, key := range scanning {
+			key.cursor = cursor{Number: number, Hash: block.Hash()}
+		}
+	}
+	for _, key := range keys {
+		s.writeCursor(key.account.Address, key.cursor)
+	}
+	return to < head
+}
+
+// viewKeys returns the view keys available, with their cursor rewound to
+// the canonical chain.
+func (s *Scanner) viewKeys() []*viewKey {
+	var keys []*viewKey
+	for _, wallet := range s.am.Wallets() {
+		w, ok := wallet.(otaWallet)
+		if !ok {
+			continue
+		}
+		for _, account := range wallet.Accounts() {
+			pub, view, err := w.OTAViewKey(account)
+			if err != nil {
+				continue
+			}
+			keys = append(keys, &viewKey{
+				account: account,
+				wallet:  w,
+				pub:     pub,
+				view:    view,
+				cursor:  s.canonicalCursor(s.readCursor(account.Address)),
+			})
+		}
+	}
+	return keys
+}
+
+// canonicalCursor rewinds a cursor to its last canonical block.
+func (s *Scanner) canonicalCursor(c cursor) cursor {
+	for c.Number > 0 && core.GetCanonicalHash(s.chainDb, c.Number) != c.Hash {
+		header := s.chain.GetHeader(c.Hash, c.Number)
+		if header == nil {
+			return cursor{}
+		}
+		c = cursor{Number: c.Number - 1, Hash: header.ParentHash}
+	}
+	return c
+}
+
+// scanBlock looks for the OTAs of keys bought in a block.
+func (s *Scanner) scanBlock(block *types.Block, keys []*viewKey) {
+	var receipts types.Receipts
+	for i, tx := range block.Transactions() {
+		wanAddr, value, ok := vm.UnpackBuyOTA(tx.To(), tx.Data())
+		if !ok {
+			continue
+		}
+		A1, R, err := keystore.GeneratePKPairFromWAddress(wanAddr)
+		if err != nil {
+			continue
+		}
+
+		for _, key := range keys {
+			if !crypto.CompareA1(key.view.D.Bytes(), key.pub, R, A1) {
+				continue
+			}
+			if receipts == nil {
+				receipts = core.GetBlockReceipts(s.chainDb, block.Hash(), block.NumberU64())
+			}
+			if i >= len(receipts) || failed(receipts[i]) {
+				continue
+			}
+
+			ota := &OTA{
+				Account:     key.account.Address,
+				WanAddr:     wanAddr,
+				Value:       value,
+				BlockNumber: block.NumberU64(),
+				BlockHash:   block.Hash(),
+				TxHash:      tx.Hash(),
+			}
+			ota.KeyImage, _ = key.wallet.OTAKeyImage(key.account, wanAddr)
+			s.addOTA(ota)
+			log.Debug("Found OTA", "account", ota.Account, "number", ota.BlockNumber, "tx", ota.TxHash)
+			s.feed.Send(ota)
+		}
+	}
+}
+
+// failed reports whether the tx of a receipt failed, the receipts made
+// before byzantium don't tell.
+func failed(receipt *types.Receipt) bool {
+	return len(receipt.PostState) == 0 && receipt.Status == types.ReceiptStatusFailed
+}
+
+// OTAs returns the OTAs of an account in the canonical chain.
+func (s *Scanner) OTAs(account common.Address) []*OTA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var otas []*OTA
+	for _, ota := range s.readOTAs(account) {
+		if core.GetCanonicalHash(s.chainDb, ota.BlockNumber) == ota.BlockHash {
+			otas = append(otas, ota)
+		}
+	}
+	return otas
+}
+
+// SetKeyImage records the key image of an OTA.
+func (s *Scanner) SetKeyImage(account common.Address, wanAddr []byte, image []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otas := s.readOTAs(account)
+	for _, ota := range otas {
+		if string(ota.WanAddr) == string(wanAddr) {
+			ota.KeyImage = image
+			s.writeOTAs(account, otas)
+			return
+		}
+	}
+}
+
+// fillKeyImage computes the missing key image of an OTA if its account is
+// unlocked.
+func (s *Scanner) fillKeyImage(ota *OTA) {
+	wallet, err := s.am.Find(accounts.Account{Address: ota.Account})
+	if err != nil {
+		return
+	}
+	w, ok := wallet.(otaWallet)
+	if !ok {
+		return
+	}
+	image, err := w.OTAKeyImage(accounts.Account{Address: ota.Account}, ota.WanAddr)
+	if err != nil {
+		return
+	}
+	ota.KeyImage = image
+	s.SetKeyImage(ota.Account, ota.WanAddr, image)
+}
+
+// addOTA adds an OTA to the index, replacing the one with the same wan
+// address if a reorg moved it.
+func (s *Scanner) addOTA(ota *OTA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otas := s.readOTAs(ota.Account)
+	for i := range otas {
+		if string(otas[i].WanAddr) == string(ota.WanAddr) {
+			otas[i] = ota
+			s.writeOTAs(ota.Account, otas)
+			return
+		}
+	}
+	s.writeOTAs(ota.Account, append(otas, ota))
+}
+
+func otasKey(account common.Address) []byte {
+	return append(append([]byte{}, otasPrefix...), account.Bytes()...)
+}
+
+func cursorKey(account common.Address) []byte {
+	return append(append([]byte{}, cursorPrefix...), account.Bytes()...)
+}
+
+func (s *Scanner) readOTAs(account common.Address) []*OTA {
+	var otas []*OTA
+	enc, err := s.db.Get(otasKey(account))
+	if err != nil {
+		return nil
+	}
+	if err := rlp.DecodeBytes(enc, &otas); err != nil {
+		log.Error("Invalid OTA index", "account", account, "err", err)
+		return nil
+	}
+	return otas
+}
+
+func (s *Scanner) writeOTAs(account common.Address, otas []*OTA) {
+	enc, err := rlp.EncodeToBytes(otas)
+	if err != nil {
+		log.Crit("Failed to encode OTAs", "err", err)
+	}
+	if err := s.db.Put(otasKey(account), enc); err != nil {
+		log.Crit("Failed to store OTAs", "err", err)
+	}
+}
+
+func (s *Scanner) readCursor(account common.Address) cursor {
+	var c cursor
+	enc, err := s.db.Get(cursorKey(account))
+	if err != nil {
+		return cursor{Hash: s.chain.Genesis().Hash()}
+	}
+	if err := rlp.DecodeBytes(enc, &c); err != nil {
+		log.Error("Invalid OTA scan cursor", "account", account, "err", err)
+		return cursor{Hash: s.chain.Genesis().Hash()}
+	}
+	return c
+}
+
+func (s *Scanner) writeCursor(account common.Address, c cursor) {
+	enc, err := rlp.EncodeToBytes(c)
+	if err != nil {
+		log.Crit("Failed to encode OTA scan cursor", "err", err)
+	}
+	if err := s.db.Put(cursorKey(account), enc); err != nil {
+		log.Crit("Failed to store OTA scan cursor", "err", err)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otascan
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+const coinSCDefinition = `[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}],"name": "buyCoinNote","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}]}]`
+
+var (
+	testBankKey, _ = crypto.HexToECDSA("f1572f76b75b40a7da72d6f2ee7fda3d1189c2d28f0a2f096347055abe344d7f")
+	testBank       = crypto.PubkeyToAddress(testBankKey.PublicKey)
+
+	wanCoinAddr = common.BytesToAddress([]byte{100})
+)
+
+// testOTA returns a new OTA of a wan address.
+func testOTA(t *testing.T, waddr common.WAddress) string {
+	A, B, err := keystore.GeneratePKPairFromWAddress(waddr[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := hexutil.PKPair2HexSlice(A, B)
+	ota, err := crypto.GenerateOneTimeKey(pair[0], pair[1], pair[2], pair[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := hexutil.MustDecode("0x" + strings.Replace(strings.Join(ota, ""), "0x", "", -1))
+	otaWaddr, err := keystore.WaddrFromUncompressedRawBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hexutil.Encode(otaWaddr[:])
+}
+
+// newTestScanner creates a chain with a wancoin bought per block for each of
+// otas, and a scanner over it.
+func newTestScanner(t *testing.T, ks *keystore.KeyStore, otas []string) *Scanner {
+	var (
+		db, _      = ethdb.NewMemDatabase()
+		engine     = ethash.NewFaker(db)
+		gspec      = core.DefaultPPOWTestingGenesisBlock()
+		genesis    = gspec.MustCommit(db)
+		signer     = types.NewEIP155Signer(gspec.Config.ChainId)
+		coinAbi, _ = abi.JSON(strings.NewReader(coinSCDefinition))
+		value, _   = new(big.Int).SetString(vm.Wancoin10, 10)
+	)
+	chain, err := core.NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := core.NewChainEnv(gspec.Config, gspec, engine, chain, db)
+	blocks, _ := env.GenerateChain(genesis, len(otas)+2, func(i int, gen *core.BlockGen) {
+		if i >= len(otas) {
+			return
+		}
+		data, err := coinAbi.Pack("buyCoinNote", otas[i], value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := types.NewTransaction(gen.TxNonce(testBank), wanCoinAddr, value, big.NewInt(200000), big.NewInt(1), data)
+		tx, _ = types.SignTx(tx, signer, testBankKey)
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	indexDb, _ := ethdb.NewMemDatabase()
+	return New(chain, db, accounts.NewManager(ks), indexDb)
+}
+
+func TestScanner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otascan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, _ := ks.NewAccount("")
+	other, _ := ks.NewAccount("")
+	waddr, _ := ks.GetWanAddress(account)
+	otherWaddr, _ := ks.GetWanAddress(other)
+
+	otas := []string{testOTA(t, waddr), testOTA(t, otherWaddr), testOTA(t, waddr)}
+	s := newTestScanner(t, ks, otas)
+	defer s.db.Close()
+
+	found := make(chan *OTA, len(otas))
+	sub := s.SubscribeOTAs(found)
+	defer sub.Unsubscribe()
+
+	// Nothing is found while the accounts are locked.
+	if s.scan() {
+		t.Fatal("blocks left to scan")
+	}
+	if have := s.OTAs(account.Address); len(have) != 0 {
+		t.Fatalf("OTAs found for a locked account: %v", have)
+	}
+
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatal(err)
+	}
+	s.scan()
+	have := s.OTAs(account.Address)
+	if len(have) != 2 {
+		t.Fatalf("OTA count mismatch: have %d, want 2", len(have))
+	}
+	for i, ota := range have {
+		if hexutil.Encode(ota.WanAddr) != otas[i*2] {
+			t.Errorf("OTA %d: have %x, want %s", i, ota.WanAddr, otas[i*2])
+		}
+		if ota.BlockNumber != uint64(i*2+1) {
+			t.Errorf("OTA %d: block mismatch: have %d, want %d", i, ota.BlockNumber, i*2+1)
+		}
+		if ota.KeyImage == nil {
+			t.Errorf("OTA %d: missing key image", i)
+		}
+		if (<-found).TxHash != ota.TxHash {
+			t.Errorf("OTA %d: notification mismatch", i)
+		}
+	}
+	if have := s.OTAs(other.Address); len(have) != 0 {
+		t.Fatalf("OTAs found for a locked account: %v", have)
+	}
+
+	// The account isn't scanned again, the other account is.
+	if err := ks.Unlock(other, ""); err != nil {
+		t.Fatal(err)
+	}
+	s.scan()
+	if have := s.OTAs(account.Address); len(have) != 2 {
+		t.Fatalf("OTA count mismatch: have %d, want 2", len(have))
+	}
+	if have := s.OTAs(other.Address); len(have) != 1 || hexutil.Encode(have[0].WanAddr) != otas[1] {
+		t.Fatalf("OTAs of the other account mismatch: %v", have)
+	}
+
+	infos, err := (&PrivateOTAAPI{s}).ListOTAs(account.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, info := range infos {
+		if info.Balance == nil || info.Balance.ToInt().Cmp(info.Value.ToInt()) != 0 {
+			t.Errorf("OTA %d: balance mismatch: have %v, want %v", i, info.Balance, info.Value)
+		}
+		if info.Spent == nil || *info.Spent {
+			t.Errorf("OTA %d: spent mismatch: have %v, want false", i, info.Spent)
+		}
+	}
+}
+
+func TestScannerViewOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otascan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	viewDir, err := ioutil.TempDir("", "otascan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(viewDir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, _ := ks.NewAccount("")
+	waddr, _ := ks.GetWanAddress(account)
+	viewKey, err := ks.ExportViewKey(account, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewKs := keystore.NewKeyStore(viewDir, keystore.LightScryptN, keystore.LightScryptP)
+	viewAccount, err := viewKs.ImportViewKey(viewKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := viewKs.Unlock(viewAccount, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	otas := []string{testOTA(t, waddr)}
+	s := newTestScanner(t, viewKs, otas)
+	defer s.db.Close()
+
+	s.scan()
+	infos, err := (&PrivateOTAAPI{s}).ListOTAs(account.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || hexutil.Encode(infos[0].WanAddr) != otas[0] {
+		t.Fatalf("OTAs mismatch: %v", infos)
+	}
+	// The key image needs the spend key.
+	if infos[0].KeyImage != nil || infos[0].Spent != nil {
+		t.Errorf("spent status known to a view-only account: %v", infos[0])
+	}
+}
@@ -21,8 +21,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/wanchain/go-wanchain/pos/posconfig"
-
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/eth/downloader"
@@ -191,7 +189,7 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		mode = downloader.FastSync
 	}
 
-	if posconfig.FirstEpochId != 0 {
+	if pm.blockchain.PosContext().FirstEpochId() != 0 {
 		mode = downloader.FullSync
 	}
 	// Run the sync cycle, and disable fast sync if we've went past the pivot block
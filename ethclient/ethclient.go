@@ -28,7 +28,6 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/core/types"
-	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
@@ -347,6 +346,79 @@ func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumb
 	return uint64(result), err
 }
 
+// AccountResult is the Merkle proof of an account and some of its storage.
+type AccountResult struct {
+	Address      common.Address
+	AccountProof [][]byte
+	Balance      *big.Int
+	CodeHash     common.Hash
+	Nonce        uint64
+	StorageHash  common.Hash
+	StorageProof []StorageResult
+}
+
+// StorageResult is the Merkle proof of a storage slot. Value is the slot as
+// stored in the storage trie.
+type StorageResult struct {
+	Key   common.Hash
+	Value []byte
+	Proof [][]byte
+}
+
+type rpcStorageResult struct {
+	Key   common.Hash     `json:"key"`
+	Value hexutil.Bytes   `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type rpcAccountResult struct {
+	Address      common.Address     `json:"address"`
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  common.Hash        `json:"storageHash"`
+	StorageProof []rpcStorageResult `json:"storageProof"`
+}
+
+// ProofAt returns the Merkle proofs of the given account and of the given
+// storage slots of it, as defined by EIP-1186.
+// The block number can be nil, in which case the proofs are taken from the latest known block.
+func (ec *Client) ProofAt(ctx context.Context, account common.Address, keys []common.Hash, blockNumber *big.Int) (*AccountResult, error) {
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+	var res rpcAccountResult
+	if err := ec.c.CallContext(ctx, &res, "eth_getProof", account, hexKeys, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if res.Balance == nil {
+		return nil, errors.New("missing balance in proof")
+	}
+	result := &AccountResult{
+		Address:      res.Address,
+		AccountProof: toByteSlices(res.AccountProof),
+		Balance:      res.Balance.ToInt(),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]StorageResult, len(res.StorageProof)),
+	}
+	for i, st := range res.StorageProof {
+		result.StorageProof[i] = StorageResult{Key: st.Key, Value: st.Value, Proof: toByteSlices(st.Proof)}
+	}
+	return result, nil
+}
+
+func toByteSlices(nodes []hexutil.Bytes) [][]byte {
+	res := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		res[i] = node
+	}
+	return res
+}
+
 // Filters
 
 // FilterLogs executes a filter query.
@@ -470,7 +542,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (*big.I
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
@@ -494,5 +566,8 @@ func toCallArg(msg ethereum.CallMsg) interface{} {
 	if msg.GasPrice != nil {
 		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
 	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
 	return arg
 }
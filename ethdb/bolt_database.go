@@ -0,0 +1,239 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/wanchain/go-wanchain/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltFileName is the name of the bolt file in the database directory.
+const boltFileName = "bolt.db"
+
+// boltBucket is the bucket holding all the entries of a bolt database.
+var boltBucket = []byte("ethdb")
+
+// errBoltNotFound is returned if a key isn't in a bolt database, the error of
+// leveldb as callers check for it.
+var errBoltNotFound = leveldb.ErrNotFound
+
+// errBoltNoBucket is returned if a bolt file opened read-only isn't a database.
+var errBoltNoBucket = errors.New("no database bucket in bolt file")
+
+// BoltDatabase is a database stored in a single bolt file, a pure Go B+tree
+// engine. Keys are stored with a one byte prefix, bolt rejecting empty keys.
+//
+// Like with leveldb, single writes aren't synced to disk. A batch write syncs
+// the file, including the single writes made before it.
+type BoltDatabase struct {
+	fn string   // directory for reporting
+	db *bolt.DB // bolt instance
+
+	ancientStore // Freezer of the immutable chain segments, if any
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewBoltDatabase opens the bolt database in the directory file, creating it
+// if it doesn't exist.
+func NewBoltDatabase(file string) (*BoltDatabase, error) {
+	if err := os.MkdirAll(file, 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(file, boltFileName), 0600, &bolt.Options{Timeout: time.Second, NoSync: true})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltDatabase{
+		fn:  file,
+		db:  db,
+		log: log.New("database", file),
+	}, nil
+}
+
+// NewBoltDatabaseReadOnly opens an existing bolt database for reading only,
+// its writes failing.
+func NewBoltDatabaseReadOnly(file string) (*BoltDatabase, error) {
+	db, err := bolt.Open(filepath.Join(file, boltFileName), 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltBucket) == nil {
+			return errBoltNoBucket
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltDatabase{
+		fn:  file,
+		db:  db,
+		log: log.New("database", file),
+	}, nil
+}
+
+// NewBoltDatabaseWithFreezer returns a bolt database keeping the immutable
+// blocks of the chain in a freezer in the ancient directory.
+func NewBoltDatabaseWithFreezer(file string, ancient string) (*BoltDatabase, error) {
+	db, err := NewBoltDatabase(file)
+	if err != nil {
+		return nil, err
+	}
+	if db.ancient, err = newFreezer(ancient); err != nil {
+		db.Close()
+		return nil, err
+	}
+	frozen, _ := db.ancient.Ancients()
+	db.log.Info("Opened ancient freezer", "path", ancient, "frozen", frozen)
+	return db, nil
+}
+
+func boltKey(key []byte) []byte {
+	return append([]byte{0}, key...)
+}
+
+// Path returns the path to the database directory.
+func (db *BoltDatabase) Path() string {
+	return db.fn
+}
+
+// Put puts the given key / value to the database.
+func (db *BoltDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(boltKey(key), value)
+	})
+}
+
+func (db *BoltDatabase) Has(key []byte) (bool, error) {
+	var has bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		has = tx.Bucket(boltBucket).Get(boltKey(key)) != nil
+		return nil
+	})
+	return has, err
+}
+
+// Get returns the given key if it's present.
+func (db *BoltDatabase) Get(key []byte) ([]byte, error) {
+	var dat []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		// The value is only valid for the life of the transaction
+		value := tx.Bucket(boltBucket).Get(boltKey(key))
+		if value == nil {
+			return errBoltNotFound
+		}
+		dat = append([]byte{}, value...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// Delete deletes the key from the database.
+func (db *BoltDatabase) Delete(key []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(boltKey(key))
+	})
+}
+
+// ForEach calls fn with each entry of the database in key order, it stops at
+// the first error returned by fn.
+func (db *BoltDatabase) ForEach(fn func(key, value []byte) error) error {
+	return db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(key, value []byte) error {
+			return fn(key[1:], value)
+		})
+	})
+}
+
+func (db *BoltDatabase) Close() {
+	if db.ancient != nil {
+		if err := db.ancient.Close(); err != nil {
+			db.log.Error("Failed to close freezer", "err", err)
+		}
+	}
+	if !db.db.IsReadOnly() {
+		if err := db.db.Sync(); err != nil {
+			db.log.Error("Failed to sync database", "err", err)
+		}
+	}
+	if err := db.db.Close(); err != nil {
+		db.log.Error("Failed to close database", "err", err)
+	} else {
+		db.log.Info("Database closed")
+	}
+}
+
+func (db *BoltDatabase) NewBatch() Batch {
+	return &boltBatch{db: db.db}
+}
+
+// boltBatch is a batch of writes to a bolt database, committed in a single
+// transaction.
+type boltBatch struct {
+	db     *bolt.DB
+	writes []kv
+	size   int
+}
+
+func (b *boltBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{boltKey(key), append([]byte{}, value...), false})
+	b.size += len(value)
+	return nil
+}
+
+func (b *boltBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{boltKey(key), nil, true})
+	b.size += 1
+	return nil
+}
+
+func (b *boltBatch) Write() error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, kv := range b.writes {
+			if kv.del {
+				if err := bucket.Delete(kv.k); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := bucket.Put(kv.k, kv.v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return b.db.Sync()
+}
+
+func (b *boltBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *boltBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
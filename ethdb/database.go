@@ -39,6 +39,8 @@ type LDBDatabase struct {
 	fn string      // filename for reporting
 	db *leveldb.DB // LevelDB instance
 
+	ancientStore // Freezer of the immutable chain segments, if any
+
 	getTimer       gometrics.Timer // Timer for measuring the database get request counts and latencies
 	putTimer       gometrics.Timer // Timer for measuring the database put request counts and latencies
 	delTimer       gometrics.Timer // Timer for measuring the database delete request counts and latencies
@@ -89,6 +91,32 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 	}, nil
 }
 
+// NewLDBDatabaseReadOnly opens an existing LevelDB database for reading only,
+// its writes failing.
+func NewLDBDatabaseReadOnly(file string, cache int, handles int) (*LDBDatabase, error) {
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+	db, err := leveldb.OpenFile(file, &opt.Options{
+		OpenFilesCacheCapacity: handles,
+		BlockCacheCapacity:     cache / 2 * opt.MiB,
+		Filter:                 filter.NewBloomFilter(10),
+		ErrorIfMissing:         true,
+		ReadOnly:               true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &LDBDatabase{
+		fn:  file,
+		db:  db,
+		log: log.New("database", file),
+	}, nil
+}
+
 // Path returns the path to the database directory.
 func (db *LDBDatabase) Path() string {
 	return db.fn
@@ -149,6 +177,20 @@ func (db *LDBDatabase) NewIterator() iterator.Iterator {
 	return db.db.NewIterator(nil, nil)
 }
 
+// ForEach calls fn with each entry of the database in key order, it stops at
+// the first error returned by fn.
+func (db *LDBDatabase) ForEach(fn func(key, value []byte) error) error {
+	it := db.db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -161,6 +203,11 @@ func (db *LDBDatabase) Close() {
 			db.log.Error("Metrics collection failed", "err", err)
 		}
 	}
+	if db.ancient != nil {
+		if err := db.ancient.Close(); err != nil {
+			db.log.Error("Failed to close freezer", "err", err)
+		}
+	}
 	err := db.db.Close()
 	if err == nil {
 		db.log.Info("Database closed")
@@ -291,6 +338,12 @@ func (b *ldbBatch) Put(key, value []byte) error {
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error {
+	b.b.Delete(key)
+	b.size += 1
+	return nil
+}
+
 func (b *ldbBatch) Write() error {
 	return b.db.Write(b.b, nil)
 }
@@ -299,6 +352,11 @@ func (b *ldbBatch) ValueSize() int {
 	return b.size
 }
 
+func (b *ldbBatch) Reset() {
+	b.b.Reset()
+	b.size = 0
+}
+
 type table struct {
 	db     Database
 	prefix string
@@ -351,6 +409,10 @@ func (tb *tableBatch) Put(key, value []byte) error {
 	return tb.batch.Put(append([]byte(tb.prefix), key...), value)
 }
 
+func (tb *tableBatch) Delete(key []byte) error {
+	return tb.batch.Delete(append([]byte(tb.prefix), key...))
+}
+
 func (tb *tableBatch) Write() error {
 	return tb.batch.Write()
 }
@@ -358,3 +420,7 @@ func (tb *tableBatch) Write() error {
 func (tb *tableBatch) ValueSize() int {
 	return tb.batch.ValueSize()
 }
+
+func (tb *tableBatch) Reset() {
+	tb.batch.Reset()
+}
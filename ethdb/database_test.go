@@ -44,6 +44,22 @@ func newTestLDB() (*ethdb.LDBDatabase, func()) {
 	}
 }
 
+func newTestBolt() (*ethdb.BoltDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+	db, err := ethdb.NewBoltDatabase(dirname)
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
 var test_values = []string{"", "a", "1251", "\x00123\x00"}
 
 func TestLDB_PutGet(t *testing.T) {
@@ -52,6 +68,12 @@ func TestLDB_PutGet(t *testing.T) {
 	testPutGet(db, t)
 }
 
+func TestBolt_PutGet(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testPutGet(db, t)
+}
+
 func TestMemoryDB_PutGet(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
 	testPutGet(db, t)
@@ -130,6 +152,12 @@ func TestLDB_ParallelPutGet(t *testing.T) {
 	testParallelPutGet(db, t)
 }
 
+func TestBolt_ParallelPutGet(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testParallelPutGet(db, t)
+}
+
 func TestMemoryDB_ParallelPutGet(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
 	testParallelPutGet(db, t)
@@ -190,3 +218,119 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_Batch(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testBatch(db, t)
+}
+
+func TestBolt_Batch(t *testing.T) {
+	db, remove := newTestBolt()
+	defer remove()
+	testBatch(db, t)
+}
+
+func TestMemoryDB_Batch(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	testBatch(db, t)
+}
+
+func testBatch(db ethdb.Database, t *testing.T) {
+	batch := db.NewBatch()
+	for _, v := range test_values {
+		if err := batch.Put([]byte(v), []byte("b"+v)); err != nil {
+			t.Fatalf("batch put failed: %v", err)
+		}
+	}
+	if has, _ := db.Has([]byte(test_values[1])); has {
+		t.Fatalf("batch written before Write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+	for _, v := range test_values {
+		data, err := db.Get([]byte(v))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if !bytes.Equal(data, []byte("b"+v)) {
+			t.Fatalf("get returned wrong result, got %q expected %q", data, "b"+v)
+		}
+	}
+	batch.Reset()
+	if batch.ValueSize() != 0 {
+		t.Fatalf("batch size after reset mismatch: have %d, want 0", batch.ValueSize())
+	}
+}
+
+func TestDatabaseEngine(t *testing.T) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+
+	// A new database is created with the requested engine
+	db, err := ethdb.NewDatabase(ethdb.EngineBolt, dirname, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.(*ethdb.BoltDatabase); !ok {
+		t.Fatalf("database type mismatch: have %T", db)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	db.Close()
+
+	// It's reopened with the same engine, which can't be changed
+	if engine := ethdb.DatabaseEngine(dirname); engine != ethdb.EngineBolt {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, ethdb.EngineBolt)
+	}
+	if _, err := ethdb.NewDatabase(ethdb.EngineLevelDB, dirname, 0, 0); err == nil {
+		t.Fatalf("opened bolt database with leveldb")
+	}
+	if db, err = ethdb.NewDatabase("", dirname, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if data, err := db.Get([]byte("key")); err != nil || string(data) != "value" {
+		t.Fatalf("value mismatch after reopen: have %q (%v)", data, err)
+	}
+	if _, err := db.Get([]byte("missing")); err == nil || err.Error() != "leveldb: not found" {
+		t.Fatalf("missing key error mismatch: have %v", err)
+	}
+	if _, err := ethdb.NewDatabase("rocks", dirname+"_", 0, 0); err == nil {
+		t.Fatalf("opened database with unknown engine")
+	}
+}
+
+func TestOpenDatabaseReadOnly(t *testing.T) {
+	for _, engine := range []string{ethdb.EngineLevelDB, ethdb.EngineBolt} {
+		dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dirname)
+
+		if _, err := ethdb.OpenDatabaseReadOnly(dirname, 0, 0, ""); err == nil {
+			t.Fatalf("%s: opened a missing database", engine)
+		}
+		db, err := ethdb.NewDatabase(engine, dirname, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Put([]byte("key"), []byte("value"))
+		db.Close()
+
+		if db, err = ethdb.OpenDatabaseReadOnly(dirname, 0, 0, ""); err != nil {
+			t.Fatalf("%s: %v", engine, err)
+		}
+		if data, err := db.Get([]byte("key")); err != nil || string(data) != "value" {
+			t.Fatalf("%s: value mismatch: have %q (%v)", engine, data, err)
+		}
+		if err := db.Put([]byte("key"), []byte("other")); err == nil {
+			t.Fatalf("%s: wrote to a read-only database", engine)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// The key-value engines a database can be stored with.
+const (
+	EngineLevelDB = "leveldb"
+	EngineBolt    = "bolt"
+)
+
+// DatabaseEngine returns the engine of the database in the directory file, an
+// empty string if there is no database there.
+func DatabaseEngine(file string) string {
+	switch {
+	case common.FileExist(filepath.Join(file, "CURRENT")):
+		return EngineLevelDB
+	case common.FileExist(filepath.Join(file, boltFileName)):
+		return EngineBolt
+	}
+	return ""
+}
+
+// NewDatabase opens the database in the directory file with the given engine,
+// creating it if it doesn't exist. Without an engine an existing database is
+// opened with the one it was created with, and a new one with leveldb.
+func NewDatabase(engine, file string, cache int, handles int) (Database, error) {
+	return newDatabase(engine, file, cache, handles, "")
+}
+
+// NewDatabaseWithFreezer opens a database like NewDatabase, keeping the
+// immutable blocks of the chain in a freezer in the ancient directory.
+func NewDatabaseWithFreezer(engine, file string, cache int, handles int, ancient string) (Database, error) {
+	return newDatabase(engine, file, cache, handles, ancient)
+}
+
+// OpenDatabaseReadOnly opens the existing database in the directory file for
+// reading only, with the engine it was created with. The freezer in the
+// ancient directory is opened too if there is one.
+func OpenDatabaseReadOnly(file string, cache int, handles int, ancient string) (Database, error) {
+	var (
+		db     Database
+		stores *ancientStore
+		err    error
+	)
+	switch engine := DatabaseEngine(file); engine {
+	case EngineLevelDB:
+		var ldb *LDBDatabase
+		if ldb, err = NewLDBDatabaseReadOnly(file, cache, handles); err == nil {
+			db, stores = ldb, &ldb.ancientStore
+		}
+	case EngineBolt:
+		var bdb *BoltDatabase
+		if bdb, err = NewBoltDatabaseReadOnly(file); err == nil {
+			db, stores = bdb, &bdb.ancientStore
+		}
+	default:
+		return nil, fmt.Errorf("no database in %s", file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ancient != "" && common.FileExist(ancient) {
+		if stores.ancient, err = openFreezer(ancient, true); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func newDatabase(engine, file string, cache int, handles int, ancient string) (Database, error) {
+	existing := DatabaseEngine(file)
+	switch {
+	case engine == "":
+		engine = existing
+		if engine == "" {
+			engine = EngineLevelDB
+		}
+	case existing != "" && existing != engine:
+		return nil, fmt.Errorf("database in %s was created with %s, not %s", file, existing, engine)
+	}
+	var (
+		db  Database
+		err error
+	)
+	switch engine {
+	case EngineLevelDB:
+		if ancient == "" {
+			db, err = NewLDBDatabase(file, cache, handles)
+		} else {
+			db, err = NewLDBDatabaseWithFreezer(file, cache, handles, ancient)
+		}
+	case EngineBolt:
+		if ancient == "" {
+			db, err = NewBoltDatabase(file)
+		} else {
+			db, err = NewBoltDatabaseWithFreezer(file, ancient)
+		}
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+	// Don't hand out a typed nil database on failure
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
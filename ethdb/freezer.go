@@ -0,0 +1,265 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethdb
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// The tables of the freezer, one item per frozen block in each.
+const (
+	AncientHeaders  = "headers"  // RLP encoded block headers
+	AncientHashes   = "hashes"   // Canonical block hashes
+	AncientBodies   = "bodies"   // RLP encoded block bodies
+	AncientReceipts = "receipts" // RLP encoded block receipts for storage
+	AncientTds      = "diffs"    // RLP encoded total difficulties
+)
+
+// freezerNoCompression lists the tables whose items aren't worth compressing.
+var freezerNoCompression = map[string]bool{
+	AncientHeaders:  false,
+	AncientHashes:   true,
+	AncientBodies:   false,
+	AncientReceipts: false,
+	AncientTds:      true,
+}
+
+var (
+	// errNoFreezer is returned by the ancient store methods of a database
+	// without a freezer.
+	errNoFreezer = errors.New("no ancient freezer")
+
+	// errUnknownTable is returned if an item is requested from an unknown table.
+	errUnknownTable = errors.New("unknown table")
+)
+
+// freezer is an append-only store of the immutable part of the chain, kept
+// in flat files instead of the key-value store. It holds the blocks from the
+// genesis one on, one item per block in each of its tables.
+type freezer struct {
+	frozen   uint64 // Number of blocks frozen, accessed atomically
+	readonly bool   // Whether the freezer was opened for reading only
+
+	lock   sync.Mutex // Lock serializing appends and truncations
+	tables map[string]*freezerTable
+}
+
+// newFreezer opens the freezer in dir, creating it if it doesn't exist. The
+// tables are cut back to the blocks stored in all of them.
+func newFreezer(dir string) (*freezer, error) {
+	return openFreezer(dir, false)
+}
+
+// openFreezer opens the freezer in dir. A read-only freezer must exist, and
+// is left as it is on disk.
+func openFreezer(dir string, readonly bool) (*freezer, error) {
+	if !readonly {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f := &freezer{readonly: readonly, tables: make(map[string]*freezerTable)}
+	for name, noCompression := range freezerNoCompression {
+		table, err := openFreezerTable(dir, name, noCompression, readonly)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tables[name] = table
+	}
+	frozen := ^uint64(0)
+	for _, table := range f.tables {
+		if items := table.Items(); items < frozen {
+			frozen = items
+		}
+	}
+	if readonly {
+		atomic.StoreUint64(&f.frozen, frozen)
+		return f, nil
+	}
+	if err := f.truncate(frozen); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// HasAncient reports whether a block is frozen.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if _, ok := f.tables[kind]; !ok {
+		return false, errUnknownTable
+	}
+	return number < atomic.LoadUint64(&f.frozen), nil
+}
+
+// Ancient returns an item of a frozen block.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownTable
+	}
+	if number >= atomic.LoadUint64(&f.frozen) {
+		return nil, errOutOfBounds
+	}
+	return table.Retrieve(number)
+}
+
+// Ancients returns the number of blocks frozen.
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+// AppendAncient adds the next block to the freezer. The tables are left as
+// they were if the block can't be added to all of them.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if number != atomic.LoadUint64(&f.frozen) {
+		return errOutOrderInsertion
+	}
+	items := map[string][]byte{
+		AncientHashes:   hash,
+		AncientHeaders:  header,
+		AncientBodies:   body,
+		AncientReceipts: receipts,
+		AncientTds:      td,
+	}
+	for kind, blob := range items {
+		if err := f.tables[kind].Append(number, blob); err != nil {
+			f.truncateTables(number)
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, number+1)
+	return nil
+}
+
+// TruncateAncients discards the frozen blocks past the given number of blocks.
+func (f *freezer) TruncateAncients(items uint64) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if items >= atomic.LoadUint64(&f.frozen) {
+		return nil
+	}
+	return f.truncate(items)
+}
+
+// truncate cuts all the tables back to items, the lock being held.
+func (f *freezer) truncate(items uint64) error {
+	if err := f.truncateTables(items); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+func (f *freezer) truncateTables(items uint64) error {
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync flushes the tables to disk.
+func (f *freezer) Sync() error {
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the tables.
+func (f *freezer) Close() error {
+	var err error
+	for _, table := range f.tables {
+		if cerr := table.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewLDBDatabaseWithFreezer returns a LevelDB wrapped object keeping the
+// immutable blocks of the chain in a freezer in the ancient directory.
+func NewLDBDatabaseWithFreezer(file string, cache int, handles int, ancient string) (*LDBDatabase, error) {
+	db, err := NewLDBDatabase(file, cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	if db.ancient, err = newFreezer(ancient); err != nil {
+		db.Close()
+		return nil, err
+	}
+	frozen, _ := db.ancient.Ancients()
+	db.log.Info("Opened ancient freezer", "path", ancient, "frozen", frozen)
+	return db, nil
+}
+
+// ancientStore implements AncientStore for a database, with the freezer
+// attached to it if any.
+type ancientStore struct {
+	ancient *freezer
+}
+
+// HasAncient implements AncientReader.
+func (db *ancientStore) HasAncient(kind string, number uint64) (bool, error) {
+	if db.ancient == nil {
+		return false, errNoFreezer
+	}
+	return db.ancient.HasAncient(kind, number)
+}
+
+// Ancient implements AncientReader.
+func (db *ancientStore) Ancient(kind string, number uint64) ([]byte, error) {
+	if db.ancient == nil {
+		return nil, errNoFreezer
+	}
+	return db.ancient.Ancient(kind, number)
+}
+
+// Ancients implements AncientReader.
+func (db *ancientStore) Ancients() (uint64, error) {
+	if db.ancient == nil {
+		return 0, errNoFreezer
+	}
+	return db.ancient.Ancients()
+}
+
+// AppendAncient implements AncientWriter.
+func (db *ancientStore) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	if db.ancient == nil {
+		return errNoFreezer
+	}
+	return db.ancient.AppendAncient(number, hash, header, body, receipts, td)
+}
+
+// TruncateAncients implements AncientWriter.
+func (db *ancientStore) TruncateAncients(items uint64) error {
+	if db.ancient == nil {
+		return errNoFreezer
+	}
+	return db.ancient.TruncateAncients(items)
+}
+
+// Sync implements AncientWriter, flushing the freezer to disk.
+func (db *ancientStore) Sync() error {
+	if db.ancient == nil {
+		return errNoFreezer
+	}
+	return db.ancient.Sync()
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// indexEntrySize is the size of an entry of a table index, the end offset of
+// an item in the data file.
+const indexEntrySize = 8
+
+var (
+	// errOutOfBounds is returned if an item is requested past the end of a table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if an item is appended to a table with a
+	// number other than the next one.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+
+	// errReadOnly is returned by the writes to a freezer opened read-only.
+	errReadOnly = errors.New("read-only freezer")
+)
+
+// freezerTable is an append-only table of the freezer. Its items are stored
+// one after the other in a data file, and their end offsets in an index file.
+type freezerTable struct {
+	lock          sync.RWMutex
+	noCompression bool     // Whether the items are stored as is, or snappy compressed
+	readonly      bool     // Whether the files are opened for reading only
+	index         *os.File // Index file, the end offset of every item
+	data          *os.File // Data file, the items one after the other
+	items         uint64   // Number of items in the table
+	offset        uint64   // End offset of the last item
+}
+
+// newFreezerTable opens the table of the given name in dir, creating it if it
+// doesn't exist. The data written after the last complete item, if any, is
+// discarded.
+func newFreezerTable(dir, name string, noCompression bool) (*freezerTable, error) {
+	return openFreezerTable(dir, name, noCompression, false)
+}
+
+// openFreezerTable opens the table of the given name in dir. A read-only table
+// must exist, and the data past the last complete item is left on disk.
+func openFreezerTable(dir, name string, noCompression bool, readonly bool) (*freezerTable, error) {
+	idxName, datName := name+".cidx", name+".cdat"
+	if noCompression {
+		idxName, datName = name+".ridx", name+".rdat"
+	}
+	flag := os.O_RDWR | os.O_CREATE
+	if readonly {
+		flag = os.O_RDONLY
+	}
+	index, err := os.OpenFile(filepath.Join(dir, idxName), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, datName), flag, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	t := &freezerTable{
+		noCompression: noCompression,
+		readonly:      readonly,
+		index:         index,
+		data:          data,
+	}
+	if err := t.repair(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair cuts the index and data files back to the last item stored in full,
+// an append interrupted by a crash leaving either of them ahead.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	items := uint64(stat.Size()) / indexEntrySize
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	size := uint64(stat.Size())
+
+	var offset uint64
+	for items > 0 {
+		if offset, err = t.readOffset(items - 1); err != nil {
+			return err
+		}
+		if offset <= size {
+			break
+		}
+		items--
+	}
+	if items == 0 {
+		offset = 0
+	}
+	if t.readonly {
+		t.items, t.offset = items, offset
+		return nil
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	t.items, t.offset = items, offset
+	return nil
+}
+
+// readOffset reads the end offset of an item from the index.
+func (t *freezerTable) readOffset(item uint64) (uint64, error) {
+	var buf [indexEntrySize]byte
+	if _, err := t.index.ReadAt(buf[:], int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// Items returns the number of items in the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.items
+}
+
+// Append adds an item at the end of the table, item having to be the number
+// of items in it.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if item != t.items {
+		return errOutOrderInsertion
+	}
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.offset)); err != nil {
+		return err
+	}
+	offset := t.offset + uint64(len(blob))
+
+	var buf [indexEntrySize]byte
+	binary.BigEndian.PutUint64(buf[:], offset)
+	if _, err := t.index.WriteAt(buf[:], int64(t.items*indexEntrySize)); err != nil {
+		return err
+	}
+	t.items, t.offset = t.items+1, offset
+	return nil
+}
+
+// Retrieve returns an item of the table.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	var (
+		start uint64
+		err   error
+	)
+	if item > 0 {
+		if start, err = t.readOffset(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.readOffset(item)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.noCompression {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// truncate discards the items of the table past the given number of items.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if items >= t.items {
+		return nil
+	}
+	var offset uint64
+	if items > 0 {
+		var err error
+		if offset, err = t.readOffset(items - 1); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	t.items, t.offset = items, offset
+	return nil
+}
+
+// Sync flushes the table files to disk.
+func (t *freezerTable) Sync() error {
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.data.Sync()
+}
+
+// Close closes the table files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	err := t.index.Close()
+	if derr := t.data.Close(); err == nil {
+		err = derr
+	}
+	return err
+}
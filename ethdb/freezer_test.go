@@ -0,0 +1,174 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// appendTestBlocks appends blocks [from, to) with recognisable items.
+func appendTestBlocks(t *testing.T, f *freezer, from, to uint64) {
+	for i := from; i < to; i++ {
+		item := []byte(fmt.Sprintf("item-%d", i))
+		if err := f.AppendAncient(i, item, item, item, item, item); err != nil {
+			t.Fatalf("block %d: append failed: %v", i, err)
+		}
+	}
+}
+
+func checkTestBlocks(t *testing.T, f *freezer, items uint64) {
+	if frozen, _ := f.Ancients(); frozen != items {
+		t.Fatalf("frozen blocks mismatch: have %d, want %d", frozen, items)
+	}
+	for i := uint64(0); i < items; i++ {
+		for kind := range freezerNoCompression {
+			blob, err := f.Ancient(kind, i)
+			if err != nil {
+				t.Fatalf("block %d, %s: retrieve failed: %v", i, kind, err)
+			}
+			if want := []byte(fmt.Sprintf("item-%d", i)); !bytes.Equal(blob, want) {
+				t.Fatalf("block %d, %s: item mismatch: have %q, want %q", i, kind, blob, want)
+			}
+		}
+	}
+	if _, err := f.Ancient(AncientHeaders, items); err != errOutOfBounds {
+		t.Fatalf("retrieve past the end error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+}
+
+func TestFreezerAppendRetrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestBlocks(t, f, 0, 100)
+	checkTestBlocks(t, f, 100)
+
+	if err := f.AppendAncient(101, nil, nil, nil, nil, nil); err != errOutOrderInsertion {
+		t.Fatalf("out of order append error mismatch: have %v, want %v", err, errOutOrderInsertion)
+	}
+	if _, err := f.Ancient("unknown", 0); err != errUnknownTable {
+		t.Fatalf("unknown table error mismatch: have %v, want %v", err, errUnknownTable)
+	}
+	// The blocks are still there once reopened
+	f.Close()
+	if f, err = newFreezer(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkTestBlocks(t, f, 100)
+	appendTestBlocks(t, f, 100, 110)
+	checkTestBlocks(t, f, 110)
+}
+
+func TestFreezerTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	appendTestBlocks(t, f, 0, 50)
+	if err := f.TruncateAncients(20); err != nil {
+		t.Fatal(err)
+	}
+	checkTestBlocks(t, f, 20)
+
+	// Truncating past the end is a noop, and appends resume after the cut
+	if err := f.TruncateAncients(30); err != nil {
+		t.Fatal(err)
+	}
+	checkTestBlocks(t, f, 20)
+	appendTestBlocks(t, f, 20, 40)
+	checkTestBlocks(t, f, 40)
+}
+
+func TestFreezerReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := openFreezer(filepath.Join(dir, "missing"), true); err == nil {
+		t.Fatal("opened a missing freezer")
+	}
+	f, err := newFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestBlocks(t, f, 0, 10)
+	f.Close()
+
+	if f, err = openFreezer(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkTestBlocks(t, f, 10)
+	if err := f.AppendAncient(10, nil, nil, nil, nil, nil); err != errReadOnly {
+		t.Fatalf("append error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if err := f.TruncateAncients(5); err != errReadOnly {
+		t.Fatalf("truncate error mismatch: have %v, want %v", err, errReadOnly)
+	}
+}
+
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestBlocks(t, f, 0, 10)
+	f.Close()
+
+	// Simulate a crash in the middle of an append: the last body is half
+	// written and the hash of the next block made it to disk
+	bodies := filepath.Join(dir, AncientBodies+".cdat")
+	stat, err := os.Stat(bodies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(bodies, stat.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+	table, err := newFreezerTable(dir, AncientHashes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Append(10, []byte("item-10")); err != nil {
+		t.Fatal(err)
+	}
+	table.Close()
+
+	// All the tables are cut back to the last block complete in each
+	if f, err = newFreezer(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkTestBlocks(t, f, 9)
+	appendTestBlocks(t, f, 9, 12)
+	checkTestBlocks(t, f, 12)
+}
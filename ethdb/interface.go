@@ -25,20 +25,68 @@ type Putter interface {
 	Put(key []byte, value []byte) error
 }
 
+// Deleter wraps the database delete operation supported by both batches and regular databases.
+type Deleter interface {
+	Delete(key []byte) error
+}
+
 // Database wraps all database operations. All methods are safe for concurrent use.
 type Database interface {
 	Putter
+	Deleter
 	Get(key []byte) ([]byte, error)
 	Has(key []byte) (bool, error)
-	Delete(key []byte) error
 	Close()
 	NewBatch() Batch
 }
 
+// Iteratee is implemented by the databases able to visit all their entries.
+type Iteratee interface {
+	// ForEach calls fn with each entry of the database in key order, it stops
+	// at the first error returned by fn. The key and value are only valid
+	// during the call.
+	ForEach(fn func(key, value []byte) error) error
+}
+
 // Batch is a write-only database that commits changes to its host database
 // when Write is called. Batch cannot be used concurrently.
 type Batch interface {
 	Putter
+	Deleter
 	ValueSize() int // amount of data in the batch
 	Write() error
+	// Reset resets the batch for reuse
+	Reset()
+}
+
+// AncientReader wraps the read operations of the freezer, the append-only
+// store of the immutable blocks of the chain.
+type AncientReader interface {
+	// HasAncient reports whether a block is frozen.
+	HasAncient(kind string, number uint64) (bool, error)
+
+	// Ancient returns an item of a frozen block.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// Ancients returns the number of blocks frozen.
+	Ancients() (uint64, error)
+}
+
+// AncientWriter wraps the write operations of the freezer.
+type AncientWriter interface {
+	// AppendAncient adds the next block to the freezer.
+	AppendAncient(number uint64, hash, header, body, receipts, td []byte) error
+
+	// TruncateAncients discards the frozen blocks past the given number of blocks.
+	TruncateAncients(items uint64) error
+
+	// Sync flushes the frozen blocks to disk.
+	Sync() error
+}
+
+// AncientStore is a database holding the immutable blocks of the chain in a
+// freezer. Its methods fail if no freezer is attached to the database.
+type AncientStore interface {
+	AncientReader
+	AncientWriter
 }
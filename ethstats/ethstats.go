@@ -1336,7 +1336,7 @@ func (s *Service) getSelfActivity(api *posapi.PosApi, epochId uint64) (uint64, u
 		return minedBlks, elActivity, rnpActivity
 	}
 
-	selfAddr := posconfig.Cfg().GetMinerAddr()
+	selfAddr := s.eth.BlockChain().PosContext().MinerAddr()
 	if (selfAddr == common.Address{}) {
 		return minedBlks, elActivity, rnpActivity
 	}
@@ -137,11 +137,13 @@ func NewPublicTxPoolAPI(b Backend) *PublicTxPoolAPI {
 	return &PublicTxPoolAPI{b}
 }
 
-// Content returns the transactions contained within the transaction pool.
+// Content returns the transactions contained within the transaction pool. The
+// privacy transactions are also listed by the key image of the stamp they spend.
 func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
 	content := map[string]map[string]map[string]*RPCTransaction{
 		"pending": make(map[string]map[string]*RPCTransaction),
 		"queued":  make(map[string]map[string]*RPCTransaction),
+		"privacy": make(map[string]map[string]*RPCTransaction),
 	}
 	pending, queue := s.b.TxPoolContent()
 
@@ -161,15 +163,23 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 		}
 		content["queued"][account.Hex()] = dump
 	}
+	// List the privacy transactions by key image
+	for image, tx := range s.b.TxPoolPrivacyContent() {
+		content["privacy"][image] = map[string]*RPCTransaction{
+			fmt.Sprintf("%d", tx.Nonce()): newRPCPendingTransaction(tx),
+		}
+	}
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
+// Status returns the number of pending and queued transaction in the pool, and
+// the number of privacy transactions among them.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
 	return map[string]hexutil.Uint{
 		"pending": hexutil.Uint(pending),
 		"queued":  hexutil.Uint(queue),
+		"privacy": hexutil.Uint(len(s.b.TxPoolPrivacyContent())),
 	}
 }
 
@@ -368,6 +378,19 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey0, privkey1 string, password str
 	return acc.Address, err
 }
 
+// ImportViewKey stores the given view key as a view-only account, encrypting
+// it with the passphrase. The account finds its OTAs but can't spend them.
+func (s *PrivateAccountAPI) ImportViewKey(viewKey hexutil.Bytes, password string) (common.Address, error) {
+	acc, err := fetchKeystore(s.am).ImportViewKey(viewKey, password)
+	return acc.Address, err
+}
+
+// ExportViewKey returns the view key of an account, to import it as a
+// view-only account. The view key also derives the PoS key3 of the account.
+func (s *PrivateAccountAPI) ExportViewKey(addr common.Address, password string) (hexutil.Bytes, error) {
+	return fetchKeystore(s.am).ExportViewKey(accounts.Account{Address: addr}, password)
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -478,7 +501,7 @@ func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args
 	}
 
 	var signed *types.Transaction
-	signed, err = types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signed, err = types.SignTx(tx, types.NewTypedTxSigner(chainID), privateKey)
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -810,6 +833,74 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the EIP-1186 proof of an account and some of its storage.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the proof of a storage slot. Value is the slot as stored
+// in the trie: the RLP encoded word of an SSTORE, or the raw bytes of the
+// byte arrays the precompiled contracts store.
+type StorageResult struct {
+	Key   string        `json:"key"`
+	Value hexutil.Bytes `json:"value"`
+	Proof []string      `json:"proof"`
+}
+
+// GetProof returns the Merkle proofs of an account and of some of its storage
+// slots at the given block number.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	storageHash := types.EmptyRootHash
+	if storageTrie := state.StorageTrie(address); storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		storageProof[i] = StorageResult{Key: key, Proof: []string{}}
+		if !state.Exist(address) {
+			continue
+		}
+		proof, value, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		storageProof[i].Value = value
+		storageProof[i].Proof = toHexSlice(proof)
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     state.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice encodes the nodes of a proof as hex strings.
+func toHexSlice(proof []rlp.RawValue) []string {
+	nodes := make([]string, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Encode(node)
+	}
+	return nodes
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -818,6 +909,8 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	AccessList *types.AccessList `json:"accessList"`
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
@@ -846,8 +939,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		gasPrice = defaultGasPrice
 	}
 
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, accessList, false)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -1058,6 +1155,13 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+
+	// Fields of the typed transactions
+	Type                 hexutil.Uint64    `json:"type"`
+	ChainID              *hexutil.Big      `json:"chainId,omitempty"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1065,7 +1169,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewTypedTxSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -1083,6 +1187,16 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
+		Type:     hexutil.Uint64(tx.Type()),
+	}
+	if tx.Type() != types.LegacyTxType {
+		accessList := tx.AccessList()
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.AccessList = &accessList
+		if tx.Type() == types.DynamicFeeTxType {
+			result.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+			result.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		}
 	}
 
 	if blockHash != (common.Hash{}) {
@@ -1113,7 +1227,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1223,8 +1337,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the binary encoding and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1237,7 +1351,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewTypedTxSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1253,6 +1367,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(tx.Type()),
 	}
 
 	// Assign receipt status or post state.
@@ -1300,6 +1415,17 @@ type SendTxArgs struct {
 	Value    *hexutil.Big    `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
+
+	// Fields of the typed transactions, any of them set makes a typed one
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas"`
+	AccessList           *types.AccessList `json:"accessList"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+}
+
+// typed reports whether the arguments are those of a typed transaction.
+func (args *SendTxArgs) typed() bool {
+	return args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil || args.AccessList != nil
 }
 
 // prepareSendTxArgs is a helper function that fills in default values for unspecified tx fields.
@@ -1307,13 +1433,35 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if args.Gas == nil {
 		args.Gas = (*hexutil.Big)(big.NewInt(defaultGas))
 	}
-	if args.GasPrice == nil {
+	dynamicFee := args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil
+	if dynamicFee && args.GasPrice != nil {
+		return errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	// There is no base fee, a missing fee cap or tip cap defaults to the other
+	switch {
+	case args.MaxFeePerGas == nil && args.MaxPriorityFeePerGas != nil:
+		args.MaxFeePerGas = args.MaxPriorityFeePerGas
+	case args.MaxFeePerGas != nil && args.MaxPriorityFeePerGas == nil:
+		args.MaxPriorityFeePerGas = args.MaxFeePerGas
+	}
+	if dynamicFee && args.MaxPriorityFeePerGas.ToInt().Cmp(args.MaxFeePerGas.ToInt()) > 0 {
+		return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+	}
+	if args.GasPrice == nil && !dynamicFee {
 		price, err := b.SuggestPrice(ctx)
 		if err != nil {
 			return err
 		}
 		args.GasPrice = (*hexutil.Big)(price)
 	}
+	if args.typed() {
+		chainID := b.ChainConfig().ChainId
+		if args.ChainID == nil {
+			args.ChainID = (*hexutil.Big)(chainID)
+		} else if args.ChainID.ToInt().Cmp(chainID) != 0 {
+			return fmt.Errorf("chainId does not match node's (have=%v, want=%v)", args.ChainID, chainID)
+		}
+	}
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
 	}
@@ -1328,6 +1476,35 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 }
 
 func (args *SendTxArgs) toTransaction() *types.Transaction {
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	switch {
+	case args.MaxFeePerGas != nil:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        (*big.Int)(args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       args.Data,
+			AccessList: accessList,
+		})
+	case args.AccessList != nil:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Gas:        (*big.Int)(args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       args.Data,
+			AccessList: accessList,
+		})
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), (*big.Int)(args.Gas), (*big.Int)(args.GasPrice), args.Data)
 	}
@@ -1531,13 +1708,18 @@ func (s *PublicTransactionPoolAPI) ComputeOTAPPKeys(ctx context.Context, address
 	otaPub := sS[0] + sS[1][2:]
 	otaPriv := sS[2]
 
-	privateKey, err := crypto.HexToECDSA(otaPriv[2:])
-	if err != nil {
-		return "", err
+	// A view-only account has no OTA private key, the OTA public key gives
+	// the address
+	pubkey := append([]byte{4}, common.FromHex(otaPub)...)
+	if otaPriv != "" {
+		privateKey, err := crypto.HexToECDSA(otaPriv[2:])
+		if err != nil {
+			return "", err
+		}
+		pubkey = crypto.FromECDSAPub(&privateKey.PublicKey)
 	}
 
 	var addr common.Address
-	pubkey := crypto.FromECDSAPub(&privateKey.PublicKey)
 	//caculate the address for replaced pub
 	copy(addr[:], crypto.Keccak256(pubkey[1:])[12:])
 
@@ -1549,7 +1731,7 @@ func (s *PublicTransactionPoolAPI) ComputeOTAPPKeys(ctx context.Context, address
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return submitTransaction(ctx, s.b, tx)
@@ -1603,7 +1785,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1622,7 +1804,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.NewTypedTxSigner(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
@@ -1650,7 +1832,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.NewTypedTxSigner(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
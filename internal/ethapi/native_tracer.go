@@ -0,0 +1,130 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+var errNoCallTraced = errors.New("no call traced")
+
+// NativeTracer is a tracer written in Go, selectable by name in place of a
+// JavaScript tracer and much faster.
+type NativeTracer interface {
+	vm.CallTracer
+
+	// GetResult returns the trace of the transaction.
+	GetResult() (interface{}, error)
+}
+
+// nativeTracers are the constructors of the native tracers by name.
+var nativeTracers = map[string]func(statedb vm.StateDB) NativeTracer{
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+}
+
+// IsNativeTracer reports whether there is a native tracer of the given name.
+func IsNativeTracer(name string) bool {
+	_, ok := nativeTracers[name]
+	return ok
+}
+
+// NewNativeTracer returns the native tracer of the given name for a single
+// transaction, false if there is none. statedb is the state before the
+// transaction, it is read by the tracer but never modified.
+func NewNativeTracer(name string, statedb vm.StateDB) (NativeTracer, bool) {
+	newTracer, ok := nativeTracers[name]
+	if !ok {
+		return nil, false
+	}
+	return newTracer(statedb), true
+}
+
+// callFrame is a call or contract creation traced by the call tracer.
+type callFrame struct {
+	Type       string             `json:"type"`
+	From       common.Address     `json:"from"`
+	To         common.Address     `json:"to"`
+	Value      *hexutil.Big       `json:"value,omitempty"`
+	Gas        hexutil.Uint64     `json:"gas"`
+	GasUsed    hexutil.Uint64     `json:"gasUsed"`
+	Input      hexutil.Bytes      `json:"input"`
+	Output     hexutil.Bytes      `json:"output,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Precompile *vm.PrecompileCall `json:"precompile,omitempty"`
+	Calls      []*callFrame       `json:"calls,omitempty"`
+}
+
+// callTracer traces the tree of the calls of a transaction, the calls to the
+// Wanchain precompiled contracts decoded.
+type callTracer struct {
+	root  *callFrame
+	stack []*callFrame // calls entered and not exited yet
+}
+
+func newCallTracer(vm.StateDB) NativeTracer {
+	return new(callTracer)
+}
+
+// CaptureEnter implements vm.CallTracer, adding a call to the tree.
+func (t *callTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	frame := &callFrame{
+		Type:       typ.String(),
+		From:       from,
+		To:         to,
+		Gas:        hexutil.Uint64(gas),
+		Input:      common.CopyBytes(input),
+		Precompile: vm.DecodePrecompileCall(to, input),
+	}
+	if value != nil {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	if len(t.stack) == 0 {
+		t.root = frame
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, frame)
+	}
+	t.stack = append(t.stack, frame)
+	return nil
+}
+
+// CaptureExit implements vm.CallTracer, recording the result of a call.
+func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	frame.GasUsed = hexutil.Uint64(gasUsed)
+	frame.Output = common.CopyBytes(output)
+	if err != nil {
+		frame.Error = err.Error()
+	}
+	return nil
+}
+
+// CaptureState implements vm.Tracer, the opcodes aren't traced.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the outermost call of the transaction.
+func (t *callTracer) GetResult() (interface{}, error) {
+	if t.root == nil {
+		return nil, errNoCallTraced
+	}
+	return t.root, nil
+}
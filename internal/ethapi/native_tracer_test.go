@@ -0,0 +1,99 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+var (
+	tracedSender   = common.HexToAddress("0x1000")
+	tracedContract = common.HexToAddress("0x2000")
+	wanCoinAddr    = common.BytesToAddress([]byte{100})
+)
+
+// runNativeTrace calls a contract that overwrites its storage slot 0 and then
+// calls getCoins of the wanCoin precompiled contract.
+func runNativeTrace(t *testing.T, name string) interface{} {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(tracedSender, big.NewInt(1000))
+	statedb.SetState(tracedContract, common.Hash{}, common.BigToHash(big.NewInt(5)))
+
+	selector := crypto.Keccak256([]byte("getCoins()"))[:4]
+	code := []byte{byte(vm.PUSH1), 7, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.PUSH32)}
+	code = append(code, common.RightPadBytes(selector, 32)...)
+	code = append(code,
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 100, byte(vm.GAS), byte(vm.CALL), byte(vm.POP), byte(vm.STOP))
+	statedb.SetCode(tracedContract, code)
+
+	tracer, ok := NewNativeTracer(name, statedb.Copy())
+	if !ok {
+		t.Fatalf("native tracer %q not found", name)
+	}
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    big.NewInt(1000000),
+		GasPrice:    big.NewInt(1),
+	}
+	env := vm.NewEVM(context, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := env.Call(vm.AccountRef(tracedSender), tracedContract, nil, 100000, big.NewInt(0)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestCallTracer(t *testing.T) {
+	root := runNativeTrace(t, "callTracer").(*callFrame)
+	if root.Type != "CALL" || root.From != tracedSender || root.To != tracedContract || root.GasUsed == 0 {
+		t.Fatalf("outer call mismatch: %+v", root)
+	}
+	if len(root.Calls) != 1 {
+		t.Fatalf("inner calls mismatch: have %d, want 1", len(root.Calls))
+	}
+	inner := root.Calls[0]
+	if inner.From != tracedContract || inner.To != wanCoinAddr || len(inner.Input) != 4 {
+		t.Fatalf("precompile call mismatch: %+v", inner)
+	}
+	if inner.Precompile == nil || inner.Precompile.Contract != "wanCoin" || inner.Precompile.Method != "getCoins" {
+		t.Fatalf("precompile call decoding mismatch: %+v", inner.Precompile)
+	}
+}
+
+func TestPrestateTracer(t *testing.T) {
+	prestate := runNativeTrace(t, "prestateTracer").(map[common.Address]*prestateAccount)
+	for _, addr := range []common.Address{tracedSender, tracedContract, wanCoinAddr} {
+		if _, ok := prestate[addr]; !ok {
+			t.Fatalf("account %x missing from prestate", addr)
+		}
+	}
+	if balance := prestate[tracedSender].Balance.ToInt(); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("sender balance mismatch: have %v, want 1000", balance)
+	}
+	// The slot is reported with its value before the transaction.
+	if value := prestate[tracedContract].Storage[common.Hash{}]; value != common.BigToHash(big.NewInt(5)) {
+		t.Fatalf("storage value mismatch: have %x, want 5", value)
+	}
+	if len(prestate[tracedContract].Code) == 0 {
+		t.Fatalf("contract code missing from prestate")
+	}
+}
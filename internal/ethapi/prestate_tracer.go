@@ -0,0 +1,97 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// prestateAccount is the state of an account before a transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateTracer returns the state before a transaction of the accounts and
+// the storage slots it touched. The accounts of the precompiled contracts
+// called are included, but not the storage they access natively.
+type prestateTracer struct {
+	statedb  vm.StateDB // state before the transaction
+	prestate map[common.Address]*prestateAccount
+}
+
+func newPrestateTracer(statedb vm.StateDB) NativeTracer {
+	return &prestateTracer{
+		statedb:  statedb,
+		prestate: make(map[common.Address]*prestateAccount),
+	}
+}
+
+// lookupAccount adds the state of an account if it isn't known yet.
+func (t *prestateTracer) lookupAccount(addr common.Address) *prestateAccount {
+	if account, ok := t.prestate[addr]; ok {
+		return account
+	}
+	account := &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.statedb.GetBalance(addr))),
+		Nonce:   t.statedb.GetNonce(addr),
+		Code:    common.CopyBytes(t.statedb.GetCode(addr)),
+	}
+	t.prestate[addr] = account
+	return account
+}
+
+// lookupStorage adds the value of a storage slot if it isn't known yet.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	account := t.lookupAccount(addr)
+	if account.Storage == nil {
+		account.Storage = make(map[common.Hash]common.Hash)
+	}
+	if _, ok := account.Storage[key]; !ok {
+		account.Storage[key] = t.statedb.GetState(addr, key)
+	}
+}
+
+// CaptureEnter implements vm.CallTracer, adding the accounts of a call.
+func (t *prestateTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	return nil
+}
+
+// CaptureExit implements vm.CallTracer.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// CaptureState implements vm.Tracer, adding the accounts and storage slots
+// an opcode accesses.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil || len(stack.Data()) == 0 {
+		return nil
+	}
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(contract.Address(), common.BigToHash(stack.Back(0)))
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		t.lookupAccount(common.BigToAddress(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the state of the accounts touched before the transaction.
+func (t *prestateTracer) GetResult() (interface{}, error) {
+	return t.prestate, nil
+}
@@ -356,6 +356,73 @@ web3._extend({
 			call: 'pos_calProbability',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'getStakers',
+			call: 'pos_getStakers',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getStakersByEpochID',
+			call: 'pos_getStakersByEpochID',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getStaker',
+			call: 'pos_getStaker',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDelegatorPositions',
+			call: 'pos_getDelegatorPositions',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPartnerPositions',
+			call: 'pos_getPartnerPositions',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getJailStatus',
+			call: 'pos_getJailStatus',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getJailEvents',
+			call: 'pos_getJailEvents',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEquivocationStatus',
+			call: 'pos_getEquivocationStatus',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDuties',
+			call: 'pos_getDuties',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getIncentiveHistory',
+			call: 'pos_getIncentiveHistory',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'buildStakeTx',
+			call: 'pos_buildStakeTx',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'simulateStake',
+			call: 'pos_simulateStake',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getEpochIDByTime',
 			call: 'pos_getEpochIDByTime',
@@ -499,22 +566,26 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'traceBlock',
 			call: 'debug_traceBlock',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'traceBlockFromFile',
 			call: 'debug_traceBlockFromFile',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'traceBlockByNumber',
 			call: 'debug_traceBlockByNumber',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'traceBlockByHash',
 			call: 'debug_traceBlockByHash',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'seedHash',
@@ -706,6 +777,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -720,6 +797,10 @@ web3._extend({
 				return formatted;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'validatorStatus',
+			getter: 'eth_validatorStatus'
+		}),
 	]
 });
 `
@@ -807,6 +888,23 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'importViewKey',
+			call: 'personal_importViewKey',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'exportViewKey',
+			call: 'personal_exportViewKey',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'listOTAs',
+			call: 'personal_listOTAs',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -125,6 +125,12 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.txPool.Content()
 }
 
+// TxPoolPrivacyContent returns no transactions, the light pool doesn't verify
+// privacy transactions so it has no privacy lane.
+func (b *LesApiBackend) TxPoolPrivacyContent() map[string]*types.Transaction {
+	return make(map[string]*types.Transaction)
+}
+
 func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
 	return b.eth.txPool.SubscribeTxPreEvent(ch)
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/eth"
@@ -41,6 +42,7 @@ import (
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discv5"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	rpc "github.com/wanchain/go-wanchain/rpc"
 	"math/big"
 )
@@ -60,7 +62,8 @@ type LightEthereum struct {
 	reqDist         *requestDistributor
 	retriever       *retrieveManager
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb ethdb.Database  // Block chain database
+	posCtx  *posctx.Context // PoS context of the light pluto engine
 
 	ApiBackend *LesApiBackend
 
@@ -88,12 +91,18 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	posCtx := posctx.NewWithEngine(ctx.ResolvePath(""), ctx.DBEngine())
+	if err := posCtx.SetChainDb(chainDb); err != nil {
+		return nil, err
+	}
+
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
 
 	eth := &LightEthereum{
 		chainConfig:    chainConfig,
 		chainDb:        chainDb,
+		posCtx:         posCtx,
 		eventMux:       ctx.EventMux,
 		peers:          peers,
 		reqDist:        newRequestDistributor(peers, quitSync),
@@ -107,6 +116,10 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	eth.serverPool = newServerPool(chainDb, quitSync, &eth.wg)
 	eth.retriever = newRetrieveManager(peers, eth.reqDist, eth.serverPool)
 	eth.odr = NewLesOdr(chainDb, eth.retriever)
+	if chainConfig.Pluto != nil {
+		// slot leader proofs are verified with leader groups retrieved on demand
+		eth.engine = pluto.NewLight(chainConfig.Pluto, chainDb, light.NewPosBackend(eth.odr), eth.posCtx)
+	}
 	if eth.blockchain, err = light.NewLightChain(eth.odr, eth.chainConfig, eth.engine); err != nil {
 		return nil, err
 	}
@@ -222,6 +235,7 @@ func (s *LightEthereum) Stop() error {
 	s.eventMux.Stop()
 
 	time.Sleep(time.Millisecond * 200)
+	s.posCtx.Close()
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
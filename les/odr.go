@@ -53,6 +53,7 @@ const (
 	MsgReceipts
 	MsgProofs
 	MsgHeaderProofs
+	MsgLeaderGroupProofs
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -22,9 +22,11 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
@@ -43,6 +45,7 @@ var (
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
+	errNotCanonical        = errors.New("header not canonical")
 )
 
 type LesOdrRequest interface {
@@ -64,6 +67,12 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
+	case *light.EpochLeadersRequest:
+		return (*EpochLeadersRequest)(r)
+	case *light.RBProposersRequest:
+		return (*RBProposersRequest)(r)
+	case *light.RandomBeaconRequest:
+		return (*RandomBeaconRequest)(r)
 	default:
 		return nil
 	}
@@ -354,3 +363,183 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 
 	return nil
 }
+
+type LeaderGroupReq struct {
+	BHash   common.Hash
+	EpochId uint64
+}
+
+// ODR request type for the epoch leader group of an epoch, see LesOdrRequest interface
+type EpochLeadersRequest light.EpochLeadersRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochLeadersRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetLeaderGroupProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochLeadersRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv2 && peer.HasBlock(r.Header.Hash(), r.Header.Number.Uint64())
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochLeadersRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting epoch leaders", "epoch", r.EpochId, "target", r.Header.Number)
+	req := &LeaderGroupReq{
+		BHash:   r.Header.Hash(),
+		EpochId: r.EpochId,
+	}
+	return peer.RequestLeaderGroupProofs(reqID, r.GetCost(peer), []*LeaderGroupReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochLeadersRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating epoch leaders", "epoch", r.EpochId, "target", r.Header.Number)
+
+	proof, err := leaderGroupProof(db, r.Header, msg)
+	if err != nil {
+		return err
+	}
+	leaders, err := light.EpochLeadersFromProof(r.Header, r.EpochId, light.FirstEpochId(db), proof)
+	if err != nil {
+		return err
+	}
+	r.Leaders = leaders
+	r.Proof = proof
+	return nil
+}
+
+// ODR request type for the random beacon proposer group of an epoch, see LesOdrRequest interface
+type RBProposersRequest light.RBProposersRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *RBProposersRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetLeaderGroupProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *RBProposersRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv2 && peer.HasBlock(r.Header.Hash(), r.Header.Number.Uint64())
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *RBProposersRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting random beacon proposers", "epoch", r.EpochId, "target", r.Header.Number)
+	req := &LeaderGroupReq{
+		BHash:   r.Header.Hash(),
+		EpochId: r.EpochId,
+	}
+	return peer.RequestLeaderGroupProofs(reqID, r.GetCost(peer), []*LeaderGroupReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *RBProposersRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating random beacon proposers", "epoch", r.EpochId, "target", r.Header.Number)
+
+	proof, err := leaderGroupProof(db, r.Header, msg)
+	if err != nil {
+		return err
+	}
+	proposers, err := light.RBProposersFromProof(r.Header, r.EpochId, light.FirstEpochId(db), proof)
+	if err != nil {
+		return err
+	}
+	r.Proposers = proposers
+	r.Proof = proof
+	return nil
+}
+
+// leaderGroupProof checks a leader group proofs reply and returns its single
+// proof. The target header must be on the local canonical chain, otherwise the
+// group computed from the proof is not the one of the epoch.
+func leaderGroupProof(db ethdb.Database, header *types.Header, msg *Msg) ([]rlp.RawValue, error) {
+	if msg.MsgType != MsgLeaderGroupProofs {
+		return nil, errInvalidMessageType
+	}
+	proofs := msg.Obj.([][]rlp.RawValue)
+	if len(proofs) != 1 {
+		return nil, errMultipleEntries
+	}
+	if core.GetCanonicalHash(db, header.Number.Uint64()) != header.Hash() {
+		return nil, errNotCanonical
+	}
+	return proofs[0], nil
+}
+
+// ODR request type for the random beacon value of an epoch, see LesOdrRequest interface
+type RandomBeaconRequest light.RandomBeaconRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *RandomBeaconRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetProofsMsg, 2)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *RandomBeaconRequest) CanSend(peer *peer) bool {
+	return peer.HasBlock(r.Id.BlockHash, r.Id.BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *RandomBeaconRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting random beacon", "root", r.Id.Root, "epoch", r.EpochId)
+	accKey, key := light.RandomBeaconKeys(r.EpochId)
+	reqs := []*ProofReq{
+		{BHash: r.Id.BlockHash, Key: accKey},
+		{BHash: r.Id.BlockHash, AccKey: accKey, Key: key},
+	}
+	return peer.RequestProofs(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *RandomBeaconRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating random beacon", "root", r.Id.Root, "epoch", r.EpochId)
+
+	if msg.MsgType != MsgProofs {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.([][]rlp.RawValue)
+	if len(proofs) == 0 || len(proofs) > 2 {
+		return errMultipleEntries
+	}
+	accKey, key := light.RandomBeaconKeys(r.EpochId)
+
+	// Verify the account of the precompile first, its storage root anchors the value
+	data, err := trie.VerifyProof(r.Id.Root, accKey, proofs[0])
+	if err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	r.Proof = proofs[0]
+	if len(data) == 0 {
+		// no account, so no value either
+		if len(proofs) != 1 {
+			return errMultipleEntries
+		}
+		r.R = nil
+		return nil
+	}
+	var acc state.Account
+	if err := rlp.DecodeBytes(data, &acc); err != nil {
+		return err
+	}
+	if len(proofs) != 2 {
+		return errMultipleEntries
+	}
+	value, err := trie.VerifyProof(acc.Root, key, proofs[1])
+	if err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	r.Proof = append(r.Proof, proofs[1]...)
+	if len(value) != 0 {
+		r.R = new(big.Int).SetBytes(value)
+	}
+	return nil
+}
@@ -217,6 +217,9 @@ func (bc *LightChain) ResetWithGenesisBlock(genesis *types.Block) {
 // Engine retrieves the light chain's consensus engine.
 func (bc *LightChain) Engine() consensus.Engine { return bc.engine }
 
+// Config retrieves the light chain's chain configuration.
+func (bc *LightChain) Config() *params.ChainConfig { return bc.hc.Config() }
+
 // Genesis returns the genesis block
 func (bc *LightChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -0,0 +1,333 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package light
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+var (
+	ErrNoEpochTarget     = errors.New("Target block of epoch not found")
+	ErrIncompleteProof   = errors.New("Leader group proof is incomplete")
+	ErrInvalidLeaderData = errors.New("Invalid leader group data")
+
+	epochLeadersPrefix = []byte("pos-el-")  // epochLeadersPrefix + epochID (uint64 big endian) + target hash -> rlp([][]byte)
+	rbProposersPrefix  = []byte("pos-rbp-") // rbProposersPrefix + epochID (uint64 big endian) + target hash -> rlp([]Proposer)
+)
+
+// EpochLeadersRequest is the ODR request type for the epoch leader group of an
+// epoch. The group is recomputed by the client from a proof of the state of
+// the epoch's target block, so it doesn't need to trust the server.
+type EpochLeadersRequest struct {
+	OdrRequest
+	EpochId uint64
+	Header  *types.Header // target block of the selection, the last block of EpochId-2
+	Leaders [][]byte
+	Proof   []rlp.RawValue
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *EpochLeadersRequest) StoreResult(db ethdb.Database) {
+	storeProof(db, req.Proof)
+	if data, err := rlp.EncodeToBytes(req.Leaders); err == nil {
+		db.Put(posKey(epochLeadersPrefix, req.EpochId, req.Header.Hash()), data)
+	}
+}
+
+// RBProposersRequest is the ODR request type for the random beacon proposer
+// group of an epoch, it is verified the same way as EpochLeadersRequest.
+type RBProposersRequest struct {
+	OdrRequest
+	EpochId   uint64
+	Header    *types.Header // target block of the selection, the last block of EpochId-2
+	Proposers []epochLeader.Proposer
+	Proof     []rlp.RawValue
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *RBProposersRequest) StoreResult(db ethdb.Database) {
+	storeProof(db, req.Proof)
+	if data, err := rlp.EncodeToBytes(req.Proposers); err == nil {
+		db.Put(posKey(rbProposersPrefix, req.EpochId, req.Header.Hash()), data)
+	}
+}
+
+// RandomBeaconRequest is the ODR request type for the random beacon value of
+// an epoch stored in the random beacon precompile.
+type RandomBeaconRequest struct {
+	OdrRequest
+	Id      *TrieID // state trie the value is read from
+	EpochId uint64
+	R       *big.Int // nil if the value isn't set in the state
+	Proof   []rlp.RawValue
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *RandomBeaconRequest) StoreResult(db ethdb.Database) {
+	storeProof(db, req.Proof)
+}
+
+// RandomBeaconKeys returns the account and storage trie keys the random value
+// of epochID is stored under.
+func RandomBeaconKeys(epochID uint64) (accKey, key []byte) {
+	return crypto.Keccak256(vm.RandomBeaconPrecompileAddr[:]), crypto.Keccak256(vm.GetRBRKeyHash(epochID)[:])
+}
+
+func posKey(prefix []byte, epochID uint64, target common.Hash) []byte {
+	key := make([]byte, len(prefix)+8+common.HashLength)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], epochID)
+	copy(key[len(prefix)+8:], target[:])
+	return key
+}
+
+// EpochTargetHeader returns the header the leader groups of epochID are
+// selected on, the last block of epochID-2 on the local canonical chain.
+func EpochTargetHeader(db ethdb.Database, epochID uint64) *types.Header {
+	getHeader := func(number uint64) *types.Header {
+		hash := core.GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return nil
+		}
+		return core.GetHeader(db, hash, number)
+	}
+	if epochID < 2 {
+		return getHeader(0)
+	}
+	hash := core.GetHeadHeaderHash(db)
+	head := core.GetHeader(db, hash, core.GetBlockNumber(db, hash))
+	if head == nil {
+		return nil
+	}
+	return util.SearchEpochLastHeader(head.Number.Uint64(), epochID-2, getHeader)
+}
+
+// FirstEpochId returns the epoch of the first pos block on the canonical chain
+// stored in db, zero if the chain hasn't reached pos.
+func FirstEpochId(db ethdb.Database) uint64 {
+	number := util.FirstPosBlockNumber()
+	hash := core.GetCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return 0
+	}
+	header := core.GetHeader(db, hash, number)
+	if header == nil {
+		return 0
+	}
+	epochID, _ := util.CalEpSlbyTd(header.Difficulty.Uint64())
+	return epochID
+}
+
+// GetEpochLeaders retrieves the epoch leader group of epochID, including the
+// white list leaders, in the order used for slot leader selection.
+func GetEpochLeaders(ctx context.Context, odr OdrBackend, epochID uint64) ([][]byte, error) {
+	db := odr.Database()
+	header := EpochTargetHeader(db, epochID)
+	if header == nil {
+		return nil, ErrNoEpochTarget
+	}
+	if data, _ := db.Get(posKey(epochLeadersPrefix, epochID, header.Hash())); len(data) > 0 {
+		var leaders [][]byte
+		if err := rlp.DecodeBytes(data, &leaders); err == nil {
+			return leaders, nil
+		}
+	}
+	r := &EpochLeadersRequest{EpochId: epochID, Header: header}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Leaders, nil
+}
+
+// GetRBProposers retrieves the random beacon proposer group of epochID.
+func GetRBProposers(ctx context.Context, odr OdrBackend, epochID uint64) ([]epochLeader.Proposer, error) {
+	db := odr.Database()
+	header := EpochTargetHeader(db, epochID)
+	if header == nil {
+		return nil, ErrNoEpochTarget
+	}
+	if data, _ := db.Get(posKey(rbProposersPrefix, epochID, header.Hash())); len(data) > 0 {
+		var proposers []epochLeader.Proposer
+		if err := rlp.DecodeBytes(data, &proposers); err == nil {
+			return proposers, nil
+		}
+	}
+	r := &RBProposersRequest{EpochId: epochID, Header: header}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Proposers, nil
+}
+
+// GetRandom retrieves the random beacon value of epochID from the state of
+// header. Like vm.GetR it falls back to the genesis random if the value of the
+// epoch is not set.
+func GetRandom(ctx context.Context, odr OdrBackend, header *types.Header, epochID uint64) (*big.Int, error) {
+	if epochID == FirstEpochId(odr.Database()) {
+		return posconfig.GetRandomGenesis(), nil
+	}
+	r := &RandomBeaconRequest{Id: StateTrieID(header), EpochId: epochID}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	if r.R == nil {
+		return posconfig.GetRandomGenesis(), nil
+	}
+	return r.R, nil
+}
+
+// ProveLeaderGroups collects the trie nodes needed to run the leader
+// selections of epochID against the state of header, the epoch's target
+// block. A client holding only the header can rerun the selections on them.
+// The state is read from nodes, which may cache the nodes not written to db.
+func ProveLeaderGroups(db ethdb.Database, nodes trie.DatabaseReader, header *types.Header, epochID uint64) ([]rlp.RawValue, error) {
+	recorder := &proofRecorder{Database: db, reader: nodes, seen: make(map[common.Hash]struct{})}
+	statedb, err := state.New(header.Root, state.NewDatabase(recorder))
+	if err != nil {
+		return nil, err
+	}
+	// the selections may fail on chains without stakers, the client will
+	// reach the same result from the recorded nodes.
+	firstEpochId := FirstEpochId(db)
+	epochLeader.EpochLeadersFromState(statedb, epochID, firstEpochId)
+	epochLeader.RBProposersFromState(statedb, epochID, firstEpochId)
+
+	return recorder.nodes, nil
+}
+
+// EpochLeadersFromProof reruns the epoch leader selection of epochID on the
+// state nodes returned by ProveLeaderGroups.
+func EpochLeadersFromProof(header *types.Header, epochID, firstEpochId uint64, proof []rlp.RawValue) ([][]byte, error) {
+	statedb, db, err := proofState(header, proof)
+	if err != nil {
+		return nil, err
+	}
+	leaders, err := epochLeader.EpochLeadersFromState(statedb, epochID, firstEpochId)
+	if db.missing {
+		return nil, ErrIncompleteProof
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(leaders) != posconfig.EpochLeaderCount {
+		return nil, ErrInvalidLeaderData
+	}
+	return leaders, nil
+}
+
+// RBProposersFromProof reruns the random beacon proposer selection of epochID
+// on the state nodes returned by ProveLeaderGroups.
+func RBProposersFromProof(header *types.Header, epochID, firstEpochId uint64, proof []rlp.RawValue) ([]epochLeader.Proposer, error) {
+	statedb, db, err := proofState(header, proof)
+	if err != nil {
+		return nil, err
+	}
+	proposers, err := epochLeader.RBProposersFromState(statedb, epochID, firstEpochId)
+	if db.missing {
+		return nil, ErrIncompleteProof
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proposers, nil
+}
+
+func proofState(header *types.Header, proof []rlp.RawValue) (*state.StateDB, *proofDatabase, error) {
+	mdb, _ := ethdb.NewMemDatabase()
+	db := &proofDatabase{MemDatabase: mdb}
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		return nil, nil, ErrIncompleteProof
+	}
+	return statedb, db, nil
+}
+
+// proofRecorder records every trie node read from the wrapped reader.
+type proofRecorder struct {
+	ethdb.Database
+	reader trie.DatabaseReader
+	lock   sync.Mutex
+	seen   map[common.Hash]struct{}
+	nodes  []rlp.RawValue
+}
+
+func (db *proofRecorder) Get(key []byte) ([]byte, error) {
+	val, err := db.reader.Get(key)
+	if err != nil || len(key) != common.HashLength {
+		return val, err
+	}
+	hash := common.BytesToHash(key)
+	if crypto.Keccak256Hash(val) != hash {
+		return val, err
+	}
+
+	db.lock.Lock()
+	if _, ok := db.seen[hash]; !ok {
+		db.seen[hash] = struct{}{}
+		db.nodes = append(db.nodes, common.CopyBytes(val))
+	}
+	db.lock.Unlock()
+	return val, err
+}
+
+// proofDatabase serves the nodes of a proof and remembers whether a trie node
+// outside of it was asked for. The state iterators stop silently on missing
+// nodes, so the result of a selection is only valid if none was.
+type proofDatabase struct {
+	*ethdb.MemDatabase
+	missing bool
+}
+
+func (db *proofDatabase) Get(key []byte) ([]byte, error) {
+	val, err := db.MemDatabase.Get(key)
+	if err != nil && len(key) == common.HashLength {
+		db.missing = true
+	}
+	return val, err
+}
+
+// PosBackend serves the PoS data needed by the light Pluto engine through on
+// demand retrieval.
+type PosBackend struct {
+	odr OdrBackend
+}
+
+// NewPosBackend creates a PosBackend retrieving data through odr.
+func NewPosBackend(odr OdrBackend) *PosBackend {
+	return &PosBackend{odr: odr}
+}
+
+// EpochLeaders retrieves the epoch leader group of epochID.
+func (b *PosBackend) EpochLeaders(ctx context.Context, epochID uint64) ([][]byte, error) {
+	return GetEpochLeaders(ctx, b.odr, epochID)
+}
+
+// Random retrieves the random beacon value of epochID from the state of header.
+func (b *PosBackend) Random(ctx context.Context, header *types.Header, epochID uint64) (*big.Int, error) {
+	return GetRandom(ctx, b.odr, header, epochID)
+}
+
+// State returns an ODR backed state of header.
+func (b *PosBackend) State(ctx context.Context, header *types.Header) (*state.StateDB, error) {
+	return NewState(ctx, header, b.odr), nil
+}
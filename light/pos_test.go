@@ -0,0 +1,72 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package light
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func TestLeaderGroupProof(t *testing.T) {
+	posconfig.Init(nil, 4)
+
+	db, _ := ethdb.NewMemDatabase()
+	header := core.DefaultPlutoGenesisBlock().MustCommit(db).Header()
+	statedb, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLeaders, err := epochLeader.EpochLeadersFromState(statedb, 0, 0)
+	if err != nil {
+		t.Fatal("select epoch leaders:", err)
+	}
+	wantProposers, err := epochLeader.RBProposersFromState(statedb, 0, 0)
+	if err != nil {
+		t.Fatal("select rb proposers:", err)
+	}
+
+	proof, err := ProveLeaderGroups(db, db, header, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaders, err := EpochLeadersFromProof(header, 0, 0, proof)
+	if err != nil {
+		t.Fatal("epoch leaders from proof:", err)
+	}
+	if len(leaders) != len(wantLeaders) {
+		t.Fatalf("epoch leader count mismatch: have %d, want %d", len(leaders), len(wantLeaders))
+	}
+	for i := range leaders {
+		if !bytes.Equal(leaders[i], wantLeaders[i]) {
+			t.Fatalf("epoch leader %d mismatch", i)
+		}
+	}
+	proposers, err := RBProposersFromProof(header, 0, 0, proof)
+	if err != nil {
+		t.Fatal("rb proposers from proof:", err)
+	}
+	if len(proposers) != len(wantProposers) {
+		t.Fatalf("rb proposer count mismatch: have %d, want %d", len(proposers), len(wantProposers))
+	}
+	for i := range proposers {
+		if !bytes.Equal(proposers[i].PubBn256, wantProposers[i].PubBn256) {
+			t.Fatalf("rb proposer %d mismatch", i)
+		}
+	}
+
+	// any node missing from the proof must be detected
+	for _, drop := range []int{0, len(proof) - 1} {
+		incomplete := append(append([]rlp.RawValue{}, proof[:drop]...), proof[drop+1:]...)
+		if _, err := EpochLeadersFromProof(header, 0, 0, incomplete); err != ErrIncompleteProof {
+			t.Errorf("dropped node %d: error mismatch: have %v, want %v", drop, err, ErrIncompleteProof)
+		}
+	}
+}
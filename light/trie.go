@@ -24,6 +24,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/trie"
 )
 
@@ -64,6 +65,10 @@ func (db *odrDatabase) CopyTrie(t state.Trie) state.Trie {
 	}
 }
 
+func (db *odrDatabase) TrieDB() *trie.NodeDatabase {
+	return nil
+}
+
 func (db *odrDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
 	if codeHash == sha3_nil {
 		return nil, nil
@@ -135,6 +140,24 @@ func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
 
+// Prove retrieves the nodes on the path to key, returning nil if they can't
+// be retrieved.
+func (t *odrTrie) Prove(key []byte) []rlp.RawValue {
+	key = crypto.Keccak256(key)
+	var proof []rlp.RawValue
+	err := t.do(key, func() error {
+		if _, err := t.trie.TryGet(key); err != nil {
+			return err
+		}
+		proof = t.trie.Prove(key)
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return proof
+}
+
 // do tries and retries to execute a function until it returns with no error or
 // an error type other than MissingNodeError
 func (t *odrTrie) do(key []byte, fn func() error) error {
@@ -61,9 +61,14 @@ type Miner struct {
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
 	//timerStop   chan interface{}
+
+	quitLock sync.Mutex
+	quit     chan struct{} // closed by Stop to end the validator loop
+
+	validator validator // state of the pos validator duties
 }
 
-func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) *Miner {
+func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) (*Miner, error) {
 	miner := &Miner{
 		eth:       eth,
 		mux:       mux,
@@ -77,9 +82,11 @@ func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine con
 	eth.BlockChain().RegisterSwitchEngine(cpuAgent)
 	eth.BlockChain().RegisterSwitchEngine(miner)
 	//posInit(eth, nil)
-	posPreInit(eth)
+	if err := posPreInit(eth); err != nil {
+		return nil, err
+	}
 	go miner.update()
-	return miner
+	return miner, nil
 }
 
 // update keeps track of the downloader events. Please be aware that this is a one shot type of update loop.
@@ -134,14 +141,27 @@ func (self *Miner) Start(coinbase common.Address) {
 	log.Info("Starting mining operation")
 	self.worker.start()
 	if self.eth.BlockChain().Config().IsPosActive {
-		go self.backendTimerLoop(self.eth)
+		go self.backendTimerLoop(self.eth, self.newQuit())
 	} else if !self.eth.BlockChain().IsInPosStage() {
 		self.worker.commitNewWork(true, 0)
 	} else {
-		go self.backendTimerLoop(self.eth)
+		go self.backendTimerLoop(self.eth, self.newQuit())
 	}
 }
 
+// newQuit returns the quit channel of a new validator loop, ending the former
+// loop if it runs.
+func (self *Miner) newQuit() <-chan struct{} {
+	self.quitLock.Lock()
+	defer self.quitLock.Unlock()
+
+	if self.quit != nil {
+		close(self.quit)
+	}
+	self.quit = make(chan struct{})
+	return self.quit
+}
+
 func (self *Miner) Stop() {
 	self.worker.stop()
 	atomic.StoreInt32(&self.mining, 0)
@@ -149,6 +169,12 @@ func (self *Miner) Stop() {
 	//if self.worker.config.Pluto != nil && posconfig.FirstEpochId != 0{
 	//	self.timerStop <- nil
 	//}
+	self.quitLock.Lock()
+	if self.quit != nil {
+		close(self.quit)
+		self.quit = nil
+	}
+	self.quitLock.Unlock()
 }
 
 func (self *Miner) Register(agent Agent) {
@@ -166,6 +192,16 @@ func (self *Miner) Mining() bool {
 	return atomic.LoadInt32(&self.mining) > 0
 }
 
+// ValidatorStatus returns the status of the pos validator duties of the miner.
+func (self *Miner) ValidatorStatus() ValidatorStatus {
+	status := self.validator.Status()
+	if status.State == ValidatorStopped.String() && atomic.LoadInt32(&self.shouldStart) == 1 &&
+		atomic.LoadInt32(&self.canStart) == 0 {
+		status.State = ValidatorSyncing.String()
+	}
+	return status
+}
+
 func (self *Miner) HashRate() (tot int64) {
 	if pow, ok := self.engine.(consensus.PoW); ok {
 		tot += int64(pow.Hashrate())
@@ -214,6 +250,6 @@ func (self *Miner) SwitchEngine(engine consensus.Engine) {
 	log.Info("SwitchEngine")
 	if posconfig.MineEnabled {
 		log.Info("SwitchEngine, start backendTimerLoop")
-		go self.backendTimerLoop(self.eth)
+		go self.backendTimerLoop(self.eth, self.newQuit())
 	}
 }
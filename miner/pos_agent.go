@@ -3,11 +3,15 @@ package miner
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
 
 	"github.com/wanchain/go-wanchain/accounts"
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus/pluto"
+	"github.com/wanchain/go-wanchain/core/types"
 
 	//"github.com/wanchain/go-wanchain/common/hexutil"
 	"time"
@@ -18,59 +22,67 @@ import (
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/randombeacon"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
+// posTxRetries is how many times a pos tx is submitted to the tx pool before
+// its failure is reported.
+const posTxRetries = 3
+
 func posWhiteList() {
 
 }
 
-func posPreInit(s Backend) {
-	posconfig.Pow2PosUpgradeBlockNumber = s.BlockChain().Config().PosFirstBlock.Uint64()
+func posPreInit(s Backend) error {
+	if err := posconfig.SetPow2PosUpgradeBlockNumber(s.BlockChain().Config().PosFirstBlock.Uint64()); err != nil {
+		return err
+	}
 	epochLeader.NewEpocher(s.BlockChain())
+	return nil
 }
 
-func PosInit(s Backend) *epochLeader.Epocher {
+func PosInit(s Backend) (*epochLeader.Epocher, error) {
 	log.Debug("PosInit is running")
 
-	posconfig.Pow2PosUpgradeBlockNumber = s.BlockChain().Config().PosFirstBlock.Uint64()
+	posCtx := s.BlockChain().PosContext()
 	h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64())
 	if nil != h {
 		epochId, _ := util.CalEpSlbyTd(h.Difficulty.Uint64())
-		posconfig.FirstEpochId = epochId
+		posCtx.SetFirstEpochId(epochId)
 	}
 	epochSelector := epochLeader.NewEpocher(s.BlockChain())
 	//Set to epochID 0 to get a default leaders for epoch 0.
 	err := epochSelector.SelectLeadersLoop(0)
 	if err != nil {
-		panic("PosInit failed.")
+		return nil, fmt.Errorf("failed to select the default epoch leaders: %v", err)
 	}
 
-	cfm.InitCFM(s.BlockChain())
+	cfm.InitCFM(posCtx, s.BlockChain())
 
-	slotleader.SlsInit()
-	sls := slotleader.GetSlotLeaderSelection()
+	sls := slotleader.SlsInit(posCtx)
 	sls.Init(s.BlockChain(), nil, nil)
 
 	incentive.Init(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup)
 
 	s.BlockChain().SetSlotValidator(sls)
 
-	return epochSelector
+	return epochSelector, nil
 }
 
-func posInitMiner(s Backend, key *keystore.Key) {
+func posInitMiner(s Backend, signer possigner.Signer) {
 	log.Debug("posInitMiner is running")
 
+	posCtx := s.BlockChain().PosContext()
 	// config
-	if key != nil {
-		posconfig.Cfg().MinerKey = key
+	if signer != nil {
+		posCtx.SetSigner(signer)
 	}
 	epochSelector := epochLeader.NewEpocher(s.BlockChain())
-	randombeacon.GetRandonBeaconInst().Init(epochSelector)
+	randombeacon.GetRandonBeaconInst(posCtx).Init(epochSelector)
 	//if posconfig.EpochBaseTime == 0 {
 	//	//todo:`switch pos from pow,the time is not 1?
 	//	h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64())
@@ -80,56 +92,138 @@ func posInitMiner(s Backend, key *keystore.Key) {
 	//}
 }
 
-// backendTimerLoop is pos main time loop
-func (self *Miner) backendTimerLoop(s Backend) {
-	self.mu.Lock()
-	defer self.mu.Unlock()
-
-	log.Debug("backendTimerLoop is running")
-	// get wallet
-	eb, errb := s.Etherbase()
-	if errb != nil {
-		panic(errb)
+// validatorSigner returns the signer of the etherbase, an error while its key
+// is locked or its wallet unavailable.
+func validatorSigner(s Backend) (possigner.Signer, accounts.Wallet, error) {
+	eb, err := s.Etherbase()
+	if err != nil {
+		return nil, nil, err
 	}
-	wallet, errf := s.AccountManager().Find(accounts.Account{Address: eb})
-	if wallet == nil || errf != nil {
-		panic(errf)
+	wallet, err := s.AccountManager().Find(accounts.Account{Address: eb})
+	if err != nil {
+		return nil, nil, err
 	}
-	type getKey interface {
-		GetUnlockedKey(address common.Address) (*keystore.Key, error)
+	signer, err := possigner.FromWallet(wallet, eb)
+	if err != nil {
+		return nil, nil, err
 	}
-	key, err := wallet.(getKey).GetUnlockedKey(eb)
-	if key == nil || err != nil {
-		panic(err)
+	return signer, wallet, nil
+}
+
+// waitValidatorSigner retries to get the validator signer every slot until
+// it's available, nil if the mining stops first.
+func (self *Miner) waitValidatorSigner(s Backend, quit <-chan struct{}) (possigner.Signer, accounts.Wallet) {
+	for {
+		signer, wallet, err := validatorSigner(s)
+		if err == nil {
+			return signer, wallet
+		}
+		self.validator.fail(err)
+		self.validator.setState(ValidatorWaitingForKey)
+
+		select {
+		case <-quit:
+			return nil, nil
+		case <-time.After(posconfig.SlotTime * time.Second):
+		}
 	}
-	log.Debug("Get unlocked key success address:" + eb.Hex())
-	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey))
+}
 
+// authorizeValidator makes signer seal the blocks and sign the pos txs.
+func (self *Miner) authorizeValidator(s Backend, wallet accounts.Wallet, signer possigner.Signer) {
 	if pluto, ok := self.engine.(*pluto.Pluto); ok {
-		pluto.Authorize(eb, wallet.SignHash, key)
+		pluto.Authorize(signer.Address(), wallet.SignHash, signer)
 	}
-	posInitMiner(s, key)
-	// get rpcClient
-	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
-	rc, err := rpc.Dial(url)
-	if err != nil {
-		fmt.Println("err:", err)
-		panic(err)
+	s.BlockChain().PosContext().SetSigner(signer)
+	self.validator.setAddress(signer.Address())
+}
+
+// posTxSender returns the sender of the slot leader selection and random
+// beacon txs, it signs them with the validator signer and adds them to the tx
+// pool, retrying the failures.
+func (self *Miner) posTxSender(s Backend) func(*rpc.Client, map[string]interface{}) {
+	var lock sync.Mutex // serializes the nonces
+	send := func(arg map[string]interface{}) error {
+		signer := s.BlockChain().PosContext().Signer()
+		if signer == nil {
+			return possigner.ErrNoKey
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		nonce := s.TxPool().State().GetNonce(signer.Address())
+		tx := types.NewTransaction(nonce, arg["to"].(common.Address), (*big.Int)(arg["value"].(*hexutil.Big)),
+			(*big.Int)(arg["gas"].(*hexutil.Big)), posconfig.Cfg().DefaultGasPrice, arg["data"].(hexutil.Bytes))
+		tx.SetTxtype(types.POS_TX)
+		signed, err := signer.SignTx(tx, s.BlockChain().Config().ChainId)
+		if err != nil {
+			return err
+		}
+		if err := s.TxPool().AddLocal(signed); err != nil {
+			return err
+		}
+		log.Debug("Submitted pos tx", "hash", signed.Hash(), "to", tx.To(), "nonce", nonce)
+		return nil
 	}
 
+	return func(_ *rpc.Client, arg map[string]interface{}) {
+		go func() {
+			if posconfig.TxDelay != 0 {
+				time.Sleep(time.Duration(rand.Intn(posconfig.TxDelay)) * time.Second)
+			}
+			var err error
+			for i := 0; i < posTxRetries; i++ {
+				if err = send(arg); err == nil {
+					return
+				}
+				log.Warn("Failed to submit pos tx", "attempt", i+1, "err", err)
+				time.Sleep(time.Second)
+			}
+			self.validator.txFailed(err)
+		}()
+	}
+}
+
+// backendTimerLoop is pos main time loop, it runs the validator duties of each
+// slot until quit is closed by Stop. The failures, a locked key included, are
+// retried in the next slots.
+func (self *Miner) backendTimerLoop(s Backend, quit <-chan struct{}) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	defer self.validator.setState(ValidatorStopped)
+
+	log.Debug("backendTimerLoop is running")
+	posCtx := s.BlockChain().PosContext()
+
+	signer, wallet := self.waitValidatorSigner(s, quit)
+	if signer == nil {
+		return
+	}
+	log.Debug("Get validator signer success address:" + signer.Address().Hex())
+	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(signer.PublicKey()))
+
+	self.authorizeValidator(s, wallet, signer)
+	posInitMiner(s, signer)
+
+	// Send the pos txs to the local tx pool
+	sendTx := self.posTxSender(s)
+	slotleader.GetSlotLeaderSelection(posCtx).SetSendTxFn(sendTx)
+	randombeacon.GetRandonBeaconInst(posCtx).SetSendTxFn(sendTx)
+
 	var epochID, slotID uint64
 	//curBlkNum := uint64(0)
 	h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64())
 
 	if nil == h {
+		self.validator.setState(ValidatorSyncing)
 		stop := self.posStartInit(s, localPublicKey)
 		if stop {
 			return
 		}
 	} else {
 		epochID, slotID = util.CalEpSlbyTd(h.Difficulty.Uint64())
-		posconfig.FirstEpochId = epochID
-		log.Info("backendTimerLoop first pos block exist :", "FirstEpochId", posconfig.FirstEpochId)
+		posCtx.SetFirstEpochId(epochID)
+		log.Info("backendTimerLoop first pos block exist :", "FirstEpochId", posCtx.FirstEpochId())
 		// todo: need not reset the slot leader.
 		//if epochID > posconfig.FirstEpochId+2 {
 		//	stop := self.posRestartInit(s, localPublicKey)
@@ -148,61 +242,90 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		////	return
 		//case <-time.After(time.Duration(time.Second * time.Duration(sleepTime))):
 		//}
-		time.Sleep(time.Second * time.Duration(sleepTime))
-		if !self.Mining() {
-			randombeacon.GetRandonBeaconInst().Stop()
+		select {
+		case <-quit:
+			randombeacon.GetRandonBeaconInst(posCtx).Stop()
 			return
+		case <-time.After(time.Second * time.Duration(sleepTime)):
 		}
 
-		util.CalEpochSlotIDByNow()
-		epochID, slotID = util.GetEpochSlotID()
+		epochID, slotID = util.CalEpochSlotID(posCtx.Now())
 		log.Debug("get current period", "epochid", epochID, "slotid", slotID)
 
-		sls := slotleader.GetSlotLeaderSelection()
-		sls.Loop(rc, key, epochID, slotID)
+		// The key may have been locked or unlocked again since the last slot
+		signer, wallet, err := validatorSigner(s)
+		if err != nil {
+			self.validator.fail(err)
+			self.validator.setState(ValidatorWaitingForKey)
+			continue
+		}
+		self.authorizeValidator(s, wallet, signer)
 
-		prePks, isDefault := sls.GetPreEpochLeadersPK(epochID)
-		targetEpochLeaderID := epochID
-		if isDefault {
-			if epochID > posconfig.FirstEpochId+2 {
-				log.Info("backendTimerLoop use default epoch leader.")
-			}
-			targetEpochLeaderID = 0
+		self.validator.slot(epochID, slotID, self.runSlot(s, signer, epochID, slotID))
+	}
+}
+
+// runSlot runs the validator duties of a slot: the slot leader selection and
+// random beacon work, and sealing the block of the slot if the validator leads
+// it. It reports the failures of the pos txs sent since the last slot too.
+func (self *Miner) runSlot(s Backend, signer possigner.Signer, epochID, slotID uint64) error {
+	posCtx := s.BlockChain().PosContext()
+	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(signer.PublicKey()))
+
+	sls := slotleader.GetSlotLeaderSelection(posCtx)
+	if err := sls.Loop(nil, signer, epochID, slotID); err != nil {
+		return err
+	}
+
+	prePks, isDefault := sls.GetPreEpochLeadersPK(epochID)
+	targetEpochLeaderID := epochID
+	if isDefault {
+		if epochID > posCtx.FirstEpochId()+2 {
+			log.Info("backendTimerLoop use default epoch leader.")
 		}
-		if sls.IsLocalPkInEpochLeaders(prePks) {
-			leaderPub, err := sls.GetSlotLeader(targetEpochLeaderID, slotID)
-			if err == nil {
-				slotTime := (epochID*posconfig.SlotCount + slotID) * posconfig.SlotTime
-				leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
-				log.Info("leader ", "leader", leader)
-				if leader == localPublicKey && len(self.worker.chainSlotTimer)< chainTimerSlotSize{
-					self.worker.chainSlotTimer <- slotTime
-				}
+		targetEpochLeaderID = 0
+	}
+	if sls.IsLocalPkInEpochLeaders(prePks) {
+		leaderPub, err := sls.GetSlotLeader(targetEpochLeaderID, slotID)
+		if err == nil {
+			slotTime := (epochID*posconfig.SlotCount + slotID) * posconfig.SlotTime
+			leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
+			log.Info("leader ", "leader", leader)
+			if leader == localPublicKey && len(self.worker.chainSlotTimer) < chainTimerSlotSize {
+				self.worker.chainSlotTimer <- slotTime
 			}
 		}
+	}
 
-		// get state of k blocks ahead the last block
-		stateDb, err := s.BlockChain().State()
-		if err == nil {
-			// random beacon loop
-			randombeacon.GetRandonBeaconInst().Loop(stateDb, rc, epochID, slotID)
-		} else {
-			log.SyslogErr("Failed to get stateDb", "err", err)
-		}
+	// get state of k blocks ahead the last block
+	stateDb, err := s.BlockChain().State()
+	if err != nil {
+		log.SyslogErr("Failed to get stateDb", "err", err)
+		return err
+	}
+	// random beacon loop
+	if err := randombeacon.GetRandonBeaconInst(posCtx).Loop(stateDb, nil, epochID, slotID); err != nil {
+		return err
+	}
 
-		memUse := float32(util.MemStat()) / 1024.0 / 1024.0 / 1024.0
+	memUse := float32(util.MemStat()) / 1024.0 / 1024.0 / 1024.0
 
-		log.Info("Memory usage(GB)", "memory", memUse)
+	log.Info("Memory usage(GB)", "memory", memUse)
 
-		//time.Sleep(time.Second)
-	}
+	return self.validator.takeTxError()
 }
 
 func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 
+	posCtx := s.BlockChain().PosContext()
+	// Wait for the last ppow block
 	h0 := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64() - 1)
-	if h0 == nil {
-		panic("last ppow block can't find")
+	for h0 == nil {
+		time.Sleep(time.Second)
+		if !self.Mining() {
+			return true
+		}
+		h0 = s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64() - 1)
 	}
 
 	epochID, slotID := util.CalEpochSlotID(h0.Time.Uint64())
@@ -214,7 +337,7 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 		slotID += 1
 	}
 
-	leaderPub, _ := slotleader.GetSlotLeaderSelection().GetSlotLeader(0, slotID)
+	leaderPub, _ := slotleader.GetSlotLeaderSelection(posCtx).GetSlotLeader(0, slotID)
 	leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 	log.Info("posStartInit leader ", "leader", leader)
 
@@ -234,8 +357,8 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 		if !self.Mining() {
 			return true
 		}
-		posconfig.FirstEpochId = epochID
-		log.Info("backendTimerLoop :", "FirstEpochId", posconfig.FirstEpochId)
+		posCtx.SetFirstEpochId(epochID)
+		log.Info("backendTimerLoop :", "FirstEpochId", posCtx.FirstEpochId())
 
 		self.worker.chainSlotTimer <- slotTime
 
@@ -259,8 +382,8 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 			log.Info("backendTimerLoop sleep,", "FirstEpochId", epochID)
 		} else {
 			epochID, slotID = util.CalEpSlbyTd(h.Difficulty.Uint64())
-			posconfig.FirstEpochId = epochID
-			log.Info("backendTimerLoop download the first pos block :", "FirstEpochId", posconfig.FirstEpochId)
+			posCtx.SetFirstEpochId(epochID)
+			log.Info("backendTimerLoop download the first pos block :", "FirstEpochId", posCtx.FirstEpochId())
 
 			break
 		}
@@ -0,0 +1,131 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package miner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/metrics"
+)
+
+// ValidatorState is the state of the PoS validator duties of the miner.
+type ValidatorState int
+
+const (
+	ValidatorStopped       ValidatorState = iota // not mining
+	ValidatorWaitingForKey                       // the validator key or signer is unavailable
+	ValidatorSyncing                             // waiting for the chain to sync
+	ValidatorActive                              // running the duties of each slot
+	ValidatorDegraded                            // the duties of the last slot failed
+)
+
+var validatorStateNames = [...]string{"stopped", "waitingForKey", "syncing", "active", "degraded"}
+
+func (s ValidatorState) String() string {
+	if int(s) < len(validatorStateNames) {
+		return validatorStateNames[s]
+	}
+	return "unknown"
+}
+
+var (
+	validatorStateGauge     = metrics.NewGauge("miner/validator/state")
+	validatorFailureCounter = metrics.NewCounter("miner/validator/failures")
+)
+
+// ValidatorStatus is the status of the validator of a mining node.
+type ValidatorStatus struct {
+	State     string         `json:"state"`
+	Address   common.Address `json:"address"`
+	Since     uint64         `json:"since"`    // unix time the state was entered
+	EpochID   uint64         `json:"epochId"`  // epoch of the last slot run
+	SlotID    uint64         `json:"slotId"`   // last slot run
+	Failures  uint64         `json:"failures"` // consecutive failures
+	LastError string         `json:"lastError,omitempty"`
+}
+
+// validator records the state of the validator duties run by the miner.
+type validator struct {
+	lock   sync.RWMutex
+	state  ValidatorState
+	status ValidatorStatus
+	txErr  error // last failure of the pos tx sender, reported with the next slot
+}
+
+func (v *validator) setState(state ValidatorState) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.state == state {
+		return
+	}
+	log.Info("Validator state changed", "from", v.state, "to", state)
+	v.state = state
+	v.status.Since = uint64(time.Now().Unix())
+	validatorStateGauge.Update(int64(state))
+}
+
+func (v *validator) setAddress(addr common.Address) {
+	v.lock.Lock()
+	v.status.Address = addr
+	v.lock.Unlock()
+}
+
+// slot records the outcome of the duties of a slot, switching between the
+// active and degraded states.
+func (v *validator) slot(epochID, slotID uint64, err error) {
+	v.lock.Lock()
+	v.status.EpochID, v.status.SlotID = epochID, slotID
+	v.lock.Unlock()
+
+	if err != nil {
+		v.fail(err)
+		v.setState(ValidatorDegraded)
+		return
+	}
+	v.lock.Lock()
+	v.status.Failures = 0
+	v.lock.Unlock()
+	v.setState(ValidatorActive)
+}
+
+// fail records a failure, to be retried.
+func (v *validator) fail(err error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.status.Failures++
+	v.status.LastError = err.Error()
+	validatorFailureCounter.Inc(1)
+	log.Warn("Validator failure, retrying", "failures", v.status.Failures, "err", err)
+}
+
+// txFailed records a failure of the pos tx sender.
+func (v *validator) txFailed(err error) {
+	v.lock.Lock()
+	v.txErr = err
+	v.lock.Unlock()
+}
+
+// takeTxError returns and clears the last failure of the pos tx sender.
+func (v *validator) takeTxError() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	err := v.txErr
+	v.txErr = nil
+	return err
+}
+
+// Status returns the status of the validator.
+func (v *validator) Status() ValidatorStatus {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	status := v.status
+	status.State = v.state.String()
+	return status
+}
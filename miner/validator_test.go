@@ -0,0 +1,40 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package miner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatorStatus(t *testing.T) {
+	var v validator
+	if have := v.Status().State; have != "stopped" {
+		t.Fatalf("initial state mismatch: have %s, want stopped", have)
+	}
+
+	errLocked := errors.New("key locked")
+	v.fail(errLocked)
+	v.setState(ValidatorWaitingForKey)
+	status := v.Status()
+	if status.State != "waitingForKey" || status.Failures != 1 || status.LastError != errLocked.Error() || status.Since == 0 {
+		t.Errorf("waiting for key status mismatch: %+v", status)
+	}
+
+	v.slot(10, 5, nil)
+	if status := v.Status(); status.State != "active" || status.Failures != 0 || status.EpochID != 10 || status.SlotID != 5 {
+		t.Errorf("active status mismatch: %+v", status)
+	}
+
+	// A failed pos tx degrades the next slot only
+	errTx := errors.New("nonce too low")
+	v.txFailed(errTx)
+	v.slot(10, 6, v.takeTxError())
+	if status := v.Status(); status.State != "degraded" || status.Failures != 1 || status.LastError != errTx.Error() {
+		t.Errorf("degraded status mismatch: %+v", status)
+	}
+	v.slot(10, 7, v.takeTxError())
+	if status := v.Status(); status.State != "active" || status.Failures != 0 {
+		t.Errorf("recovered status mismatch: %+v", status)
+	}
+}
@@ -396,7 +396,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	}
 	work := &Work{
 		config:    self.config,
-		signer:    types.NewEIP155Signer(self.config.ChainId),
+		signer:    types.MakeSigner(self.config, header.Number),
 		state:     state,
 		ancestors: set.New(),
 		family:    set.New(),
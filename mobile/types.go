@@ -287,7 +287,7 @@ func (tx *Transaction) GetTo() *Address {
 func (tx *Transaction) WithSignature(sig []byte, chainID *BigInt) (signedTx *Transaction, _ error) {
 	var signer types.Signer = types.HomesteadSigner{}
 	if chainID != nil {
-		signer = types.NewEIP155Signer(chainID.bigint)
+		signer = types.NewTypedTxSigner(chainID.bigint)
 	}
 	rawTx, err := tx.tx.WithSignature(signer, common.CopyBytes(sig))
 	return &Transaction{rawTx}, err
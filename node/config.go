@@ -66,6 +66,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value engine of the databases of the node, "leveldb"
+	// or "bolt". If empty, existing databases are opened with the engine they
+	// were created with and new ones are created with leveldb.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -272,6 +277,15 @@ func (c *Config) resolvePath(path string) string {
 	return filepath.Join(c.instanceDir(), path)
 }
 
+// resolveAncient returns the directory of the ancient freezer of the database
+// in root, the given freezer directory resolved like the other paths.
+func (c *Config) resolveAncient(root, freezer string) string {
+	if freezer == "" {
+		return filepath.Join(root, "ancient")
+	}
+	return c.resolvePath(freezer)
+}
+
 func (c *Config) instanceDir() string {
 	if c.DataDir == "" {
 		return ""
@@ -639,7 +639,29 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.NewDatabase(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens a database like OpenDatabase, with the ancient
+// freezer of the chain attached to it. The freezer is kept inside the database
+// unless another directory is given.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer string) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return ethdb.NewMemDatabase()
+	}
+	root := n.config.resolvePath(name)
+	return ethdb.NewDatabaseWithFreezer(n.config.DBEngine, root, cache, handles, n.config.resolveAncient(root, freezer))
+}
+
+// OpenDatabaseReadOnly opens an existing database of the node's instance
+// directory for reading only, with its freezer if it has one. The freezer
+// directory is resolved like in OpenDatabaseWithFreezer.
+func (n *Node) OpenDatabaseReadOnly(name string, cache, handles int, freezer string) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return nil, errors.New("no datadir to open the database from")
+	}
+	root := n.config.resolvePath(name)
+	return ethdb.OpenDatabaseReadOnly(root, cache, handles, n.config.resolveAncient(root, freezer))
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
@@ -43,13 +43,33 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := ethdb.NewDatabase(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens a database like OpenDatabase, with the ancient
+// freezer of the chain attached to it. The freezer is kept inside the database
+// unless another directory is given.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string) (ethdb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return ethdb.NewMemDatabase()
+	}
+	root := ctx.config.resolvePath(name)
+	db, err := ethdb.NewDatabaseWithFreezer(ctx.config.DBEngine, root, cache, handles, ctx.config.resolveAncient(root, freezer))
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// DBEngine returns the key-value engine of the databases of the node.
+func (ctx *ServiceContext) DBEngine() string {
+	return ctx.config.DBEngine
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
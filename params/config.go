@@ -148,7 +148,7 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(100), false, new(EthashConfig), nil, nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(100), false, big.NewInt(0), nil, big.NewInt(0), new(EthashConfig), nil, nil}
 
 	TestChainConfig = &ChainConfig{
 		ChainId:        big.NewInt(1),
@@ -183,9 +183,17 @@ type ChainConfig struct {
 	//EIP155Block *big.Int `json:"eip155Block,omitempty"` // EIP155 HF block
 	//EIP158Block *big.Int `json:"eip158Block,omitempty"` // EIP158 HF block
 
-	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
-	PosFirstBlock  *big.Int `json:"posFirstBlock,omitempty"`
-	IsPosActive    bool     `json:"isPosActive,omitempty"`
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+	TypedTxBlock        *big.Int `json:"typedTxBlock,omitempty"`        // Typed transactions switch block (nil = no fork, 0 = already activated)
+
+	PosFirstBlock    *big.Int       `json:"posFirstBlock,omitempty"`
+	IsPosActive      bool           `json:"isPosActive,omitempty"`
+	PosJailBlock     *big.Int       `json:"posJailBlock,omitempty"`     // Validator jailing switch block (nil = no fork)
+	PosJail          *PosJailConfig `json:"posJail,omitempty"`          // Validator jailing thresholds (nil = DefaultPosJailConfig)
+	PosEvidenceBlock *big.Int       `json:"posEvidenceBlock,omitempty"` // Slot leader equivocation evidence switch block (nil = no fork)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -223,6 +231,23 @@ func (c *PlutoConfig) String() string {
 	return "pluto"
 }
 
+// PosJailConfig is the configuration of the validator jailing, from
+// PosJailBlock.
+type PosJailConfig struct {
+	MinDuties          uint64 `json:"minDuties"`          // epoch leader and random proposer duties of an epoch below which a validator isn't judged
+	MinActivityPercent uint64 `json:"minActivityPercent"` // percent of its duties of an epoch a validator must fulfil not to be jailed
+	Epochs             uint64 `json:"epochs"`             // epochs a jailed validator isn't selected in
+	BurnPercent        uint64 `json:"burnPercent"`        // percent of its own stake a validator loses when jailed
+}
+
+// DefaultPosJailConfig is the jailing configuration of the chains setting none.
+var DefaultPosJailConfig = &PosJailConfig{
+	MinDuties:          4,
+	MinActivityPercent: 50,
+	Epochs:             6,
+	BurnPercent:        0,
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -237,7 +262,7 @@ func (c *ChainConfig) String() string {
 		engine = "unknown"
 	}
 	//return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
-	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v TypedTx: %v Engine: %v}",
 		c.ChainId,
 		//c.HomesteadBlock,
 		//c.DAOForkBlock,
@@ -247,6 +272,10 @@ func (c *ChainConfig) String() string {
 		//c.EIP158Block,
 
 		c.ByzantiumBlock,
+		c.ConstantinopleBlock,
+		c.PetersburgBlock,
+		c.IstanbulBlock,
+		c.TypedTxBlock,
 		engine,
 	)
 }
@@ -296,6 +325,12 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 	//	return GasTableHomestead
 	//}
 
+	switch {
+	case c.IsIstanbul(num):
+		return GasTableIstanbul
+	case c.IsConstantinople(num):
+		return GasTableConstantinople
+	}
 	return GasTableEIP158
 }
 
@@ -350,6 +385,28 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	//	return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	//}
 
+	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
+		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
+	}
+	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
+		return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	}
+	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
+		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
+	}
+	if isForkIncompatible(c.TypedTxBlock, newcfg.TypedTxBlock, head) {
+		return newCompatError("typed transactions fork block", c.TypedTxBlock, newcfg.TypedTxBlock)
+	}
+	if isForkIncompatible(c.PosJailBlock, newcfg.PosJailBlock, head) {
+		return newCompatError("PoS jail fork block", c.PosJailBlock, newcfg.PosJailBlock)
+	}
+	if c.IsPosJail(head) && *c.JailConfig() != *newcfg.JailConfig() {
+		return newCompatError("PoS jail config", c.PosJailBlock, newcfg.PosJailBlock)
+	}
+	if isForkIncompatible(c.PosEvidenceBlock, newcfg.PosEvidenceBlock, head) {
+		return newCompatError("PoS evidence fork block", c.PosEvidenceBlock, newcfg.PosEvidenceBlock)
+	}
+
 	return nil
 }
 
@@ -417,6 +474,7 @@ type Rules struct {
 	ChainId *big.Int
 	//IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
 	//IsByzantium                               bool
+	IsConstantinople, IsPetersburg, IsIstanbul bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -426,7 +484,12 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	}
 	//return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: /*c.IsHomestead(num)*/false, IsEIP150: false/*c.IsEIP150(num)*/, IsEIP155: false/*c.IsEIP155(num)*/, IsEIP158:false/* c.IsEIP158(num)*/, IsByzantium: c.IsByzantium(num)}
 
-	return Rules{ChainId: new(big.Int).Set(chainId)}
+	return Rules{
+		ChainId:          new(big.Int).Set(chainId),
+		IsConstantinople: c.IsConstantinople(num),
+		IsPetersburg:     c.IsPetersburg(num),
+		IsIstanbul:       c.IsIstanbul(num),
+	}
 }
 
 func (c *ChainConfig) SetPosActive() {
@@ -438,6 +501,54 @@ func (c *ChainConfig) IsPosBlockNumber(n *big.Int) bool {
 	return n.Cmp(c.PosFirstBlock) >= 0
 }
 
+// IsConstantinople returns whether num is either equal to the Constantinople
+// fork block or greater.
+func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
+	return isForked(c.ConstantinopleBlock, num)
+}
+
+// IsPetersburg returns whether num is either equal to the Petersburg fork
+// block or greater, or the Constantinople fork block if no Petersburg fork
+// block is set. Petersburg drops the net gas metering of EIP-1283.
+func (c *ChainConfig) IsPetersburg(num *big.Int) bool {
+	return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+}
+
+// IsIstanbul returns whether num is either equal to the Istanbul fork block
+// or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
+// IsTypedTx returns whether num is either equal to the typed transactions fork
+// block or greater, accepting the EIP-2930 access list and EIP-1559 dynamic-fee
+// transactions. Only their encoding and signing is adopted: there is no base
+// fee, a dynamic-fee transaction pays min(tip cap, fee cap) per gas, and the
+// access list only adds intrinsic gas, without warm or cold access pricing.
+func (c *ChainConfig) IsTypedTx(num *big.Int) bool {
+	return isForked(c.TypedTxBlock, num)
+}
+
+// IsPosJail returns whether num is either equal to the validator jailing fork
+// block or greater.
+func (c *ChainConfig) IsPosJail(num *big.Int) bool {
+	return isForked(c.PosJailBlock, num)
+}
+
+// JailConfig returns the validator jailing configuration of the chain.
+func (c *ChainConfig) JailConfig() *PosJailConfig {
+	if c.PosJail == nil {
+		return DefaultPosJailConfig
+	}
+	return c.PosJail
+}
+
+// IsPosEvidence returns whether num is either equal to the slot leader
+// equivocation evidence fork block or greater.
+func (c *ChainConfig) IsPosEvidence(num *big.Int) bool {
+	return isForked(c.PosEvidenceBlock, num)
+}
+
 var (
 
 	isPosActive = false
@@ -37,6 +37,29 @@ func TestCheckCompatible(t *testing.T) {
 			head:    9,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{PosJailBlock: big.NewInt(10)},
+			new:     &ChainConfig{PosJailBlock: big.NewInt(10), PosJail: &PosJailConfig{MinDuties: 4, MinActivityPercent: 50, Epochs: 6}},
+			head:    20,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{PosJailBlock: big.NewInt(10)},
+			new:     &ChainConfig{PosJailBlock: big.NewInt(10), PosJail: &PosJailConfig{MinDuties: 4, MinActivityPercent: 60, Epochs: 6}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{PosJailBlock: big.NewInt(10)},
+			new:    &ChainConfig{PosJailBlock: big.NewInt(10), PosJail: &PosJailConfig{MinDuties: 4, MinActivityPercent: 60, Epochs: 6}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "PoS jail config",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 		//{
 		//	stored: AllProtocolChanges,
 		//	new:    &ChainConfig{ByzantiumBlock: nil},
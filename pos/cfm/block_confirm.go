@@ -8,7 +8,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"time"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 const (
@@ -24,6 +24,7 @@ var (
 )
 
 type CFM struct {
+	ctx       *posctx.Context
 	bc        *core.BlockChain
 	whiteList map[common.Address]int
 }
@@ -38,10 +39,12 @@ type BlkStatus struct {
 	Stable    bool
 }
 
-var c *CFM
+const serviceName = "cfm"
 
-func InitCFM(bc *core.BlockChain) {
-	c = &CFM{}
+// InitCFM creates the block confirmation service of the node and registers it in ctx.
+func InitCFM(ctx *posctx.Context, bc *core.BlockChain) *CFM {
+	c := &CFM{}
+	c.ctx = ctx
 	c.bc = bc
 	c.whiteList = make(map[common.Address]int, 0)
 	for _, value := range posconfig.WhiteList {
@@ -50,20 +53,28 @@ func InitCFM(bc *core.BlockChain) {
 		address := crypto.PubkeyToAddress(*(crypto.ToECDSAPub(b)))
 		c.whiteList[address] = 1
 	}
+	ctx.Register(serviceName, c)
+	// Don't let the chain freeze blocks that aren't stable yet
+	if bc != nil {
+		bc.SetStableBlockFn(c.GetMaxStableBlkNumber)
+	}
 	log.Info("InitCFM success")
+	return c
 }
 
-func GetCFM() *CFM {
+// GetCFM returns the block confirmation service of the node, nil before InitCFM.
+func GetCFM(ctx *posctx.Context) *CFM {
+	c, _ := ctx.Service(serviceName).(*CFM)
 	return c
 }
 
 func (c *CFM) GetMaxStableBlkNumber() uint64 {
 	// In pow phase
-	if posconfig.FirstEpochId == 0 {
+	if c.ctx.FirstEpochId() == 0 {
 		return c.getPowMaxStableBlkNumber(c.getCurrentBlkNumber())
 	}
 	// In pos phase
-	timeNow := uint64(time.Now().Unix())
+	timeNow := c.ctx.Now()
 	// stopNumber is the min block number, startNumber is max bock number
 	blkStatusArr, stopNumber, startNumber, err := c.scanAllBlockStatus(timeNow)
 
@@ -72,7 +83,7 @@ func (c *CFM) GetMaxStableBlkNumber() uint64 {
 	log.Debug("GetMaxStableBlkNumber",
 		"maxStableBlkNumber", maxStableBlkNumber,
 		"Pow2PosUpgradeBlockNumber", posconfig.Pow2PosUpgradeBlockNumber,
-		"FirstEpochId", posconfig.FirstEpochId)
+		"FirstEpochId", c.ctx.FirstEpochId())
 
 	// get max stable on block of pos phase
 	if maxStableBlkNumber >= posconfig.Pow2PosUpgradeBlockNumber {
@@ -8,6 +8,7 @@ import (
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 func TestIsInWhiteList(t *testing.T) {
@@ -18,8 +19,7 @@ func TestIsInWhiteList(t *testing.T) {
 	var networkId uint64
 	networkId = 6
 	posconfig.Init(nil,networkId)
-	InitCFM(nil)
-	c := GetCFM()
+	c := InitCFM(posctx.New(""), nil)
 	c.whiteList = make(map[common.Address]int, 0)
 	for _, value := range WhiteList {
 		b := hexutil.MustDecode(value)
@@ -42,10 +42,10 @@ func TestIsInWhiteList(t *testing.T) {
 
 func TestGetCFM(t *testing.T) {
 
-	InitCFM(nil)
-	c := GetCFM()
+	ctx := posctx.New("")
+	c := InitCFM(ctx, nil)
 
-	if c == nil {
+	if GetCFM(ctx) != c {
 		t.Fail()
 	}
 }
@@ -54,8 +54,7 @@ func TestInitCFM(t *testing.T) {
 	var networkId uint64
 	networkId = 6
 	posconfig.Init(nil,networkId)
-	InitCFM(nil)
-	c := GetCFM()
+	c := InitCFM(posctx.New(""), nil)
 
 	if len(c.whiteList) == 0 {
 		t.Logf("No white list coinbase exisit")
@@ -68,8 +67,7 @@ func TestGetSlotsCount(t *testing.T) {
 	var networkId uint64
 	networkId = 6
 	posconfig.Init(nil,networkId)
-	InitCFM(nil)
-	c := GetCFM()
+	c := InitCFM(posctx.New(""), nil)
 
 	start := uint64(time.Now().Unix())
 	stop := uint64(start + posconfig.SlotTime - 1)
@@ -100,8 +98,7 @@ func TestGetMaxStableBlkNumber(t *testing.T) {
 	posconfig.Init(nil,networkId)
 
 	blkStatusArr := make([]*BlkStatus, 0)
-	InitCFM(nil)
-	c := GetCFM()
+	c := InitCFM(posctx.New(""), nil)
 
 	if c.getMaxStableBlkNumber(blkStatusArr, 0, 0, nil) != 0 {
 		t.Fail()
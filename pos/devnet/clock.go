@@ -0,0 +1,52 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package devnet
+
+import (
+	"sync/atomic"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Clock is a virtual PoS clock, it only moves when it is told to.
+type Clock struct {
+	now uint64 // unix seconds, accessed atomically
+}
+
+// NewClock creates a clock at the start of a slot.
+func NewClock(epochID, slotID uint64) *Clock {
+	c := new(Clock)
+	c.SetSlot(epochID, slotID)
+	return c
+}
+
+// Now returns the current time in unix seconds.
+func (c *Clock) Now() uint64 {
+	return atomic.LoadUint64(&c.now)
+}
+
+// Set moves the clock to a time in unix seconds.
+func (c *Clock) Set(now uint64) {
+	atomic.StoreUint64(&c.now, now)
+}
+
+// SetSlot moves the clock to the start of a slot.
+func (c *Clock) SetSlot(epochID, slotID uint64) {
+	c.Set(SlotTime(epochID, slotID))
+}
+
+// AddSlots moves the clock forward by count slots.
+func (c *Clock) AddSlots(count uint64) {
+	atomic.AddUint64(&c.now, count*posconfig.SlotTime)
+}
+
+// EpochSlot returns the current epoch and slot.
+func (c *Clock) EpochSlot() (epochID, slotID uint64) {
+	return util.CalEpochSlotID(c.Now())
+}
+
+// SlotTime returns the start time of a slot in unix seconds.
+func SlotTime(epochID, slotID uint64) uint64 {
+	return (epochID*posconfig.SlotCount + slotID) * posconfig.SlotTime
+}
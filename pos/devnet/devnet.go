@@ -0,0 +1,486 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package devnet runs a network of in-memory PoS validators in one process,
+// driven by a virtual clock, so that whole epochs can be tested in seconds.
+//
+// The PoS modules still keep some configuration in package variables: the
+// white list, the pos upgrade block and the incentive hooks. A network sets
+// them on creation and restores them on Close, so only one network runs at a
+// time: New fails while another one is open, and every network must be closed,
+// even by a failing test.
+package devnet
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+var (
+	errNoValidators  = errors.New("devnet needs at least one validator")
+	errNotSlotLeader = errors.New("not the slot leader")
+	errRunning       = errors.New("another devnet is running")
+)
+
+// running is set while a network owns the package variables.
+var running int32
+
+// txGas is the gas limit of the txs sent by Transact.
+var txGas = big.NewInt(1000000)
+
+// Config is the configuration of a devnet.
+type Config struct {
+	Validators int    // number of validators, all of them stake in the genesis
+	WhiteList  int    // number of validators in the white list, zero for all
+	StartEpoch uint64 // epoch of the genesis and of the first pos block
+	SlotStride uint64 // slots the clock moves forward on each step
+
+	Balance  *big.Int // genesis balance of each validator
+	Stake    *big.Int // genesis stake of each validator
+	GasPrice *big.Int // gas price of the pos txs
+}
+
+// DefaultConfig runs four validators, all of them in the white list. The
+// start epoch is later than the pos upgrades enabled by epoch id and earlier
+// than the wall clock.
+var DefaultConfig = Config{
+	Validators: 4,
+	StartEpoch: 18500,
+	SlotStride: 360,
+	Balance:    new(big.Int).Mul(big.NewInt(1000000), big.NewInt(params.Wan)),
+	Stake:      new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Wan)),
+	GasPrice:   big.NewInt(180 * params.Shannon),
+}
+
+// posTx is a tx sent by a node or a test, it is signed when it is put in a
+// block.
+type posTx struct {
+	key    *keystore.Key
+	txType uint64
+	to     common.Address
+	value  *big.Int
+	gas    *big.Int
+	data   []byte
+}
+
+// globals holds the package variables a network overrides.
+type globals struct {
+	whiteList        [210]string
+	epochLeadersHold [][]byte
+	upgradeBlock     uint64
+	posActive        bool
+	restoreIncentive func()
+}
+
+// Network is a devnet of validators sharing a virtual clock. Every validator
+// runs its own chain and PoS services, the blocks are gossiped by inserting
+// them into all the chains.
+type Network struct {
+	Clock *Clock
+	Nodes []*Node
+
+	config  Config
+	chain   *params.ChainConfig
+	genesis *core.Genesis
+	saved   globals
+
+	lock    sync.Mutex
+	pending []*posTx // pos txs waiting for a block, in sending order
+}
+
+// New creates a devnet, its genesis is at the start of config.StartEpoch.
+func New(config Config) (*Network, error) {
+	if config.Validators <= 0 {
+		return nil, errNoValidators
+	}
+	if config.WhiteList <= 0 || config.WhiteList > config.Validators {
+		config.WhiteList = config.Validators
+	}
+	if config.SlotStride == 0 {
+		config.SlotStride = DefaultConfig.SlotStride
+	}
+	if config.Balance == nil {
+		config.Balance = DefaultConfig.Balance
+	}
+	if config.Stake == nil {
+		config.Stake = DefaultConfig.Stake
+	}
+	if config.GasPrice == nil {
+		config.GasPrice = DefaultConfig.GasPrice
+	}
+
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return nil, errRunning
+	}
+	chainConfig := *params.PlutoChainConfig
+	n := &Network{
+		Clock:  NewClock(config.StartEpoch, 0),
+		config: config,
+		chain:  &chainConfig,
+	}
+
+	keys := make([]*keystore.Key, config.Validators)
+	for i := range keys {
+		keys[i] = ValidatorKey(i)
+	}
+	n.genesis = n.makeGenesis(keys)
+	if err := n.setGlobals(keys[:config.WhiteList]); err != nil {
+		n.Close()
+		return nil, err
+	}
+
+	for _, key := range keys {
+		node, err := newNode(n, key)
+		if err != nil {
+			n.Close()
+			return nil, err
+		}
+		n.Nodes = append(n.Nodes, node)
+	}
+	// The incentive hooks are shared, all the nodes select the same leaders.
+	epocher := n.Nodes[0].Epocher
+	incentive.Init(epocher.GetEpochProbability, epocher.SetEpochIncentive, epocher.GetRBProposerGroup)
+	return n, nil
+}
+
+// ValidatorKey returns the key of the validator of an index, the same index
+// always gives the same key.
+func ValidatorKey(index int) *keystore.Key {
+	seed := crypto.Keccak256([]byte(fmt.Sprintf("devnet validator %d", index)))
+	key := &keystore.Key{
+		PrivateKey:  mustToECDSA(seed),
+		PrivateKey2: mustToECDSA(crypto.Keccak256(seed)),
+	}
+	key.Address = crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+	return key
+}
+
+// Bn256PK returns the serialized bn256 public key of a validator key, as
+// staked with it.
+func Bn256PK(key *keystore.Key) []byte {
+	return new(bn256.G1).ScalarBaseMult(posconfig.GenerateD3byKey2(key.PrivateKey2)).Marshal()
+}
+
+func mustToECDSA(seed []byte) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(seed)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func (n *Network) makeGenesis(keys []*keystore.Key) *core.Genesis {
+	alloc := make(core.GenesisAlloc)
+	for _, key := range keys {
+		alloc[key.Address] = core.GenesisAccount{
+			Balance: n.config.Balance,
+			Staking: core.GenesisAccountStaking{
+				Amount:  n.config.Stake,
+				S256pk:  crypto.FromECDSAPub(&key.PrivateKey.PublicKey),
+				Bn256pk: Bn256PK(key),
+			},
+		}
+	}
+	return &core.Genesis{
+		Config:     n.chain,
+		Timestamp:  SlotTime(n.config.StartEpoch, 0),
+		GasLimit:   0x47b760,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+}
+
+// setGlobals fills the white list with the keys and switches to pos from
+// the first block. It fails if a node of another chain runs in the process.
+func (n *Network) setGlobals(whiteKeys []*keystore.Key) error {
+	n.saved = globals{
+		whiteList:        posconfig.WhiteList,
+		epochLeadersHold: posconfig.EpochLeadersHold,
+		upgradeBlock:     posconfig.Pow2PosUpgradeBlockNumber,
+		posActive:        params.IsPosActive(),
+		restoreIncentive: incentive.Save(),
+	}
+	if err := posconfig.SetPow2PosUpgradeBlockNumber(n.chain.PosFirstBlock.Uint64()); err != nil {
+		return err
+	}
+
+	posconfig.EpochLeadersHold = make([][]byte, len(posconfig.WhiteList))
+	for i := range posconfig.WhiteList {
+		pk := crypto.FromECDSAPub(&whiteKeys[i%len(whiteKeys)].PrivateKey.PublicKey)
+		posconfig.WhiteList[i] = hexutil.Encode(pk)
+		posconfig.EpochLeadersHold[i] = pk
+	}
+	n.chain.SetPosActive()
+	return nil
+}
+
+// Close stops the nodes and restores the package variables.
+func (n *Network) Close() {
+	for _, node := range n.Nodes {
+		node.close()
+	}
+	posconfig.WhiteList = n.saved.whiteList
+	posconfig.EpochLeadersHold = n.saved.epochLeadersHold
+	posconfig.Pow2PosUpgradeBlockNumber = n.saved.upgradeBlock
+	params.SetPosActive(n.saved.posActive)
+	n.saved.restoreIncentive()
+	atomic.StoreInt32(&running, 0)
+}
+
+// sendTx returns the tx sender of a node, it queues the tx for the next block.
+func (n *Network) sendTx(key *keystore.Key) func(*rpc.Client, map[string]interface{}) {
+	return func(_ *rpc.Client, arg map[string]interface{}) {
+		n.queue(&posTx{
+			key:    key,
+			txType: types.POS_TX,
+			to:     arg["to"].(common.Address),
+			value:  (*big.Int)(arg["value"].(*hexutil.Big)),
+			gas:    (*big.Int)(arg["gas"].(*hexutil.Big)),
+			data:   arg["data"].(hexutil.Bytes),
+		})
+	}
+}
+
+// Transact queues a normal tx from the account of the key for the next block,
+// such as a call to the staking contract.
+func (n *Network) Transact(key *keystore.Key, to common.Address, value *big.Int, data []byte) {
+	n.queue(&posTx{
+		key:    key,
+		txType: types.NORMAL_TX,
+		to:     to,
+		value:  value,
+		gas:    txGas,
+		data:   data,
+	})
+}
+
+func (n *Network) queue(tx *posTx) {
+	n.lock.Lock()
+	n.pending = append(n.pending, tx)
+	n.lock.Unlock()
+}
+
+// takePending removes and returns the queued txs.
+func (n *Network) takePending() []*posTx {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	txs := n.pending
+	n.pending = nil
+	return txs
+}
+
+// Step moves the clock forward by the slot stride and runs the new slot.
+func (n *Network) Step() error {
+	n.Clock.AddSlots(n.config.SlotStride)
+	return n.RunSlot()
+}
+
+// RunUntil steps until the clock reaches the slot.
+func (n *Network) RunUntil(epochID, slotID uint64) error {
+	for n.Clock.Now() < SlotTime(epochID, slotID) {
+		if err := n.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunEpochs steps until the start of the epoch count epochs later.
+func (n *Network) RunEpochs(count uint64) error {
+	epochID, _ := n.Clock.EpochSlot()
+	return n.RunUntil(epochID+count, 0)
+}
+
+// RunSlot runs the PoS workflow of the current slot on every node, then the
+// slot leader puts the queued pos txs in a block which every node inserts.
+// A slot without a leader among the nodes has no block.
+func (n *Network) RunSlot() error {
+	epochID, slotID := n.Clock.EpochSlot()
+	for _, node := range n.Nodes {
+		if err := node.runSlot(epochID, slotID); err != nil {
+			return err
+		}
+	}
+	for _, node := range n.Nodes {
+		node.Ctx.Wait()
+	}
+
+	leader := n.slotLeader(epochID, slotID)
+	if leader == nil {
+		log.Debug("devnet slot without leader", "epochID", epochID, "slotID", slotID)
+		return nil
+	}
+	block, err := leader.produce(n.takePending())
+	if err != nil {
+		return fmt.Errorf("node %x failed to produce block in epoch %d slot %d: %v", leader.Key.Address, epochID, slotID, err)
+	}
+	for _, node := range n.Nodes {
+		if _, err := node.Chain.InsertChain(types.Blocks{block}); err != nil {
+			return fmt.Errorf("node %x rejected block %d: %v", node.Key.Address, block.NumberU64(), err)
+		}
+	}
+	for _, node := range n.Nodes {
+		node.Ctx.Wait()
+	}
+	return nil
+}
+
+// slotLeader returns the node leading the slot, or nil.
+func (n *Network) slotLeader(epochID, slotID uint64) *Node {
+	for _, node := range n.Nodes {
+		if node.isSlotLeader(epochID, slotID) {
+			return node
+		}
+	}
+	return nil
+}
+
+// signTx signs a queued tx for the block of a number with the next nonce of
+// its sender.
+func (n *Network) signTx(tx *posTx, number *big.Int, nonce uint64) (*types.Transaction, error) {
+	unsigned := types.NewTransaction(nonce, tx.to, tx.value, tx.gas, n.config.GasPrice, tx.data)
+	unsigned.SetTxtype(tx.txType)
+	return types.SignTx(unsigned, types.MakeSigner(n.chain, number), tx.key.PrivateKey)
+}
+
+// signHash signs a hash with the key, as the pluto engine expects it.
+func signHash(key *keystore.Key) func(accounts.Account, []byte) ([]byte, error) {
+	return func(_ accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key.PrivateKey)
+	}
+}
+
+// pkAddress returns the address of a serialized secp256k1 public key.
+func pkAddress(pk []byte) common.Address {
+	pub := crypto.ToECDSAPub(pk)
+	if pub == nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+// Head returns the current block of the first node.
+func (n *Network) Head() *types.Block {
+	return n.Nodes[0].Chain.CurrentBlock()
+}
+
+// CheckConsensus returns an error if the nodes don't share the same head.
+func (n *Network) CheckConsensus() error {
+	head := n.Head()
+	for _, node := range n.Nodes[1:] {
+		if other := node.Chain.CurrentBlock(); other.Hash() != head.Hash() {
+			return fmt.Errorf("node %x head %d %x, want %d %x", node.Key.Address,
+				other.NumberU64(), other.Hash(), head.NumberU64(), head.Hash())
+		}
+	}
+	return nil
+}
+
+// EpochLeaders returns the addresses of the epoch leaders of an epoch.
+func (n *Network) EpochLeaders(epochID uint64) []common.Address {
+	pks := n.Nodes[0].Epocher.GetEpochLeaders(epochID)
+	addrs := make([]common.Address, 0, len(pks))
+	for _, pk := range pks {
+		addrs = append(addrs, pkAddress(pk))
+	}
+	return addrs
+}
+
+// RBProposers returns the addresses of the random beacon proposers of an epoch.
+func (n *Network) RBProposers(epochID uint64) []common.Address {
+	leaders := n.Nodes[0].Epocher.GetRBProposerGroup(epochID)
+	addrs := make([]common.Address, 0, len(leaders))
+	for _, leader := range leaders {
+		addrs = append(addrs, leader.SecAddr)
+	}
+	return addrs
+}
+
+// RandomBeacon returns the random number of an epoch in the head state, nil
+// if the random beacon hasn't produced it.
+func (n *Network) RandomBeacon(epochID uint64) (*big.Int, error) {
+	return n.Nodes[0].RandomBeacon(epochID)
+}
+
+// Payouts returns the incentive paid to each validator for an epoch.
+func (n *Network) Payouts(epochID uint64) (map[common.Address]*big.Int, error) {
+	details, err := incentive.GetEpochPayDetail(n.Nodes[0].Ctx, epochID)
+	if err != nil {
+		return nil, err
+	}
+	payouts := make(map[common.Address]*big.Int)
+	for _, clients := range details {
+		for _, client := range clients {
+			if payouts[client.ValidatorAddr] == nil {
+				payouts[client.ValidatorAddr] = new(big.Int)
+			}
+			payouts[client.ValidatorAddr].Add(payouts[client.ValidatorAddr], client.Incentive)
+		}
+	}
+	return payouts, nil
+}
+
+// Staker returns the staker info of a validator in the head state, nil if it
+// isn't staking.
+func (n *Network) Staker(addr common.Address) (*vm.StakerInfo, error) {
+	state, err := n.Nodes[0].Chain.State()
+	if err != nil {
+		return nil, err
+	}
+	if data, _ := vm.GetInfo(state, vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr)); data == nil {
+		return nil, nil
+	}
+	return vm.GetStakerInfo(state, addr)
+}
+
+// StakeOuts returns the stakes refunded at the beginning of an epoch.
+func (n *Network) StakeOuts(epochID uint64) ([]epochLeader.RefundInfo, error) {
+	data, err := n.Nodes[0].Ctx.LocalDb().Get(epochID, posconfig.StakeOutEpochKey)
+	if err != nil {
+		return nil, nil
+	}
+	var refunds []epochLeader.RefundInfo
+	if err := rlp.DecodeBytes(data, &refunds); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// Producers returns the number of blocks each validator produced in an epoch.
+func (n *Network) Producers(epochID uint64) map[common.Address]int {
+	chain := n.Nodes[0].Chain
+	producers := make(map[common.Address]int)
+	for number := chain.CurrentBlock().NumberU64(); number > 0; number-- {
+		header := chain.GetHeaderByNumber(number)
+		headerEpochID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		if headerEpochID < epochID {
+			break
+		}
+		if headerEpochID == epochID {
+			producers[header.Coinbase]++
+		}
+	}
+	return producers
+}
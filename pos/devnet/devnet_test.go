@@ -0,0 +1,212 @@
+package devnet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// newTestNetwork creates a devnet closed when the test ends, restoring the
+// package variables it overrides.
+func newTestNetwork(t *testing.T, config Config) *Network {
+	n, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(n.Close)
+	return n
+}
+
+func TestClock(t *testing.T) {
+	c := NewClock(10, posconfig.SlotCount-1)
+	if epochID, slotID := c.EpochSlot(); epochID != 10 || slotID != posconfig.SlotCount-1 {
+		t.Fatalf("epoch slot mismatch: have %d %d, want 10 %d", epochID, slotID, posconfig.SlotCount-1)
+	}
+	c.AddSlots(2)
+	if epochID, slotID := c.EpochSlot(); epochID != 11 || slotID != 1 {
+		t.Fatalf("epoch slot mismatch: have %d %d, want 11 1", epochID, slotID)
+	}
+}
+
+func TestValidatorKey(t *testing.T) {
+	if ValidatorKey(0).Address != ValidatorKey(0).Address {
+		t.Fatal("validator key isn't deterministic")
+	}
+	if ValidatorKey(0).Address == ValidatorKey(1).Address {
+		t.Fatal("validators share a key")
+	}
+}
+
+func TestSingleNetwork(t *testing.T) {
+	config := DefaultConfig
+	config.Validators = 1
+	n, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(config); err != errRunning {
+		n.Close()
+		t.Fatalf("second network error mismatch: have %v, want %v", err, errRunning)
+	}
+	n.Close()
+	newTestNetwork(t, config)
+}
+
+// TestEpochs runs the network from the default leaders of the first epochs
+// to leaders selected from the stakers, with random beacon and incentive.
+func TestEpochs(t *testing.T) {
+	config := DefaultConfig
+	config.Validators = 3
+	config.WhiteList = 1
+	n := newTestNetwork(t, config)
+
+	first := config.StartEpoch
+	white := n.Nodes[0].Key.Address
+	if err := n.RunUntil(first+3, 0); err != nil {
+		t.Fatal(err)
+	}
+	for epochID := first; epochID < first+3; epochID++ {
+		producers := n.Producers(epochID)
+		if len(producers) != 1 || producers[white] == 0 {
+			t.Fatalf("epoch %d isn't produced by the white list: %v", epochID, producers)
+		}
+	}
+
+	if err := n.RunUntil(first+5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.CheckConsensus(); err != nil {
+		t.Fatal(err)
+	}
+
+	epochID := first + 4
+	leaders := make(map[common.Address]bool)
+	for _, addr := range n.EpochLeaders(epochID) {
+		leaders[addr] = true
+	}
+	for _, node := range n.Nodes {
+		if !leaders[node.Key.Address] {
+			t.Errorf("validator %x isn't an epoch leader of epoch %d", node.Key.Address, epochID)
+		}
+	}
+	if len(n.RBProposers(epochID)) != posconfig.RandomProperCount {
+		t.Errorf("random beacon proposer count mismatch: have %d, want %d", len(n.RBProposers(epochID)), posconfig.RandomProperCount)
+	}
+	if producers := n.Producers(epochID); len(producers) < 2 {
+		t.Errorf("epoch %d is produced by the white list only: %v", epochID, producers)
+	}
+
+	r3, err := n.RandomBeacon(first + 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r4, err := n.RandomBeacon(first + 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r3 == nil || r4 == nil || r3.Cmp(r4) == 0 {
+		t.Errorf("random beacon didn't run: %v %v", r3, r4)
+	}
+
+	payouts, err := n.Payouts(first + 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range n.Nodes {
+		if payout := payouts[node.Key.Address]; payout == nil || payout.Sign() <= 0 {
+			t.Errorf("validator %x wasn't paid for epoch %d", node.Key.Address, first+3)
+		}
+	}
+}
+
+// TestStakeOut stakes a new validator in, asks it not to renew and checks its
+// stake is refunded once its lock epochs end.
+func TestStakeOut(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the stake out epochs in short mode")
+	}
+	config := DefaultConfig
+	config.Validators = 2
+	config.WhiteList = 1
+	n := newTestNetwork(t, config)
+
+	var (
+		first  = config.StartEpoch
+		funder = n.Nodes[0].Key
+		key    = ValidatorKey(config.Validators)
+		lock   = uint64(vm.PSMinEpochNum)
+		amount = new(big.Int).Mul(big.NewInt(vm.PSMinStakeholderStake), big.NewInt(params.Wan))
+	)
+	stakeIn, err := vm.PackStakeIn(&vm.StakeInParam{
+		SecPk:      crypto.FromECDSAPub(&key.PrivateKey.PublicKey),
+		Bn256Pk:    Bn256PK(key),
+		LockEpochs: new(big.Int).SetUint64(lock),
+		FeeRate:    big.NewInt(100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRenew, err := vm.PackStakeUpdate(&vm.StakeUpdateParam{Addr: key.Address, LockEpochs: new(big.Int)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Transact(funder, key.Address, new(big.Int).Mul(amount, big.NewInt(2)), nil)
+	n.Transact(key, vm.WanCscPrecompileAddr, amount, stakeIn)
+	n.Transact(key, vm.WanCscPrecompileAddr, new(big.Int), noRenew)
+	if err := n.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	staker, err := n.Staker(key.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staker == nil {
+		t.Fatalf("validator %x didn't stake in", key.Address)
+	}
+	if staker.StakingEpoch != first+vm.JoinDelay || staker.LockEpochs != lock || staker.NextLockEpochs != 0 {
+		t.Fatalf("staker mismatch: have epoch %d lock %d next %d, want %d %d 0",
+			staker.StakingEpoch, staker.LockEpochs, staker.NextLockEpochs, first+vm.JoinDelay, lock)
+	}
+
+	outEpoch := staker.StakingEpoch + staker.LockEpochs
+	if err := n.RunUntil(outEpoch-1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if staker, err := n.Staker(key.Address); err != nil || staker == nil {
+		t.Fatalf("validator %x staked out before epoch %d: %v", key.Address, outEpoch, err)
+	}
+	before, err := n.Nodes[0].Balance(key.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.RunUntil(outEpoch+1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.CheckConsensus(); err != nil {
+		t.Fatal(err)
+	}
+	if staker, err := n.Staker(key.Address); err != nil || staker != nil {
+		t.Fatalf("validator %x still staking after epoch %d: %v", key.Address, outEpoch, err)
+	}
+	refunds, err := n.StakeOuts(outEpoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].Addr != key.Address || refunds[0].Amount.Cmp(amount) != 0 {
+		t.Fatalf("stake out mismatch: have %v, want %x %v", refunds, key.Address, amount)
+	}
+	after, err := n.Nodes[0].Balance(key.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund := new(big.Int).Sub(after, before); refund.Cmp(amount) < 0 {
+		t.Fatalf("balance refund mismatch: have %v, want at least %v", refund, amount)
+	}
+}
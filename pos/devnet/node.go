@@ -0,0 +1,196 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package devnet
+
+import (
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/cfm"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+	"github.com/wanchain/go-wanchain/pos/randombeacon"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Node is a validator of a devnet.
+type Node struct {
+	Key     *keystore.Key
+	Signer  possigner.Signer
+	Ctx     *posctx.Context
+	Chain   *core.BlockChain
+	Engine  *pluto.Pluto
+	Epocher *epochLeader.Epocher
+
+	network *Network
+	db      ethdb.Database
+	sls     *slotleader.SLS
+	rb      *randombeacon.RandomBeacon
+}
+
+// newNode creates a validator with an in-memory chain, and initializes its
+// PoS services as a mining gwan does.
+func newNode(n *Network, key *keystore.Key) (*Node, error) {
+	db, err := ethdb.NewMemDatabase()
+	if err != nil {
+		return nil, err
+	}
+	n.genesis.MustCommit(db)
+
+	ctx := posctx.New("")
+	ctx.SetClock(n.Clock)
+	ctx.SetFirstEpochId(n.config.StartEpoch)
+
+	engine := pluto.New(n.chain.Pluto, db, ctx)
+	chain, err := core.NewBlockChain(db, n.chain, engine, vm.Config{}, engine)
+	if err != nil {
+		ctx.Close()
+		return nil, err
+	}
+	node := &Node{
+		Key:     key,
+		Signer:  possigner.NewLocal(key),
+		Ctx:     ctx,
+		Chain:   chain,
+		Engine:  engine,
+		network: n,
+		db:      db,
+	}
+
+	node.Epocher = epochLeader.NewEpocher(chain)
+	if err := node.Epocher.SelectLeadersLoop(0); err != nil {
+		node.close()
+		return nil, err
+	}
+	cfm.InitCFM(ctx, chain)
+
+	node.sls = slotleader.SlsInit(ctx)
+	node.sls.Init(chain, nil, node.Signer)
+	node.sls.SetSendTxFn(n.sendTx(key))
+	chain.SetSlotValidator(node.sls)
+
+	ctx.SetSigner(node.Signer)
+	node.rb = randombeacon.GetRandonBeaconInst(ctx)
+	node.rb.Init(node.Epocher)
+	node.rb.SetSendTxFn(n.sendTx(key))
+	engine.Authorize(key.Address, signHash(key), node.Signer)
+	return node, nil
+}
+
+func (node *Node) close() {
+	node.rb.Stop()
+	node.Chain.Stop()
+	node.Ctx.Close()
+	node.db.Close()
+}
+
+// runSlot runs the slot leader selection and the random beacon of the slot.
+func (node *Node) runSlot(epochID, slotID uint64) error {
+	if err := node.sls.Loop(nil, node.Signer, epochID, slotID); err != nil {
+		return err
+	}
+	state, err := node.Chain.State()
+	if err != nil {
+		return err
+	}
+	if err := node.rb.LoopSync(state, nil, epochID, slotID); err != nil {
+		log.Warn("devnet random beacon failed", "epochID", epochID, "slotID", slotID, "err", err)
+	}
+	return nil
+}
+
+// isSlotLeader reports whether the node leads the slot, it follows the
+// checks of the miner.
+func (node *Node) isSlotLeader(epochID, slotID uint64) bool {
+	prePks, isDefault := node.sls.GetPreEpochLeadersPK(epochID)
+	if !node.sls.IsLocalPkInEpochLeaders(prePks) {
+		return false
+	}
+	targetEpochID := epochID
+	if isDefault {
+		targetEpochID = 0
+	}
+	leader, err := node.sls.GetSlotLeader(targetEpochID, slotID)
+	return err == nil && util.PkEqual(leader, &node.Key.PrivateKey.PublicKey)
+}
+
+// produce builds and seals a block at the current slot with the pos txs.
+// The txs failing to apply are dropped, as the miner does.
+func (node *Node) produce(txs []*posTx) (*types.Block, error) {
+	n := node.network
+	parent := node.Chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent),
+		GasUsed:    new(big.Int),
+		Time:       new(big.Int).SetUint64(n.Clock.Now()),
+	}
+	if err := node.Engine.Prepare(node.Chain, header, true); err != nil {
+		return nil, err
+	}
+	state, err := node.Chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		included []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for _, ptx := range txs {
+		tx, err := n.signTx(ptx, header.Number, state.GetNonce(ptx.key.Address))
+		if err != nil {
+			return nil, err
+		}
+		state.Prepare(tx.Hash(), common.Hash{}, len(included))
+		snap := state.Snapshot()
+		receipt, _, err := core.ApplyTransaction(n.chain, node.Chain, &node.Key.Address, gp, state, header, tx, header.GasUsed, vm.Config{})
+		if err != nil {
+			state.RevertToSnapshot(snap)
+			log.Warn("devnet dropped pos tx", "from", ptx.key.Address, "to", ptx.to, "err", err)
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+
+	block, err := node.Engine.Finalize(node.Chain, header, state, included, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := node.Engine.Seal(node.Chain, block, nil)
+	if err == nil && sealed == nil {
+		err = errNotSlotLeader
+	}
+	return sealed, err
+}
+
+// RandomBeacon returns the random number of an epoch in the head state of
+// the node, nil if the random beacon hasn't produced it.
+func (node *Node) RandomBeacon(epochID uint64) (*big.Int, error) {
+	state, err := node.Chain.State()
+	if err != nil {
+		return nil, err
+	}
+	return vm.GetStateR(state, epochID, node.Ctx.FirstEpochId()), nil
+}
+
+// Balance returns the balance of an address in the head state of the node.
+func (node *Node) Balance(addr common.Address) (*big.Int, error) {
+	state, err := node.Chain.State()
+	if err != nil {
+		return nil, err
+	}
+	return state.GetBalance(addr), nil
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"math/big"
 	"sort"
-	"time"
 
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/pos/util"
@@ -19,9 +18,10 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/posdb"
-	"github.com/wanchain/go-wanchain/pos/incentive"
 
 )
 
@@ -44,6 +44,7 @@ type Epocher struct {
 	rbLeadersDb    *posdb.Db
 	epochLeadersDb *posdb.Db
 	blkChain       *core.BlockChain
+	ctx            *posctx.Context
 }
 
 type RefundInfo struct {
@@ -56,33 +57,43 @@ type EpochInfo struct {
 	BlockNumber uint64
 }
 
+// serviceName is the name the epocher is registered with in the pos context.
+const serviceName = "epochLeader"
 
-var epocherInst *Epocher = nil
-
+// NewEpocher returns the epocher of the node running blc, creating it on
+// first use.
 func NewEpocher(blc *core.BlockChain) *Epocher {
 
 	if blc == nil {
 		return nil
 	}
 
-	if epocherInst == nil {
-		epocherInst = NewEpocherWithLBN(blc, posconfig.RbLocalDB, posconfig.EpLocalDB)
+	ctx := blc.PosContext()
+	if inst := GetEpocher(ctx); inst != nil {
+		return inst
 	}
 
-	return epocherInst
+	inst := NewEpocherWithLBN(blc, posconfig.RbLocalDB, posconfig.EpLocalDB)
+	ctx.Register(serviceName, inst)
+	return inst
 }
 
-func GetEpocher() *Epocher {
-	return epocherInst
+// GetEpocher returns the epocher of the node owning ctx, nil if it hasn't
+// been created.
+func GetEpocher(ctx *posctx.Context) *Epocher {
+	if ctx == nil {
+		return nil
+	}
+	inst, _ := ctx.Service(serviceName).(*Epocher)
+	return inst
 }
 
 func NewEpocherWithLBN(blc *core.BlockChain, rbn string, epdbn string) *Epocher {
 
-	rbdb := posdb.NewDb(rbn)
-	epdb := posdb.NewDb(epdbn)
-	inst := &Epocher{rbdb, epdb, blc}
+	ctx := blc.PosContext()
+	inst := &Epocher{ctx.Db(rbn), ctx.Db(epdbn), blc, ctx}
 
-	util.SetEpocherInst(inst)
+	ctx.SetSelector(inst)
 	return inst
 }
 
@@ -109,7 +120,7 @@ func (e *Epocher) GetTargetBlkNumber(epochId uint64) uint64 {
 	targetEpochId := epochId - 2
 
 	//return e.GetEpochLastBlkNumber(targetEpochId)
-	return util.GetEpochBlock(targetEpochId)
+	return e.ctx.EpochBlock(targetEpochId)
 }
 
 /*
@@ -130,9 +141,9 @@ func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
 	}
 
 	targetBlkNum := curNum
-	epochid, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
-	if targetEpochId < epochid && targetEpochId >= posconfig.FirstEpochId {
-		util.SetEpochBlock(targetEpochId, targetBlkNum, curBlockHeader.Hash())
+	epochid, _ := util.CalEpochSlotID(e.ctx.Now())
+	if targetEpochId < epochid && targetEpochId >= e.ctx.FirstEpochId() {
+		e.ctx.SetEpochBlock(targetEpochId, targetBlkNum, curBlockHeader.Hash())
 	}
 
 	return targetBlkNum
@@ -148,23 +159,66 @@ func (e *Epocher) SelectLeadersLoop(epochId uint64) error {
 		return err
 	}
 
+	r := selectionRandom(stateDb, epochId, e.ctx.FirstEpochId())
+	err = e.selectLeaders(r, stateDb, epochId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// selectionRandom returns the random beacon value the leaders of epochId are
+// selected with, statedb is the state of the epoch's target block.
+func selectionRandom(statedb *state.StateDB, epochId uint64, firstEpochId uint64) []byte {
 	epochIdIn := epochId
 	if epochIdIn > 0 {
 		epochIdIn--
 	}
-	rb := vm.GetR(stateDb, epochIdIn)
+	rb := vm.GetR(statedb, epochIdIn, firstEpochId)
 	if rb == nil {
 		log.Error(fmt.Sprintln("vm.GetR return nil at epochId:", epochId))
 		rb = new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
 	}
 
-	r := rb.Bytes()
-	err = e.selectLeaders(r, stateDb, epochId)
+	return rb.Bytes()
+}
+
+// EpochLeadersFromState runs the epoch leader selection of epochId against
+// statedb, the state of the epoch's target block, without touching the local
+// database. The result matches GetEpochLeaders, white list leaders included,
+// so it can be used by nodes that don't run the selection themselves.
+// firstEpochId is the epoch of the first pos block.
+func EpochLeadersFromState(statedb *state.StateDB, epochId uint64, firstEpochId uint64) ([][]byte, error) {
+	ps, err := createStakerProbabilityArray(statedb, epochId, firstEpochId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	info := vm.GetEpochWLInfo(statedb, epochId)
+	leaders, err := selectProposers(selectionRandom(statedb, epochId, firstEpochId), 0, ps,
+		posconfig.EpochLeaderCount-int(info.WlCount.Uint64()))
+	if err != nil {
+		return nil, ErrInvalidRandomProposerSelection
+	}
+
+	pks := make([][]byte, 0, posconfig.EpochLeaderCount)
+	for i := range leaders {
+		pks = append(pks, leaders[i].PubSec256)
+	}
+	wa := posconfig.EpochLeadersHold[info.WlIndex.Uint64() : info.WlIndex.Uint64()+info.WlCount.Uint64()]
+	return append(pks, wa...), nil
+}
+
+// RBProposersFromState runs the random beacon proposer selection of epochId
+// against statedb, the state of the epoch's target block, without touching
+// the local database.
+func RBProposersFromState(statedb *state.StateDB, epochId uint64, firstEpochId uint64) ([]Proposer, error) {
+	ps, err := createStakerProbabilityArray(statedb, epochId, firstEpochId)
+	if err != nil {
+		return nil, err
+	}
+	return selectProposers(selectionRandom(statedb, epochId, firstEpochId), 1, ps, posconfig.RandomProperCount)
 }
 
 func (e *Epocher) reportSelectELFailed(epochId uint64) {
@@ -217,14 +271,24 @@ func (e *Epocher) selectLeaders(r []byte, statedb *state.StateDB, epochId uint64
 		return err
 	}
 
-	err = e.epochLeaderSelection(r, pa, epochId)
+	// The leaders of the epoch are stored all at once, so that a crash can't
+	// leave part of them in the database
+	epochLeadersDb, rbLeadersDb := e.epochLeadersDb.NewBatch(), e.rbLeadersDb.NewBatch()
+
+	err = e.epochLeaderSelection(epochLeadersDb, r, pa, epochId)
 	if err != nil {
 		e.reportSelectELFailed(epochId)
+	} else if err = epochLeadersDb.Commit(); err != nil {
+		log.Error("Failed to store the epoch leaders", "epochId", epochId, "err", err)
+		e.reportSelectELFailed(epochId)
 	}
 
-	err = e.randomProposerSelection(r, pa, epochId)
+	err = e.randomProposerSelection(rbLeadersDb, r, pa, epochId)
 	if err != nil {
 		e.reportSelectRBPFailed(epochId)
+	} else if err = rbLeadersDb.Commit(); err != nil {
+		log.Error("Failed to store the random proposers", "epochId", epochId, "err", err)
+		e.reportSelectRBPFailed(epochId)
 	}
 
 	return nil
@@ -272,6 +336,10 @@ func (e *Epocher) CalProbability(amountWin *big.Int, lockTime uint64) *big.Int {
 }
 
 func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB, epochID uint64) (ProposerSorter, error) {
+	return createStakerProbabilityArray(statedb, epochID, e.ctx.FirstEpochId())
+}
+
+func createStakerProbabilityArray(statedb *state.StateDB, epochID uint64, firstEpochId uint64) (ProposerSorter, error) {
 	if statedb == nil {
 		return nil, vm.ErrUnknown
 	}
@@ -286,7 +354,11 @@ func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB, epochID u
 			log.Error(err.Error())
 			return true
 		}
-		_, p, err := CalEpochProbabilityStaker(&staker, epochID)
+		if vm.IsJailed(statedb, staker.Address, epochID) {
+			log.Debug("skip jailed validator", "address", staker.Address, "epochID", epochID)
+			return true
+		}
+		_, p, err := CalEpochProbabilityStaker(&staker, epochID, firstEpochId)
 		if err != nil || p == nil {
 			// this validator has no enough
 			return true
@@ -317,49 +389,60 @@ func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB, epochID u
 }
 
 //select epoch leader from PublicKeys based on proportion of Probabilities
-func (e *Epocher) epochLeaderSelection(r []byte, ps ProposerSorter, epochId uint64) error {
-	if r == nil || len(ps) == 0 {
+func (e *Epocher) epochLeaderSelection(db *posdb.Db, r []byte, ps ProposerSorter, epochId uint64) error {
+	selectionCount := posconfig.EpochLeaderCount
+	info, err := e.GetWhiteInfo(epochId)
+	if err == nil {
+		selectionCount = posconfig.EpochLeaderCount - int(info.WlCount.Uint64())
+	}
+
+	log.Debug("epochLeaderSelection selecting")
+	leaders, err := selectProposers(r, 0, ps, selectionCount)
+	if err != nil {
 		return ErrInvalidRandomProposerSelection
 	}
 
+	for i := range leaders {
+		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", leaders[i].PubSec256)
+		val, err := rlp.EncodeToBytes(&leaders[i])
+		if err != nil {
+			continue
+		}
+		db.PutWithIndex(epochId, uint64(i), "", val)
+	}
+
+	return nil
+}
+
+// selectProposers samples count proposers from ps based on proportion of
+// Probabilities. The seed is hash(prefix||r), ps must be the accumulated
+// probability array created by createStakerProbabilityArray.
+func selectProposers(r []byte, prefix byte, ps ProposerSorter, count int) ([]Proposer, error) {
+	if r == nil || len(ps) == 0 {
+		return nil, ErrInvalidEpochProposerSelection
+	}
+
 	//the last one is total properties
 	tp := ps[len(ps)-1].Probabilities
 
-	var Byte0 = []byte{byte(0)}
 	var buffer bytes.Buffer
-	buffer.Write(Byte0)
+	buffer.WriteByte(prefix)
 	buffer.Write(r)
-	r0 := buffer.Bytes()       //r0 = 0||r
-	cr := crypto.Keccak256(r0) //cr = hash(r0)
-
-	//randomProposerPublicKeys := make([]*ecdsa.PublicKey, 0)  //store the selected publickeys
-	log.Debug("epochLeaderSelection selecting")
-	selectionCount := posconfig.EpochLeaderCount
-	info, err := e.GetWhiteInfo(epochId)
-	if err == nil {
-		selectionCount = posconfig.EpochLeaderCount - int(info.WlCount.Uint64())
-	}
-	for i := 0; i < selectionCount; i++ {
+	cr := crypto.Keccak256(buffer.Bytes()) //cr = hash(prefix||r)
 
+	selected := make([]Proposer, 0, count)
+	for i := 0; i < count; i++ {
 		crBig := new(big.Int).SetBytes(cr)
 		crBig = crBig.Mod(crBig, tp) //cr_big = cr mod tp
 
-		//fmt.Println("epoch leader mod tp=" + common.ToHex(crBig.Bytes()))
 		//select pki whose probability bigger than cr_big left
 		idx := sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
-
-		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", ps[idx].PubSec256)
-		//randomProposerPublicKeys = append(randomProposerPublicKeys, ps[idx].PubSec256)
-		val, err := rlp.EncodeToBytes(&ps[idx])
-		if err != nil {
-			continue
-		}
-		e.epochLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
+		selected = append(selected, ps[idx])
 
 		cr = crypto.Keccak256(cr)
 	}
 
-	return nil
+	return selected, nil
 }
 
 func (e *Epocher) GetWhiteInfo(epochId uint64) (*vm.UpgradeWhiteEpochLeaderParam, error) {
@@ -398,51 +481,32 @@ func (e *Epocher) GetWhiteArrayByEpochId(epochId uint64) ([][]byte, error) {
 
 //*bn256.G1
 //samples ne epoch leaders by random number r from PublicKeys based on proportion of Probabilities
-func (e *Epocher) randomProposerSelection(r []byte, ps ProposerSorter, epochId uint64) error {
-	if r == nil || len(ps) == 0 {
-		return ErrInvalidEpochProposerSelection
-	}
-
-	//the last one is total properties
-	tp := ps[len(ps)-1].Probabilities
-
-	var Byte1 = []byte{byte(1)}
-	var buffer bytes.Buffer
-	buffer.Write(Byte1)
-	buffer.Write(r)
-	r1 := buffer.Bytes()       //r1 = 1||r
-	cr := crypto.Keccak256(r1) //cr = hash(r1)
-
+func (e *Epocher) randomProposerSelection(db *posdb.Db, r []byte, ps ProposerSorter, epochId uint64) error {
 	log.Info("random proposer selecting...\n")
-	for i := 0; i < posconfig.RandomProperCount; i++ {
-
-		crBig := new(big.Int).SetBytes(cr)
-		crBig = crBig.Mod(crBig, tp) //cr_big = cr mod tp
-
-		//select pki whose probability bigger than cr_big left
-		idx := sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
-
-		val, err := rlp.EncodeToBytes(ps[idx])
+	proposers, err := selectProposers(r, 1, ps, posconfig.RandomProperCount)
+	if err != nil {
+		return err
+	}
 
+	for i := range proposers {
+		val, err := rlp.EncodeToBytes(proposers[i])
 		if err != nil {
 			continue
 		}
 
-		e.rbLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
-
-		cr = crypto.Keccak256(cr)
+		db.PutWithIndex(epochId, uint64(i), "", val)
 	}
 
 	return nil
 }
 
 func (e *Epocher) IsGenerateELSuc(epochID uint64) bool {
-	epArray := posdb.GetEpochLeaderGroup(epochID)
+	epArray := e.epochLeadersDb.GetEpochLeaderGroup(epochID)
 	return len(epArray) != 0
 }
 
 func (e *Epocher) IsGenerateRBPSuc(epochID uint64) bool {
-	rbArray := posdb.GetRBProposerGroup(epochID)
+	rbArray := e.rbLeadersDb.GetRBProposerGroup(epochID)
 	return len(rbArray) != 0
 }
 
@@ -450,7 +514,7 @@ func (e *Epocher) IsGenerateRBPSuc(epochID uint64) bool {
 func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 
 	// TODO: how to cache these
-	epArray := posdb.GetEpochLeaderGroup(epochID)
+	epArray := e.epochLeadersDb.GetEpochLeaderGroup(epochID)
 	wa, err := e.GetWhiteArrayByEpochId(epochID)
 	if err == nil {
 		if len(epArray) == posconfig.EpochLeaderCount-len(wa) {
@@ -462,7 +526,7 @@ func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 }
 func (e *Epocher) GetRBProposer(epochID uint64) [][]byte {
 	// TODO: how to cache these
-	rbArray := posdb.GetRBProposerGroup(epochID)
+	rbArray := e.rbLeadersDb.GetRBProposerGroup(epochID)
 	return rbArray
 
 }
@@ -591,11 +655,11 @@ func (e *Epocher) GetProposerBn256PK(epochID uint64, idx uint64, addr common.Add
 }
 
 // TODO Is this  right?
-func CalEpochProbabilityStaker(staker *vm.StakerInfo, epochID uint64) (infors []vm.ClientProbability, totalProbability *big.Int, err error) {
+func CalEpochProbabilityStaker(staker *vm.StakerInfo, epochID uint64, firstEpochId uint64) (infors []vm.ClientProbability, totalProbability *big.Int, err error) {
 	if staker.StakingEpoch == 0 && staker.LockEpochs != 0 {
-		staker.StakingEpoch = posconfig.FirstEpochId + 2
+		staker.StakingEpoch = firstEpochId + 2
 		for j := 0; j < len(staker.Partners); j++ {
-			staker.Partners[j].StakingEpoch = posconfig.FirstEpochId + 2
+			staker.Partners[j].StakingEpoch = firstEpochId + 2
 		}
 	}
 	// check validator is exiting.
@@ -669,7 +733,7 @@ func (e *Epocher) GetEpochProbability(epochId uint64, addr common.Address) (*vm.
 		return nil, err
 	}
 
-	infors, totalProbability, err := CalEpochProbabilityStaker(&staker, epochId)
+	infors, totalProbability, err := CalEpochProbabilityStaker(&staker, epochId, e.ctx.FirstEpochId())
 	if err != nil {
 		return nil, err
 	}
@@ -710,12 +774,12 @@ func recordStakeOut(infos []RefundInfo, addr common.Address, amount *big.Int)([]
 	infos = append(infos, record)
 	return infos
 }
-func saveStakeOut(stakeOutInfo []RefundInfo, epochID uint64) error {
+func saveStakeOut(ctx *posctx.Context, stakeOutInfo []RefundInfo, epochID uint64) error {
 	stakeByte, err := rlp.EncodeToBytes(stakeOutInfo)
 	if err != nil {
 		return err
 	}
-	_, err = posdb.GetDb().Put(epochID, posconfig.StakeOutEpochKey,stakeByte)
+	_, err = ctx.LocalDb().Put(epochID, posconfig.StakeOutEpochKey,stakeByte)
 	if err != nil {
 		log.Error("saveStakeOut Failed:", "error", err)
 		return err
@@ -765,7 +829,7 @@ func CleanInactiveValidator(stateDb *state.StateDB, epochID uint64){
 		}
 	}
 }
-func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
+func StakeOutRun(ctx *posctx.Context, stateDb *state.StateDB, epochID uint64) bool {
 	if vm.StakeoutIsFinished(stateDb, epochID) {
 		return true
 	}
@@ -783,9 +847,9 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 
 		// handle the staker registed in pow phase. only once
 		if staker.StakingEpoch == 0 && staker.LockEpochs != 0 {
-			staker.StakingEpoch = posconfig.FirstEpochId + 2
+			staker.StakingEpoch = ctx.FirstEpochId() + 2
 			for j := 0; j < len(staker.Partners); j++ {
-				staker.Partners[j].StakingEpoch = posconfig.FirstEpochId + 2
+				staker.Partners[j].StakingEpoch = ctx.FirstEpochId() + 2
 			}
 			changed = true
 		}
@@ -901,6 +965,6 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 			vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), stakerBytes)
 		}
 	}
-	saveStakeOut(stakeOutInfo, epochID)
+	saveStakeOut(ctx, stakeOutInfo, epochID)
 	return true
 }
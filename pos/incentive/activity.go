@@ -8,6 +8,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
@@ -44,13 +45,19 @@ func checkEpochLeaders(epochLeaders [][]byte) bool {
 	return true
 }
 
-func getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+func getEpochLeaderActivity(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
 	if stateDb == nil {
 		log.SyslogErr("getEpochLeaderActivity with an empty stateDb")
 		return []common.Address{}, []int{}
 	}
 
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+	selector := ctx.Selector()
+	if selector == nil {
+		log.SyslogErr("getEpochLeaderActivity without epoch leader selector")
+		return []common.Address{}, []int{}
+	}
+
+	epochLeaders := selector.GetEpochLeaders(epochID)
 	if !checkEpochLeaders(epochLeaders) {
 		log.SyslogErr("incentive activity GetEpochLeaders error", "epochID", epochID)
 		return []common.Address{}, []int{}
@@ -14,7 +14,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-    "github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/posctx"
     "github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
@@ -89,11 +89,12 @@ func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 	posconfig.Init(nil, 4)
 	activityInit()
 	epochID := uint64(0)
-	util.SetEpocherInst(&TestSelectLead{})
+	ctx := posctx.New("")
+	ctx.SetSelector(&TestSelectLead{})
 
 	//test bad input
 	clearTestAddrs()
-	getEpochLeaderActivity(statedb, epochID)
+	getEpochLeaderActivity(ctx, statedb, epochID)
 
 	//test good input
 	generateTestAddrs()
@@ -111,7 +112,7 @@ func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 		statedb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), keyHash, buf)
 	}
 
-	addrs, activity := getEpochLeaderActivity(statedb, epochID)
+	addrs, activity := getEpochLeaderActivity(ctx, statedb, epochID)
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != epAddrs[i].Hex() {
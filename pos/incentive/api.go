@@ -13,12 +13,12 @@ import (
 
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
-var localDb *posdb.Db
-
 var (
 	dictAllTotal       = "all_total"
 	dictEpochTotal     = "epoch_total"
@@ -33,13 +33,17 @@ var (
 	dictCurrentEpoch   = "current_epoch"
 	dictAddressCnt     = "address_count"
 	dictEpochPayDetail = "epoch_pay_detail"
+
+	dictParentPayDetail = "parent_pay_detail_" // + parent hash
+	dictFirstPayDetail  = "first_pay_detail"   // earliest epoch with a parent pay detail
 )
 
-func initLocalDb(dbName string) {
-	localDb = posdb.NewDb(dbName)
+// incentiveDb returns the incentive database of a node.
+func incentiveDb(ctx *posctx.Context) *posdb.Db {
+	return ctx.Db(posconfig.IncentiveLocalDB)
 }
 
-func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
+func saveIncentiveHistory(localDb *posdb.Db, epochID uint64, payments [][]vm.ClientIncentive) {
 	if payments == nil {
 		return
 	}
@@ -51,18 +55,41 @@ func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
 	}
 	localDb.Put(epochID, dictEpochPayDetail, buf)
 
-	saveOtherInfomation(epochID, payments)
+	saveOtherInfomation(localDb, epochID, payments)
+}
+
+// saveParentPayDetail records the payments of an epoch made by a child of
+// parent, as the epoch pay detail is overwritten by the blocks of side chains.
+func saveParentPayDetail(localDb *posdb.Db, epochID uint64, parent common.Hash, payments [][]vm.ClientIncentive) {
+	buf, err := rlp.EncodeToBytes(payments)
+	if err != nil {
+		log.SyslogErr(err.Error())
+		return
+	}
+	localDb.Put(epochID, dictParentPayDetail+parent.Hex(), buf)
+
+	if first, ok := firstPayDetailEpoch(localDb); !ok || epochID < first {
+		localDb.Put(0, dictFirstPayDetail, convert.Uint64ToBytes(epochID))
+	}
+}
+
+func firstPayDetailEpoch(localDb *posdb.Db) (uint64, bool) {
+	buf, err := localDb.Get(0, dictFirstPayDetail)
+	if err != nil || len(buf) == 0 {
+		return 0, false
+	}
+	return convert.BytesToUint64(buf), true
 }
 
-func saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func saveTotalIncentive(localDb *posdb.Db, epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDbAddValue(0, dictAllTotal, totalIncome)
+	localDbAddValue(localDb, 0, dictAllTotal, totalIncome)
 }
 
-func saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func saveEpochTotalIncentive(localDb *posdb.Db, epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
@@ -70,25 +97,25 @@ func saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive)
 	localDb.Put(epochID, dictEpochTotal, totalIncome.Bytes())
 }
 
-func saveRemain(epochID uint64, remain *big.Int) {
+func saveRemain(localDb *posdb.Db, epochID uint64, remain *big.Int) {
 	if remain == nil {
 		return
 	}
 	localDb.Put(epochID, dictEpochRemain, remain.Bytes())
-	localDbAddValue(0, dictTotalRemain, remain)
+	localDbAddValue(localDb, 0, dictTotalRemain, remain)
 }
 
-func addRunTimes() {
-	localDbAddValue(0, dictRunTimes, big.NewInt(1))
+func addRunTimes(localDb *posdb.Db) {
+	localDbAddValue(localDb, 0, dictRunTimes, big.NewInt(1))
 }
 
-func saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
-	saveTotalIncentive(epochID, incentives)
-	saveEpochTotalIncentive(epochID, incentives)
-	addRunTimes()
+func saveOtherInfomation(localDb *posdb.Db, epochID uint64, incentives [][]vm.ClientIncentive) {
+	saveTotalIncentive(localDb, epochID, incentives)
+	saveEpochTotalIncentive(localDb, epochID, incentives)
+	addRunTimes(localDb)
 }
 
-func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
+func localDbGetValue(localDb *posdb.Db, epochID uint64, key string) (*big.Int, error) {
 	total, err := localDb.Get(epochID, key)
 	if err != nil && err.Error() != "leveldb: not found" {
 		log.SyslogErr(err.Error())
@@ -101,11 +128,11 @@ func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
 
 	return big.NewInt(0).SetBytes(total), nil
 }
-func localDbSetValue(epochID uint64, key string, value *big.Int) {
+func localDbSetValue(localDb *posdb.Db, epochID uint64, key string, value *big.Int) {
 	localDb.Put(epochID, key, value.Bytes())
 }
 
-func localDbAddValue(epochID uint64, key string, value *big.Int) {
+func localDbAddValue(localDb *posdb.Db, epochID uint64, key string, value *big.Int) {
 	total, err := localDb.Get(epochID, key)
 	if err != nil && err.Error() != "leveldb: not found" {
 		log.SyslogErr(err.Error())
@@ -120,8 +147,8 @@ func localDbAddValue(epochID uint64, key string, value *big.Int) {
 }
 
 // GetEpochPayDetail use to get detail payment array
-func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	buf, err := localDb.Get(epochID, dictEpochPayDetail)
+func GetEpochPayDetail(ctx *posctx.Context, epochID uint64) ([][]vm.ClientIncentive, error) {
+	buf, err := incentiveDb(ctx).Get(epochID, dictEpochPayDetail)
 	if err != nil {
 		log.SyslogErr(err.Error())
 		return nil, err
@@ -138,33 +165,55 @@ func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
 	return payment, nil
 }
 
+// GetParentPayDetail returns the payments of an epoch made by a child block
+// of parent, nil if no child of parent paid the epoch.
+func GetParentPayDetail(ctx *posctx.Context, epochID uint64, parent common.Hash) ([][]vm.ClientIncentive, error) {
+	buf, err := incentiveDb(ctx).Get(epochID, dictParentPayDetail+parent.Hex())
+	if err != nil || len(buf) == 0 {
+		return nil, nil
+	}
+
+	var payment [][]vm.ClientIncentive
+	if err := rlp.DecodeBytes(buf, &payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// FirstPayDetailEpoch returns the earliest epoch the node recorded the
+// payments by parent of, false if it recorded none. The earlier epochs were
+// paid by blocks the node didn't execute, e.g. before a fast sync pivot.
+func FirstPayDetailEpoch(ctx *posctx.Context) (uint64, bool) {
+	return firstPayDetailEpoch(incentiveDb(ctx))
+}
+
 // GetTotalIncentive get total incentive of all epoch
-func GetTotalIncentive() (*big.Int, error) {
-	return localDbGetValue(0, dictAllTotal)
+func GetTotalIncentive(ctx *posctx.Context) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), 0, dictAllTotal)
 }
 
 // GetEpochIncentive get total incentive of all epoch
-func GetEpochIncentive(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochTotal)
+func GetEpochIncentive(ctx *posctx.Context, epochID uint64) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), epochID, dictEpochTotal)
 }
 
-func GetEpochIncentiveBlockNumber(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochBlock)
+func GetEpochIncentiveBlockNumber(ctx *posctx.Context, epochID uint64) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), epochID, dictEpochBlock)
 }
 
 // GetEpochRemain get remain of epoch input
-func GetEpochRemain(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochRemain)
+func GetEpochRemain(ctx *posctx.Context, epochID uint64) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), epochID, dictEpochRemain)
 }
 
 // GetTotalRemain get remain of epoch input
-func GetTotalRemain() (*big.Int, error) {
-	return localDbGetValue(0, dictTotalRemain)
+func GetTotalRemain(ctx *posctx.Context) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), 0, dictTotalRemain)
 }
 
 // GetRunTimes returns incentive run times
-func GetRunTimes() (*big.Int, error) {
-	return localDbGetValue(0, dictRunTimes)
+func GetRunTimes(ctx *posctx.Context) (*big.Int, error) {
+	return localDbGetValue(incentiveDb(ctx), 0, dictRunTimes)
 }
 
 // GetEpochGasPool use to get epoch gas pool
@@ -188,13 +237,13 @@ func GetRBAddress(epochID uint64) []common.Address {
 }
 
 // GetIncentivePool can get the total incentive, foundation part and gas pool part.
-func GetIncentivePool(stateDb *state.StateDB, epochID uint64) (*big.Int, *big.Int, *big.Int) {
-	return calculateIncentivePool(stateDb, epochID)
+func GetIncentivePool(ctx *posctx.Context, stateDb *state.StateDB, epochID uint64) (*big.Int, *big.Int, *big.Int) {
+	return calculateIncentivePool(stateDb, epochID, ctx.FirstEpochId())
 }
 
 // GetEpochLeaderActivity can get the address and activity of epoch leaders
-func GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	return getEpochLeaderActivity(stateDb, epochID)
+func GetEpochLeaderActivity(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	return getEpochLeaderActivity(ctx, stateDb, epochID)
 }
 
 // GetEpochRBLeaderActivity can get the address and activity of RB leaders
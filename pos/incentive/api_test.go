@@ -2,25 +2,30 @@ package incentive
 
 import (
 	"math/big"
-	"os"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
-func testInitDb() {
-	os.RemoveAll("/tmp/pluto/gwan/incentive")
-	initLocalDb("/tmp/pluto/gwan/incentive")
+func testInitDb() *posctx.Context {
+	return posctx.New("")
 }
 
 func TestInitLocalDB(t *testing.T) {
-	testInitDb()
+	ctx := testInitDb()
+	defer ctx.Close()
+	if incentiveDb(ctx) == nil {
+		t.FailNow()
+	}
 }
 
 func TestGetEpochPayDetail(t *testing.T) {
 	epochID := uint64(0)
 	generateTestAddrs()
-	testInitDb()
+	ctx := testInitDb()
+	defer ctx.Close()
+	localDb := incentiveDb(ctx)
 
 	payExample := [][]vm.ClientIncentive{
 		{
@@ -34,9 +39,9 @@ func TestGetEpochPayDetail(t *testing.T) {
 		},
 	}
 
-	saveIncentiveHistory(epochID, nil)
-	saveIncentiveHistory(epochID, payExample)
-	pay, err := GetEpochPayDetail(epochID)
+	saveIncentiveHistory(localDb, epochID, nil)
+	saveIncentiveHistory(localDb, epochID, payExample)
+	pay, err := GetEpochPayDetail(ctx, epochID)
 	if err != nil {
 		t.FailNow()
 	}
@@ -49,31 +54,31 @@ func TestGetEpochPayDetail(t *testing.T) {
 		}
 	}
 
-	saveIncentiveHistory(1, payExample)
+	saveIncentiveHistory(localDb, 1, payExample)
 
-	total, err := GetTotalIncentive()
+	total, err := GetTotalIncentive(ctx)
 	if total.Uint64() != 3000 || err != nil {
 		t.FailNow()
 	}
 
-	total, err = GetEpochIncentive(1)
+	total, err = GetEpochIncentive(ctx, 1)
 	if total.Uint64() != 1500 || err != nil {
 		t.FailNow()
 	}
 
-	saveRemain(0, big.NewInt(100))
-	saveRemain(1, big.NewInt(300))
+	saveRemain(localDb, 0, big.NewInt(100))
+	saveRemain(localDb, 1, big.NewInt(300))
 
-	epRemain, err := GetEpochRemain(1)
+	epRemain, err := GetEpochRemain(ctx, 1)
 	if err != nil || epRemain.Uint64() != 300 {
 		t.FailNow()
 	}
-	epRemain, err = GetTotalRemain()
+	epRemain, err = GetTotalRemain(ctx)
 	if err != nil || epRemain.Uint64() != 400 {
 		t.FailNow()
 	}
 
-	value, err := GetRunTimes()
+	value, err := GetRunTimes(ctx)
 	if err != nil || value.Uint64() != 2 {
 		t.FailNow()
 	}
@@ -97,12 +102,12 @@ func TestOtherApiSuccess(t *testing.T) {
 		//t.FailNow()
 	}
 
-	total, fdt, pool := GetIncentivePool(statedb, 0)
+	total, fdt, pool := GetIncentivePool(nil, statedb, 0)
 	if total.String() != "0" || fdt.String() != "0" || pool.String() != "0" {
 		//t.FailNow()
 	}
 
-	// addr, act := GetEpochLeaderActivity(statedb, 0)
+	// addr, act := GetEpochLeaderActivity(nil, statedb, 0)
 	// if len(addr) != 0 || len(act) != 0 {
 	// 	//t.FailNow()
 	// }
@@ -132,12 +137,12 @@ func TestOtherApiFail(t *testing.T) {
 	}
 	getRandomProposerAddress = tmp
 
-	total, fdt, pool := GetIncentivePool(nil, 0)
+	total, fdt, pool := GetIncentivePool(nil, nil, 0)
 	if total.String() != "0" || fdt.String() != "0" || pool.String() != "0" {
 		t.FailNow()
 	}
 
-	addr, act := GetEpochLeaderActivity(nil, 0)
+	addr, act := GetEpochLeaderActivity(nil, nil, 0)
 	if len(addr) != 0 || len(act) != 0 {
 		t.FailNow()
 	}
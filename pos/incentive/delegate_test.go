@@ -11,7 +11,7 @@ func TestDelegate(t *testing.T) {
 	TestSetActivityInterface(t)
 	TestSetStakerInterface(t)
 
-	epAddrs, _ := getEpochLeaderInfo(statedb, 0)
+	epAddrs, _ := getEpochLeaderInfo(nil, statedb, 0)
 
 	values := make([]*big.Int, len(epAddrs))
 	for i := 0; i < len(values); i++ {
@@ -17,6 +17,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 
 	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 var (
@@ -46,15 +47,14 @@ func Init(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerA
 	setStakerInterface(get, set)
 	setActivityInterface(getEpochLeaderActivity, getRandomProposerActivity, getSlotLeaderActivity)
 	setRBAddressInterface(getRbAddr)
-
-	initLocalDb(posconfig.IncentiveLocalDB)
 	log.Info("--------Incentive Init Finish----------")
 }
 
-// Run is use to run the incentive should be called in Finalize of consensus
-func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bool {
-	if chain == nil || stateDb == nil {
-		log.SyslogErr("incentive Run input param error (chain == nil || stateDb == nil)")
+// Run is use to run the incentive should be called in Finalize of consensus,
+// parent is the parent hash of the block paying the incentives.
+func Run(ctx *posctx.Context, chain consensus.ChainReader, parent common.Hash, stateDb *state.StateDB, epochID uint64) bool {
+	if ctx == nil || chain == nil || stateDb == nil {
+		log.SyslogErr("incentive Run input param error (ctx == nil || chain == nil || stateDb == nil)")
 		return false
 	}
 
@@ -64,10 +64,10 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 	finalIncentive := make([][]vm.ClientIncentive, 0)
 	remainsAll := big.NewInt(0)
 
-	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID)
+	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID, ctx.FirstEpochId())
 	saveIncentiveIncome(total, foundation, gasPool)
 
-	epAddrs, epAct := getEpochLeaderInfo(stateDb, epochID)
+	epAddrs, epAct := getEpochLeaderInfo(ctx, stateDb, epochID)
 	log.Info("epoch addr", "len", len(epAddrs))
 	rpAddrs, rpAct := getRandomProposerInfo(stateDb, epochID)
 	log.Info("rp Addrs", "len", len(rpAddrs))
@@ -144,13 +144,16 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 	}
 
 	addRemainIncentivePool(stateDb, epochID, remainsAll)
-	saveRemain(epochID, remainsAll)
+	// The incentive data is written with the block
+	localDb := ctx.BlockDb(posconfig.IncentiveLocalDB, stateDb)
+	saveRemain(localDb, epochID, remainsAll)
 
 	pay(finalIncentive, stateDb)
 
 	setStakerInfo(epochID, finalIncentive)
-	saveIncentiveHistory(epochID, finalIncentive)
-	localDbSetValue(epochID, dictEpochBlock, chain.CurrentHeader().Number)
+	saveIncentiveHistory(localDb, epochID, finalIncentive)
+	saveParentPayDetail(localDb, epochID, parent, finalIncentive)
+	localDbSetValue(localDb, epochID, dictEpochBlock, chain.CurrentHeader().Number)
 
 	finished(stateDb, epochID)
 	return true
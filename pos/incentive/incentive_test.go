@@ -10,6 +10,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 // Prepare a simulate stateDB ---------------------------------------------
@@ -24,19 +25,34 @@ func TestRun(t *testing.T) {
 	TestSetActivityInterface(t)
 	TestSetStakerInterface(t)
 
+	ctx := posctx.New("")
+	defer ctx.Close()
+
 	testTimes := 1
 
 	for i := 0; i < testTimes; i++ {
 		for m := 0; m < posconfig.SlotCount; m++ {
-			if !Run(&TestChainReader{}, statedb, uint64(i)) {
+			if !Run(ctx, &TestChainReader{}, common.Hash{}, statedb, uint64(i)) {
 				t.FailNow()
 			}
 		}
 
-		total, foundation, gasPool := calculateIncentivePool(statedb, uint64(i))
+		total, foundation, gasPool := calculateIncentivePool(statedb, uint64(i), ctx.FirstEpochId())
 		if total.String() != (big.NewInt(0).Add(foundation, gasPool)).String() {
 			t.FailNow()
 		}
+
+		epochPays, _ := GetEpochPayDetail(ctx, uint64(i))
+		parentPays, err := GetParentPayDetail(ctx, uint64(i), common.Hash{})
+		if err != nil || sumToPay(parentPays).Cmp(sumToPay(epochPays)) != 0 {
+			t.Fatalf("parent pay detail mismatch: have %v, want %v", parentPays, epochPays)
+		}
+		if pays, _ := GetParentPayDetail(ctx, uint64(i), common.HexToHash("0x01")); pays != nil {
+			t.Fatalf("pay detail of another parent: %v", pays)
+		}
+	}
+	if first, ok := FirstPayDetailEpoch(ctx); !ok || first != 0 {
+		t.Fatalf("first pay detail epoch mismatch: have %d %v, want 0 true", first, ok)
 	}
 
 	sumTotal := big.NewInt(0)
@@ -55,7 +71,7 @@ func TestRun(t *testing.T) {
 }
 
 func TestRunFail(t *testing.T) {
-	if Run(nil, nil, 0) {
+	if Run(nil, nil, common.Hash{}, nil, 0) {
 		t.FailNow()
 	}
 }
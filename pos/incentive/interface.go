@@ -4,6 +4,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 // GetStakerInfoFn is a function use to get staker info
@@ -13,7 +14,7 @@ type GetStakerInfoFn func(uint64, common.Address) (*vm.ValidatorInfo,  error)
 type SetStakerInfoFn func(uint64, [][]vm.ClientIncentive) error
 
 // GetEpochLeaderInfoFn is a function use to get epoch activity and address
-type GetEpochLeaderInfoFn func(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int)
+type GetEpochLeaderInfoFn func(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int)
 
 // GetRandomProposerInfoFn is use to get rb group and activity
 type GetRandomProposerInfoFn func(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int)
@@ -53,3 +54,17 @@ func setActivityInterface(getEpl GetEpochLeaderInfoFn, getRnp GetRandomProposerI
 func setRBAddressInterface(getRBAddress GetRandomProposerAddressFn) {
 	getRandomProposerAddress = getRBAddress
 }
+
+// Save returns a function restoring the interfaces and the white list set by
+// Init, for the tests running Init with their own validators.
+func Save() (restore func()) {
+	getStaker, setStaker := getStakerInfo, setStakerInfo
+	getEpl, getRnp, getSlr := getEpochLeaderInfo, getRandomProposerInfo, getSlotLeaderInfo
+	getRbAddr, wl := getRandomProposerAddress, whiteList
+	return func() {
+		setStakerInterface(getStaker, setStaker)
+		setActivityInterface(getEpl, getRnp, getSlr)
+		setRBAddressInterface(getRbAddr)
+		whiteList = wl
+	}
+}
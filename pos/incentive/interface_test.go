@@ -13,6 +13,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 var (
@@ -26,7 +27,7 @@ var (
 	slBlks     = make([]int, addrsCount)
 )
 
-func testgetEpLeader(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+func testgetEpLeader(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
 	return epAddrs, epActs
 }
 
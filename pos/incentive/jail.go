@@ -0,0 +1,180 @@
+package incentive
+
+import (
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// duties counts the epoch leader and random proposer duties of a validator
+// in an epoch, and those it fulfilled.
+type duties struct {
+	assigned  uint64
+	fulfilled uint64
+}
+
+// inactive reports whether a validator fulfilled too few of its duties.
+func (d *duties) inactive(config *params.PosJailConfig) bool {
+	return d.assigned >= config.MinDuties &&
+		d.fulfilled*100 < d.assigned*config.MinActivityPercent
+}
+
+// epochDuties returns the duties of the validators of an epoch, and the
+// validators in the order they were first assigned a duty.
+func epochDuties(ctx *posctx.Context, stateDb *state.StateDB, epochID uint64) (map[common.Address]*duties, []common.Address) {
+	all := make(map[common.Address]*duties)
+	addrs := make([]common.Address, 0)
+	count := func(leaders []common.Address, acts []int) {
+		for i := 0; i < len(leaders) && i < len(acts); i++ {
+			if isInWhiteList(leaders[i]) {
+				continue
+			}
+			d, ok := all[leaders[i]]
+			if !ok {
+				d = new(duties)
+				all[leaders[i]] = d
+				addrs = append(addrs, leaders[i])
+			}
+			d.assigned++
+			if acts[i] == 1 {
+				d.fulfilled++
+			}
+		}
+	}
+	count(getEpochLeaderInfo(ctx, stateDb, epochID))
+	count(getRandomProposerInfo(stateDb, epochID))
+	return all, addrs
+}
+
+// Jail jails the validators that fulfilled too few of their epoch leader and
+// random proposer duties in an epoch, so that they aren't selected for
+// config.Epochs epochs, and burns config.BurnPercent of their own stake. Each
+// jailing is recorded in the state, see vm.GetJailEvents, and logged by the
+// block, as are the releases of the validators selectable again in the next
+// epoch. It should be called in Finalize of consensus once the jailing fork
+// is active, after Run paid the epoch.
+func Jail(ctx *posctx.Context, config *params.PosJailConfig, stateDb *state.StateDB, blockNumber *big.Int, epochID uint64) bool {
+	if ctx == nil || stateDb == nil {
+		log.SyslogErr("incentive Jail input param error (ctx == nil || stateDb == nil)")
+		return false
+	}
+	if vm.JailIsFinished(stateDb, epochID) {
+		return true
+	}
+
+	all, addrs := epochDuties(ctx, stateDb, epochID)
+	for _, addr := range addrs {
+		d := all[addr]
+		if !d.inactive(config) {
+			continue
+		}
+		staker, err := vm.GetStakerInfo(stateDb, addr)
+		if err != nil {
+			// The validator staked out already.
+			continue
+		}
+		info, err := vm.GetJailInfo(stateDb, addr)
+		if err != nil {
+			log.SyslogErr("incentive Jail get jail info error", "address", addr, "error", err.Error())
+			return false
+		}
+		if info == nil {
+			info = &vm.JailInfo{Address: addr, Burnt: big.NewInt(0)}
+		}
+		burnt, err := burnStake(stateDb, staker, config.BurnPercent)
+		if err != nil {
+			log.SyslogErr("incentive Jail burn stake error", "address", addr, "error", err.Error())
+			return false
+		}
+		info.JailedEpoch = epochID
+		info.ReleaseEpoch = epochID + posconfig.IncentiveDelayEpochs + 1 + config.Epochs
+		info.Count++
+		info.Burnt.Add(info.Burnt, burnt)
+		if err := vm.SetJailInfo(stateDb, info); err != nil {
+			log.SyslogErr("incentive Jail set jail info error", "address", addr, "error", err.Error())
+			return false
+		}
+		event := &vm.JailEvent{
+			Address:      addr,
+			Duties:       d.assigned,
+			Fulfilled:    d.fulfilled,
+			ReleaseEpoch: info.ReleaseEpoch,
+			Burnt:        burnt,
+		}
+		if err := vm.AddJailEvent(stateDb, epochID, event); err != nil {
+			log.SyslogErr("incentive Jail add jail event error", "address", addr, "error", err.Error())
+			return false
+		}
+		if err := vm.JailLog(stateDb, blockNumber, epochID, event); err != nil {
+			log.SyslogErr("incentive Jail log error", "address", addr, "error", err.Error())
+			return false
+		}
+		log.Warn("Validator jailed", "address", addr, "epochID", epochID, "duties", d.assigned,
+			"fulfilled", d.fulfilled, "releaseEpoch", info.ReleaseEpoch, "burnt", burnt)
+	}
+
+	if err := releaseLog(stateDb, config, blockNumber, epochID); err != nil {
+		log.SyslogErr("incentive Jail release log error", "epochID", epochID, "error", err.Error())
+		return false
+	}
+
+	vm.JailSetFinished(stateDb, epochID)
+	return true
+}
+
+// releaseLog logs the releases of the validators selectable again in the
+// epoch after the one Jail runs in for epochID: those jailed for the
+// inactivity of config.Epochs epochs before and not jailed again since.
+func releaseLog(stateDb *state.StateDB, config *params.PosJailConfig, blockNumber *big.Int, epochID uint64) error {
+	if epochID < config.Epochs {
+		return nil
+	}
+	events, err := vm.GetJailEvents(stateDb, epochID-config.Epochs)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		info, err := vm.GetJailInfo(stateDb, event.Address)
+		if err != nil {
+			return err
+		}
+		if info == nil || info.ReleaseEpoch != event.ReleaseEpoch {
+			continue
+		}
+		if err := vm.ReleaseLog(stateDb, blockNumber, event.Address, event.ReleaseEpoch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// burnStake burns percent of the own stake of a validator, keeping the voting
+// power of the rest, and returns the amount burnt.
+func burnStake(stateDb *state.StateDB, staker *vm.StakerInfo, percent uint64) (*big.Int, error) {
+	burnt := calcPercent(staker.Amount, float64(percent))
+	if burnt.Sign() == 0 {
+		return burnt, nil
+	}
+	if staker.Amount.Sign() > 0 {
+		stakeBurnt := new(big.Int).Mul(staker.StakeAmount, burnt)
+		stakeBurnt.Div(stakeBurnt, staker.Amount)
+		staker.StakeAmount.Sub(staker.StakeAmount, stakeBurnt)
+	}
+	staker.Amount.Sub(staker.Amount, burnt)
+	infoBytes, err := rlp.EncodeToBytes(staker)
+	if err != nil {
+		return nil, err
+	}
+	if err := vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), infoBytes); err != nil {
+		return nil, err
+	}
+	stateDb.SubBalance(vm.WanCscPrecompileAddr, burnt)
+	return burnt, nil
+}
@@ -0,0 +1,123 @@
+package incentive
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func TestJail(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	ctx := posctx.New("")
+	defer ctx.Close()
+
+	var (
+		active   = common.HexToAddress("0x01")
+		inactive = common.HexToAddress("0x02")
+	)
+	// Both validators hold four epoch leader seats and one random proposer
+	// seat, the inactive one fulfilled two of them only.
+	epLeader := func(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+		return []common.Address{active, inactive, active, inactive, active, inactive, active, inactive},
+			[]int{1, 1, 1, 1, 1, 0, 1, 0}
+	}
+	rProposer := func(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+		return []common.Address{active, inactive}, []int{1, 0}
+	}
+	defer setActivityInterface(getEpochLeaderInfo, getRandomProposerInfo, getSlotLeaderInfo)
+	setActivityInterface(epLeader, rProposer, getSlotLeaderInfo)
+
+	config := *params.DefaultPosJailConfig
+	config.BurnPercent = 10
+	for _, addr := range []common.Address{active, inactive} {
+		staker := &vm.StakerInfo{Address: addr, Amount: big.NewInt(1000), StakeAmount: big.NewInt(5000)}
+		buf, _ := rlp.EncodeToBytes(staker)
+		vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr), buf)
+	}
+	stateDb.AddBalance(vm.WanCscPrecompileAddr, big.NewInt(2000))
+
+	epochID := uint64(100)
+	number := big.NewInt(1000)
+	stateDb.Prepare(common.Hash{1}, common.Hash{}, 0)
+	if !Jail(ctx, &config, stateDb, number, epochID) {
+		t.Fatal("jail failed")
+	}
+	if vm.IsJailed(stateDb, active, epochID+2) {
+		t.Error("active validator jailed")
+	}
+	info, err := vm.GetJailInfo(stateDb, inactive)
+	if err != nil || info == nil {
+		t.Fatalf("no jail info: %v", err)
+	}
+	release := epochID + posconfig.IncentiveDelayEpochs + 1 + config.Epochs
+	if info.JailedEpoch != epochID || info.ReleaseEpoch != release || info.Count != 1 || info.Burnt.Uint64() != 100 {
+		t.Errorf("jail info mismatch: %+v", info)
+	}
+	if !info.Jailed(release-1) || info.Jailed(release) {
+		t.Errorf("jail span mismatch: release %d", info.ReleaseEpoch)
+	}
+	staker, _ := vm.GetStakerInfo(stateDb, inactive)
+	if staker.Amount.Uint64() != 900 || staker.StakeAmount.Uint64() != 4500 {
+		t.Errorf("stake not burnt: amount %v, stake amount %v", staker.Amount, staker.StakeAmount)
+	}
+	if balance := stateDb.GetBalance(vm.WanCscPrecompileAddr); balance.Uint64() != 1900 {
+		t.Errorf("balance mismatch: have %v, want 1900", balance)
+	}
+	events, err := vm.GetJailEvents(stateDb, epochID)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("jail events mismatch: have %v %v, want 1", events, err)
+	}
+	if e := events[0]; e.Address != inactive || e.Duties != 5 || e.Fulfilled != 2 || e.ReleaseEpoch != release || e.Burnt.Uint64() != 100 {
+		t.Errorf("jail event mismatch: %+v", e)
+	}
+
+	logs := stateDb.GetLogs(common.Hash{1})
+	if len(logs) != 1 {
+		t.Fatalf("jail logs mismatch: have %d, want 1", len(logs))
+	}
+	if l := logs[0]; l.Address != vm.WanCscPrecompileAddr || len(l.Topics) != 3 || l.Topics[1] != inactive.Hash() ||
+		l.Topics[2] != common.BigToHash(new(big.Int).SetUint64(epochID)) || len(l.Data) != 4*32 || l.BlockNumber != number.Uint64() {
+		t.Errorf("jail log mismatch: %+v", l)
+	}
+
+	// An epoch is judged once.
+	if !Jail(ctx, &config, stateDb, number, epochID) {
+		t.Fatal("jail rerun failed")
+	}
+	if info, _ := vm.GetJailInfo(stateDb, inactive); info.Count != 1 {
+		t.Errorf("epoch judged twice: %+v", info)
+	}
+	if logs := stateDb.GetLogs(common.Hash{1}); len(logs) != 1 {
+		t.Errorf("epoch logged twice: have %d logs, want 1", len(logs))
+	}
+
+	// The release is logged by the run of the epoch before it, in which both
+	// validators are active.
+	allActive := func(ctx *posctx.Context, stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+		return []common.Address{active, inactive}, []int{1, 1}
+	}
+	noProposer := func(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+		return nil, nil
+	}
+	setActivityInterface(allActive, noProposer, getSlotLeaderInfo)
+	stateDb.Prepare(common.Hash{2}, common.Hash{}, 0)
+	if !Jail(ctx, &config, stateDb, number, epochID+config.Epochs) {
+		t.Fatal("jail of the release epoch failed")
+	}
+	logs = stateDb.GetLogs(common.Hash{2})
+	if len(logs) != 1 {
+		t.Fatalf("release logs mismatch: have %d, want 1", len(logs))
+	}
+	if l := logs[0]; len(l.Topics) != 3 || l.Topics[1] != inactive.Hash() || l.Topics[2] != common.BigToHash(new(big.Int).SetUint64(release)) {
+		t.Errorf("release log mismatch: %+v", l)
+	}
+}
@@ -14,7 +14,7 @@ func TestAddRemain(t *testing.T) {
 
 	remainConst := big.NewInt(0).SetUint64(99885844748858447)
 
-	subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval)
+	subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval, 0)
 	fmt.Println(subsidy.String(), util.FromWin(subsidy))
 
 	fmt.Println(subsidyReductionInterval)
@@ -31,7 +31,7 @@ func TestAddRemain(t *testing.T) {
 		t.FailNow()
 	}
 
-	subsidy2 := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval)
+	subsidy2 := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval, 0)
 	fmt.Println(subsidy2.String(), util.FromWin(subsidy2))
 
 	subsidy2 = subsidy2.Sub(subsidy2, subsidy)
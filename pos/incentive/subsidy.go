@@ -4,8 +4,6 @@ import (
 	"math"
 	"math/big"
 
-	"github.com/wanchain/go-wanchain/pos/posconfig"
-
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/log"
 )
@@ -24,17 +22,17 @@ func calcBaseSubsidy(baseValue *big.Int) *big.Int {
 // should have. This is mainly used for determining how much the incentive for
 // newly generated blocks awards as well as validating the incentive for blocks
 // has the expected value.
-func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.Int {
+func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64, firstEpochId uint64) *big.Int {
 	if stateDb == nil {
 		log.SyslogErr("getBaseSubsidyTotalForEpoch with an empty stateDb")
 		return big.NewInt(0)
 	}
 
-	if epochID < posconfig.FirstEpochId {
+	if epochID < firstEpochId {
 		return big.NewInt(0)
 	}
 
-	epochIDOffset := epochID - posconfig.FirstEpochId
+	epochIDOffset := epochID - firstEpochId
 
 	baseSubsidy := calcBaseSubsidy(firstPeriodReward)
 
@@ -42,7 +40,7 @@ func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.In
 	baseSubsidyReduction := calcPercent(baseSubsidy, redutionRateNow*100.0)
 
 	log.Info("getBaseSubsidyTotalForEpoch",
-		"FirstEpochId", posconfig.FirstEpochId,
+		"FirstEpochId", firstEpochId,
 		"epochID", epochID,
 		"reduceTimes", epochIDOffset/subsidyReductionInterval,
 		"reduceRate", redutionRateNow,
@@ -60,23 +58,23 @@ func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.In
 }
 
 // calcWanFromFoundation returns subsidy Of Epoch from wan foundation by Wei
-func calcWanFromFoundation(stateDb *state.StateDB, epochID uint64) *big.Int {
+func calcWanFromFoundation(stateDb *state.StateDB, epochID uint64, firstEpochId uint64) *big.Int {
 	if stateDb == nil {
 		log.SyslogErr("calcWanFromFoundation with an empty stateDb")
 		return big.NewInt(0)
 	}
 
-	return getBaseSubsidyTotalForEpoch(stateDb, epochID)
+	return getBaseSubsidyTotalForEpoch(stateDb, epochID, firstEpochId)
 }
 
 // calculateIncentivePool returns subsidy of Epoch from all
-func calculateIncentivePool(stateDb *state.StateDB, epochID uint64) (total *big.Int, foundation *big.Int, gasPool *big.Int) {
+func calculateIncentivePool(stateDb *state.StateDB, epochID uint64, firstEpochId uint64) (total *big.Int, foundation *big.Int, gasPool *big.Int) {
 	if stateDb == nil {
 		log.SyslogErr("calculateIncentivePool with an empty stateDb")
 		return big.NewInt(0), big.NewInt(0), big.NewInt(0)
 	}
 
-	foundation = calcWanFromFoundation(stateDb, epochID)
+	foundation = calcWanFromFoundation(stateDb, epochID, firstEpochId)
 	gasPool = getEpochGas(stateDb, epochID)
 	total = big.NewInt(0).Add(foundation, gasPool)
 	return
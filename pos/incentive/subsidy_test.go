@@ -30,7 +30,7 @@ func TestGetBaseSubsidyTotal(t *testing.T) {
 	fmt.Println(subsidyReductionInterval)
 
 	for i := uint64(1); i < uint64(500); i++ {
-		subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval*i)
+		subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval*i, 0)
 		if subsidy.Uint64() == 0 {
 			fmt.Println("finish", i)
 			return
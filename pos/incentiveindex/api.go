@@ -0,0 +1,130 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package incentiveindex
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// maxHistoryEpochs is the largest epoch range of a history query.
+const maxHistoryEpochs = 10000
+
+var (
+	errHistoryRange = errors.New("invalid epoch range")
+	errNotIndexed   = errors.New("no incentive payment executed by this node yet")
+)
+
+// IncentivePayment is an incentive paid to an address in an epoch.
+type IncentivePayment struct {
+	EpochID     uint64                `json:"epochId"`
+	BlockNumber uint64                `json:"blockNumber"`
+	Validator   common.Address        `json:"validator"`
+	Type        string                `json:"type"` // validator or delegator
+	Incentive   *math.HexOrDecimal256 `json:"incentive"`
+}
+
+// IncentiveHistory is the incentives paid to an address in a range of epochs.
+type IncentiveHistory struct {
+	Address          common.Address        `json:"address"`
+	FromEpoch        uint64                `json:"fromEpoch"`
+	ToEpoch          uint64                `json:"toEpoch"`
+	FirstEpoch       uint64                `json:"firstEpoch"`   // payments from this epoch are indexed
+	IndexedBlock     uint64                `json:"indexedBlock"` // payments up to this block are indexed
+	Total            *math.HexOrDecimal256 `json:"total"`
+	TotalAsValidator *math.HexOrDecimal256 `json:"totalAsValidator"`
+	TotalAsDelegator *math.HexOrDecimal256 `json:"totalAsDelegator"`
+	Payments         []IncentivePayment    `json:"payments"`
+}
+
+// PublicIncentiveAPI serves the incentive history of the index.
+type PublicIncentiveAPI struct {
+	indexer *core.ChainIndexer
+	db      ethdb.Database
+	ctx     *posctx.Context
+}
+
+// NewPublicIncentiveAPI returns the API of an index created by New.
+func NewPublicIncentiveAPI(indexer *core.ChainIndexer, chainDb ethdb.Database, ctx *posctx.Context) *PublicIncentiveAPI {
+	return &PublicIncentiveAPI{indexer, ethdb.NewTable(chainDb, string(IndexPrefix)), ctx}
+}
+
+// APIs returns the RPC of the index.
+func APIs(indexer *core.ChainIndexer, chainDb ethdb.Database, ctx *posctx.Context) []rpc.API {
+	return []rpc.API{{
+		Namespace: "pos",
+		Version:   "1.0",
+		Service:   NewPublicIncentiveAPI(indexer, chainDb, ctx),
+		Public:    true,
+	}}
+}
+
+// GetIncentiveHistory returns the incentives paid to an address for the
+// epochs fromEpoch to toEpoch, with their totals. Only the epochs paid by the
+// blocks this node executed are indexed, the queries of earlier epochs fail.
+func (api *PublicIncentiveAPI) GetIncentiveHistory(addr common.Address, fromEpoch uint64, toEpoch uint64) (*IncentiveHistory, error) {
+	if toEpoch < fromEpoch || toEpoch-fromEpoch >= maxHistoryEpochs {
+		return nil, errHistoryRange
+	}
+	first, ok := incentive.FirstPayDetailEpoch(api.ctx)
+	if !ok {
+		return nil, errNotIndexed
+	}
+	if fromEpoch < first {
+		return nil, fmt.Errorf("incentives are indexed from epoch %d, the earlier blocks weren't executed by this node", first)
+	}
+	var (
+		total     = new(big.Int)
+		validator = new(big.Int)
+		delegator = new(big.Int)
+	)
+	history := &IncentiveHistory{
+		Address:    addr,
+		FromEpoch:  fromEpoch,
+		ToEpoch:    toEpoch,
+		FirstEpoch: first,
+		Payments:   make([]IncentivePayment, 0),
+	}
+	if sections, head, _ := api.indexer.Sections(); sections != 0 {
+		history.IndexedBlock = head
+	}
+	for epochID := fromEpoch; epochID <= toEpoch; epochID++ {
+		payments, err := readPayments(api.db, addr, epochID)
+		if err != nil {
+			return nil, err
+		}
+		if payments == nil {
+			continue
+		}
+		for _, pay := range payments.Payments {
+			payType := "validator"
+			if pay.Delegator {
+				payType = "delegator"
+				delegator.Add(delegator, pay.Amount)
+			} else {
+				validator.Add(validator, pay.Amount)
+			}
+			total.Add(total, pay.Amount)
+			history.Payments = append(history.Payments, IncentivePayment{
+				EpochID:     epochID,
+				BlockNumber: payments.BlockNumber,
+				Validator:   pay.Validator,
+				Type:        payType,
+				Incentive:   (*math.HexOrDecimal256)(pay.Amount),
+			})
+		}
+	}
+	history.Total = (*math.HexOrDecimal256)(total)
+	history.TotalAsValidator = (*math.HexOrDecimal256)(validator)
+	history.TotalAsDelegator = (*math.HexOrDecimal256)(delegator)
+	return history, nil
+}
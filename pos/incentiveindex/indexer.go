@@ -0,0 +1,204 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package incentiveindex indexes the incentives paid to each address on the
+// canonical chain.
+package incentiveindex
+
+import (
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+const (
+	// SectionSize is the number of blocks of an index section.
+	SectionSize = 4096
+
+	// confirms is the number of confirmation blocks before a section is
+	// indexed, to avoid indexing payments of side chains.
+	confirms = 256
+
+	// throttling is the time to wait between processing two sections.
+	throttling = 100 * time.Millisecond
+)
+
+var (
+	// IndexPrefix is the table of the index in the chain database.
+	IndexPrefix = []byte("iI")
+
+	paymentsPrefix = []byte("p") // paymentsPrefix + address + epoch (uint64 big endian) -> epoch payments
+	sectionPrefix  = []byte("k") // sectionPrefix + section (uint64 big endian) -> payment keys of the section
+)
+
+// payment is an incentive paid to an address in an epoch.
+type payment struct {
+	Validator common.Address
+	Delegator bool // paid as a delegator of Validator, else as Validator
+	Amount    *big.Int
+}
+
+// epochPayments are the incentives of an epoch paid to an address.
+type epochPayments struct {
+	BlockNumber uint64 // block paying the epoch
+	Payments    []payment
+}
+
+// add adds an incentive to the payments, merging it with the one of the
+// same validator and role.
+func (p *epochPayments) add(validator common.Address, delegator bool, amount *big.Int) {
+	for i := range p.Payments {
+		if p.Payments[i].Validator == validator && p.Payments[i].Delegator == delegator {
+			p.Payments[i].Amount.Add(p.Payments[i].Amount, amount)
+			return
+		}
+	}
+	p.Payments = append(p.Payments, payment{validator, delegator, new(big.Int).Set(amount)})
+}
+
+func paymentsKey(addr common.Address, epochID uint64) []byte {
+	key := make([]byte, len(paymentsPrefix)+common.AddressLength+8)
+	n := copy(key, paymentsPrefix)
+	n += copy(key[n:], addr[:])
+	binary.BigEndian.PutUint64(key[n:], epochID)
+	return key
+}
+
+func sectionKey(section uint64) []byte {
+	key := make([]byte, len(sectionPrefix)+8)
+	binary.BigEndian.PutUint64(key[copy(key, sectionPrefix):], section)
+	return key
+}
+
+// readPayments returns the payments of an epoch to an address, nil if none
+// are indexed.
+func readPayments(db ethdb.Database, addr common.Address, epochID uint64) (*epochPayments, error) {
+	data, _ := db.Get(paymentsKey(addr, epochID))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	payments := new(epochPayments)
+	if err := rlp.DecodeBytes(data, payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// Indexer implements core.ChainIndexerBackend, indexing the incentives paid
+// per address and epoch. The payments of a block are found through the pay
+// detail the incentive package records by parent hash, so only the blocks
+// this node executed are indexed, from incentive.FirstPayDetailEpoch.
+type Indexer struct {
+	ctx  *posctx.Context
+	db   ethdb.Database // index table
+	size uint64
+
+	section  uint64
+	payments map[string]*epochPayments // payments of the section by key
+}
+
+// New returns a chain indexer of the incentives paid per address.
+func New(chainDb ethdb.Database, ctx *posctx.Context, size uint64) *core.ChainIndexer {
+	table := ethdb.NewTable(chainDb, string(IndexPrefix))
+	backend := &Indexer{
+		ctx:  ctx,
+		db:   table,
+		size: size,
+	}
+	return core.NewChainIndexer(chainDb, table, backend, size, confirms, throttling, "incentives")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (idx *Indexer) Reset(section uint64) {
+	idx.section = section
+	idx.payments = make(map[string]*epochPayments)
+}
+
+// Process implements core.ChainIndexerBackend, adding the incentives paid by
+// a header to the index.
+func (idx *Indexer) Process(header *types.Header) {
+	if !util.IsPosBlock(header.Number.Uint64()) {
+		return
+	}
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	if epochID < posconfig.IncentiveDelayEpochs {
+		return
+	}
+	paidEpoch := epochID - posconfig.IncentiveDelayEpochs
+	pays, err := incentive.GetParentPayDetail(idx.ctx, paidEpoch, header.ParentHash)
+	if err != nil {
+		log.Error("Failed to read incentive pay detail", "number", header.Number, "epochID", paidEpoch, "err", err)
+		return
+	}
+	for _, group := range pays {
+		for i, pay := range group {
+			if pay.Incentive == nil || pay.Incentive.Sign() == 0 {
+				continue
+			}
+			key := string(paymentsKey(pay.WalletAddr, paidEpoch))
+			payments, ok := idx.payments[key]
+			if !ok {
+				payments = &epochPayments{BlockNumber: header.Number.Uint64()}
+				idx.payments[key] = payments
+			}
+			// The first incentive of a group pays the validator, the
+			// others its delegators.
+			payments.add(pay.ValidatorAddr, i > 0, pay.Incentive)
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, writing the payments of the
+// section and dropping those indexed by a former version of it.
+func (idx *Indexer) Commit() error {
+	if data, _ := idx.db.Get(sectionKey(idx.section)); len(data) != 0 {
+		var stale [][]byte
+		if err := rlp.DecodeBytes(data, &stale); err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if _, ok := idx.payments[string(key)]; ok {
+				continue
+			}
+			// The epoch may be paid in another section since the reorg.
+			payments := new(epochPayments)
+			if data, _ := idx.db.Get(key); len(data) != 0 && rlp.DecodeBytes(data, payments) == nil && payments.BlockNumber/idx.size != idx.section {
+				continue
+			}
+			if err := idx.db.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	batch := idx.db.NewBatch()
+	keys := make([][]byte, 0, len(idx.payments))
+	for key, payments := range idx.payments {
+		data, err := rlp.EncodeToBytes(payments)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put([]byte(key), data); err != nil {
+			return err
+		}
+		keys = append(keys, []byte(key))
+	}
+	data, err := rlp.EncodeToBytes(keys)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(sectionKey(idx.section), data); err != nil {
+		return err
+	}
+	return batch.Write()
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package incentiveindex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// putPayDetail records the payments of an epoch made by a child of parent,
+// as incentive.Run does.
+func putPayDetail(t *testing.T, ctx *posctx.Context, epochID uint64, parent common.Hash, payments [][]vm.ClientIncentive) {
+	buf, err := rlp.EncodeToBytes(payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Db(posconfig.IncentiveLocalDB).Put(epochID, "parent_pay_detail_"+parent.Hex(), buf)
+}
+
+// putFirstPayDetail records the earliest epoch with a pay detail, as
+// incentive.Run does.
+func putFirstPayDetail(ctx *posctx.Context, epochID uint64) {
+	ctx.Db(posconfig.IncentiveLocalDB).Put(0, "first_pay_detail", convert.Uint64ToBytes(epochID))
+}
+
+func payingHeader(number, epochID uint64, parent common.Hash) *types.Header {
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | 1<<8),
+		ParentHash: parent,
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	ctx := posctx.New("")
+	defer ctx.Close()
+	db, _ := ethdb.NewMemDatabase()
+
+	var (
+		validator = common.HexToAddress("0x01")
+		wallet    = common.HexToAddress("0x02")
+		delegator = common.HexToAddress("0x03")
+		parent    = common.HexToHash("0x04")
+	)
+	// The validator delegates to itself from its wallet.
+	putPayDetail(t, ctx, 10, parent, [][]vm.ClientIncentive{{
+		{ValidatorAddr: validator, WalletAddr: wallet, Incentive: big.NewInt(100)},
+		{ValidatorAddr: validator, WalletAddr: delegator, Incentive: big.NewInt(20)},
+		{ValidatorAddr: validator, WalletAddr: wallet, Incentive: big.NewInt(5)},
+	}})
+
+	indexer := New(db, ctx, 4)
+	defer indexer.Close()
+	idx := &Indexer{ctx: ctx, db: ethdb.NewTable(db, string(IndexPrefix)), size: 4}
+	idx.Reset(0)
+	idx.Process(payingHeader(1, 11, common.HexToHash("0x05"))) // another parent
+	idx.Process(payingHeader(2, 11, parent))
+	if err := idx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewPublicIncentiveAPI(indexer, db, ctx)
+	if _, err := api.GetIncentiveHistory(wallet, 0, 20); err != errNotIndexed {
+		t.Fatalf("unindexed error mismatch: have %v, want %v", err, errNotIndexed)
+	}
+	putFirstPayDetail(ctx, 10)
+	if _, err := api.GetIncentiveHistory(wallet, 9, 20); err == nil {
+		t.Fatal("no error for the epochs before the first indexed")
+	}
+	history, err := api.GetIncentiveHistory(wallet, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Payments) != 2 || history.Payments[0].EpochID != 10 || history.Payments[0].BlockNumber != 2 {
+		t.Fatalf("wallet payments mismatch: %+v", history.Payments)
+	}
+	if (*big.Int)(history.Total).Uint64() != 105 || (*big.Int)(history.TotalAsValidator).Uint64() != 100 || (*big.Int)(history.TotalAsDelegator).Uint64() != 5 {
+		t.Fatalf("wallet totals mismatch: %v %v %v", history.Total, history.TotalAsValidator, history.TotalAsDelegator)
+	}
+	if history, _ := api.GetIncentiveHistory(delegator, 10, 10); len(history.Payments) != 1 || history.Payments[0].Type != "delegator" {
+		t.Fatalf("delegator payments mismatch: %+v", history.Payments)
+	}
+	if _, err := api.GetIncentiveHistory(wallet, 20, 10); err != errHistoryRange {
+		t.Fatalf("reversed range error mismatch: have %v, want %v", err, errHistoryRange)
+	}
+
+	// A reorg of the section drops the payments of the former version.
+	idx.Reset(0)
+	idx.Process(payingHeader(2, 11, common.HexToHash("0x05")))
+	if err := idx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := api.GetIncentiveHistory(wallet, 10, 20); len(history.Payments) != 0 {
+		t.Fatalf("payments left after reorg: %+v", history.Payments)
+	}
+}
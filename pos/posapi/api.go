@@ -4,8 +4,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
-	"time"
 
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 
 	"github.com/wanchain/go-wanchain/pos/cfm"
@@ -32,7 +32,7 @@ import (
 	"github.com/wanchain/go-wanchain/internal/ethapi"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -62,6 +62,9 @@ type PosChainReader interface {
 
 	//get chain quality,return quality * 1000
 	ChainQuality(epochid uint64, slotid uint64) (uint64, error)
+
+	// PosContext returns the PoS context of the node.
+	PosContext() *posctx.Context
 }
 
 type PosApi struct {
@@ -83,10 +86,10 @@ func (a PosApi) Version() string {
 }
 
 func (a PosApi) GetSlotLeaderByEpochIDAndSlotID(epochID uint64, slotID uint64) string {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage."
 	}
-	slp, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochID, slotID)
+	slp, err := slotleader.GetSlotLeaderSelection(a.chain.PosContext()).GetSlotLeader(epochID, slotID)
 	if err != nil {
 		return err.Error()
 	}
@@ -94,13 +97,13 @@ func (a PosApi) GetSlotLeaderByEpochIDAndSlotID(epochID uint64, slotID uint64) s
 }
 
 func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
 	infoMap := make(map[string]string, 0)
 
-	selector := epochLeader.GetEpocher()
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
 
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
@@ -116,11 +119,11 @@ func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, err
 }
 
 func (a PosApi) GetEpochLeadersAddrByEpochID(epochID uint64) ([]common.Address, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
-	selector := epochLeader.GetEpocher()
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -140,10 +143,10 @@ func (a PosApi) GetEpochLeadersAddrByEpochID(epochID uint64) ([]common.Address,
 	return addres, nil
 }
 func (a PosApi) GetLeaderGroupByEpochID(epochID uint64) ([]LeaderJson, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -151,10 +154,10 @@ func (a PosApi) GetLeaderGroupByEpochID(epochID uint64) ([]LeaderJson, error) {
 }
 
 func (a PosApi) GetLocalPK() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	SLS := slotleader.GetSlotLeaderSelection()
+	SLS := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	if SLS == nil {
 		return "nil", errors.New("This function can not use in POW stage.")
 	}
@@ -167,24 +170,24 @@ func (a PosApi) GetLocalPK() (string, error) {
 }
 
 func (a PosApi) GetBootNodePK() string {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage."
 	}
 	return posconfig.GenesisPK
 }
 
 func (a PosApi) GetSlotScCallTimesByEpochID(epochID uint64) uint64 {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0
 	}
-	return vm.GetSlotScCallTimes(epochID)
+	return vm.GetSlotScCallTimes(a.chain.PosContext(), epochID)
 }
 
 func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	pks, _, err := slotleader.GetSlotLeaderSelection().GetSma(epochID)
+	pks, _, err := slotleader.GetSlotLeaderSelection(a.chain.PosContext()).GetSma(epochID)
 	if err != nil {
 		return nil, err
 	}
@@ -199,10 +202,10 @@ func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
 }
 
 func (a PosApi) GetRandomProposersByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -217,10 +220,10 @@ func (a PosApi) GetRandomProposersByEpochID(epochID uint64) (map[string]string,
 }
 
 func (a PosApi) GetRandomProposersAddrByEpochID(epochID uint64) ([]common.Address, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -235,14 +238,14 @@ func (a PosApi) GetRandomProposersAddrByEpochID(epochID uint64) ([]common.Addres
 }
 
 func (a PosApi) GetSlotCreateStatusByEpochID(epochID uint64) bool {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return false
 	}
-	return slotleader.GetSlotLeaderSelection().GetSlotCreateStatusByEpochID(epochID)
+	return slotleader.GetSlotLeaderSelection(a.chain.PosContext()).GetSlotCreateStatusByEpochID(epochID)
 }
 
 func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
@@ -261,7 +264,7 @@ func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
 		return nil, err
 	}
 
-	r := vm.GetStateR(state, epochId)
+	r := vm.GetStateR(state, epochId, a.chain.PosContext().FirstEpochId())
 	if r == nil {
 		return nil, errors.New("no random number exists")
 	}
@@ -270,17 +273,17 @@ func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
 }
 
 func (a PosApi) GetChainQuality(epochid uint64, slotid uint64) (uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 1000, nil
 	}
 	return a.chain.ChainQuality(epochid, slotid)
 }
 
 func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
+	reOrgDb := a.chain.PosContext().Db(posconfig.ReorgLocalDB)
 	if reOrgDb == nil {
 		return []uint64{0, 0}, nil
 	}
@@ -303,7 +306,7 @@ func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
 }
 
 func (a PosApi) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0, nil
 	}
 
@@ -330,7 +333,7 @@ func (a PosApi) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error)
 
 func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (ApiStakerInfo, error) {
 	skInfo := ApiStakerInfo{}
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	if epocherInst == nil {
 		return skInfo, errors.New("epocher instance does not exist")
 	}
@@ -338,6 +341,13 @@ func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (ApiStak
 	if err != nil {
 		return skInfo, err
 	}
+	skInfo = toApiStakerInfo(validator)
+	skInfo.Addr = addr
+	return skInfo, nil
+}
+
+func toApiStakerInfo(validator *vm.ValidatorInfo) ApiStakerInfo {
+	skInfo := ApiStakerInfo{}
 	skInfo.TotalProbability = (*math.HexOrDecimal256)(validator.TotalProbability)
 	skInfo.FeeRate = validator.FeeRate
 	skInfo.Infors = make([]ApiClientProbability, len(validator.Infos))
@@ -349,14 +359,14 @@ func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (ApiStak
 		}
 		skInfo.Infors[i].Probability = (*math.HexOrDecimal256)(validator.Infos[i].Probability)
 	}
-	skInfo.Addr = addr
-	return skInfo, nil
+	skInfo.Addr = validator.ValidatorAddr
+	return skInfo
 }
 
 // this is the static snap of stekers by the block Number.
 func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 	stakers := make([]*StakerJson, 0)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	if epocherInst == nil {
 		return stakers, errors.New("epocher instance do not exist")
 	}
@@ -377,44 +387,40 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 			log.SyslogErr(err.Error())
 			return true
 		}
-		stakeJson := ToStakerJson(&staker)
-		// add NextFeeRate MaxFeeRate
-		keyFee := vm.GetStakeInKeyHash(staker.Address)
-		newFeeBytes, err := vm.GetInfo(stateDb, vm.StakersFeeAddr, keyFee)
-		if err == nil && newFeeBytes != nil {
-			var newFee vm.UpdateFeeRate
-			err = rlp.DecodeBytes(newFeeBytes, &newFee)
-			if err == nil {
-				stakeJson.MaxFeeRate = newFee.MaxFeeRate
-				stakeJson.FeeRateChangedEpoch = newFee.ChangedEpoch
-			} else {
-				stakeJson.MaxFeeRate = staker.FeeRate
-				stakeJson.FeeRateChangedEpoch = 0
-			}
-		} else {
-			stakeJson.MaxFeeRate = staker.FeeRate
-			stakeJson.FeeRateChangedEpoch = 0
-		}
-
-		stakers = append(stakers, stakeJson)
+		stakers = append(stakers, toStakerJsonAt(stateDb, &staker))
 		return true
 	})
 	return stakers, nil
 }
 
-func isPosStage() bool {
-	return posconfig.FirstEpochId != 0
+// toStakerJsonAt converts a staker of a state, with its fee rate changes.
+func toStakerJsonAt(stateDb *state.StateDB, staker *vm.StakerInfo) *StakerJson {
+	stakeJson := ToStakerJson(staker)
+	// add NextFeeRate MaxFeeRate
+	newFee, err := vm.GetStakeFeeRate(stateDb, staker.Address)
+	if err == nil && newFee != nil {
+		stakeJson.MaxFeeRate = newFee.MaxFeeRate
+		stakeJson.FeeRateChangedEpoch = newFee.ChangedEpoch
+	} else {
+		stakeJson.MaxFeeRate = staker.FeeRate
+		stakeJson.FeeRateChangedEpoch = 0
+	}
+	return stakeJson
+}
+
+func (a PosApi) isPosStage() bool {
+	return a.chain.PosContext().FirstEpochId() != 0
 }
 
 func (a PosApi) GetPosInfo() (info PosInfoJson) {
-	info.FirstEpochId = posconfig.FirstEpochId
+	info.FirstEpochId = a.chain.PosContext().FirstEpochId()
 	info.FirstBlockNumber = posconfig.Pow2PosUpgradeBlockNumber
 	return
 }
 
 func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]ApiStakerInfo, error) {
-	targetBlkNum := epochLeader.GetEpocher().GetTargetBlkNumber(epochID)
-	epocherInst := epochLeader.GetEpocher()
+	targetBlkNum := epochLeader.GetEpocher(a.chain.PosContext()).GetTargetBlkNumber(epochID)
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	if epocherInst == nil {
 		return nil, errors.New("epocher instance do not exist")
 	}
@@ -436,7 +442,7 @@ func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]ApiStakerInfo, error) {
 			return true
 		}
 
-		infors, pb, err := epochLeader.CalEpochProbabilityStaker(&staker, epochID)
+		infors, pb, err := epochLeader.CalEpochProbabilityStaker(&staker, epochID, a.chain.PosContext().FirstEpochId())
 		if err != nil || pb == nil {
 			// this validator has no enough
 			return true
@@ -469,73 +475,48 @@ func biToString(value *big.Int, err error) (string, error) {
 }
 
 func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([]ValidatorInfo, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	c, err := incentive.GetEpochPayDetail(epochID)
+	c, err := incentive.GetEpochPayDetail(a.chain.PosContext(), epochID)
 	if err != nil {
 		return []ValidatorInfo{}, nil
 	}
-
-	ret := make([]ValidatorInfo, len(c))
-	for i := 0; i < len(c); i++ {
-		if len(c[i]) == 0 {
-			continue
-		}
-
-		delegators := make([]DelegatorInfo, len(c[i])-1)
-
-		for m := 1; m < len(c[i]); m++ {
-			delegators[m-1] = DelegatorInfo{}
-			delegators[m-1].Address = c[i][m].WalletAddr
-			delegators[m-1].Incentive = (*math.HexOrDecimal256)(c[i][m].Incentive)
-			delegators[m-1].Type = "delegator"
-		}
-
-		ret[i] = ValidatorInfo{
-			Address:       c[i][0].ValidatorAddr,
-			WalletAddress: c[i][0].WalletAddr,
-			Incentive:     (*math.HexOrDecimal256)(c[i][0].Incentive),
-			Type:          "validator",
-			Delegators:    delegators,
-		}
-	}
-
-	return ret, nil
+	return ToValidatorInfos(c), nil
 }
 
 func (a PosApi) GetTotalIncentive() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetTotalIncentive())
+	return biToString(incentive.GetTotalIncentive(a.chain.PosContext()))
 }
 func (a PosApi) GetEpochIncentiveBlockNumber(epochID uint64) (uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0, nil
 	}
-	number, err := incentive.GetEpochIncentiveBlockNumber(epochID)
+	number, err := incentive.GetEpochIncentiveBlockNumber(a.chain.PosContext(), epochID)
 	if err == nil {
 		return number.Uint64(), nil
 	}
 	return 0, err
 }
 func (a PosApi) GetEpochIncentive(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetEpochIncentive(epochID))
+	return biToString(incentive.GetEpochIncentive(a.chain.PosContext(), epochID))
 }
 
 func (a PosApi) GetEpochRemain(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetEpochRemain(epochID))
+	return biToString(incentive.GetEpochRemain(a.chain.PosContext(), epochID))
 }
 
 func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	infos := make(vm.WhiteInfos, 0)
 	if epocherInst == nil {
 		return infos, errors.New("epocher instance do not exist")
@@ -563,7 +544,7 @@ func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error)
 }
 
 func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	if epocherInst == nil {
 		return make([]string, 0), errors.New("epocher instance do not exist")
 	}
@@ -571,24 +552,24 @@ func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
 }
 
 func (a PosApi) GetTotalRemain() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetTotalRemain())
+	return biToString(incentive.GetTotalRemain(a.chain.PosContext()))
 }
 
 func (a PosApi) GetIncentiveRunTimes() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetRunTimes())
+	return biToString(incentive.GetRunTimes(a.chain.PosContext()))
 }
 
 func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return "", err
@@ -597,38 +578,38 @@ func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
 }
 
 func (a PosApi) GetRBAddress(epochID uint64) []common.Address {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil
 	}
 	return incentive.GetRBAddress(epochID)
 }
 
 func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
-	total, foundation, gasPool := incentive.GetIncentivePool(db, epochID)
+	total, foundation, gasPool := incentive.GetIncentivePool(a.chain.PosContext(), db, epochID)
 	return []string{total.String(), foundation.String(), gasPool.String()}, nil
 }
 
 // GetActivity get epoch leader, random proposer, slot leader 's addresses and activity
 func (a PosApi) GetActivity(epochID uint64) (*Activity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := Activity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(a.chain.PosContext(), db, epochID)
 	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
 	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
@@ -636,27 +617,27 @@ func (a PosApi) GetActivity(epochID uint64) (*Activity, error) {
 
 // GetEpRnpActivity get epoch leader, random leader proposer activity
 func (a PosApi) GetEpRnpActivity(epochID uint64) (*EpRnpActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := EpRnpActivity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(a.chain.PosContext(), db, epochID)
 	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
 	return &activity, nil
 }
 
 // GetSlotActivity get slot activity of epoch
 func (a PosApi) GetSlotActivity(epochID uint64) (*SlotActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	activity := SlotActivity{}
 	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
@@ -664,7 +645,7 @@ func (a PosApi) GetSlotActivity(epochID uint64) (*SlotActivity, error) {
 
 // GetValidatorActivity get epoch leader, random proposer addresses and activity
 func (a PosApi) GetValidatorActivity(epochID uint64) (*ValidatorActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 	epID := a.GetEpochID()
@@ -672,14 +653,14 @@ func (a PosApi) GetValidatorActivity(epochID uint64) (*ValidatorActivity, error)
 		return nil, nil
 	}
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := ValidatorActivity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(a.chain.PosContext(), db, epochID)
 	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
 	if len(activity.EpLeader) == 0 &&
 		len(activity.EpActivity) == 0 &&
@@ -692,12 +673,12 @@ func (a PosApi) GetValidatorActivity(epochID uint64) (*ValidatorActivity, error)
 }
 
 func (a PosApi) GetEpochID() uint64 {
-	ep, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	ep, _ := util.CalEpochSlotID(a.chain.PosContext().Now())
 	return ep
 }
 
 func (a PosApi) GetSlotID() uint64 {
-	_, sl := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	_, sl := util.CalEpochSlotID(a.chain.PosContext().Now())
 	return sl
 }
 
@@ -710,17 +691,17 @@ func (a PosApi) GetSlotTime() int {
 }
 
 func (a PosApi) GetMaxStableBlkNumber() uint64 {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0
 	}
-	return cfm.GetCFM().GetMaxStableBlkNumber()
+	return cfm.GetCFM(a.chain.PosContext()).GetMaxStableBlkNumber()
 }
 
 // CalProbability use to calc the probability of a staker with amount by stake wan coins.
 // The probability is different in different time, so you should input each epoch ID you want to calc
 // Such as CalProbability(390, 10000, 60, 360) means begin from epoch 360 lock 60 epochs stake 10000 to calc 390's probability.
 func (a PosApi) CalProbability(amountCoin uint64, lockTime uint64) (string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
 	if epocherInst == nil {
 		return "", errors.New("epocher instance do not exist")
 	}
@@ -932,7 +913,7 @@ func (a PosApi) GetEpochIdByBlockNumber(blockNumber uint64) uint64 {
 }
 
 func (a PosApi) GetEpochStakeOut(epochID uint64) ([]RefundInfo, error) {
-	stakeOutByte, err := posdb.GetDb().Get(epochID, posconfig.StakeOutEpochKey)
+	stakeOutByte, err := a.chain.PosContext().LocalDb().Get(epochID, posconfig.StakeOutEpochKey)
 	if err != nil {
 		//return nil, err
 		info := make([]RefundInfo, 0)
@@ -950,7 +931,7 @@ func (a PosApi) GetEpochStakeOut(epochID uint64) ([]RefundInfo, error) {
 // GetTps used to get tps value
 func (a PosApi) GetTps(fromNumber uint64, toNumber uint64) (string, error) {
 	sRet := fmt.Sprintf("Get tps from %d to %d, ", fromNumber, toNumber)
-	s := slotleader.GetSlotLeaderSelection()
+	s := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
 	reader := s.GetChainReader()
 
 	totalTx := uint64(0)
@@ -0,0 +1,341 @@
+package posapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// The duties of a validator in an epoch.
+const (
+	DutySlot     = "slot"     // seal the block of a slot
+	DutySma1     = "sma1"     // send the slot leader selection stage one tx
+	DutySma2     = "sma2"     // send the slot leader selection stage two tx
+	DutyDkg1     = "dkg1"     // send the random beacon dkg1 tx
+	DutyDkg2     = "dkg2"     // send the random beacon dkg2 tx
+	DutySigShare = "sigShare" // send the random beacon sigShare tx
+)
+
+// The events of the duty subscriptions.
+const (
+	DutyEventDuties   = "duties"   // the duties of a new epoch
+	DutyEventUpcoming = "upcoming" // a duty starts within dutyLeadSlots slots
+	DutyEventIncluded = "included" // the block or tx of a duty is on chain
+	DutyEventMissed   = "missed"   // the window of a duty ended without it
+)
+
+const (
+	// dutyLeadSlots is how many slots before a duty starts the subscribers are
+	// warned of it.
+	dutyLeadSlots = 12
+
+	// dutyGraceSlots is how many slots after the window of a duty its block
+	// or tx may still be imported before it's reported missed.
+	dutyGraceSlots = 2
+)
+
+var errNotPosStage = errors.New("not POS stage")
+
+// Duty is a block or tx a validator is to send within a window of slots.
+type Duty struct {
+	Type      string `json:"type"`
+	Index     uint64 `json:"index"` // slot ID, epoch leader index or random proposer ID
+	StartSlot uint64 `json:"startSlot"`
+	EndSlot   uint64 `json:"endSlot"`
+	StartTime uint64 `json:"startTime"` // time the first slot of the window starts
+	EndTime   uint64 `json:"endTime"`   // time the last slot of the window ends
+	Included  bool   `json:"included"`
+}
+
+// Duties are the duties of a validator in an epoch, sorted by window.
+type Duties struct {
+	Address    common.Address `json:"address"`
+	EpochID    uint64         `json:"epochId"`
+	SlotsKnown bool           `json:"slotsKnown"` // false before the epoch begins
+	Duties     []*Duty        `json:"duties"`
+}
+
+// DutyEvent is a notification of a duty subscription.
+type DutyEvent struct {
+	Event   string  `json:"event"`
+	EpochID uint64  `json:"epochId"`
+	SlotID  uint64  `json:"slotId"` // slot the event happened in
+	Duty    *Duty   `json:"duty,omitempty"`
+	Duties  *Duties `json:"duties,omitempty"`
+}
+
+func newDuty(typ string, index, epochID, startSlot, endSlot uint64) *Duty {
+	epochStart := epochID * posconfig.SlotCount
+	return &Duty{
+		Type:      typ,
+		Index:     index,
+		StartSlot: startSlot,
+		EndSlot:   endSlot,
+		StartTime: (epochStart + startSlot) * posconfig.SlotTime,
+		EndTime:   (epochStart + endSlot + 1) * posconfig.SlotTime,
+	}
+}
+
+// stageIncluded reports whether the slot leader selection or random beacon tx
+// of a duty is in stateDb.
+func stageIncluded(stateDb vm.StateDB, epochID uint64, duty *Duty) bool {
+	epochIDBuf, indexBuf := convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(duty.Index)
+	switch duty.Type {
+	case DutySma1:
+		return stateDb.GetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage1KeyHash(epochIDBuf, indexBuf)) != nil
+	case DutySma2:
+		return stateDb.GetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage2KeyHash(epochIDBuf, indexBuf)) != nil
+	case DutyDkg1:
+		cji, err := vm.GetCji(stateDb, epochID, uint32(duty.Index))
+		return err == nil && len(cji) != 0
+	case DutyDkg2:
+		share, err := vm.GetEncryptShare(stateDb, epochID, uint32(duty.Index))
+		return err == nil && len(share) != 0
+	case DutySigShare:
+		sig, err := vm.GetSig(stateDb, epochID, uint32(duty.Index))
+		return err == nil && sig != nil
+	}
+	return false
+}
+
+// sealedSlots returns the slots of an epoch the canonical chain has a block of
+// addr for.
+func (a PosApi) sealedSlots(addr common.Address, epochID uint64) map[uint64]bool {
+	sealed := make(map[uint64]bool)
+	head := a.chain.CurrentHeader()
+	if head == nil {
+		return sealed
+	}
+
+	// Skip the later epochs a slot count of blocks at a time, the chain has at
+	// most a block per slot
+	step := uint64(posconfig.SlotCount)
+	num := head.Number.Uint64()
+	for num > step && util.IsPosBlock(num-step) {
+		header := a.chain.GetHeaderByNumber(num - step)
+		if header == nil {
+			break
+		}
+		if epID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); epID <= epochID {
+			break
+		}
+		num -= step
+	}
+
+	for ; num >= util.FirstPosBlockNumber() && num != 0; num-- {
+		header := a.chain.GetHeaderByNumber(num)
+		if header == nil {
+			break
+		}
+		epID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		if epID < epochID {
+			break
+		}
+		if epID == epochID && header.Coinbase == addr {
+			sealed[slotID] = true
+		}
+	}
+	return sealed
+}
+
+// GetDuties returns the slots a validator is the leader of in an epoch and the
+// windows of its slot leader selection and random beacon txs, with whether
+// each block or tx is on chain. The slots are only known from the beginning of
+// the epoch.
+func (a PosApi) GetDuties(ctx context.Context, addr common.Address, epochID uint64) (*Duties, error) {
+	if !a.isPosStage() {
+		return nil, errNotPosStage
+	}
+	selector := epochLeader.GetEpocher(a.chain.PosContext())
+	sls := slotleader.GetSlotLeaderSelection(a.chain.PosContext())
+	if selector == nil || sls == nil {
+		return nil, errors.New("GetEpocherInst error")
+	}
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+
+	duties := &Duties{Address: addr, EpochID: epochID, Duties: make([]*Duty, 0)}
+	leaders, err := sls.GetSlotLeaders(epochID)
+	if err != nil && err != slotleader.ErrFutureEpoch {
+		return nil, err
+	}
+	if err == nil {
+		duties.SlotsKnown = true
+		sealed := a.sealedSlots(addr, epochID)
+		for i, leader := range leaders {
+			if leader == nil || crypto.PubkeyToAddress(*leader) != addr {
+				continue
+			}
+			slotID := uint64(i)
+			duty := newDuty(DutySlot, slotID, epochID, slotID, slotID)
+			duty.Included = sealed[slotID]
+			duties.Duties = append(duties.Duties, duty)
+		}
+	}
+
+	for i, pk := range selector.GetEpochLeaders(epochID) {
+		pub := crypto.ToECDSAPub(pk)
+		if pub == nil || crypto.PubkeyToAddress(*pub) != addr {
+			continue
+		}
+		duties.Duties = append(duties.Duties,
+			newDuty(DutySma1, uint64(i), epochID, posconfig.Sma1Start, posconfig.Sma1End),
+			newDuty(DutySma2, uint64(i), epochID, posconfig.Sma2Start, posconfig.Sma2End))
+	}
+	cfg := posconfig.Cfg()
+	for i, proposer := range selector.GetRBProposerGroup(epochID) {
+		if proposer.SecAddr != addr {
+			continue
+		}
+		duties.Duties = append(duties.Duties,
+			newDuty(DutyDkg1, uint64(i), epochID, 0, cfg.Dkg1End),
+			newDuty(DutyDkg2, uint64(i), epochID, cfg.Dkg2Begin, cfg.Dkg2End),
+			newDuty(DutySigShare, uint64(i), epochID, cfg.SignBegin, cfg.SignEnd))
+	}
+	for _, duty := range duties.Duties {
+		if duty.Type != DutySlot {
+			duty.Included = stageIncluded(stateDb, epochID, duty)
+		}
+	}
+
+	sort.SliceStable(duties.Duties, func(i, j int) bool {
+		return duties.Duties[i].StartSlot < duties.Duties[j].StartSlot
+	})
+	return duties, nil
+}
+
+// dutyKey identifies a duty of an epoch.
+type dutyKey struct {
+	typ   string
+	index uint64
+}
+
+// SubscribeDuties creates a subscription to the duties of a validator, e.g.
+// pos_subscribe("subscribeDuties", address). It notifies the duties of each
+// epoch as it begins, then each duty dutyLeadSlots slots before its window,
+// once its block or tx is on chain and once its window passes without it.
+func (a PosApi) SubscribeDuties(ctx context.Context, addr common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		heads := make(chan core.ChainHeadEvent, 16)
+		headSub := a.backend.SubscribeChainHeadEvent(heads)
+		defer headSub.Unsubscribe()
+
+		ticker := time.NewTicker(posconfig.SlotTime * time.Second)
+		defer ticker.Stop()
+
+		var (
+			duties   *Duties
+			notified = make(map[dutyKey]string)
+		)
+		update := func(head *types.Header) {
+			epochID, slotID := util.CalEpochSlotID(a.chain.PosContext().Now())
+
+			// Fetch the duties of a new epoch, again while its slots are unknown
+			if duties == nil || duties.EpochID != epochID || !duties.SlotsKnown {
+				newDuties, err := a.GetDuties(context.Background(), addr, epochID)
+				if err != nil {
+					return
+				}
+				if duties == nil || duties.EpochID != epochID || newDuties.SlotsKnown {
+					notifier.Notify(rpcSub.ID, &DutyEvent{Event: DutyEventDuties, EpochID: epochID, SlotID: slotID, Duties: newDuties})
+
+					// The duties event already tells the past ones
+					notified = make(map[dutyKey]string)
+					for _, duty := range newDuties.Duties {
+						if duty.Included {
+							notified[dutyKey{duty.Type, duty.Index}] = DutyEventIncluded
+						} else if slotID > duty.EndSlot+dutyGraceSlots {
+							notified[dutyKey{duty.Type, duty.Index}] = DutyEventMissed
+						}
+					}
+				}
+				duties = newDuties
+			}
+
+			var (
+				stateDb vm.StateDB
+				sealed  map[uint64]bool
+			)
+			if head != nil {
+				stateDb, _, _ = a.backend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+			}
+			for _, duty := range duties.Duties {
+				key := dutyKey{duty.Type, duty.Index}
+				event := notified[key]
+				if event == DutyEventIncluded || event == DutyEventMissed {
+					continue
+				}
+				switch {
+				case duty.Included:
+				case duty.Type == DutySlot && head != nil:
+					epID, slID := util.GetEpochSlotIDFromDifficulty(head.Difficulty)
+					duty.Included = epID == epochID && slID == duty.Index && head.Coinbase == addr
+				case duty.Type != DutySlot && stateDb != nil && slotID >= duty.StartSlot:
+					duty.Included = stageIncluded(stateDb, epochID, duty)
+				}
+				// A head event may be missed, look for the block in the chain
+				if duty.Type == DutySlot && !duty.Included && slotID > duty.EndSlot+dutyGraceSlots {
+					if sealed == nil {
+						sealed = a.sealedSlots(addr, epochID)
+					}
+					duty.Included = sealed[duty.Index]
+				}
+
+				switch {
+				case duty.Included:
+					event = DutyEventIncluded
+				case slotID > duty.EndSlot+dutyGraceSlots:
+					event = DutyEventMissed
+				case event == "" && slotID+dutyLeadSlots >= duty.StartSlot:
+					event = DutyEventUpcoming
+				default:
+					continue
+				}
+				if notified[key] != event {
+					notified[key] = event
+					notifier.Notify(rpcSub.ID, &DutyEvent{Event: event, EpochID: epochID, SlotID: slotID, Duty: duty})
+				}
+			}
+		}
+
+		update(a.chain.CurrentHeader())
+		for {
+			select {
+			case ev := <-heads:
+				update(ev.Block.Header())
+			case <-ticker.C:
+				update(nil)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-headSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
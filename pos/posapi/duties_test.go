@@ -0,0 +1,38 @@
+package posapi
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+)
+
+func TestDuties(t *testing.T) {
+	epochID := uint64(18000)
+	duty := newDuty(DutySma2, 3, epochID, posconfig.Sma2Start, posconfig.Sma2End)
+	if eid, sid := util.CalEpochSlotID(duty.StartTime); eid != epochID || sid != posconfig.Sma2Start {
+		t.Errorf("window start mismatch: have %d/%d, want %d/%d", eid, sid, epochID, posconfig.Sma2Start)
+	}
+	if eid, sid := util.CalEpochSlotID(duty.EndTime - 1); eid != epochID || sid != posconfig.Sma2End {
+		t.Errorf("window end mismatch: have %d/%d, want %d/%d", eid, sid, epochID, posconfig.Sma2End)
+	}
+
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	if stageIncluded(stateDb, epochID, duty) {
+		t.Fatal("stage two tx included in an empty state")
+	}
+	keyHash := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(3))
+	stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), keyHash, []byte{1})
+	if !stageIncluded(stateDb, epochID, duty) {
+		t.Error("stage two tx not included")
+	}
+	if stageIncluded(stateDb, epochID+1, duty) || stageIncluded(stateDb, epochID, newDuty(DutySma1, 3, epochID, 0, 0)) {
+		t.Error("stage two tx of another duty included")
+	}
+}
@@ -0,0 +1,214 @@
+package posapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// defaultStakeGas is the gas limit of a staking tx if none is given.
+const defaultStakeGas = 200000
+
+var errUnknownStakeMethod = errors.New("unknown staking method")
+
+// StakeArgs are the arguments of a tx calling the staking contract. Method
+// is one of stakeIn, stakeRegister, stakeUpdate, stakeAppend, partnerIn,
+// delegateIn, delegateOut, stakeUpdateFeeRate and equivocationEvidence, and
+// picks the params used.
+type StakeArgs struct {
+	From     common.Address  `json:"from"`
+	Gas      *hexutil.Big    `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Nonce    *hexutil.Uint64 `json:"nonce"`
+
+	Method     string         `json:"method"`
+	SecPk      hexutil.Bytes  `json:"secPk"`      // stakeIn, stakeRegister
+	Bn256Pk    hexutil.Bytes  `json:"bn256Pk"`    // stakeIn, stakeRegister
+	LockEpochs uint64         `json:"lockEpochs"` // stakeIn, stakeRegister, stakeUpdate
+	FeeRate    uint64         `json:"feeRate"`    // stakeIn, stakeRegister, stakeUpdateFeeRate
+	MaxFeeRate uint64         `json:"maxFeeRate"` // stakeRegister
+	Addr       common.Address `json:"addr"`       // validator of the other methods
+	Renewal    bool           `json:"renewal"`    // partnerIn
+	Header1    hexutil.Bytes  `json:"header1"`    // equivocationEvidence, rlp encoded
+	Header2    hexutil.Bytes  `json:"header2"`    // equivocationEvidence, rlp encoded
+}
+
+// input packs the input of the staking contract call.
+func (args *StakeArgs) input() ([]byte, error) {
+	stakeIn := vm.StakeInParam{
+		SecPk:      args.SecPk,
+		Bn256Pk:    args.Bn256Pk,
+		LockEpochs: new(big.Int).SetUint64(args.LockEpochs),
+		FeeRate:    new(big.Int).SetUint64(args.FeeRate),
+	}
+	switch args.Method {
+	case "stakeIn":
+		return vm.PackStakeIn(&stakeIn)
+	case "stakeRegister":
+		return vm.PackStakeRegister(&vm.StakeRegisterParam{StakeInParam: stakeIn, MaxFeeRate: new(big.Int).SetUint64(args.MaxFeeRate)})
+	case "stakeUpdate":
+		return vm.PackStakeUpdate(&vm.StakeUpdateParam{Addr: args.Addr, LockEpochs: stakeIn.LockEpochs})
+	case "stakeAppend":
+		return vm.PackStakeAppend(args.Addr)
+	case "partnerIn":
+		return vm.PackPartnerIn(&vm.PartnerInParam{Addr: args.Addr, Renewal: args.Renewal})
+	case "delegateIn":
+		return vm.PackDelegateIn(&vm.DelegateParam{DelegateAddress: args.Addr})
+	case "delegateOut":
+		return vm.PackDelegateOut(&vm.DelegateParam{DelegateAddress: args.Addr})
+	case "stakeUpdateFeeRate":
+		return vm.PackStakeUpdateFeeRate(&vm.UpdateFeeRateParam{Addr: args.Addr, FeeRate: stakeIn.FeeRate})
+	case "equivocationEvidence":
+		return vm.PackEquivocationEvidence(&vm.EquivocationEvidenceParam{Header1: args.Header1, Header2: args.Header2})
+	}
+	return nil, errUnknownStakeMethod
+}
+
+// validator returns the address of the validator staked to.
+func (args *StakeArgs) validator() common.Address {
+	if args.Method == "stakeIn" || args.Method == "stakeRegister" {
+		if pub := crypto.ToECDSAPub(args.SecPk); pub != nil {
+			return crypto.PubkeyToAddress(*pub)
+		}
+	}
+	return args.Addr
+}
+
+// StakeTx is an unsigned staking tx.
+type StakeTx struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// StakeSimulation is the outcome of a staking tx run on the pending state.
+type StakeSimulation struct {
+	Tx *types.Transaction `json:"tx"`
+
+	ValidError string `json:"validError,omitempty"` // rejection by the tx pool
+	Error      string `json:"error,omitempty"`      // failure of the staking contract
+
+	StakerInfo       *StakerJson    `json:"stakerInfo"`
+	FirstEpochId     uint64         `json:"firstEpochId"` // first epoch the stake may be selected in
+	Probability      *ApiStakerInfo `json:"probability"`
+	ProbabilityError string         `json:"probabilityError,omitempty"` // why the validator can't be selected then
+}
+
+// stakeTx fills in the defaults of args and returns the unsigned tx.
+func (a PosApi) stakeTx(ctx context.Context, args *StakeArgs) (*types.Transaction, error) {
+	input, err := args.input()
+	if err != nil {
+		return nil, err
+	}
+	if args.Gas == nil {
+		args.Gas = (*hexutil.Big)(big.NewInt(defaultStakeGas))
+	}
+	if args.GasPrice == nil {
+		price, err := a.backend.SuggestPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args.GasPrice = (*hexutil.Big)(price)
+	}
+	if args.Value == nil {
+		args.Value = new(hexutil.Big)
+	}
+	if args.Nonce == nil {
+		nonce, err := a.backend.GetPoolNonce(ctx, args.From)
+		if err != nil {
+			return nil, err
+		}
+		args.Nonce = (*hexutil.Uint64)(&nonce)
+	}
+	return types.NewTransaction(uint64(*args.Nonce), vm.WanCscPrecompileAddr, (*big.Int)(args.Value), (*big.Int)(args.Gas), (*big.Int)(args.GasPrice), input), nil
+}
+
+// BuildStakeTx returns the unsigned tx of a call to the staking contract,
+// ready to be signed by args.From.
+func (a PosApi) BuildStakeTx(ctx context.Context, args StakeArgs) (*StakeTx, error) {
+	tx, err := a.stakeTx(ctx, &args)
+	if err != nil {
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &StakeTx{data, tx}, nil
+}
+
+// SimulateStake runs the staking tx of args on the pending state. It returns
+// why the tx pool or the staking contract rejects it, or else the staker info
+// of the validator afterwards with its probability in the first epoch the
+// stake may be selected in.
+func (a PosApi) SimulateStake(ctx context.Context, args StakeArgs) (*StakeSimulation, error) {
+	tx, err := a.stakeTx(ctx, &args)
+	if err != nil {
+		return nil, err
+	}
+	state, header, err := a.backend.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	sim := &StakeSimulation{Tx: tx}
+	signer := types.MakeSigner(a.backend.ChainConfig(), header.Number)
+	if state.GetBalance(args.From).Cmp(tx.Cost()) < 0 {
+		sim.ValidError = core.ErrInsufficientFunds.Error()
+	} else if err := (&vm.PosStaking{}).ValidTx(a.chain.PosContext(), state, signer, tx); err != nil {
+		sim.ValidError = err.Error()
+	}
+
+	// The EVM tops up the balance of the sender, the real one is checked.
+	msg := types.NewMessage(args.From, tx.To(), tx.Nonce(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data(), tx.AccessList(), false)
+	balance := new(big.Int).Set(state.GetBalance(args.From))
+	evm, vmError, err := a.backend.GetEVM(ctx, msg, state, header, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	state.SetBalance(args.From, balance)
+	_, _, err = evm.Call(vm.AccountRef(args.From), vm.WanCscPrecompileAddr, tx.Data(), tx.Gas().Uint64(), tx.Value())
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		sim.Error = err.Error()
+		return sim, nil
+	}
+
+	staker, err := vm.GetStakerInfo(state, args.validator())
+	if err != nil {
+		return nil, err
+	}
+	sim.StakerInfo = ToStakerJson(staker)
+
+	eidNow, _ := util.CalEpochSlotID(header.Time.Uint64())
+	sim.FirstEpochId = eidNow + vm.JoinDelay
+	if staker.StakingEpoch > sim.FirstEpochId {
+		sim.FirstEpochId = staker.StakingEpoch
+	}
+	infos, total, err := epochLeader.CalEpochProbabilityStaker(staker, sim.FirstEpochId, a.chain.PosContext().FirstEpochId())
+	if err != nil {
+		sim.ProbabilityError = err.Error()
+		return sim, nil
+	}
+	info := toApiStakerInfo(&vm.ValidatorInfo{
+		TotalProbability: total,
+		FeeRate:          staker.FeeRate,
+		ValidatorAddr:    staker.Address,
+		Infos:            infos,
+	})
+	sim.Probability = &info
+	return sim, nil
+}
@@ -0,0 +1,364 @@
+package posapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+const (
+	// defaultStakerPageSize is the page size of the staker queries if none
+	// is given.
+	defaultStakerPageSize = 100
+
+	// maxStakerPageSize is the largest page size of the staker queries.
+	maxStakerPageSize = 1000
+)
+
+// StakerState is a validator of a staking state, with the epochs its stake
+// locks end and is refunded in.
+type StakerState struct {
+	*StakerJson
+	LockExpiryEpoch uint64 `json:"lockExpiryEpoch"` // 0 if the stake doesn't expire
+	StakeOutEpoch   uint64 `json:"stakeOutEpoch"`   // 0 if the stake renews or doesn't expire
+}
+
+// StakerPage is a page of the validators of a staking state, sorted by
+// address.
+type StakerPage struct {
+	BlockNumber uint64         `json:"blockNumber"`
+	Total       int            `json:"total"`
+	Stakers     []*StakerState `json:"stakers"`
+}
+
+// DelegatorPosition is a delegation to a validator.
+type DelegatorPosition struct {
+	Validator     common.Address        `json:"validator"`
+	Address       common.Address        `json:"address"`
+	Amount        *math.HexOrDecimal256 `json:"amount"`
+	StakeAmount   *math.HexOrDecimal256 `json:"votingPower"`
+	QuitEpoch     uint64                `json:"quitEpoch"`
+	StakeOutEpoch uint64                `json:"stakeOutEpoch"` // 0 if the delegation doesn't end
+}
+
+// PartnerPosition is a partnership of a validator.
+type PartnerPosition struct {
+	Validator       common.Address        `json:"validator"`
+	Address         common.Address        `json:"address"`
+	Amount          *math.HexOrDecimal256 `json:"amount"`
+	StakeAmount     *math.HexOrDecimal256 `json:"votingPower"`
+	Renewal         bool                  `json:"renewal"`
+	LockEpochs      uint64                `json:"lockEpochs"`
+	StakingEpoch    uint64                `json:"stakingEpoch"`
+	LockExpiryEpoch uint64                `json:"lockExpiryEpoch"`
+	StakeOutEpoch   uint64                `json:"stakeOutEpoch"` // 0 if the partnership renews
+}
+
+// stakeOutSchedule projects the stake-out of a validator as run by
+// epochLeader.StakeOutRun, assuming no further staking tx.
+type stakeOutSchedule struct {
+	staker *vm.StakerInfo
+}
+
+// newStakeOutSchedule returns the schedule of a staker, whose staking epoch
+// is set if it staked before pos.
+func newStakeOutSchedule(staker *vm.StakerInfo, firstEpochId uint64) stakeOutSchedule {
+	if staker.StakingEpoch == 0 && staker.LockEpochs != 0 && firstEpochId != 0 {
+		staker.StakingEpoch = firstEpochId + 2
+		for i := range staker.Partners {
+			staker.Partners[i].StakingEpoch = firstEpochId + 2
+		}
+	}
+	return stakeOutSchedule{staker}
+}
+
+// lockExpiry returns the epoch the lock of the validator ends in, 0 if it
+// doesn't expire.
+func (s stakeOutSchedule) lockExpiry() uint64 {
+	if s.staker.LockEpochs == 0 {
+		return 0
+	}
+	return s.staker.StakingEpoch + s.staker.LockEpochs
+}
+
+// renews reports whether the validator stakes again when its lock ends.
+func (s stakeOutSchedule) renews() bool {
+	return s.staker.LockEpochs == 0 || s.staker.NextLockEpochs != 0
+}
+
+// stakeOut returns the epoch the validator is refunded in, 0 if it renews.
+func (s stakeOutSchedule) stakeOut() uint64 {
+	if s.renews() {
+		return 0
+	}
+	return s.lockExpiry()
+}
+
+// clientStakeOut returns the epoch a delegation is refunded in, 0 if it
+// doesn't end.
+func (s stakeOutSchedule) clientStakeOut(client *vm.ClientInfo) uint64 {
+	return firstEpoch(client.QuitEpoch, s.stakeOut())
+}
+
+// partnerStakeOut returns the epoch a partnership is refunded in, 0 if it
+// renews.
+func (s stakeOutSchedule) partnerStakeOut(partner *vm.PartnerInfo) uint64 {
+	if s.staker.LockEpochs == 0 {
+		return 0
+	}
+	if partner.Renewal && s.renews() {
+		return 0
+	}
+	return firstEpoch(partner.StakingEpoch+partner.LockEpochs, s.stakeOut())
+}
+
+// firstEpoch returns the earliest of two epochs, 0 meaning never.
+func firstEpoch(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// stakersByAddress sorts validators by address.
+type stakersByAddress []vm.StakerInfo
+
+func (s stakersByAddress) Len() int      { return len(s) }
+func (s stakersByAddress) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s stakersByAddress) Less(i, j int) bool {
+	return bytes.Compare(s[i].Address[:], s[j].Address[:]) < 0
+}
+
+// stakersAt returns the validators of a state, sorted by address.
+func stakersAt(stateDb *state.StateDB) []vm.StakerInfo {
+	stakers := vm.GetStakersSnap(stateDb)
+	sort.Sort(stakersByAddress(stakers))
+	return stakers
+}
+
+func (a PosApi) toStakerState(stateDb *state.StateDB, staker *vm.StakerInfo) *StakerState {
+	stakeJson := toStakerJsonAt(stateDb, staker)
+	s := newStakeOutSchedule(staker, a.chain.PosContext().FirstEpochId())
+	return &StakerState{
+		StakerJson:      stakeJson,
+		LockExpiryEpoch: s.lockExpiry(),
+		StakeOutEpoch:   s.stakeOut(),
+	}
+}
+
+// GetStakers returns a page of the validators at a block, sorted by address,
+// with their delegators, partners, fee rate changes and stake-out epochs.
+func (a PosApi) GetStakers(ctx context.Context, blockNr rpc.BlockNumber, offset uint64, limit uint64) (*StakerPage, error) {
+	stateDb, header, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultStakerPageSize
+	}
+	if limit > maxStakerPageSize {
+		limit = maxStakerPageSize
+	}
+
+	stakers := stakersAt(stateDb)
+	page := &StakerPage{
+		BlockNumber: header.Number.Uint64(),
+		Total:       len(stakers),
+		Stakers:     make([]*StakerState, 0),
+	}
+	for i := offset; i < uint64(len(stakers)) && i < offset+limit; i++ {
+		page.Stakers = append(page.Stakers, a.toStakerState(stateDb, &stakers[i]))
+	}
+	return page, nil
+}
+
+// GetStakersByEpochID returns a page of the validators the leaders of an
+// epoch are selected from.
+func (a PosApi) GetStakersByEpochID(ctx context.Context, epochID uint64, offset uint64, limit uint64) (*StakerPage, error) {
+	epocherInst := epochLeader.GetEpocher(a.chain.PosContext())
+	if epocherInst == nil {
+		return nil, errors.New("epocher instance do not exist")
+	}
+	return a.GetStakers(ctx, rpc.BlockNumber(epocherInst.GetTargetBlkNumber(epochID)), offset, limit)
+}
+
+// GetStaker returns a validator at a block.
+func (a PosApi) GetStaker(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) (*StakerState, error) {
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	staker, err := vm.GetStakerInfo(stateDb, addr)
+	if err != nil {
+		return nil, err
+	}
+	return a.toStakerState(stateDb, staker), nil
+}
+
+// GetDelegatorPositions returns the delegations of an address at a block.
+func (a PosApi) GetDelegatorPositions(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) ([]*DelegatorPosition, error) {
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	positions := make([]*DelegatorPosition, 0)
+	stakers := stakersAt(stateDb)
+	for i := range stakers {
+		s := newStakeOutSchedule(&stakers[i], a.chain.PosContext().FirstEpochId())
+		for j := range stakers[i].Clients {
+			client := &stakers[i].Clients[j]
+			if client.Address != addr {
+				continue
+			}
+			positions = append(positions, &DelegatorPosition{
+				Validator:     stakers[i].Address,
+				Address:       client.Address,
+				Amount:        (*math.HexOrDecimal256)(client.Amount),
+				StakeAmount:   (*math.HexOrDecimal256)(client.StakeAmount),
+				QuitEpoch:     client.QuitEpoch,
+				StakeOutEpoch: s.clientStakeOut(client),
+			})
+		}
+	}
+	return positions, nil
+}
+
+// GetPartnerPositions returns the partnerships of an address at a block.
+func (a PosApi) GetPartnerPositions(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) ([]*PartnerPosition, error) {
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	positions := make([]*PartnerPosition, 0)
+	stakers := stakersAt(stateDb)
+	for i := range stakers {
+		s := newStakeOutSchedule(&stakers[i], a.chain.PosContext().FirstEpochId())
+		for j := range stakers[i].Partners {
+			partner := &stakers[i].Partners[j]
+			if partner.Address != addr {
+				continue
+			}
+			positions = append(positions, &PartnerPosition{
+				Validator:       stakers[i].Address,
+				Address:         partner.Address,
+				Amount:          (*math.HexOrDecimal256)(partner.Amount),
+				StakeAmount:     (*math.HexOrDecimal256)(partner.StakeAmount),
+				Renewal:         partner.Renewal,
+				LockEpochs:      partner.LockEpochs,
+				StakingEpoch:    partner.StakingEpoch,
+				LockExpiryEpoch: partner.StakingEpoch + partner.LockEpochs,
+				StakeOutEpoch:   s.partnerStakeOut(partner),
+			})
+		}
+	}
+	return positions, nil
+}
+
+// JailStatus is the jailing record of a validator at a block.
+type JailStatus struct {
+	Address      common.Address        `json:"address"`
+	Jailed       bool                  `json:"jailed"`       // excluded from the selections of the epoch of the block
+	JailedEpoch  uint64                `json:"jailedEpoch"`  // epoch whose inactivity it was last jailed for
+	ReleaseEpoch uint64                `json:"releaseEpoch"` // first epoch it may be selected in again
+	Count        uint64                `json:"count"`
+	Burnt        *math.HexOrDecimal256 `json:"burnt"`
+}
+
+// GetJailStatus returns the jailing record of a validator at a block.
+func (a PosApi) GetJailStatus(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) (*JailStatus, error) {
+	stateDb, header, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	info, err := vm.GetJailInfo(stateDb, addr)
+	if err != nil {
+		return nil, err
+	}
+	status := &JailStatus{Address: addr, Burnt: (*math.HexOrDecimal256)(new(big.Int))}
+	if info == nil {
+		return status, nil
+	}
+	epochID, _ := util.CalEpochSlotID(header.Time.Uint64())
+	status.Jailed = info.Jailed(epochID)
+	status.JailedEpoch = info.JailedEpoch
+	status.ReleaseEpoch = info.ReleaseEpoch
+	status.Count = info.Count
+	status.Burnt = (*math.HexOrDecimal256)(info.Burnt)
+	return status, nil
+}
+
+// JailEvent is the jailing of a validator for the inactivity of an epoch.
+type JailEvent struct {
+	Address      common.Address        `json:"address"`
+	Duties       uint64                `json:"duties"`       // epoch leader and random proposer duties of the epoch
+	Fulfilled    uint64                `json:"fulfilled"`    // duties fulfilled
+	ReleaseEpoch uint64                `json:"releaseEpoch"` // first epoch it may be selected in again
+	Burnt        *math.HexOrDecimal256 `json:"burnt"`
+}
+
+// GetJailEvents returns the validators jailed for the inactivity of an epoch,
+// as recorded by the chain at a block.
+func (a PosApi) GetJailEvents(ctx context.Context, epochID uint64, blockNr rpc.BlockNumber) ([]JailEvent, error) {
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	events, err := vm.GetJailEvents(stateDb, epochID)
+	if err != nil {
+		return nil, err
+	}
+	jailed := make([]JailEvent, 0, len(events))
+	for _, event := range events {
+		jailed = append(jailed, JailEvent{
+			Address:      event.Address,
+			Duties:       event.Duties,
+			Fulfilled:    event.Fulfilled,
+			ReleaseEpoch: event.ReleaseEpoch,
+			Burnt:        (*math.HexOrDecimal256)(event.Burnt),
+		})
+	}
+	return jailed, nil
+}
+
+// EquivocationStatus is the slot leader equivocation record of a validator at
+// a block.
+type EquivocationStatus struct {
+	Address common.Address        `json:"address"`
+	EpochID uint64                `json:"epochId"` // epoch of the last slot it equivocated in
+	SlotID  uint64                `json:"slotId"`  // last slot it equivocated in
+	Count   uint64                `json:"count"`
+	Burnt   *math.HexOrDecimal256 `json:"burnt"`
+}
+
+// GetEquivocationStatus returns the slot leader equivocation record of a
+// validator at a block, built from the evidence submitted to the staking
+// contract.
+func (a PosApi) GetEquivocationStatus(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) (*EquivocationStatus, error) {
+	stateDb, _, err := a.backend.StateAndHeaderByNumber(ctx, blockNr)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	info, err := vm.GetEquivocationInfo(stateDb, addr)
+	if err != nil {
+		return nil, err
+	}
+	status := &EquivocationStatus{Address: addr, Burnt: (*math.HexOrDecimal256)(new(big.Int))}
+	if info == nil {
+		return status, nil
+	}
+	status.EpochID = info.EpochID
+	status.SlotID = info.SlotID
+	status.Count = info.Count
+	status.Burnt = (*math.HexOrDecimal256)(info.Burnt)
+	return status, nil
+}
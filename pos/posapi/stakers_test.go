@@ -0,0 +1,91 @@
+package posapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+func TestStakeOutSchedule(t *testing.T) {
+	newStaker := func() *vm.StakerInfo {
+		return &vm.StakerInfo{
+			Address:        common.HexToAddress("0x01"),
+			Amount:         big.NewInt(1),
+			StakingEpoch:   100,
+			LockEpochs:     10,
+			NextLockEpochs: 10,
+			Clients: []vm.ClientInfo{
+				{Address: common.HexToAddress("0x02")},
+				{Address: common.HexToAddress("0x03"), QuitEpoch: 105},
+			},
+			Partners: []vm.PartnerInfo{
+				{Address: common.HexToAddress("0x04"), StakingEpoch: 102, LockEpochs: 8, Renewal: true},
+				{Address: common.HexToAddress("0x05"), StakingEpoch: 102, LockEpochs: 8},
+			},
+		}
+	}
+
+	// The validator renews, so do the delegations and renewing partners.
+	staker := newStaker()
+	s := newStakeOutSchedule(staker, 50)
+	if have := s.lockExpiry(); have != 110 {
+		t.Errorf("lock expiry mismatch: have %d, want 110", have)
+	}
+	if have := s.stakeOut(); have != 0 {
+		t.Errorf("stake-out of a renewing validator: have %d, want 0", have)
+	}
+	if have := s.clientStakeOut(&staker.Clients[0]); have != 0 {
+		t.Errorf("stake-out of a delegator: have %d, want 0", have)
+	}
+	if have := s.clientStakeOut(&staker.Clients[1]); have != 105 {
+		t.Errorf("stake-out of a quitting delegator: have %d, want 105", have)
+	}
+	if have := s.partnerStakeOut(&staker.Partners[0]); have != 0 {
+		t.Errorf("stake-out of a renewing partner: have %d, want 0", have)
+	}
+	if have := s.partnerStakeOut(&staker.Partners[1]); have != 110 {
+		t.Errorf("stake-out of a partner: have %d, want 110", have)
+	}
+
+	// The validator quits, taking everyone with it.
+	staker = newStaker()
+	staker.NextLockEpochs = 0
+	staker.Partners[1].LockEpochs = 20
+	s = newStakeOutSchedule(staker, 50)
+	if have := s.stakeOut(); have != 110 {
+		t.Errorf("stake-out of a quitting validator: have %d, want 110", have)
+	}
+	if have := s.clientStakeOut(&staker.Clients[0]); have != 110 {
+		t.Errorf("stake-out of a delegator: have %d, want 110", have)
+	}
+	if have := s.clientStakeOut(&staker.Clients[1]); have != 105 {
+		t.Errorf("stake-out of a quitting delegator: have %d, want 105", have)
+	}
+	if have := s.partnerStakeOut(&staker.Partners[0]); have != 110 {
+		t.Errorf("stake-out of a renewing partner: have %d, want 110", have)
+	}
+	if have := s.partnerStakeOut(&staker.Partners[1]); have != 110 {
+		t.Errorf("stake-out of a partner: have %d, want 110", have)
+	}
+
+	// A validator staked before pos starts two epochs after it.
+	staker = newStaker()
+	staker.StakingEpoch = 0
+	s = newStakeOutSchedule(staker, 50)
+	if have := s.lockExpiry(); have != 62 {
+		t.Errorf("lock expiry mismatch: have %d, want 62", have)
+	}
+	if staker.Partners[0].StakingEpoch != 52 {
+		t.Errorf("partner staking epoch mismatch: have %d, want 52", staker.Partners[0].StakingEpoch)
+	}
+
+	// Builtin validators never expire.
+	staker = newStaker()
+	staker.LockEpochs, staker.NextLockEpochs = 0, 0
+	s = newStakeOutSchedule(staker, 50)
+	if s.lockExpiry() != 0 || s.stakeOut() != 0 || s.clientStakeOut(&staker.Clients[0]) != 0 || s.partnerStakeOut(&staker.Partners[1]) != 0 {
+		t.Error("builtin validator expires")
+	}
+}
@@ -178,3 +178,32 @@ func ToStakerJson(staker *vm.StakerInfo) *StakerJson {
 
 	return &stakeJson
 }
+
+// ToValidatorInfos converts the incentive payments of an epoch, as stored by
+// the incentive package, to their json form.
+func ToValidatorInfos(c [][]vm.ClientIncentive) []ValidatorInfo {
+	ret := make([]ValidatorInfo, len(c))
+	for i := 0; i < len(c); i++ {
+		if len(c[i]) == 0 {
+			continue
+		}
+
+		delegators := make([]DelegatorInfo, len(c[i])-1)
+
+		for m := 1; m < len(c[i]); m++ {
+			delegators[m-1] = DelegatorInfo{}
+			delegators[m-1].Address = c[i][m].WalletAddr
+			delegators[m-1].Incentive = (*math.HexOrDecimal256)(c[i][m].Incentive)
+			delegators[m-1].Type = "delegator"
+		}
+
+		ret[i] = ValidatorInfo{
+			Address:       c[i][0].ValidatorAddr,
+			WalletAddress: c[i][0].WalletAddr,
+			Incentive:     (*math.HexOrDecimal256)(c[i][0].Incentive),
+			Type:          "validator",
+			Delegators:    delegators,
+		}
+	}
+	return ret
+}
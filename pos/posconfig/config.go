@@ -3,13 +3,12 @@ package posconfig
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/crypto"
-	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 
 	"github.com/wanchain/go-wanchain/node"
 )
@@ -17,13 +16,9 @@ import (
 var (
 	// EpochBaseTime is the pos start time such as: 2018-12-12 00:00:00 == 1544544000
 	//EpochBaseTime = uint64(0)
-	FirstEpochId              = uint64(0)
-	CurrentEpochId            = uint64(0)
 	Pow2PosUpgradeBlockNumber = uint64(0)
-	// SelfTestMode config whether it is in a simlate tese mode
-	SelfTestMode = false
-	IsDev        = false
-	MineEnabled  = false
+	IsDev                     = false
+	MineEnabled               = false
 )
 
 const (
@@ -33,6 +28,7 @@ const (
 	PosLocalDB       = "pos"
 	IncentiveLocalDB = "incentive"
 	ReorgLocalDB     = "forkdb"
+	ProtectionDB     = "signprotect"
 	ApolloEpochID     = 18104
 	AugustEpochID     = 18116  //TODO change it as mainnet 8.8
 
@@ -107,6 +103,35 @@ const (
 
 var TxDelay = K
 
+// Penalties of a slot leader equivocation, combined in EquivocationPenalty.
+const (
+	PenaltyFeeRateReset = 1 << iota // the fee rate drops to 0 for good
+	PenaltyBurn                     // EquivocationBurnPercent of the own stake is burnt
+	PenaltyStakeOut                 // the validator quits after vm.QuitDelay epochs
+)
+
+// Slot leader equivocation evidence, accepted from
+// params.ChainConfig.PosEvidenceBlock. The penalties must be configured alike
+// by all the nodes of a network.
+//
+// The slot leader proof of the headers isn't verified, it needs the local pos
+// data of the epoch. The evidence only proves that a stage two epoch leader
+// of the previous epoch signed two headers of the slot, so it punishes such
+// a leader even if it didn't lead the slot.
+var (
+	// EquivocationPenalty is the combination of penalties of a validator
+	// proven to have sealed two blocks for a slot.
+	EquivocationPenalty = uint64(PenaltyFeeRateReset | PenaltyBurn)
+
+	// EquivocationBurnPercent is the percent of its own stake a validator
+	// loses for each slot it equivocated in, with PenaltyBurn.
+	EquivocationBurnPercent = uint64(10)
+
+	// EquivocationEvidenceEpochs is the number of epochs, after the one of
+	// the slot, the evidence of an equivocation is accepted for.
+	EquivocationEvidenceEpochs = uint64(2)
+)
+
 var GenesisPK string
 
 //var GenesisPK = "04dc40d03866f7335e40084e39c3446fe676b021d1fcead11f2e2715e10a399b498e8875d348ee40358545e262994318e4dcadbc865bcf9aac1fc330f22ae2c786"
@@ -122,8 +147,6 @@ type Config struct {
 	RBThres       uint
 	EpochInterval uint64
 	PosStartTime  int64
-	MinerKey      *keystore.Key
-	Dbpath        string
 	NodeCfg       *node.Config
 	Dkg1End       uint64
 	Dkg2Begin     uint64
@@ -145,8 +168,6 @@ var DefaultConfig = Config{
 	0,
 	0,
 	nil,
-	"",
-	nil,
 	Stage2K - 1,
 	Stage4K,
 	Stage6K - 1,
@@ -160,21 +181,21 @@ var DefaultConfig = Config{
 	0,
 }
 
-func Cfg() *Config {
-	return &DefaultConfig
-}
+var errPosUpgradeBlock = errors.New("pos upgrade block already set by another chain")
 
-func (c *Config) GetMinerAddr() common.Address {
-	if c.MinerKey == nil {
-		return common.Address{}
+// SetPow2PosUpgradeBlockNumber sets the pos upgrade block of the chain the
+// process runs. It fails if another chain set a different block, as the pos
+// modules read it from Pow2PosUpgradeBlockNumber.
+func SetPow2PosUpgradeBlockNumber(number uint64) error {
+	if Pow2PosUpgradeBlockNumber != 0 && Pow2PosUpgradeBlockNumber != number {
+		return errPosUpgradeBlock
 	}
-
-	return c.MinerKey.Address
+	Pow2PosUpgradeBlockNumber = number
+	return nil
 }
 
-func (c *Config) GetMinerBn256PK() *bn256.G1 {
-	D3 := GenerateD3byKey2(c.MinerKey.PrivateKey2)
-	return new(bn256.G1).ScalarBaseMult(D3)
+func Cfg() *Config {
+	return &DefaultConfig
 }
 
 func GenerateD3byKey2(PrivateKey *ecdsa.PrivateKey) *big.Int {
@@ -193,10 +214,6 @@ func GenerateD3byKey2(PrivateKey *ecdsa.PrivateKey) *big.Int {
 	return d3
 }
 
-func (c *Config) GetMinerBn256SK() *big.Int {
-	return GenerateD3byKey2(c.MinerKey.PrivateKey2)
-}
-
 func Init(nodeCfg *node.Config, networkId uint64) {
 	if networkId == 1 {
 		// this is mainnet. *****
@@ -0,0 +1,615 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package posctx holds the PoS state of one node, so that several nodes
+// can run in the same process.
+package posctx
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+	"github.com/wanchain/go-wanchain/pos/signprotect"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Clock is the source of the PoS time, which drives the epochs and slots.
+type Clock interface {
+	// Now returns the current time in unix seconds.
+	Now() uint64
+}
+
+type wallClock struct{}
+
+// chainDbs are the PoS databases derived from the chain. Once the context has
+// a chain database they are stored in tables of it, written with the blocks.
+var chainDbs = map[string]bool{
+	posconfig.RbLocalDB:        true,
+	posconfig.EpLocalDB:        true,
+	posconfig.StakerLocalDB:    true,
+	posconfig.IncentiveLocalDB: true,
+	posconfig.ReorgLocalDB:     true,
+}
+
+// chainDbPrefix prefixes the tables of the PoS databases in the chain database.
+const chainDbPrefix = "pos-"
+
+// maxBlockWrites is the number of blocks the writes are buffered for, until
+// they are written with the block.
+const maxBlockWrites = 256
+
+func (wallClock) Now() uint64 { return uint64(time.Now().Unix()) }
+
+// Context is the PoS context of a node. It owns the node's PoS databases,
+// the epoch bookkeeping and the PoS services running on top of the chain.
+type Context struct {
+	datadir  string
+	tmpdir   bool
+	dbEngine string // key-value engine of the databases
+
+	firstEpochId   uint64 // epoch of the first pos block, accessed atomically
+	currentEpochId uint64 // epoch of the last inserted block, accessed atomically
+	selfTestMode   uint32 // non zero in self test mode, accessed atomically
+
+	dbLock      sync.Mutex
+	dbs         map[string]*posdb.Db
+	chainDb     ethdb.Database  // database of the chain, nil if not set
+	protection  *signprotect.DB // double-sign protection database, nil until opened
+	blockWrites *lru.Cache      // state of a block -> buffered writes of the block
+
+	clock Clock
+	wg    sync.WaitGroup // background work started with Go
+
+	lock            sync.RWMutex
+	minerKey        *keystore.Key
+	signer          possigner.Signer
+	selector        util.SelectLead
+	sealVerifier    util.SealVerifier
+	services        map[string]interface{}
+	lastBlockEpoch  map[uint64]uint64
+	lastHashEpoch   map[uint64]common.Hash
+	selectedEpochId uint64
+}
+
+// New creates the PoS context of a node storing its databases in datadir.
+// An empty datadir keeps the databases in a temporary directory, created on
+// first use and removed on Close.
+func New(datadir string) *Context {
+	return NewWithEngine(datadir, "")
+}
+
+// NewWithEngine creates the PoS context of a node storing its databases in
+// datadir with the given key-value engine, see ethdb.NewDatabase.
+func NewWithEngine(datadir, engine string) *Context {
+	blockWrites, _ := lru.New(maxBlockWrites)
+	c := &Context{
+		datadir:        datadir,
+		dbEngine:       engine,
+		clock:          wallClock{},
+		dbs:            make(map[string]*posdb.Db),
+		blockWrites:    blockWrites,
+		services:       make(map[string]interface{}),
+		lastBlockEpoch: make(map[uint64]uint64),
+		lastHashEpoch:  make(map[uint64]common.Hash),
+	}
+	return c
+}
+
+// NewSelfTest creates a context in self test mode, in which the PoS
+// services use fixed data instead of the chain.
+func NewSelfTest() *Context {
+	c := New("")
+	c.SetSelfTestMode(true)
+	return c
+}
+
+// SelfTestMode reports whether the node runs in the simulated self test mode.
+func (c *Context) SelfTestMode() bool {
+	return c != nil && atomic.LoadUint32(&c.selfTestMode) != 0
+}
+
+// SetSelfTestMode switches the self test mode on or off.
+func (c *Context) SetSelfTestMode(on bool) {
+	var mode uint32
+	if on {
+		mode = 1
+	}
+	atomic.StoreUint32(&c.selfTestMode, mode)
+}
+
+// FirstEpochId returns the epoch of the first pos block, zero before the
+// switch to pos. A nil context has no pos blocks.
+func (c *Context) FirstEpochId() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.firstEpochId)
+}
+
+// SetFirstEpochId records the epoch of the first pos block.
+func (c *Context) SetFirstEpochId(epochId uint64) {
+	atomic.StoreUint64(&c.firstEpochId, epochId)
+}
+
+// CurrentEpochId returns the epoch of the last block inserted into the chain.
+func (c *Context) CurrentEpochId() uint64 {
+	return atomic.LoadUint64(&c.currentEpochId)
+}
+
+// Now returns the current PoS time in unix seconds. A nil context uses the
+// wall clock.
+func (c *Context) Now() uint64 {
+	if c == nil {
+		return wallClock{}.Now()
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.clock.Now()
+}
+
+// SetClock replaces the clock of the context, nil restores the wall clock.
+func (c *Context) SetClock(clock Clock) {
+	if clock == nil {
+		clock = wallClock{}
+	}
+	c.lock.Lock()
+	c.clock = clock
+	c.lock.Unlock()
+}
+
+// Go runs fn in a new goroutine tracked by the context.
+func (c *Context) Go(fn func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until all the work started with Go has returned.
+func (c *Context) Wait() {
+	c.wg.Wait()
+}
+
+// Db returns the PoS database of the given name, opening it on first use.
+func (c *Context) Db(name string) *posdb.Db {
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	if db, ok := c.dbs[name]; ok {
+		return db
+	}
+	if c.chainDb != nil && chainDbs[name] {
+		db := posdb.NewTable(c.chainDb, chainDbTable(name))
+		c.dbs[name] = db
+		return db
+	}
+	db := posdb.NewDbWithEngine(c.dbPath(name), c.dbEngine)
+	c.dbs[name] = db
+	return db
+}
+
+// dbPath returns the path of the database of the given name in the datadir,
+// creating a temporary datadir without one. The caller holds dbLock.
+func (c *Context) dbPath(name string) string {
+	if c.datadir == "" {
+		dir, err := ioutil.TempDir("", "wanpos_tmpdb_")
+		if err != nil {
+			panic("failed to create wanpos_tmpdb dir: " + err.Error())
+		}
+		c.datadir, c.tmpdir = dir, true
+	}
+	return filepath.Join(c.datadir, name)
+}
+
+// Protection returns the double-sign protection database of the validator,
+// opening it on first use. It is kept in the datadir, out of the chain
+// database, so that it outlives a resync of the chain.
+func (c *Context) Protection() (*signprotect.DB, error) {
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	if c.protection == nil {
+		db, err := signprotect.Open(c.dbEngine, c.dbPath(posconfig.ProtectionDB))
+		if err != nil {
+			return nil, err
+		}
+		c.protection = db
+	}
+	return c.protection, nil
+}
+
+func chainDbTable(name string) string {
+	return chainDbPrefix + name + "-"
+}
+
+// SetChainDb moves the PoS databases derived from the chain into tables of
+// db, the chain database. The databases of an existing datadir are migrated
+// into the tables, and removed. It must be called before the databases are
+// used.
+func (c *Context) SetChainDb(db ethdb.Database) error {
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	c.chainDb = db
+	for name := range chainDbs {
+		if old, ok := c.dbs[name]; ok {
+			old.DbClose()
+			delete(c.dbs, name)
+		}
+		if c.datadir == "" || c.tmpdir {
+			continue
+		}
+		if err := c.migrate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate copies the PoS database of the given name stored in the datadir to
+// its table of the chain database, and removes it.
+func (c *Context) migrate(name string) error {
+	dir := filepath.Join(c.datadir, name)
+	engine := ethdb.DatabaseEngine(dir)
+	if engine == "" {
+		return nil
+	}
+	src, err := ethdb.NewDatabase(engine, dir, 0, 256)
+	if err != nil {
+		return err
+	}
+	err = posdb.NewTable(c.chainDb, chainDbTable(name)).Import(src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	log.Info("Migrated PoS database into the chain database", "name", name)
+	return os.RemoveAll(dir)
+}
+
+// BlockDb returns a batch of the PoS database of the given name for the
+// writes made while processing the block whose state is statedb. The writes
+// are made with the block by CommitBlock. Without a chain database the writes
+// go to the database directly.
+func (c *Context) BlockDb(name string, statedb *state.StateDB) *posdb.Db {
+	db := c.Db(name)
+
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	if c.chainDb == nil || !chainDbs[name] {
+		return db
+	}
+	var writes map[string]*posdb.Db
+	if cached, ok := c.blockWrites.Get(statedb); ok {
+		writes = cached.(map[string]*posdb.Db)
+	} else {
+		writes = make(map[string]*posdb.Db)
+		c.blockWrites.Add(statedb, writes)
+	}
+	if batch, ok := writes[name]; ok {
+		return batch
+	}
+	batch := db.NewBatch()
+	writes[name] = batch
+	return batch
+}
+
+// CommitBlock adds the writes made for the block whose state is statedb to
+// batch, the chain database batch writing the block.
+func (c *Context) CommitBlock(batch ethdb.Batch, statedb *state.StateDB) error {
+	c.dbLock.Lock()
+	cached, ok := c.blockWrites.Get(statedb)
+	c.blockWrites.Remove(statedb)
+	c.dbLock.Unlock()
+
+	if !ok {
+		return nil
+	}
+	for _, db := range cached.(map[string]*posdb.Db) {
+		if err := db.Write(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewind drops the PoS data derived from the blocks after the epoch epochID,
+// once the chain head was set back to a block of that epoch. The deletes are
+// added to batch, the chain database batch writing the new head.
+func (c *Context) Rewind(batch ethdb.Batch, epochID uint64) error {
+	c.blockWrites.Purge()
+	return c.RewindBranch(batch, epochID)
+}
+
+// RewindBranch drops the PoS data derived from the blocks after the epoch
+// epochID, once the canonical chain switched from a block of that epoch to
+// another branch. Unlike Rewind it keeps the writes of the blocks not
+// committed yet, such as the block of the new branch being written with
+// batch, which must be added to batch after the deletes.
+func (c *Context) RewindBranch(batch ethdb.Batch, epochID uint64) error {
+	if err := c.RewindLeaders(batch, epochID); err != nil {
+		return err
+	}
+	for _, name := range []string{posconfig.StakerLocalDB, posconfig.IncentiveLocalDB, posconfig.ReorgLocalDB} {
+		if err := c.deleteFrom(batch, name, epochID+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RewindLeaders drops the leaders selected after the epoch epochID, once the
+// blocks after epochID were removed from the canonical chain. The leaders of
+// epoch epochID+1 are kept, they derive from the state of the epoch before.
+func (c *Context) RewindLeaders(batch ethdb.Batch, epochID uint64) error {
+	for _, name := range []string{posconfig.RbLocalDB, posconfig.EpLocalDB} {
+		if err := c.deleteFrom(batch, name, epochID+2); err != nil {
+			return err
+		}
+	}
+
+	c.lock.Lock()
+	if c.selectedEpochId > epochID+1 {
+		c.selectedEpochId = epochID + 1
+	}
+	// The last blocks of the epochs are looked up in the chain again
+	for e := range c.lastBlockEpoch {
+		if e >= epochID {
+			delete(c.lastBlockEpoch, e)
+			delete(c.lastHashEpoch, e)
+		}
+	}
+	c.lock.Unlock()
+	atomic.StoreUint64(&c.currentEpochId, epochID)
+	return nil
+}
+
+// deleteFrom removes the entries of the PoS database of the given name from
+// the epoch epochID on. The deletes of a database in the chain database are
+// added to batch, the others are made directly.
+func (c *Context) deleteFrom(batch ethdb.Batch, name string, epochID uint64) error {
+	db := c.Db(name)
+
+	c.dbLock.Lock()
+	inChainDb := c.chainDb != nil && chainDbs[name]
+	c.dbLock.Unlock()
+
+	if !inChainDb {
+		return db.DeleteFrom(epochID)
+	}
+	deletes := db.NewBatch()
+	if err := deletes.DeleteFrom(epochID); err != nil {
+		return err
+	}
+	return deletes.Write(batch)
+}
+
+// LocalDb returns the general purpose PoS database of the node.
+func (c *Context) LocalDb() *posdb.Db {
+	return c.Db(posconfig.PosLocalDB)
+}
+
+// Close waits for the background work and closes the databases of the context.
+func (c *Context) Close() {
+	c.Wait()
+
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	for name, db := range c.dbs {
+		db.DbClose()
+		delete(c.dbs, name)
+	}
+	if c.protection != nil {
+		c.protection.Close()
+		c.protection = nil
+	}
+	if c.tmpdir {
+		os.RemoveAll(c.datadir)
+		c.datadir, c.tmpdir = "", false
+	}
+}
+
+// MinerKey returns the unlocked key of the local validator, nil if its keys
+// are held by a remote signer.
+func (c *Context) MinerKey() *keystore.Key {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.minerKey
+}
+
+// SetMinerKey sets the unlocked key of the local validator, its signer is a
+// possigner.Local of the key.
+func (c *Context) SetMinerKey(key *keystore.Key) {
+	var signer possigner.Signer
+	if key != nil {
+		signer = possigner.NewLocal(key)
+	}
+	c.lock.Lock()
+	c.minerKey, c.signer = key, signer
+	c.lock.Unlock()
+}
+
+// Signer returns the signer of the local validator, nil if there is none.
+func (c *Context) Signer() possigner.Signer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.signer
+}
+
+// SetSigner sets the signer of the local validator.
+func (c *Context) SetSigner(signer possigner.Signer) {
+	c.lock.Lock()
+	c.minerKey, c.signer = nil, signer
+	c.lock.Unlock()
+}
+
+// MinerAddr returns the address of the local validator.
+func (c *Context) MinerAddr() common.Address {
+	if signer := c.Signer(); signer != nil {
+		return signer.Address()
+	}
+	return common.Address{}
+}
+
+// MinerBn256PK returns the bn256 public key of the local validator, nil
+// without a signer.
+func (c *Context) MinerBn256PK() *bn256.G1 {
+	signer := c.Signer()
+	if signer == nil {
+		return nil
+	}
+	return signer.Bn256PublicKey()
+}
+
+// MinerBn256SK returns the bn256 secret key of the local validator, nil
+// without a miner key.
+func (c *Context) MinerBn256SK() *big.Int {
+	key := c.MinerKey()
+	if key == nil {
+		return nil
+	}
+	return posconfig.GenerateD3byKey2(key.PrivateKey2)
+}
+
+// Register stores the PoS service of a name, replacing any former one.
+func (c *Context) Register(name string, service interface{}) {
+	c.lock.Lock()
+	c.services[name] = service
+	c.lock.Unlock()
+}
+
+// Service returns the PoS service registered under name or nil.
+func (c *Context) Service(name string) interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.services[name]
+}
+
+// LoadOrRegister returns the PoS service registered under name, registering
+// the one returned by create if there is none.
+func (c *Context) LoadOrRegister(name string, create func() interface{}) interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if service, ok := c.services[name]; ok {
+		return service
+	}
+	service := create()
+	c.services[name] = service
+	return service
+}
+
+// SetSelector sets the epoch leader selector of the node.
+func (c *Context) SetSelector(selector util.SelectLead) {
+	c.lock.Lock()
+	c.selector = selector
+	c.lock.Unlock()
+}
+
+// Selector returns the epoch leader selector, nil before the switch to pos.
+func (c *Context) Selector() util.SelectLead {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.selector
+}
+
+// SetSealVerifier sets the verifier of the seals of the pos headers.
+func (c *Context) SetSealVerifier(verifier util.SealVerifier) {
+	c.lock.Lock()
+	c.sealVerifier = verifier
+	c.lock.Unlock()
+}
+
+// SealVerifier returns the verifier of the seals of the pos headers, nil if
+// the node runs no pos consensus engine.
+func (c *Context) SealVerifier() util.SealVerifier {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.sealVerifier
+}
+
+// CurrentBlkEpochSlotID returns the epoch and slot of the current block.
+func (c *Context) CurrentBlkEpochSlotID() (epochID, slotID uint64) {
+	selector := c.Selector()
+	if selector == nil {
+		return 0, 0
+	}
+	header := selector.GetCurrentHeader()
+	if header == nil {
+		return 0, 0
+	}
+	return util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+}
+
+// UpdateEpochBlock records block as the last block of its epoch, and starts
+// the epoch leader selection of the next epoch once the block is late enough.
+func (c *Context) UpdateEpochBlock(block *types.Block) {
+	epochID, slotID := util.CalEpSlbyTd(block.Difficulty().Uint64())
+	atomic.StoreUint64(&c.currentEpochId, epochID)
+
+	c.lock.Lock()
+	selector := c.selector
+	// there is 2K slot, so need not think about reorg  // selec epoch leader from the whole epoch.
+	selectNext := slotID >= 2*posconfig.K+1 && c.selectedEpochId != epochID+1 && epochID != c.FirstEpochId()
+	if selectNext && selector != nil {
+		c.selectedEpochId = epochID + 1
+	}
+	c.lock.Unlock()
+
+	if selectNext && selector != nil {
+		c.Go(func() { selector.SelectLeadersLoop(epochID + 1) })
+	}
+	c.SetEpochBlock(epochID, block.NumberU64(), block.Hash())
+}
+
+// SetEpochBlock records the last block of an epoch.
+func (c *Context) SetEpochBlock(epochID uint64, blockNumber uint64, hash common.Hash) {
+	c.lock.Lock()
+	c.lastBlockEpoch[epochID] = blockNumber
+	c.lastHashEpoch[epochID] = hash
+	c.lock.Unlock()
+}
+
+// EpochBlock returns the number of the last block of an epoch, it falls back
+// to a lookup in the chain if the epoch hasn't been recorded.
+func (c *Context) EpochBlock(epochID uint64) uint64 {
+	c.lock.RLock()
+	number, selector := c.lastBlockEpoch[epochID], c.selector
+	c.lock.RUnlock()
+
+	if number == 0 && selector != nil {
+		number = selector.GetEpochLastBlkNumber(epochID)
+	}
+	return number
+}
+
+// EpochBlockHash returns the hash of the last recorded block of an epoch.
+func (c *Context) EpochBlockHash(epochID uint64) common.Hash {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lastHashEpoch[epochID]
+}
@@ -0,0 +1,220 @@
+package posctx
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+)
+
+func TestContextsAreIndependent(t *testing.T) {
+	c1, c2 := New(""), New("")
+	defer c1.Close()
+	defer c2.Close()
+
+	c1.SetFirstEpochId(10)
+	if c1.FirstEpochId() != 10 || c2.FirstEpochId() != 0 {
+		t.Fatalf("first epoch id shared: %d %d", c1.FirstEpochId(), c2.FirstEpochId())
+	}
+
+	c1.SetSelfTestMode(true)
+	if !c1.SelfTestMode() || c2.SelfTestMode() {
+		t.Fatal("self test mode shared")
+	}
+
+	if _, err := c1.LocalDb().Put(1, "key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := c2.LocalDb().Get(1, "key"); value != nil {
+		t.Fatal("local db shared")
+	}
+
+	c1.Register("service", 1)
+	if c1.Service("service") != 1 || c2.Service("service") != nil {
+		t.Fatal("service shared")
+	}
+	if c2.LoadOrRegister("service", func() interface{} { return 2 }) != 2 {
+		t.Fatal("service not registered")
+	}
+	if c2.LoadOrRegister("service", func() interface{} { return 3 }) != 2 {
+		t.Fatal("service registered twice")
+	}
+}
+
+func TestMinerKey(t *testing.T) {
+	c := New("")
+	defer c.Close()
+
+	if c.MinerBn256PK() != nil || c.MinerBn256SK() != nil {
+		t.Fatal("bn256 keys without miner key")
+	}
+	key := new(keystore.Key)
+	key.PrivateKey, _ = crypto.GenerateKey()
+	key.PrivateKey2, _ = crypto.GenerateKey()
+	key.Address = crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+	c.SetMinerKey(key)
+
+	if c.MinerAddr() != key.Address {
+		t.Fatalf("miner address mismatch: have %x, want %x", c.MinerAddr(), key.Address)
+	}
+	if c.MinerBn256PK() == nil || c.MinerBn256SK() == nil {
+		t.Fatal("missing bn256 keys")
+	}
+}
+
+func TestNilContext(t *testing.T) {
+	var c *Context
+	if c.FirstEpochId() != 0 || c.SelfTestMode() || c.Selector() != nil {
+		t.Fatal("nil context isn't empty")
+	}
+}
+
+type fixedClock uint64
+
+func (c fixedClock) Now() uint64 { return uint64(c) }
+
+func TestClock(t *testing.T) {
+	c := New("")
+	defer c.Close()
+
+	if c.Now() == 0 {
+		t.Fatal("wall clock not set")
+	}
+	c.SetClock(fixedClock(42))
+	if c.Now() != 42 {
+		t.Fatalf("clock mismatch: have %d, want 42", c.Now())
+	}
+	c.SetClock(nil)
+	if c.Now() == 42 {
+		t.Fatal("wall clock not restored")
+	}
+}
+
+func TestGoWait(t *testing.T) {
+	c := New("")
+	defer c.Close()
+
+	done := make([]bool, 4)
+	for i := range done {
+		i := i
+		c.Go(func() { done[i] = true })
+	}
+	c.Wait()
+	for i, ok := range done {
+		if !ok {
+			t.Fatalf("work %d not done", i)
+		}
+	}
+}
+
+func TestChainDb(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "posctx-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	// A datadir of a former version has databases of its own
+	old := posdb.NewDb(filepath.Join(datadir, posconfig.EpLocalDB))
+	old.PutWithIndex(3, 0, "", []byte{3})
+	old.DbClose()
+
+	chainDb, _ := ethdb.NewMemDatabase()
+	c := New(datadir)
+	defer c.Close()
+	if err := c.SetChainDb(chainDb); err != nil {
+		t.Fatal(err)
+	}
+	if common.FileExist(filepath.Join(datadir, posconfig.EpLocalDB)) {
+		t.Fatal("migrated database not removed")
+	}
+	if arrays := c.Db(posconfig.EpLocalDB).GetStorageByteArray(3); len(arrays) != 1 || arrays[0][0] != 3 {
+		t.Fatalf("migrated entries mismatch: %v", arrays)
+	}
+	if common.FileExist(filepath.Join(datadir, posconfig.IncentiveLocalDB)) {
+		t.Fatal("database of the chain opened in the datadir")
+	}
+
+	// Block writes are only made with the block, sibling blocks keep their
+	// writes apart
+	block, _ := state.New(common.Hash{}, state.NewDatabase(chainDb))
+	sibling, _ := state.New(common.Hash{}, state.NewDatabase(chainDb))
+	c.BlockDb(posconfig.IncentiveLocalDB, block).Put(4, "payment", []byte{2})
+	c.BlockDb(posconfig.IncentiveLocalDB, sibling).Put(4, "payment", []byte{3})
+	c.BlockDb(posconfig.IncentiveLocalDB, sibling).Put(4, "sibling", []byte{3})
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(4, "payment"); value != nil {
+		t.Fatal("block write made before the block")
+	}
+	batch := chainDb.NewBatch()
+	if err := c.CommitBlock(batch, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(4, "payment"); len(value) != 1 || value[0] != 2 {
+		t.Fatalf("block write mismatch: %v", value)
+	}
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(4, "sibling"); value != nil {
+		t.Fatal("write of a sibling block made")
+	}
+
+	// Rewinding to epoch 2 keeps the leaders of epoch 3, selected in epoch 2,
+	// the data is dropped with the batch
+	c.Db(posconfig.EpLocalDB).PutWithIndex(4, 0, "", []byte{4})
+	batch = chainDb.NewBatch()
+	if err := c.Rewind(batch, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Db(posconfig.EpLocalDB).GetStorageByteArray(4)) != 1 {
+		t.Fatal("leaders dropped before the batch is written")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Db(posconfig.EpLocalDB).GetStorageByteArray(3)) != 1 {
+		t.Fatal("leaders of the next epoch removed")
+	}
+	if len(c.Db(posconfig.EpLocalDB).GetStorageByteArray(4)) != 0 {
+		t.Fatal("leaders of a rewound epoch kept")
+	}
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(4, "payment"); value != nil {
+		t.Fatal("incentive of a rewound epoch kept")
+	}
+	if c.CurrentEpochId() != 2 {
+		t.Fatalf("current epoch mismatch: have %d, want 2", c.CurrentEpochId())
+	}
+	batch = chainDb.NewBatch()
+	if err := c.CommitBlock(batch, sibling); err != nil || batch.ValueSize() != 0 {
+		t.Fatalf("writes of a block kept over a rewind: %v", err)
+	}
+
+	// Switching branches keeps the writes of the block not committed yet, the
+	// block is written after the drop of the old branch
+	c.Db(posconfig.IncentiveLocalDB).Put(3, "payment", []byte{3})
+	c.BlockDb(posconfig.IncentiveLocalDB, block).Put(3, "pending", []byte{5})
+	batch = chainDb.NewBatch()
+	if err := c.RewindBranch(batch, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitBlock(batch, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(3, "payment"); value != nil {
+		t.Fatal("incentive of the old branch kept")
+	}
+	if value, _ := c.Db(posconfig.IncentiveLocalDB).Get(3, "pending"); len(value) != 1 || value[0] != 5 {
+		t.Fatalf("pending write of the new branch mismatch: %v", value)
+	}
+}
@@ -1,11 +1,9 @@
 package posdb
 
 import (
-	"io/ioutil"
+	"errors"
 	"math/big"
-	"os"
-	"path"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,108 +13,153 @@ import (
 
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
-//Db is the wanpos leveldb class
-type Db struct {
-	db *ethdb.LDBDatabase
-}
+// maxEpochKey is the key of the highest epoch with entries, stored at epoch 0.
+const maxEpochKey = "maxEpoch"
 
 var (
-	dbInstMap = make(map[string]*Db)
-	//RANDOMBEACON_DB_KEY = "PosRandomBeacon"
-
-	mu sync.RWMutex
+	errNoIteration = errors.New("database can't be iterated")
+	errNotFound    = errors.New("not found")
 )
 
-func NewDb(fileName string) *Db {
-	mu.Lock()
-	defer mu.Unlock()
-	db := GetDbByName(fileName)
-	if db != nil {
-		return db
-	}
-
-	dbInst := &Db{db: nil}
-
-	dbInst.DbInit(fileName)
+//Db is the wanpos database class
+type Db struct {
+	db      ethdb.Database
+	prefix  []byte         // prefix of the entries in db, nil for a database of its own
+	own     bool           // whether db is closed by DbClose
+	pending *pendingWrites // buffered writes of a batch, nil otherwise
+}
 
-	dbInstMap[fileName] = dbInst
+// pendingWrites are the writes of a batch, not in the database yet. A nil
+// value marks a deleted key.
+type pendingWrites struct {
+	lock   sync.RWMutex
+	writes map[string][]byte
+}
 
-	return dbInst
+// NewDb opens the wanpos database stored in dirname.
+func NewDb(dirname string) *Db {
+	return NewDbWithEngine(dirname, "")
 }
 
-var dbInstance *Db
+// NewDbWithEngine opens the wanpos database stored in dirname with the given
+// key-value engine, see ethdb.NewDatabase.
+func NewDbWithEngine(dirname, engine string) *Db {
+	db, err := ethdb.NewDatabase(engine, dirname, 0, 256)
+	if err != nil {
+		panic("failed to create wanpos database: " + dirname + "_" + err.Error())
+	}
+	return &Db{db: db, own: true}
+}
 
-func init() {
-	dbInstance = NewDb("")
+// NewTable returns the wanpos database stored in the entries of db starting
+// with prefix. Closing it leaves db open.
+func NewTable(db ethdb.Database, prefix string) *Db {
+	return &Db{db: db, prefix: []byte(prefix)}
 }
 
-// DbInitAll init all db files
-func DbInitAll(pathname string) {
-	posconfig.Cfg().Dbpath = pathname
-	dbInstance = NewDb(posconfig.PosLocalDB)
-	NewDb(posconfig.RbLocalDB)
-	NewDb(posconfig.EpLocalDB)
+// NewBatch returns a database buffering its writes in memory until Write or
+// Commit, reads see the buffered writes.
+func (s *Db) NewBatch() *Db {
+	return &Db{
+		db:      s.db,
+		prefix:  s.prefix,
+		pending: &pendingWrites{writes: make(map[string][]byte)},
+	}
 }
 
-//GetDb can get a Db instance to use
-func GetDb() *Db {
-	return dbInstance
+// Write adds the buffered writes of a batch to w, a batch of the database the
+// writes are for, so that they are written with the other writes of w.
+func (s *Db) Write(w ethdb.Batch) error {
+	if s.pending == nil {
+		return nil
+	}
+	s.pending.lock.RLock()
+	defer s.pending.lock.RUnlock()
+
+	for key, value := range s.pending.writes {
+		var err error
+		if value == nil {
+			err = w.Delete(s.dbKey([]byte(key)))
+		} else {
+			err = w.Put(s.dbKey([]byte(key)), value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func GetDbByName(name string) *Db {
-	return dbInstMap[name]
+// Commit writes the buffered writes of a batch to the database at once.
+func (s *Db) Commit() error {
+	batch := s.db.NewBatch()
+	if err := s.Write(batch); err != nil {
+		return err
+	}
+	return batch.Write()
 }
 
-//DbInit use to init leveldb in this object, user should not use this. It is automate called in init().
-func (s *Db) DbInit(dbPath string) {
-	var dirname string
-	var err error
-	if dbPath == "" {
-		//Remove old unuse tmp files and directorys.
-		files, _ := filepath.Glob(filepath.Join(os.TempDir(), "wanpos_tmpdb_*"))
-		for i := 0; i < len(files); i++ {
-			os.RemoveAll(files[i])
-		}
+func (s *Db) dbKey(key []byte) []byte {
+	if s.prefix == nil {
+		return key
+	}
+	return append(append([]byte{}, s.prefix...), key...)
+}
 
-		dirname, err = ioutil.TempDir(os.TempDir(), "wanpos_tmpdb_")
-		if err != nil {
-			panic("failed to create wanpos_tmpdb file: " + err.Error())
+func (s *Db) dbGet(key []byte) ([]byte, error) {
+	if s.pending != nil {
+		s.pending.lock.RLock()
+		value, ok := s.pending.writes[string(key)]
+		s.pending.lock.RUnlock()
+		if ok && value == nil {
+			return nil, errNotFound
 		}
-	} else {
-		nameIdx := strings.LastIndex(dbPath, string(os.PathSeparator))
-		if nameIdx < 0 {
-			dirname = path.Join(posconfig.Cfg().Dbpath, "gwan")
-			dirname = path.Join(dirname, dbPath)
-		} else {
-			dbPath = path.Join(dbPath, "gwan")
-			dirname = path.Join(dbPath, "wanposdb")
+		if ok {
+			return common.CopyBytes(value), nil
 		}
 	}
+	return s.db.Get(s.dbKey(key))
+}
 
-	if s.db != nil {
-		s.db.Close()
+func (s *Db) dbHas(key []byte) (bool, error) {
+	if s.pending != nil {
+		s.pending.lock.RLock()
+		value, ok := s.pending.writes[string(key)]
+		s.pending.lock.RUnlock()
+		if ok {
+			return value != nil, nil
+		}
 	}
+	return s.db.Has(s.dbKey(key))
+}
 
-	inst, ok := dbInstMap[dbPath]
-
-	if ok {
-		inst.DbClose()
+func (s *Db) dbPut(key []byte, value []byte) error {
+	if s.pending != nil {
+		s.pending.lock.Lock()
+		s.pending.writes[string(key)] = append([]byte{}, value...)
+		s.pending.lock.Unlock()
+		return nil
 	}
+	return s.db.Put(s.dbKey(key), value)
+}
 
-	s.db, err = ethdb.NewLDBDatabase(dirname, 0, 256)
-	if err != nil {
-		panic("failed to create wanpos_tmpdb database: " + dbPath + "_" + err.Error())
+func (s *Db) dbDelete(key []byte) error {
+	if s.pending != nil {
+		s.pending.lock.Lock()
+		s.pending.writes[string(key)] = nil
+		s.pending.lock.Unlock()
+		return nil
 	}
+	return s.db.Delete(s.dbKey(key))
 }
 
 func (s *Db) put(epochID uint64, index uint64, key string, value []byte, saveKey bool) ([]byte, error) {
 	newKey := s.getUniqueKeyBytes(epochID, index, key)
 
-	has, err := s.db.Has(newKey)
+	has, err := s.dbHas(newKey)
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +168,7 @@ func (s *Db) put(epochID uint64, index uint64, key string, value []byte, saveKey
 		saveKey = false
 	}
 
-	s.db.Put(newKey, value)
+	s.dbPut(newKey, value)
 	if saveKey {
 		err = s.saveKey(newKey, epochID)
 	}
@@ -141,7 +184,7 @@ func (s *Db) PutWithIndex(epochID uint64, index uint64, key string, value []byte
 func (s *Db) GetWithIndex(epochID uint64, index uint64, key string) ([]byte, error) {
 	newKey := s.getUniqueKeyBytes(epochID, index, key)
 
-	ret, err := s.db.Get(newKey)
+	ret, err := s.dbGet(newKey)
 	if err != nil {
 		//debug.PrintStack()
 	}
@@ -174,7 +217,93 @@ func (s *Db) saveKey(key []byte, epochID uint64) error {
 		return err
 	}
 
-	return nil
+	if max, ok := s.maxEpoch(); !ok || epochID > max {
+		_, err = s.putNoCount(0, maxEpochKey, convert.Uint64ToBytes(epochID))
+	}
+	return err
+}
+
+// maxEpoch returns the highest epoch with entries, ok is false if there are
+// none.
+func (s *Db) maxEpoch() (epochID uint64, ok bool) {
+	ret, err := s.Get(0, maxEpochKey)
+	if err != nil {
+		return 0, false
+	}
+	return convert.BytesToUint64(ret), true
+}
+
+// DeleteFrom removes the entries of epochID and of all the later epochs from
+// the database, a batch buffers the deletes. The entries of epoch 0 hold the
+// totals over all the epochs and are kept.
+func (s *Db) DeleteFrom(epochID uint64) error {
+	if epochID == 0 {
+		epochID = 1
+	}
+	max, ok := s.maxEpoch()
+	if !ok || max < epochID {
+		return nil
+	}
+	for e := max; e >= epochID; e-- {
+		for i, key := range s.getAllKeys(e) {
+			if key != "" {
+				if err := s.dbDelete([]byte(key)); err != nil {
+					return err
+				}
+			}
+			if err := s.dbDelete(s.getUniqueKeyBytes(0, 0, s.getKeyName(e, uint64(i)))); err != nil {
+				return err
+			}
+		}
+		if err := s.dbDelete(s.getUniqueKeyBytes(0, 0, s.getKeyCountName(e))); err != nil {
+			return err
+		}
+	}
+	_, err := s.putNoCount(0, maxEpochKey, convert.Uint64ToBytes(epochID-1))
+	return err
+}
+
+// Import copies all the entries of src, a wanpos database of its own, into
+// the database.
+func (s *Db) Import(src ethdb.Database) error {
+	it, ok := src.(ethdb.Iteratee)
+	if !ok {
+		return errNoIteration
+	}
+	var (
+		batch    = s.db.NewBatch()
+		max      uint64
+		hasEpoch bool
+		countKey = s.getUniqueKey(0, 0, "keyCount_")
+	)
+	err := it.ForEach(func(key, value []byte) error {
+		if k := string(key); strings.HasPrefix(k, countKey) {
+			if epochID, err := strconv.ParseUint(k[len(countKey):], 10, 64); err == nil && (!hasEpoch || epochID > max) {
+				max, hasEpoch = epochID, true
+			}
+		}
+		if err := batch.Put(s.dbKey(key), common.CopyBytes(value)); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if !hasEpoch {
+		return nil
+	}
+	_, err = s.putNoCount(0, maxEpochKey, convert.Uint64ToBytes(max))
+	return err
 }
 
 func (s *Db) getKeyCount(epochID uint64) uint64 {
@@ -218,7 +347,9 @@ func (s *Db) putNoCount(epochID uint64, key string, value []byte) ([]byte, error
 
 //DbClose use to close db file
 func (s *Db) DbClose() {
-	s.db.Close()
+	if s.own {
+		s.db.Close()
+	}
 }
 
 // GetStorageByteArray : cb is callback function. cb return true indicating like to continue, return false indicating stop
@@ -229,7 +360,7 @@ func (s *Db) GetStorageByteArray(epochID uint64) [][]byte {
 	arrays := make([][]byte, len(keys))
 
 	for i := 0; i < len(keys); i++ {
-		ret, err := s.db.Get([]byte(keys[i]))
+		ret, err := s.dbGet([]byte(keys[i]))
 		if err != nil {
 			log.Warn(err.Error())
 			continue
@@ -256,15 +387,10 @@ type Proposer struct {
 	Probabilities *big.Int
 }
 
-func GetRBProposerGroup(epochId uint64) [][]byte {
-	db := NewDb(posconfig.RbLocalDB)
-	if db == nil {
-		// todo : os.Exit ??
-		log.SyslogErr("GetRBProposerGroup create db error")
-		return nil
-	}
-
-	proposersArray := db.GetStorageByteArray(epochId)
+// GetRBProposerGroup returns the bn256 public keys of the random beacon
+// proposers stored in the db.
+func (s *Db) GetRBProposerGroup(epochId uint64) [][]byte {
+	proposersArray := s.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	g1s := make([][]byte, length, length)
 
@@ -281,13 +407,8 @@ func GetRBProposerGroup(epochId uint64) [][]byte {
 
 }
 
-func GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
-	db := NewDb(posconfig.StakerLocalDB)
-	if db == nil {
-		log.SyslogErr("GetStakerInfo create db error")
-		return nil
-	}
-	stakerBytes, err := db.GetWithIndex(epochId, 0, common.ToHex(addr[:]))
+func (s *Db) GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
+	stakerBytes, err := s.GetWithIndex(epochId, 0, common.ToHex(addr[:]))
 	if err != nil {
 		return nil
 	}
@@ -295,14 +416,10 @@ func GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
 	return stakerBytes
 }
 
-func GetEpochLeaderGroup(epochId uint64) [][]byte {
-	db := NewDb(posconfig.EpLocalDB)
-	if db == nil {
-		log.SyslogErr("GetEpochLeaderGroup create db error")
-		return nil
-	}
-
-	proposersArray := db.GetStorageByteArray(epochId)
+// GetEpochLeaderGroup returns the secp256k1 public keys of the epoch leaders
+// stored in the db.
+func (s *Db) GetEpochLeaderGroup(epochId uint64) [][]byte {
+	proposersArray := s.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	pks := make([][]byte, length, length)
 
@@ -3,37 +3,41 @@ package posdb
 import (
 	"encoding/hex"
 	"fmt"
-	"os"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/common"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
 )
 
-func TestDbInitAll(t *testing.T) {
-	os.RemoveAll("/tmp/gwan")
-	DbInitAll("/tmp")
-	db := NewDb(posconfig.PosLocalDB)
-	if db == nil {
-		t.Fail()
-	}
+func newTestDb(t *testing.T, name string) *Db {
+	return newTestDbWithEngine(t, name, "")
+}
 
-	db = NewDb(posconfig.RbLocalDB)
-	if db == nil {
-		t.Fail()
+func newTestDbWithEngine(t *testing.T, name, engine string) *Db {
+	dir, err := ioutil.TempDir("", "posdb-test")
+	if err != nil {
+		t.Fatal(err)
 	}
+	return NewDbWithEngine(filepath.Join(dir, name), engine)
+}
 
-	db = NewDb(posconfig.EpLocalDB)
-	if db == nil {
-		t.Fail()
+func TestDbConcurrentAccess(t *testing.T) {
+	dbs := []*Db{
+		newTestDb(t, posconfig.PosLocalDB),
+		newTestDb(t, posconfig.RbLocalDB),
+		newTestDb(t, posconfig.EpLocalDB),
+		newTestDbWithEngine(t, posconfig.ReorgLocalDB, ethdb.EngineBolt),
 	}
 
 	testCount := 1000
 	keys := make([][]byte, testCount)
-	for i := 0; i < 1000; i++ {
+	for i := 0; i < testCount; i++ {
 		key, _ := crypto.GenerateKey()
 		keys[i] = crypto.FromECDSAPub(&key.PublicKey)
 		if !util.PkEqual(&key.PublicKey, &key.PublicKey) {
@@ -41,109 +45,194 @@ func TestDbInitAll(t *testing.T) {
 		}
 	}
 
-	allQuit := make(chan struct{}, 3)
-
-	go func() {
-		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.PosLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
-		}
-
-		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
-			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
-				t.Fail()
-			}
-		}
-
-		bufs := db.GetStorageByteArray(0)
-		for i := 0; i < testCount; i++ {
-			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
-				t.Fail()
-			}
-		}
-
-		allQuit <- struct{}{}
-	}()
-
-	go func() {
-		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.RbLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
-		}
-
-		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
-			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
-				t.Fail()
-			}
-		}
-
-		bufs := db.GetStorageByteArray(0)
-		for i := 0; i < testCount; i++ {
-			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
-				t.Fail()
+	allQuit := make(chan struct{}, len(dbs))
+	for _, db := range dbs {
+		go func(db *Db) {
+			for i := 0; i < testCount; i++ {
+				db.PutWithIndex(0, uint64(i), "", keys[i])
 			}
-		}
-
-		allQuit <- struct{}{}
-	}()
 
-	go func() {
-		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.EpLocalDB)
-			db.PutWithIndex(0, uint64(i), "", keys[i])
-		}
-
-		for i := 0; i < testCount; i++ {
-			value, err := db.GetWithIndex(0, uint64(i), "")
-			if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
-				t.Fail()
+			for i := 0; i < testCount; i++ {
+				value, err := db.GetWithIndex(0, uint64(i), "")
+				if hex.EncodeToString(value) != hex.EncodeToString(keys[i]) || err != nil {
+					t.Fail()
+				}
 			}
-		}
 
-		bufs := db.GetStorageByteArray(0)
-		for i := 0; i < testCount; i++ {
-			if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
+			bufs := db.GetStorageByteArray(0)
+			if len(bufs) != testCount {
 				t.Fail()
+			} else {
+				for i := 0; i < testCount; i++ {
+					if hex.EncodeToString(bufs[i]) != hex.EncodeToString(keys[i]) {
+						t.Fail()
+					}
+				}
 			}
-		}
-
-		allQuit <- struct{}{}
-	}()
 
-	select {
-	case <-allQuit:
+			allQuit <- struct{}{}
+		}(db)
 	}
-	select {
-	case <-allQuit:
-	}
-	select {
-	case <-allQuit:
+	for range dbs {
+		<-allQuit
 	}
 
-	db = NewDb("test")
+	db := newTestDb(t, "test")
 	db.Put(0, "hello", []byte{1, 2, 3})
 	buf, err := db.Get(0, "hello")
 	if buf[0] != 1 || buf[1] != 2 || buf[2] != 3 || err != nil {
 		t.Fail()
 	}
-
-	db = GetDb()
 	db.Put(0, "hello", []byte{3, 4, 5})
 	buf, err = db.Get(0, "hello")
 	if buf[0] != 3 || buf[1] != 4 || buf[2] != 5 || err != nil {
 		t.Fail()
 	}
 
-	db.DbClose()
+	for _, db := range append(dbs, db) {
+		db.DbClose()
+	}
 }
 
 func TestInfomationGet(t *testing.T) {
-	buf := GetRBProposerGroup(0)
+	db := newTestDb(t, "info")
+	defer db.DbClose()
+
+	buf := db.GetRBProposerGroup(0)
 	fmt.Println(buf)
-	buf2 := GetStakerInfoBytes(0, common.Address{})
+	buf2 := db.GetStakerInfoBytes(0, common.Address{})
 	fmt.Println(buf2)
-	buf4 := GetEpochLeaderGroup(0)
+	buf4 := db.GetEpochLeaderGroup(0)
 	fmt.Println(buf4)
 }
+
+func TestTableBatch(t *testing.T) {
+	chainDb, _ := ethdb.NewMemDatabase()
+	db := NewTable(chainDb, "pos-test-")
+	db.Put(1, "committed", []byte{1})
+
+	batch := db.NewBatch()
+	batch.Put(1, "pending", []byte{2})
+	if value, err := batch.Get(1, "committed"); err != nil || value[0] != 1 {
+		t.Fatalf("committed value not seen by batch: %v, %v", value, err)
+	}
+	if value, err := batch.Get(1, "pending"); err != nil || value[0] != 2 {
+		t.Fatalf("buffered value not seen by batch: %v, %v", value, err)
+	}
+	if value, _ := db.Get(1, "pending"); value != nil {
+		t.Fatal("buffered value written before commit")
+	}
+	// The writes land in the table of the chain database
+	chainBatch := chainDb.NewBatch()
+	if err := batch.Write(chainBatch); err != nil {
+		t.Fatal(err)
+	}
+	if err := chainBatch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := db.Get(1, "pending"); err != nil || value[0] != 2 {
+		t.Fatalf("buffered value not written: %v, %v", value, err)
+	}
+	if has, _ := chainDb.Has([]byte("pos-test-1_0_pending")); !has {
+		t.Fatal("value not stored in the table")
+	}
+	if len(db.GetStorageByteArray(1)) != 2 {
+		t.Fatal("key index not written with the batch")
+	}
+	// Closing the table leaves the chain database open
+	db.DbClose()
+	if _, err := chainDb.Get([]byte("pos-test-1_0_pending")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteFrom(t *testing.T) {
+	chainDb, _ := ethdb.NewMemDatabase()
+	db := NewTable(chainDb, "pos-test-")
+	db.Put(0, "total", []byte{0})
+	for epochID := uint64(1); epochID <= 5; epochID++ {
+		for i := uint64(0); i < 3; i++ {
+			db.PutWithIndex(epochID, i, "", []byte{byte(epochID), byte(i)})
+		}
+	}
+	if err := db.DeleteFrom(3); err != nil {
+		t.Fatal(err)
+	}
+	for epochID := uint64(1); epochID <= 5; epochID++ {
+		want := 3
+		if epochID >= 3 {
+			want = 0
+		}
+		if have := len(db.GetStorageByteArray(epochID)); have != want {
+			t.Fatalf("epoch %d: entries mismatch: have %d, want %d", epochID, have, want)
+		}
+		if _, err := db.GetWithIndex(epochID, 0, ""); (err == nil) != (epochID < 3) {
+			t.Fatalf("epoch %d: entry presence mismatch: %v", epochID, err)
+		}
+	}
+	if value, err := db.Get(0, "total"); err != nil || value[0] != 0 {
+		t.Fatal("epoch 0 entries removed")
+	}
+	// Epochs written again after the rewind can be dropped again
+	db.PutWithIndex(4, 0, "", []byte{4})
+	if err := db.DeleteFrom(4); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.GetStorageByteArray(4)) != 0 {
+		t.Fatal("rewritten epoch not removed")
+	}
+	// A batch buffers the deletes until written
+	batch := db.NewBatch()
+	if err := batch.DeleteFrom(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.GetWithIndex(2, 0, ""); err == nil {
+		t.Fatal("deleted entry read through the batch")
+	}
+	if _, err := db.GetWithIndex(2, 0, ""); err != nil {
+		t.Fatalf("entry deleted before the batch is written: %v", err)
+	}
+	chainBatch := chainDb.NewBatch()
+	if err := batch.Write(chainBatch); err != nil {
+		t.Fatal(err)
+	}
+	if err := chainBatch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.GetStorageByteArray(2)) != 0 {
+		t.Fatal("batched delete not written")
+	}
+	if len(db.GetStorageByteArray(1)) != 3 {
+		t.Fatal("earlier epoch removed")
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "posdb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewDb(filepath.Join(dir, posconfig.EpLocalDB))
+	for epochID := uint64(1); epochID <= 3; epochID++ {
+		src.PutWithIndex(epochID, 0, "", []byte{byte(epochID)})
+	}
+	chainDb, _ := ethdb.NewMemDatabase()
+	db := NewTable(chainDb, "pos-test-")
+	if err := db.Import(src.db); err != nil {
+		t.Fatal(err)
+	}
+	src.DbClose()
+
+	for epochID := uint64(1); epochID <= 3; epochID++ {
+		if arrays := db.GetStorageByteArray(epochID); len(arrays) != 1 || arrays[0][0] != byte(epochID) {
+			t.Fatalf("epoch %d: imported entries mismatch: %v", epochID, arrays)
+		}
+	}
+	// The imported epochs can be rewound
+	if err := db.DeleteFrom(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.GetStorageByteArray(3)) != 0 {
+		t.Fatal("imported epoch not removed")
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package possigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// remoteTimeout bounds a call to the signer process, a slot proof must still
+// make it into the block of the slot.
+const remoteTimeout = 2 * time.Second
+
+// Account is the public part of the keys of a validator.
+type Account struct {
+	Address        common.Address `json:"address"`
+	PublicKey      hexutil.Bytes  `json:"publicKey"`
+	Bn256PublicKey hexutil.Bytes  `json:"bn256PublicKey"`
+}
+
+// SlotProofArgs are the arguments of possigner_slotLeaderProof.
+type SlotProofArgs struct {
+	SMA          []hexutil.Bytes `json:"sma"`
+	EpochLeaders []hexutil.Bytes `json:"epochLeaders"`
+	RB           hexutil.Bytes   `json:"rb"`
+	SlotID       hexutil.Uint64  `json:"slotId"`
+	EpochID      hexutil.Uint64  `json:"epochId"`
+}
+
+// SlotProof is the result of possigner_slotLeaderProof.
+type SlotProof struct {
+	ProofMeg []hexutil.Bytes `json:"proofMeg"`
+	Proof    []*hexutil.Big  `json:"proof"`
+}
+
+func encodePks(pks []*ecdsa.PublicKey) []hexutil.Bytes {
+	enc := make([]hexutil.Bytes, len(pks))
+	for i, pk := range pks {
+		enc[i] = crypto.FromECDSAPub(pk)
+	}
+	return enc
+}
+
+func decodePks(enc []hexutil.Bytes) ([]*ecdsa.PublicKey, error) {
+	pks := make([]*ecdsa.PublicKey, len(enc))
+	for i := range enc {
+		if pks[i] = crypto.ToECDSAPub(enc[i]); pks[i] == nil {
+			return nil, ErrInvalidKey
+		}
+	}
+	return pks, nil
+}
+
+// API serves a Signer to the nodes of the validator, under the possigner
+// namespace.
+type API struct {
+	signer Signer
+}
+
+// NewAPI creates the RPC API of a signer.
+func NewAPI(signer Signer) *API {
+	return &API{signer: signer}
+}
+
+// NewServer creates an RPC server serving the API of a signer.
+func NewServer(signer Signer) (*rpc.Server, error) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("possigner", NewAPI(signer)); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+// Account returns the public keys of the validator.
+func (api *API) Account() Account {
+	account := Account{
+		Address:   api.signer.Address(),
+		PublicKey: crypto.FromECDSAPub(api.signer.PublicKey()),
+	}
+	if pk := api.signer.Bn256PublicKey(); pk != nil {
+		account.Bn256PublicKey = pk.Marshal()
+	}
+	return account
+}
+
+// SignHash signs a block seal hash.
+func (api *API) SignHash(hash hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.signer.SignHash(hash)
+}
+
+// SignTx signs a binary encoded PoS tx and returns it encoded.
+func (api *API) SignTx(data hexutil.Bytes, chainID *hexutil.Big) (hexutil.Bytes, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	signed, err := api.signer.SignTx(tx, (*big.Int)(chainID))
+	if err != nil {
+		return nil, err
+	}
+	return signed.MarshalBinary()
+}
+
+// SlotLeaderProof generates a slot leader proof.
+func (api *API) SlotLeaderProof(args SlotProofArgs) (*SlotProof, error) {
+	sma, err := decodePks(args.SMA)
+	if err != nil {
+		return nil, err
+	}
+	epochLeaders, err := decodePks(args.EpochLeaders)
+	if err != nil {
+		return nil, err
+	}
+	proofMeg, proof, err := api.signer.SlotLeaderProof(sma, epochLeaders, args.RB, uint64(args.SlotID), uint64(args.EpochID))
+	if err != nil {
+		return nil, err
+	}
+	res := &SlotProof{ProofMeg: encodePks(proofMeg), Proof: make([]*hexutil.Big, len(proof))}
+	for i := range proof {
+		res.Proof[i] = (*hexutil.Big)(proof[i])
+	}
+	return res, nil
+}
+
+// GenerateSMA generates a secret message array.
+func (api *API) GenerateSMA(pieces []hexutil.Bytes) ([]hexutil.Bytes, error) {
+	pks, err := decodePks(pieces)
+	if err != nil {
+		return nil, err
+	}
+	sma, err := api.signer.GenerateSMA(pks)
+	if err != nil {
+		return nil, err
+	}
+	return encodePks(sma), nil
+}
+
+// DecryptShare decrypts a sum of encrypted DKG shares.
+func (api *API) DecryptShare(enshare hexutil.Bytes) (hexutil.Bytes, error) {
+	point := new(bn256.G1)
+	if _, err := point.Unmarshal(enshare); err != nil {
+		return nil, err
+	}
+	share, err := api.signer.DecryptShare(point)
+	if err != nil {
+		return nil, err
+	}
+	return share.Marshal(), nil
+}
+
+// Remote is a Signer forwarding the operations to the API of a signer
+// process, which keeps the keys of the validator.
+type Remote struct {
+	client  *rpc.Client
+	address common.Address
+	pk      *ecdsa.PublicKey
+	bn256   *bn256.G1
+}
+
+// Dial connects to the signer process listening on an endpoint, an IPC path
+// or a http or websocket URL.
+func Dial(endpoint string) (*Remote, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewRemote(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewRemote creates a Signer using the API served to client.
+func NewRemote(client *rpc.Client) (*Remote, error) {
+	s := &Remote{client: client}
+
+	var account Account
+	if err := s.call(&account, "possigner_account"); err != nil {
+		return nil, err
+	}
+	s.address = account.Address
+	if s.pk = crypto.ToECDSAPub(account.PublicKey); s.pk == nil || crypto.PubkeyToAddress(*s.pk) != s.address {
+		return nil, ErrInvalidKey
+	}
+	if len(account.Bn256PublicKey) > 0 {
+		s.bn256 = new(bn256.G1)
+		if _, err := s.bn256.Unmarshal(account.Bn256PublicKey); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close closes the connection to the signer process.
+func (s *Remote) Close() {
+	s.client.Close()
+}
+
+func (s *Remote) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	return s.client.CallContext(ctx, result, method, args...)
+}
+
+func (s *Remote) Address() common.Address {
+	return s.address
+}
+
+func (s *Remote) PublicKey() *ecdsa.PublicKey {
+	return s.pk
+}
+
+func (s *Remote) Bn256PublicKey() *bn256.G1 {
+	return s.bn256
+}
+
+func (s *Remote) SignHash(hash []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.call(&sig, "possigner_signHash", hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (s *Remote) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var res hexutil.Bytes
+	if err := s.call(&res, "possigner_signTx", hexutil.Bytes(data), (*hexutil.Big)(chainID)); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (s *Remote) SlotLeaderProof(sma []*ecdsa.PublicKey, epochLeaders []*ecdsa.PublicKey, rb []byte,
+	slotID uint64, epochID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
+	args := SlotProofArgs{
+		SMA:          encodePks(sma),
+		EpochLeaders: encodePks(epochLeaders),
+		RB:           rb,
+		SlotID:       hexutil.Uint64(slotID),
+		EpochID:      hexutil.Uint64(epochID),
+	}
+	var res SlotProof
+	if err := s.call(&res, "possigner_slotLeaderProof", args); err != nil {
+		return nil, nil, err
+	}
+	proofMeg, err := decodePks(res.ProofMeg)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := make([]*big.Int, len(res.Proof))
+	for i := range res.Proof {
+		proof[i] = (*big.Int)(res.Proof[i])
+	}
+	return proofMeg, proof, nil
+}
+
+func (s *Remote) GenerateSMA(pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error) {
+	var res []hexutil.Bytes
+	if err := s.call(&res, "possigner_generateSMA", encodePks(pieces)); err != nil {
+		return nil, err
+	}
+	return decodePks(res)
+}
+
+func (s *Remote) DecryptShare(enshare *bn256.G1) (*bn256.G1, error) {
+	var res hexutil.Bytes
+	if err := s.call(&res, "possigner_decryptShare", hexutil.Bytes(enshare.Marshal())); err != nil {
+		return nil, err
+	}
+	share := new(bn256.G1)
+	if _, err := share.Unmarshal(res); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package possigner holds the keys of a PoS validator behind the operations
+// the node needs them for, so that they can be kept out of the node process.
+package possigner
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+)
+
+var (
+	ErrNoKey      = errors.New("validator key is not unlocked")
+	ErrInvalidKey = errors.New("invalid validator public key")
+)
+
+// Signer performs the operations that need the secp256k1 and bn256 keys of a
+// validator.
+type Signer interface {
+	// Address returns the address of the validator.
+	Address() common.Address
+
+	// PublicKey returns the secp256k1 public key of the validator.
+	PublicKey() *ecdsa.PublicKey
+
+	// Bn256PublicKey returns the bn256 public key of the validator.
+	Bn256PublicKey() *bn256.G1
+
+	// SignHash signs a hash with the secp256k1 key, it seals the blocks.
+	SignHash(hash []byte) ([]byte, error)
+
+	// SignTx signs the PoS txs of the validator, the SMA stage and random
+	// beacon txs.
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+	// SlotLeaderProof generates the proof that the validator leads a slot,
+	// see uleaderselection.GenerateSlotLeaderProof.
+	SlotLeaderProof(sma []*ecdsa.PublicKey, epochLeaders []*ecdsa.PublicKey, rb []byte,
+		slotID uint64, epochID uint64) ([]*ecdsa.PublicKey, []*big.Int, error)
+
+	// GenerateSMA generates the secret message array of the validator from the
+	// array pieces of the stage two txs, see uleaderselection.GenerateSMA.
+	GenerateSMA(pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error)
+
+	// DecryptShare decrypts the sum of the encrypted DKG shares of the
+	// validator with the bn256 key, it's the group secret key share the
+	// random beacon signature share is made with.
+	DecryptShare(enshare *bn256.G1) (*bn256.G1, error)
+}
+
+// Local is a Signer holding the unlocked key of the validator in process.
+type Local struct {
+	key   *keystore.Key
+	bn256 *big.Int // nil if the key has no bn256 part
+}
+
+// NewLocal creates a Signer of an unlocked keystore key.
+func NewLocal(key *keystore.Key) *Local {
+	s := &Local{key: key}
+	if key.PrivateKey2 != nil {
+		s.bn256 = posconfig.GenerateD3byKey2(key.PrivateKey2)
+	}
+	return s
+}
+
+func (s *Local) Address() common.Address {
+	return s.key.Address
+}
+
+func (s *Local) PublicKey() *ecdsa.PublicKey {
+	return &s.key.PrivateKey.PublicKey
+}
+
+func (s *Local) Bn256PublicKey() *bn256.G1 {
+	if s.bn256 == nil {
+		return nil
+	}
+	return new(bn256.G1).ScalarBaseMult(s.bn256)
+}
+
+func (s *Local) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key.PrivateKey)
+}
+
+// SignTx signs tx like the keystore.
+func (s *Local) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if chainID != nil {
+		return types.SignTx(tx, types.NewTypedTxSigner(chainID), s.key.PrivateKey)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, s.key.PrivateKey)
+}
+
+func (s *Local) SlotLeaderProof(sma []*ecdsa.PublicKey, epochLeaders []*ecdsa.PublicKey, rb []byte,
+	slotID uint64, epochID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
+	return uleaderselection.GenerateSlotLeaderProof(s.key.PrivateKey, sma, epochLeaders, rb, slotID, epochID)
+}
+
+func (s *Local) GenerateSMA(pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error) {
+	return uleaderselection.GenerateSMA(s.key.PrivateKey, pieces)
+}
+
+func (s *Local) DecryptShare(enshare *bn256.G1) (*bn256.G1, error) {
+	if s.bn256 == nil {
+		return nil, ErrNoKey
+	}
+	skinver := new(big.Int).ModInverse(s.bn256, bn256.Order)
+	return new(bn256.G1).ScalarMult(enshare, skinver), nil
+}
+
+// walletSigner is implemented by the wallets holding a Signer, see Wallet.
+type walletSigner interface {
+	Signer() Signer
+}
+
+// unlockedKeyGetter is implemented by the keystore wallets.
+type unlockedKeyGetter interface {
+	GetUnlockedKey(address common.Address) (*keystore.Key, error)
+}
+
+// FromWallet returns the Signer of the validator address held by a wallet of
+// the account manager: the remote signer of a Wallet, or a Local signer of
+// the key unlocked in a keystore wallet.
+func FromWallet(wallet interface{}, address common.Address) (Signer, error) {
+	switch w := wallet.(type) {
+	case walletSigner:
+		if s := w.Signer(); s.Address() == address {
+			return s, nil
+		}
+	case unlockedKeyGetter:
+		key, err := w.GetUnlockedKey(address)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil || key.PrivateKey == nil {
+			return nil, ErrNoKey
+		}
+		return NewLocal(key), nil
+	}
+	return nil, ErrNoKey
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package possigner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+func newTestKey(t *testing.T) *keystore.Key {
+	sk1, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk2, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keystore.Key{Address: crypto.PubkeyToAddress(sk1.PublicKey), PrivateKey: sk1, PrivateKey2: sk2}
+}
+
+// newTestRemote serves a local signer in process and connects a Remote to it.
+func newTestRemote(t *testing.T) (*Local, *Remote) {
+	local := NewLocal(newTestKey(t))
+	srv, err := NewServer(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := NewRemote(rpc.DialInProc(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local, remote
+}
+
+func randomPks(t *testing.T, n int) []*ecdsa.PublicKey {
+	pks := make([]*ecdsa.PublicKey, n)
+	for i := range pks {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pks[i] = &key.PublicKey
+	}
+	return pks
+}
+
+func TestRemoteAccount(t *testing.T) {
+	local, remote := newTestRemote(t)
+	defer remote.Close()
+
+	if remote.Address() != local.Address() {
+		t.Errorf("address mismatch: have %x, want %x", remote.Address(), local.Address())
+	}
+	if !uleaderselection.PublicKeyEqual(remote.PublicKey(), local.PublicKey()) {
+		t.Errorf("public key mismatch")
+	}
+	if !bytes.Equal(remote.Bn256PublicKey().Marshal(), local.Bn256PublicKey().Marshal()) {
+		t.Errorf("bn256 public key mismatch")
+	}
+}
+
+func TestRemoteSign(t *testing.T) {
+	local, remote := newTestRemote(t)
+	defer remote.Close()
+
+	hash := crypto.Keccak256([]byte("block"))
+	sig, err := remote.SignHash(hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != local.Address() {
+		t.Errorf("hash signer mismatch: have %x, want %x", crypto.PubkeyToAddress(*pub), local.Address())
+	}
+
+	chainID := big.NewInt(6)
+	tx := types.NewTransaction(1, common.Address{1}, big.NewInt(0), big.NewInt(100000), big.NewInt(1), []byte{1, 2, 3})
+	signed, err := remote.SignTx(tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	from, err := types.Sender(types.NewTypedTxSigner(chainID), signed)
+	if err != nil {
+		t.Fatalf("failed to recover tx sender: %v", err)
+	}
+	if from != local.Address() {
+		t.Errorf("tx sender mismatch: have %x, want %x", from, local.Address())
+	}
+}
+
+func TestRemoteSlotLeaderProof(t *testing.T) {
+	local, remote := newTestRemote(t)
+	defer remote.Close()
+
+	// The validator is the only epoch leader, so it leads every slot.
+	epochLeaders := []*ecdsa.PublicKey{local.PublicKey(), local.PublicKey(), local.PublicKey()}
+	sma := randomPks(t, 3)
+	rb := crypto.Keccak256([]byte("random"))
+
+	proofMeg, proof, err := remote.SlotLeaderProof(sma, epochLeaders, rb, 5, 18000)
+	if err != nil {
+		t.Fatalf("failed to generate proof: %v", err)
+	}
+	if !uleaderselection.VerifySlotLeaderProof(proof, proofMeg, epochLeaders, rb) {
+		t.Errorf("remote slot leader proof doesn't verify")
+	}
+
+	pieces := randomPks(t, 4)
+	want, err := local.GenerateSMA(pieces)
+	if err != nil {
+		t.Fatalf("failed to generate local SMA: %v", err)
+	}
+	have, err := remote.GenerateSMA(pieces)
+	if err != nil {
+		t.Fatalf("failed to generate remote SMA: %v", err)
+	}
+	if len(have) != len(want) {
+		t.Fatalf("SMA length mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if !uleaderselection.PublicKeyEqual(have[i], want[i]) {
+			t.Errorf("SMA %d mismatch", i)
+		}
+	}
+}
+
+func TestRemoteDecryptShare(t *testing.T) {
+	local, remote := newTestRemote(t)
+	defer remote.Close()
+
+	// A share encrypted with the bn256 public key decrypts to share·G.
+	share := big.NewInt(123456789)
+	enshare := new(bn256.G1).ScalarMult(local.Bn256PublicKey(), share)
+	have, err := remote.DecryptShare(enshare)
+	if err != nil {
+		t.Fatalf("failed to decrypt share: %v", err)
+	}
+	if want := new(bn256.G1).ScalarBaseMult(share); !bytes.Equal(have.Marshal(), want.Marshal()) {
+		t.Errorf("decrypted share mismatch")
+	}
+
+	key := newTestKey(t)
+	key.PrivateKey2 = nil
+	if _, err := NewLocal(key).DecryptShare(enshare); err != ErrNoKey {
+		t.Errorf("error mismatch without bn256 key: have %v, want %v", err, ErrNoKey)
+	}
+}
+
+func TestFromWallet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "possigner-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := ks.Wallets()[0]
+	if _, err := FromWallet(wallet, account.Address); err == nil {
+		t.Errorf("got a signer of a locked account")
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := FromWallet(wallet, account.Address)
+	if err != nil {
+		t.Fatalf("failed to get keystore signer: %v", err)
+	}
+	if signer.Address() != account.Address || signer.Bn256PublicKey() == nil {
+		t.Errorf("keystore signer mismatch")
+	}
+
+	backend := NewBackend(signer, accounts.URL{Scheme: "possigner", Path: "test.ipc"})
+	remoteWallet := backend.Wallets()[0]
+	if !remoteWallet.Contains(accounts.Account{Address: account.Address}) {
+		t.Errorf("signer wallet doesn't contain the validator account")
+	}
+	if s, err := FromWallet(remoteWallet, account.Address); err != nil || s != signer {
+		t.Errorf("signer wallet signer mismatch: %v", err)
+	}
+	if _, err := FromWallet(remoteWallet, common.Address{1}); err != ErrNoKey {
+		t.Errorf("error mismatch for an other address: have %v, want %v", err, ErrNoKey)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package possigner
+
+import (
+	"math/big"
+
+	ethereum "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/event"
+)
+
+// Wallet is the accounts.Wallet of the validator account of a Signer. Added
+// to the account manager it signs the block seals and the PoS txs of the
+// validator, which are sent through eth_sendPosTransaction.
+type Wallet struct {
+	signer Signer
+	url    accounts.URL
+}
+
+// NewWallet creates the wallet of a signer, url locates the signer.
+func NewWallet(signer Signer, url accounts.URL) *Wallet {
+	return &Wallet{signer: signer, url: url}
+}
+
+// Signer returns the signer of the wallet.
+func (w *Wallet) Signer() Signer {
+	return w.signer
+}
+
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+func (w *Wallet) Status() (string, error) {
+	return "Online", nil
+}
+
+func (w *Wallet) Open(passphrase string) error {
+	return nil
+}
+
+func (w *Wallet) Close() error {
+	return nil
+}
+
+func (w *Wallet) Accounts() []accounts.Account {
+	return []accounts.Account{{Address: w.signer.Address(), URL: w.url}}
+}
+
+func (w *Wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.signer.Address() && (account.URL == (accounts.URL{}) || account.URL == w.url)
+}
+
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+func (w *Wallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+func (w *Wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.signer.SignHash(hash)
+}
+
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.signer.SignTx(tx, chainID)
+}
+
+// SignHashWithPassphrase isn't supported, the signer process holds the key
+// unlocked.
+func (w *Wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase isn't supported, the signer process holds the key
+// unlocked.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *Wallet) GetWanAddress(account accounts.Account) (common.WAddress, error) {
+	return common.WAddress{}, accounts.ErrNotSupported
+}
+
+func (w *Wallet) ComputeOTAPPKeys(account accounts.Account, AX, AY, BX, BY string) ([]string, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// Backend is the accounts.Backend of the wallet of a signer.
+type Backend struct {
+	wallet *Wallet
+	feed   event.Feed
+}
+
+// NewBackend creates the backend of the wallet of a signer.
+func NewBackend(signer Signer, url accounts.URL) *Backend {
+	return &Backend{wallet: NewWallet(signer, url)}
+}
+
+func (b *Backend) Wallets() []accounts.Wallet {
+	return []accounts.Wallet{b.wallet}
+}
+
+// Subscribe implements accounts.Backend, the wallet never changes.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.feed.Subscribe(sink)
+}
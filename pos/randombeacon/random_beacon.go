@@ -3,7 +3,6 @@ package randombeacon
 import (
 	"crypto/rand"
 	"errors"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"io"
 	"sync"
@@ -20,6 +19,8 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/signprotect"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -124,9 +125,11 @@ type RandomBeacon struct {
 	proposerPks  []bn256.G1
 	myPropserIds []uint32
 
+	ctx       *posctx.Context
 	statedb   vm.StateDB
 	epocher   *epochLeader.Epocher
 	rpcClient *rpc.Client
+	sendTxFn  func(rc *rpc.Client, tx map[string]interface{}) // nil sends with util.SendPosTxAsync
 
 	wg sync.WaitGroup
 	mutex sync.Mutex
@@ -145,7 +148,6 @@ type RandomBeacon struct {
 var (
 	maxUint64      = uint64(1<<64 - 1)
 	loopEventCount = 1000
-	randomBeacon   RandomBeacon
 	rbPloys        = "RB_PLOYS"
 )
 
@@ -157,10 +159,17 @@ var (
 	errInsufficient    = errors.New("insufficient proposer")
 	errUninitialized   = errors.New("random beacon uninitialized")
 	errNotAllTaskSuc   = errors.New("not all task succeed")
+	errNoMinerKey      = errors.New("no miner key")
 )
 
-func GetRandonBeaconInst() *RandomBeacon {
-	return &randomBeacon
+const serviceName = "randomBeacon"
+
+// GetRandonBeaconInst returns the random beacon of the node, it is created
+// uninitialized on first use.
+func GetRandonBeaconInst(ctx *posctx.Context) *RandomBeacon {
+	return ctx.LoadOrRegister(serviceName, func() interface{} {
+		return &RandomBeacon{ctx: ctx}
+	}).(*RandomBeacon)
 }
 
 func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
@@ -181,10 +190,12 @@ func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
 	rb.epocher = epocher
 
 	// function
-	rb.getRBProposerGroupF = epochLeader.GetEpocher().GetRBProposerG1
+	rb.getRBProposerGroupF = epocher.GetRBProposerG1
 	rb.getCji = vm.GetCji
 	rb.getEns = vm.GetEncryptShare
-	rb.getRBM = vm.GetRBM
+	rb.getRBM = func(db vm.StateDB, epochId uint64) ([]byte, error) {
+		return vm.GetRBM(db, epochId, rb.ctx.FirstEpochId())
+	}
 	rb.fDoDKG1s = rb.doDKG1s
 	rb.fDoDKG2s = rb.doDKG2s
 	rb.fDoSIGs = rb.doSIGs
@@ -224,7 +235,8 @@ func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid
 		return errUninitialized
 	}
 
-	if statedb == nil || rc == nil {
+	// Without rc, the txs can only be sent by the sender of SetSendTxFn
+	if statedb == nil || (rc == nil && rb.sendTxFn == nil) {
 		log.SyslogErr("invalid RB loop input param")
 		return errInvalidInParam
 	}
@@ -233,6 +245,29 @@ func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid
 	return
 }
 
+// LoopSync runs the work of a slot in the calling goroutine, for callers
+// driving the random beacon themselves instead of using Loop. The tx sender
+// decides what to do with a nil rc.
+func (rb *RandomBeacon) LoopSync(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid uint64) error {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	if rb.loopEvents == nil {
+		return errUninitialized
+	}
+	if statedb == nil {
+		return errInvalidInParam
+	}
+	return rb.doLoop(statedb, rc, eid, sid)
+}
+
+// SetSendTxFn replaces the function sending the random beacon txs.
+func (rb *RandomBeacon) SetSendTxFn(fn func(rc *rpc.Client, tx map[string]interface{})) {
+	rb.mutex.Lock()
+	rb.sendTxFn = fn
+	rb.mutex.Unlock()
+}
+
 func (rb *RandomBeacon) LoopRoutine() {
 	defer rb.wg.Done()
 
@@ -361,7 +396,7 @@ func (rb *RandomBeacon) doLoop(statedb vm.StateDB, rc *rpc.Client, epochId uint6
 }
 
 func (rb *RandomBeacon) isTaskAllDone() bool {
-	if posconfig.SelfTestMode {
+	if rb.ctx.SelfTestMode() {
 		return true
 	}
 
@@ -387,7 +422,7 @@ func (rb *RandomBeacon) getMyRBProposerId(epochId uint64) []uint32 {
 	}
 
 	log.SyslogInfo("get my RBP id", "RBP group pk", pks)
-	selfPk := posconfig.Cfg().GetMinerBn256PK()
+	selfPk := rb.ctx.MinerBn256PK()
 	if selfPk == nil {
 		log.SyslogInfo("get my RBP id, can't get miner bn256 pk")
 		return nil
@@ -616,7 +651,10 @@ func (rb *RandomBeacon) doSIG(proposerId uint32) error {
 }
 
 func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, error) {
-	prikey := posconfig.Cfg().GetMinerBn256SK()
+	signer := rb.ctx.Signer()
+	if signer == nil {
+		return nil, errNoMinerKey
+	}
 	datas := make([]RbEnsDataCollector, 0)
 
 	for id, pk := range rb.proposerPks {
@@ -638,15 +676,15 @@ func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, erro
 	// Random proposers get information from the blockchain and compute its group secret share.
 
 	//set zero
-	gskshare := new(bn256.G1).ScalarBaseMult(big.NewInt(int64(0)))
-
-	// sk^-1
-	skinver := new(big.Int).ModInverse(prikey, bn256.Order)
+	enshare := new(bn256.G1).ScalarBaseMult(big.NewInt(int64(0)))
 	for i := 0; i < dkgCount; i++ {
-		temp := new(bn256.G1).ScalarMult(datas[i].ens[proposerId], skinver)
+		enshare.Add(enshare, datas[i].ens[proposerId])
+	}
 
-		// gskshare[i] = (sk^-1)*(enshare[1][i]+...+enshare[Nr][i])
-		gskshare.Add(gskshare, temp)
+	// gskshare[i] = (sk^-1)*(enshare[1][i]+...+enshare[Nr][i])
+	gskshare, err := signer.DecryptShare(enshare)
+	if err != nil {
+		return nil, err
 	}
 
 	// Signing Stage
@@ -669,6 +707,9 @@ func (rb *RandomBeacon) sendDKG1(payloadObj *vm.RbDKG1FlatTxPayload) error {
 	if err != nil {
 		return err
 	}
+	if payload, err = rb.protect(signprotect.DKG1, payloadObj.EpochId, payloadObj.ProposerId, payload); err != nil {
+		return err
+	}
 
 	return rb.doSendRBTx(payload)
 }
@@ -679,6 +720,9 @@ func (rb *RandomBeacon) sendDKG2(payloadObj *vm.RbDKG2FlatTxPayload) error {
 	if err != nil {
 		return err
 	}
+	if payload, err = rb.protect(signprotect.DKG2, payloadObj.EpochId, payloadObj.ProposerId, payload); err != nil {
+		return err
+	}
 
 	return rb.doSendRBTx(payload)
 }
@@ -689,10 +733,28 @@ func (rb *RandomBeacon) sendSIG(payloadObj *vm.RbSIGTxPayload) error {
 	if err != nil {
 		return err
 	}
+	if payload, err = rb.protect(signprotect.SigShare, payloadObj.EpochId, payloadObj.ProposerId, payload); err != nil {
+		return err
+	}
 
 	return rb.doSendRBTx(payload)
 }
 
+// protect records the payload of a stage in the double-sign protection
+// database and returns the payload to send. If a payload of the stage was
+// already sent, that one is returned to be resent as is.
+func (rb *RandomBeacon) protect(kind signprotect.RBKind, epochId uint64, proposerId uint32, payload []byte) ([]byte, error) {
+	protection, err := rb.ctx.Protection()
+	if err != nil {
+		return nil, err
+	}
+	payload, err = protection.SignRBPayload(rb.getTxFrom(), epochId, kind, proposerId, payload)
+	if err != nil {
+		log.SyslogErr("refuse to send rb payload", "kind", kind.String(), "epochId", epochId, "proposerId", proposerId, "err", err.Error())
+	}
+	return payload, err
+}
+
 func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
 	to := vm.GetRBAddress()
 	data := hexutil.Bytes(payload)
@@ -708,12 +770,16 @@ func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
 
 
 	log.SyslogInfo("do send rb tx", "payload len", len(payload))
-	go util.SendPosTx(rb.rpcClient, arg)
+	send := rb.sendTxFn
+	if send == nil {
+		send = util.SendPosTxAsync
+	}
+	send(rb.rpcClient, arg)
 	return nil
 }
 
 func (rb *RandomBeacon) getTxFrom() common.Address {
-	return posconfig.Cfg().GetMinerAddr()
+	return rb.ctx.MinerAddr()
 }
 
 func (rb *RandomBeacon) storePolys() error {
@@ -727,7 +793,7 @@ func (rb *RandomBeacon) storePolys() error {
 		return err
 	}
 
-	_, err = posdb.GetDb().Put(rb.epochId, rbPloys, b)
+	_, err = rb.ctx.LocalDb().Put(rb.epochId, rbPloys, b)
 	if err != nil {
 		log.SyslogErr("random beacon store polys fail", "err", err)
 		return err
@@ -737,7 +803,7 @@ func (rb *RandomBeacon) storePolys() error {
 }
 
 func (rb *RandomBeacon) loadPolys() error {
-	b, err := posdb.GetDb().Get(rb.epochId, rbPloys)
+	b, err := rb.ctx.LocalDb().Get(rb.epochId, rbPloys)
 	if err != nil {
 		log.SyslogDebug("random beacon load polys fail", "err", err)
 		return err
@@ -13,6 +13,7 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -45,10 +46,10 @@ func initKeystore(rb *RandomBeacon) error {
 		return err
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	commityPrivate = selfPrivate
 
@@ -154,7 +155,8 @@ func tmpGetCji(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G2, e
 func TestRandomBeacon_GetMyRBProposerId(t *testing.T) {
 	var epocher epochLeader.Epocher
 	var key keystore.Key
-	var rb RandomBeacon
+	rb := RandomBeacon{ctx: posctx.New("")}
+	defer rb.ctx.Close()
 
 	var err error
 
@@ -164,9 +166,9 @@ func TestRandomBeacon_GetMyRBProposerId(t *testing.T) {
 		t.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
-	commityPrivate.D	 = posconfig.Cfg().GetMinerBn256SK()
-	commityPrivate.G1 	 = posconfig.Cfg().GetMinerBn256PK()
+	rb.ctx.SetMinerKey(&key)
+	commityPrivate.D	 = rb.ctx.MinerBn256SK()
+	commityPrivate.G1 	 = rb.ctx.MinerBn256PK()
 
 	// commityPrivate not equal selfPrivate
 	key.PrivateKey2, err = crypto.GenerateKey()
@@ -174,10 +176,10 @@ func TestRandomBeacon_GetMyRBProposerId(t *testing.T) {
 		t.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	rb.Init(&epocher)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
@@ -205,7 +207,8 @@ func TestRandomBeacon_GetMyRBProposerId(t *testing.T) {
 }
 
 func TestRandomBeacon_updateEpochId(t *testing.T) {
-	rb := GetRandonBeaconInst()
+	rb := GetRandonBeaconInst(posctx.New(""))
+	defer rb.ctx.Close()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
@@ -247,7 +250,8 @@ func TestRandomBeacon_updateEpochId(t *testing.T) {
 }
 
 func TestRandomBeacon_updateStage(t *testing.T) {
-	rb := GetRandonBeaconInst()
+	rb := GetRandonBeaconInst(posctx.New(""))
+	defer rb.ctx.Close()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
@@ -273,7 +277,8 @@ func TestRandomBeacon_updateStage(t *testing.T) {
 func TestRandomBeacon_Init(t *testing.T) {
 	var epocher epochLeader.Epocher
 	//var key keystore.Key
-	var rb RandomBeacon
+	rb := RandomBeacon{ctx: posctx.New("")}
+	defer rb.ctx.Close()
 
 	rb.Init(&epocher)
 
@@ -303,7 +308,8 @@ func TestRandomBeacon_Init(t *testing.T) {
 func TestRandomBeacon_DoGenerateDKG1(t *testing.T) {
 	var epocher epochLeader.Epocher
 	var key keystore.Key
-	var rb RandomBeacon
+	rb := RandomBeacon{ctx: posctx.New("")}
+	defer rb.ctx.Close()
 
 	var err error
 
@@ -312,10 +318,10 @@ func TestRandomBeacon_DoGenerateDKG1(t *testing.T) {
 		t.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	commityPrivate = selfPrivate
 
@@ -375,7 +381,8 @@ func TestRandomBeacon_DoGenerateDKG1(t *testing.T) {
 func TestRandomBeacon_GenerateDKG2(t *testing.T) {
 	var epocher epochLeader.Epocher
 	var key keystore.Key
-	var rb RandomBeacon
+	rb := RandomBeacon{ctx: posctx.New("")}
+	defer rb.ctx.Close()
 
 	dkg1s := make([]*vm.RbDKG1TxPayload, 0)
 
@@ -386,10 +393,10 @@ func TestRandomBeacon_GenerateDKG2(t *testing.T) {
 		t.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	commityPrivate = selfPrivate
 
@@ -472,7 +479,8 @@ func TestRandomBeacon_GenerateDKG2(t *testing.T) {
 func TestRandomBeacon_GenerateSIG(t *testing.T) {
 	var epocher epochLeader.Epocher
 	var key keystore.Key
-	var rb RandomBeacon
+	rb := RandomBeacon{ctx: posctx.New("")}
+	defer rb.ctx.Close()
 
 	dkg1s := make([]*vm.RbDKG1TxPayload, 0)
 	dkg2s := make([]*vm.RbDKG2TxPayload, 0)
@@ -484,10 +492,10 @@ func TestRandomBeacon_GenerateSIG(t *testing.T) {
 		t.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	commityPrivate = selfPrivate
 
@@ -584,7 +592,6 @@ func TestRandomBeacon_GenerateSIG(t *testing.T) {
 }
 
 func TestRandomBeacon_doLoop(t *testing.T) {
-	posconfig.SelfTestMode = true
 	var (
 		db, _      = ethdb.NewMemDatabase()
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
@@ -597,7 +604,8 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		actureSIGsCallTimes = 0
 	)
 
-	rb := GetRandonBeaconInst()
+	rb := GetRandonBeaconInst(posctx.NewSelfTest())
+	defer rb.ctx.Close()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
@@ -621,10 +629,10 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		if err != nil {
 			t.Error("generate sec256 fail, ", err)
 		}
-		posconfig.Cfg().MinerKey = &key
+		rb.ctx.SetMinerKey(&key)
 
-		selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-		selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+		selfPrivate.D = rb.ctx.MinerBn256SK()
+		selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 		err = rb.doLoop(statedb, rc, epochId, slotId)
 		if err != nil {
@@ -717,7 +725,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	}
 
 	{
-		posconfig.SelfTestMode = false
+		rb.ctx.SetSelfTestMode(false)
 		slotId++
 		rb.fDoDKG1s = DoDKG1sFail
 		err := rb.doLoop(statedb, rc, epochId, slotId)
@@ -737,13 +745,13 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		if dkg1sCallTimes != actureDkg1sCallTimes || dkg2sCallTimes != actureDkg2sCallTimes || sigsCallTimes != actureSIGsCallTimes {
 			t.Error("invalid stage work run times")
 		}
-		posconfig.SelfTestMode = true
+		rb.ctx.SetSelfTestMode(true)
 		rb.fDoDKG1s = DoDKG1sSuc
 	}
 
 	{
 		slotId++
-		posconfig.SelfTestMode = true
+		rb.ctx.SetSelfTestMode(true)
 		err := rb.doLoop(statedb, rc, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
@@ -786,7 +794,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId = 40
-		posconfig.SelfTestMode = true
+		rb.ctx.SetSelfTestMode(true)
 		err := rb.doLoop(statedb, rc, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
@@ -809,7 +817,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		slotId = posconfig.Cfg().Dkg2Begin + 1
 		rb.fDoDKG2s = DoDKG2sFail
 
-		posconfig.SelfTestMode = false
+		rb.ctx.SetSelfTestMode(false)
 
 		err := rb.doLoop(statedb, rc, epochId, slotId)
 		if err == nil {
@@ -834,7 +842,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		posconfig.SelfTestMode = true
+		rb.ctx.SetSelfTestMode(true)
 		err := rb.doLoop(statedb, rc, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
@@ -895,7 +903,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	}
 
 	{
-		posconfig.SelfTestMode = false
+		rb.ctx.SetSelfTestMode(false)
 		slotId = posconfig.Cfg().SignBegin + 1
 		rb.fDoSIGs = DoSIGsFail
 		err := rb.doLoop(statedb, rc, epochId, slotId)
@@ -917,7 +925,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		}
 
 		rb.fDoSIGs = DoSIGsSuc
-		posconfig.SelfTestMode = true
+		rb.ctx.SetSelfTestMode(true)
 	}
 
 	{
@@ -1003,10 +1011,15 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		}
 	}
 
-	posconfig.SelfTestMode = false
 }
 
 func TestPolyMap_storePolys(t *testing.T) {
+	ctx := posctx.New("")
+	defer ctx.Close()
+	testStorePolys(t, ctx)
+}
+
+func testStorePolys(t *testing.T, ctx *posctx.Context) {
 	poly1 := make(rbselection.Polynomial, 0)
 	poly2 := make(rbselection.Polynomial, 0)
 	poly1 = append(poly1, *big.NewInt(11))
@@ -1014,7 +1027,7 @@ func TestPolyMap_storePolys(t *testing.T) {
 	poly2 = append(poly2, *big.NewInt(21))
 	poly2 = append(poly2, *big.NewInt(22))
 
-	rb := RandomBeacon{}
+	rb := RandomBeacon{ctx: ctx}
 	rb.polys = make(PolyMap)
 	rb.polys[1] = PolyInfo{poly1, big.NewInt(1)}
 	rb.polys[2] = PolyInfo{poly2, big.NewInt(2)}
@@ -1026,9 +1039,11 @@ func TestPolyMap_storePolys(t *testing.T) {
 }
 
 func TestPolyMap_loadPolys(t *testing.T) {
-	TestPolyMap_storePolys(t)
+	ctx := posctx.New("")
+	defer ctx.Close()
+	testStorePolys(t, ctx)
 
-	rb := RandomBeacon{}
+	rb := RandomBeacon{ctx: ctx}
 	rb.polys = make(PolyMap)
 	err := rb.loadPolys()
 	if err != nil {
@@ -1128,6 +1143,8 @@ func BenchmarkRandomBeacon_Stop(b *testing.B) {
 		rb RandomBeacon
 		wg sync.WaitGroup
 	)
+	rb.ctx = posctx.New("")
+	defer rb.ctx.Close()
 
 	var err error
 
@@ -1136,10 +1153,10 @@ func BenchmarkRandomBeacon_Stop(b *testing.B) {
 		b.Error("generate sec256 fail, ", err)
 	}
 
-	posconfig.Cfg().MinerKey = &key
+	rb.ctx.SetMinerKey(&key)
 
-	selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
-	selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
+	selfPrivate.D = rb.ctx.MinerBn256SK()
+	selfPrivate.G1 = rb.ctx.MinerBn256PK()
 
 	commityPrivate = selfPrivate
 
@@ -0,0 +1,173 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package signprotect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+// InterchangeVersion is the version of the interchange format written by
+// Export.
+const InterchangeVersion = "1"
+
+var errGenesisMismatch = errors.New("interchange data is for another chain")
+
+// Interchange is the JSON interchange format of the records of a protection
+// database, used to move the validators of a node to another one.
+type Interchange struct {
+	Metadata InterchangeMetadata `json:"metadata"`
+	Data     []ValidatorRecords  `json:"data"`
+}
+
+// InterchangeMetadata identifies the chain the records are for.
+type InterchangeMetadata struct {
+	Version     string      `json:"interchange_format_version"`
+	GenesisHash common.Hash `json:"genesis_hash"`
+}
+
+// ValidatorRecords are the records of a validator.
+type ValidatorRecords struct {
+	Address      common.Address `json:"address"`
+	SignedBlocks []SignedBlock  `json:"signed_blocks"`
+	SignedRB     []SignedRB     `json:"signed_rb"`
+}
+
+// SignedBlock records the block sealed by a validator for a slot.
+type SignedBlock struct {
+	Epoch       uint64      `json:"epoch,string"`
+	Slot        uint64      `json:"slot,string"`
+	SigningRoot common.Hash `json:"signing_root"`
+}
+
+// SignedRB records a random beacon payload sent by a validator.
+type SignedRB struct {
+	Epoch       uint64      `json:"epoch,string"`
+	ProposerID  uint32      `json:"proposer_id,string"`
+	Kind        string      `json:"kind"`
+	PayloadHash common.Hash `json:"payload_hash"`
+}
+
+// Export returns the records of the database, for the chain of the genesis
+// block genesis.
+func (p *DB) Export(genesis common.Hash) (*Interchange, error) {
+	it, ok := p.db.(ethdb.Iteratee)
+	if !ok {
+		return nil, errNoIteration
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	records := make(map[common.Address]*ValidatorRecords)
+	get := func(addr common.Address) *ValidatorRecords {
+		if records[addr] == nil {
+			records[addr] = &ValidatorRecords{Address: addr, SignedBlocks: []SignedBlock{}, SignedRB: []SignedRB{}}
+		}
+		return records[addr]
+	}
+	err := it.ForEach(func(key, value []byte) error {
+		switch {
+		case len(key) == blockKeyLen && bytes.HasPrefix(key, blockPrefix):
+			n := len(blockPrefix)
+			v := get(common.BytesToAddress(key[n : n+common.AddressLength]))
+			n += common.AddressLength
+			v.SignedBlocks = append(v.SignedBlocks, SignedBlock{
+				Epoch:       binary.BigEndian.Uint64(key[n:]),
+				Slot:        binary.BigEndian.Uint64(key[n+8:]),
+				SigningRoot: common.BytesToHash(value),
+			})
+		case len(key) == rbKeyLen && bytes.HasPrefix(key, rbPrefix):
+			n := len(rbPrefix)
+			v := get(common.BytesToAddress(key[n : n+common.AddressLength]))
+			n += common.AddressLength
+			v.SignedRB = append(v.SignedRB, SignedRB{
+				Epoch:       binary.BigEndian.Uint64(key[n:]),
+				Kind:        RBKind(key[n+8]).String(),
+				ProposerID:  binary.BigEndian.Uint32(key[n+9:]),
+				PayloadHash: common.BytesToHash(value),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Interchange{
+		Metadata: InterchangeMetadata{Version: InterchangeVersion, GenesisHash: genesis},
+		Data:     make([]ValidatorRecords, 0, len(records)),
+	}
+	for _, v := range records {
+		data.Data = append(data.Data, *v)
+	}
+	sort.Slice(data.Data, func(i, j int) bool {
+		return bytes.Compare(data.Data[i].Address[:], data.Data[j].Address[:]) < 0
+	})
+	return data, nil
+}
+
+// Import merges the records of data, for the chain of the genesis block
+// genesis, into the database. A record conflicting with a local one is
+// stored as a conflict, no message is signed for its position anymore.
+func (p *DB) Import(data *Interchange, genesis common.Hash) error {
+	if data.Metadata.Version != InterchangeVersion {
+		return fmt.Errorf("unsupported interchange format version %q", data.Metadata.Version)
+	}
+	if data.Metadata.GenesisHash != genesis {
+		return errGenesisMismatch
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		batch   = p.db.NewBatch()
+		pending = make(map[string]common.Hash)
+	)
+	for _, v := range data.Data {
+		for _, b := range v.SignedBlocks {
+			if err := p.merge(batch, pending, blockKey(v.Address, b.Epoch, b.Slot), b.SigningRoot); err != nil {
+				return err
+			}
+		}
+		for _, r := range v.SignedRB {
+			kind, err := parseRBKind(r.Kind)
+			if err != nil {
+				return err
+			}
+			if err := p.merge(batch, pending, rbKey(v.Address, r.Epoch, kind, r.ProposerID), r.PayloadHash); err != nil {
+				return err
+			}
+		}
+	}
+	return batch.Write()
+}
+
+// merge adds the imported record of root under key to batch, a zero root if
+// the database or the pending writes of batch hold another root for it.
+func (p *DB) merge(batch ethdb.Batch, pending map[string]common.Hash, key []byte, root common.Hash) error {
+	if written, ok := pending[string(key)]; ok {
+		if written == root {
+			return nil
+		}
+		root = common.Hash{}
+	} else if has, err := p.db.Has(key); err != nil {
+		return err
+	} else if has {
+		signed, err := p.db.Get(key)
+		if err != nil {
+			return err
+		}
+		if common.BytesToHash(signed) == root {
+			return nil
+		}
+		root = common.Hash{}
+	}
+	pending[string(key)] = root
+	return batch.Put(key, root[:])
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package signprotect keeps a record of the messages a PoS validator signed,
+// so that it never signs two conflicting messages for the same slot or random
+// beacon stage, even after a restore from a backup or with two instances of
+// the validator running.
+package signprotect
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+var (
+	ErrDoubleSign  = errors.New("refusing to sign a message conflicting with a signed one")
+	errNoIteration = errors.New("protection database can't be iterated")
+)
+
+// RBKind is a random beacon stage a proposer signs a payload for.
+type RBKind uint8
+
+const (
+	DKG1 RBKind = iota + 1
+	DKG2
+	SigShare
+)
+
+var rbKindNames = map[RBKind]string{
+	DKG1:     "dkg1",
+	DKG2:     "dkg2",
+	SigShare: "sigShare",
+}
+
+func (k RBKind) String() string {
+	if name, ok := rbKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("RBKind(%d)", uint8(k))
+}
+
+// parseRBKind returns the kind of a name of String.
+func parseRBKind(name string) (RBKind, error) {
+	for kind, n := range rbKindNames {
+		if n == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown random beacon stage %q", name)
+}
+
+// The records are keyed by kind, validator and position, and hold the root
+// of the signed message. A zero root records conflicting messages, found on
+// import, and matches none.
+var (
+	blockPrefix = []byte("b") // blockPrefix + address + epoch + slot -> root
+	rbPrefix    = []byte("r") // rbPrefix + address + epoch + kind + proposer -> root
+
+	rbPayloadPrefix = []byte("p") // rbPayloadPrefix + address + epoch + kind + proposer -> payload
+)
+
+const (
+	blockKeyLen = 1 + common.AddressLength + 8 + 8
+	rbKeyLen    = 1 + common.AddressLength + 8 + 1 + 4
+)
+
+func blockKey(validator common.Address, epochID, slotID uint64) []byte {
+	key := make([]byte, blockKeyLen)
+	n := copy(key, blockPrefix)
+	n += copy(key[n:], validator[:])
+	binary.BigEndian.PutUint64(key[n:], epochID)
+	binary.BigEndian.PutUint64(key[n+8:], slotID)
+	return key
+}
+
+func rbKey(validator common.Address, epochID uint64, kind RBKind, proposerID uint32) []byte {
+	key := make([]byte, rbKeyLen)
+	n := copy(key, rbPrefix)
+	n += copy(key[n:], validator[:])
+	binary.BigEndian.PutUint64(key[n:], epochID)
+	key[n+8] = byte(kind)
+	binary.BigEndian.PutUint32(key[n+9:], proposerID)
+	return key
+}
+
+func rbPayloadKey(validator common.Address, epochID uint64, kind RBKind, proposerID uint32) []byte {
+	key := rbKey(validator, epochID, kind, proposerID)
+	copy(key, rbPayloadPrefix)
+	return key
+}
+
+// DB is the protection database of the validators of a node.
+type DB struct {
+	db   ethdb.Database
+	lock sync.Mutex // serializes the check and the record of a message
+}
+
+// New creates the protection database stored in db.
+func New(db ethdb.Database) *DB {
+	return &DB{db: db}
+}
+
+// Open opens the protection database in the directory file with the given
+// key-value engine, see ethdb.NewDatabase.
+func Open(engine, file string) (*DB, error) {
+	db, err := ethdb.NewDatabase(engine, file, 0, 16)
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+// Close closes the database.
+func (p *DB) Close() {
+	p.db.Close()
+}
+
+// record stores root under key, unless the key holds another root.
+func (p *DB) record(key []byte, root common.Hash) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if signed, err := p.signed(key, root); err != nil || signed {
+		return err
+	}
+	return p.db.Put(key, root[:])
+}
+
+// signed reports whether key holds root, it fails with ErrDoubleSign if the
+// key holds another root.
+func (p *DB) signed(key []byte, root common.Hash) (bool, error) {
+	has, err := p.db.Has(key)
+	if err != nil || !has {
+		return false, err
+	}
+	signed, err := p.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if common.BytesToHash(signed) != root || root == (common.Hash{}) {
+		return false, ErrDoubleSign
+	}
+	return true, nil
+}
+
+// SignBlock records that validator seals the block of a slot with the seal
+// hash root. It fails with ErrDoubleSign if the validator sealed another
+// block for the slot, the block must not be sealed then.
+func (p *DB) SignBlock(validator common.Address, epochID, slotID uint64, root common.Hash) error {
+	return p.record(blockKey(validator, epochID, slotID), root)
+}
+
+// SignRB records that validator sends the random beacon payload of a stage
+// of an epoch as proposer proposerID, root is the hash of the payload. It
+// fails with ErrDoubleSign if the validator sent another payload for it, the
+// payload must not be sent then.
+func (p *DB) SignRB(validator common.Address, epochID uint64, kind RBKind, proposerID uint32, root common.Hash) error {
+	return p.record(rbKey(validator, epochID, kind, proposerID), root)
+}
+
+// SignRBPayload records that validator sends payload for the random beacon
+// stage of an epoch as proposer proposerID, as SignRB does, and keeps the
+// payload. The payloads carry fresh randomness, so if a payload was already
+// sent for the stage, that one is returned and must be sent again instead.
+func (p *DB) SignRBPayload(validator common.Address, epochID uint64, kind RBKind, proposerID uint32, payload []byte) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	payloadKey := rbPayloadKey(validator, epochID, kind, proposerID)
+	if has, err := p.db.Has(payloadKey); err != nil {
+		return nil, err
+	} else if has {
+		if payload, err = p.db.Get(payloadKey); err != nil {
+			return nil, err
+		}
+	}
+	key, root := rbKey(validator, epochID, kind, proposerID), crypto.Keccak256Hash(payload)
+	if signed, err := p.signed(key, root); err != nil {
+		return nil, err
+	} else if signed {
+		return payload, nil
+	}
+	batch := p.db.NewBatch()
+	if err := batch.Put(key, root[:]); err != nil {
+		return nil, err
+	}
+	if err := batch.Put(payloadKey, payload); err != nil {
+		return nil, err
+	}
+	return payload, batch.Write()
+}
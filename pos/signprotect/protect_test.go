@@ -0,0 +1,196 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package signprotect
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+func openTestDB(t *testing.T) (*DB, string) {
+	dir, err := ioutil.TempDir("", "signprotect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open("", filepath.Join(dir, "db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return db, dir
+}
+
+func TestSignBlock(t *testing.T) {
+	db, dir := openTestDB(t)
+	defer os.RemoveAll(dir)
+
+	validator := common.Address{1}
+	if err := db.SignBlock(validator, 10, 3, common.Hash{1}); err != nil {
+		t.Fatalf("first seal refused: %v", err)
+	}
+	if err := db.SignBlock(validator, 10, 3, common.Hash{1}); err != nil {
+		t.Errorf("same block refused: %v", err)
+	}
+	if err := db.SignBlock(validator, 10, 3, common.Hash{2}); err != ErrDoubleSign {
+		t.Errorf("conflicting block error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+	if err := db.SignBlock(validator, 10, 4, common.Hash{2}); err != nil {
+		t.Errorf("block of another slot refused: %v", err)
+	}
+	if err := db.SignBlock(common.Address{2}, 10, 3, common.Hash{2}); err != nil {
+		t.Errorf("block of another validator refused: %v", err)
+	}
+
+	// The records outlive the process
+	db.Close()
+	db, err := Open("", filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.SignBlock(validator, 10, 3, common.Hash{3}); err != ErrDoubleSign {
+		t.Errorf("conflicting block error mismatch after reopen: have %v, want %v", err, ErrDoubleSign)
+	}
+}
+
+func TestSignRB(t *testing.T) {
+	db, dir := openTestDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	validator := common.Address{1}
+	for _, kind := range []RBKind{DKG1, DKG2, SigShare} {
+		if err := db.SignRB(validator, 10, kind, 7, common.Hash{byte(kind)}); err != nil {
+			t.Fatalf("first %v refused: %v", kind, err)
+		}
+		if err := db.SignRB(validator, 10, kind, 7, common.Hash{byte(kind)}); err != nil {
+			t.Errorf("same %v refused: %v", kind, err)
+		}
+		if err := db.SignRB(validator, 10, kind, 7, common.Hash{9}); err != ErrDoubleSign {
+			t.Errorf("conflicting %v error mismatch: have %v, want %v", kind, err, ErrDoubleSign)
+		}
+		if err := db.SignRB(validator, 10, kind, 8, common.Hash{9}); err != nil {
+			t.Errorf("%v of another proposer refused: %v", kind, err)
+		}
+		if err := db.SignRB(validator, 11, kind, 7, common.Hash{9}); err != nil {
+			t.Errorf("%v of another epoch refused: %v", kind, err)
+		}
+	}
+}
+
+func TestSignRBPayload(t *testing.T) {
+	db, dir := openTestDB(t)
+	defer os.RemoveAll(dir)
+
+	validator := common.Address{1}
+	sent, err := db.SignRBPayload(validator, 10, DKG2, 7, []byte("first"))
+	if err != nil {
+		t.Fatalf("first payload refused: %v", err)
+	}
+	if !bytes.Equal(sent, []byte("first")) {
+		t.Fatalf("first payload mismatch: have %q, want %q", sent, "first")
+	}
+	// A retry with a fresh payload sends the recorded one again
+	if sent, err = db.SignRBPayload(validator, 10, DKG2, 7, []byte("second")); err != nil {
+		t.Fatalf("retried payload refused: %v", err)
+	}
+	if !bytes.Equal(sent, []byte("first")) {
+		t.Errorf("retried payload mismatch: have %q, want %q", sent, "first")
+	}
+	if err := db.SignRB(validator, 10, DKG2, 7, crypto.Keccak256Hash([]byte("second"))); err != ErrDoubleSign {
+		t.Errorf("conflicting payload error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+	// A payload signed without being kept can't be replaced
+	db.SignRB(validator, 10, DKG1, 7, common.Hash{1})
+	if _, err := db.SignRBPayload(validator, 10, DKG1, 7, []byte("first")); err != ErrDoubleSign {
+		t.Errorf("unkept payload error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+
+	// The payloads outlive the process, and aren't exported
+	db.Close()
+	if db, err = Open("", filepath.Join(dir, "db")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if sent, err = db.SignRBPayload(validator, 10, DKG2, 7, []byte("third")); err != nil || !bytes.Equal(sent, []byte("first")) {
+		t.Errorf("payload after reopen mismatch: have %q, %v, want %q", sent, err, "first")
+	}
+	data, err := db.Export(common.Hash{})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(data.Data) != 1 || len(data.Data[0].SignedRB) != 2 {
+		t.Errorf("exported records mismatch: %+v", data.Data)
+	}
+}
+
+func TestInterchange(t *testing.T) {
+	src, srcDir := openTestDB(t)
+	defer os.RemoveAll(srcDir)
+	defer src.Close()
+
+	genesis := common.Hash{0xaa}
+	validator := common.Address{1}
+	src.SignBlock(validator, 10, 3, common.Hash{1})
+	src.SignBlock(validator, 10, 5, common.Hash{2})
+	src.SignRB(validator, 10, DKG2, 7, common.Hash{3})
+
+	data, err := src.Export(genesis)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	enc, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Interchange
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("failed to decode the interchange data: %v", err)
+	}
+
+	dst, dstDir := openTestDB(t)
+	defer os.RemoveAll(dstDir)
+	defer dst.Close()
+
+	if err := dst.Import(&dec, common.Hash{0xbb}); err != errGenesisMismatch {
+		t.Errorf("import error mismatch for another chain: have %v, want %v", err, errGenesisMismatch)
+	}
+	// The slot 5 record conflicts with a local one
+	dst.SignBlock(validator, 10, 5, common.Hash{4})
+	if err := dst.Import(&dec, genesis); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	if err := dst.SignBlock(validator, 10, 3, common.Hash{1}); err != nil {
+		t.Errorf("imported block refused: %v", err)
+	}
+	if err := dst.SignBlock(validator, 10, 3, common.Hash{9}); err != ErrDoubleSign {
+		t.Errorf("block conflicting with an imported one error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+	for _, root := range []common.Hash{{2}, {4}} {
+		if err := dst.SignBlock(validator, 10, 5, root); err != ErrDoubleSign {
+			t.Errorf("block of a conflicting slot error mismatch: have %v, want %v", err, ErrDoubleSign)
+		}
+	}
+	if err := dst.SignRB(validator, 10, DKG2, 7, common.Hash{9}); err != ErrDoubleSign {
+		t.Errorf("payload conflicting with an imported one error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+
+	out, err := dst.Export(genesis)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(out.Data) != 1 || len(out.Data[0].SignedBlocks) != 2 || len(out.Data[0].SignedRB) != 1 {
+		t.Fatalf("exported records mismatch: %+v", out.Data)
+	}
+	if out.Data[0].SignedRB[0].Kind != "dkg2" || out.Data[0].SignedBlocks[1].SigningRoot != (common.Hash{}) {
+		t.Errorf("exported records mismatch: %+v", out.Data[0])
+	}
+}
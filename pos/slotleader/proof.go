@@ -6,13 +6,12 @@ import (
 	"encoding/hex"
 	"math/big"
 
-	"github.com/wanchain/go-wanchain/pos/util"
-
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
@@ -25,10 +24,55 @@ const (
 	LenProofMeg = 3
 )
 
+// SlotProofContext holds the chain data the slot leader proofs of one epoch
+// are verified against.
+type SlotProofContext struct {
+	EpochID      uint64             // epoch mixed into skGt, zero for the genesis context
+	EpochLeaders []*ecdsa.PublicKey // epoch leaders of the previous epoch
+	Random       []byte             // random beacon value of the epoch
+	// true: the stage two tx of the epoch leader is on chain and can be used
+	ValidIndexes [posconfig.EpochLeaderCount]bool
+	AlphaPKi     [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
+}
+
+// NewGenesisProofContext builds the context used before the first stage two
+// transactions are on chain, leaders are the default epoch leaders.
+func NewGenesisProofContext(leaders []*ecdsa.PublicKey) *SlotProofContext {
+	ctx := &SlotProofContext{
+		EpochLeaders: leaders,
+		Random:       posconfig.GetRandomGenesis().Bytes(),
+	}
+
+	alphas := genesisAlphas(leaders)
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		ctx.ValidIndexes[i] = true
+		for j := 0; j < posconfig.EpochLeaderCount; j++ {
+			// AlphaIPki stage2Genesis, used to verify genesis proof
+			alphaIPkj := new(ecdsa.PublicKey)
+			alphaIPkj.Curve = crypto.S256()
+			alphaIPkj.X, alphaIPkj.Y = crypto.S256().ScalarMult(leaders[j].X, leaders[j].Y, alphas[i].Bytes())
+
+			ctx.AlphaPKi[i][j] = alphaIPkj
+		}
+	}
+	return ctx
+}
+
+// genesisAlphas derives the genesis SMA alphas from the default epoch leaders.
+func genesisAlphas(leaders []*ecdsa.PublicKey) []*big.Int {
+	alphas := make([]*big.Int, 0, len(leaders))
+	for _, value := range leaders {
+		tempInt := new(big.Int).SetInt64(0)
+		tempInt.SetBytes(crypto.Keccak256(crypto.FromECDSAPub(value)))
+		alphas = append(alphas, tempInt)
+	}
+	return alphas
+}
+
 //ProofMes 	= [PK, Gt, skGt] 	[]*PublicKey
 //Proof 	= [e,z] 			[]*big.Int
 func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey) bool {
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.ctx.FirstEpochId()+2 {
 		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
 	}
 
@@ -71,11 +115,29 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
 	}
 
+	ctx := &SlotProofContext{
+		EpochID:      epochID,
+		EpochLeaders: epochLeadersPtrPre,
+		Random:       rbBytes,
+		ValidIndexes: validEpochLeadersIndex,
+		AlphaPKi:     stageTwoAlphaPKi,
+	}
+	return VerifySlotProofWithContext(ctx, slotID, Proof, ProofMeg)
+}
+
+// VerifySlotProofWithContext checks the slot leader proof of slotID against
+// the epoch data in ctx. It does not depend on any local state, so it can be
+// used by nodes which gather ctx on their own (e.g. light clients).
+func VerifySlotProofWithContext(ctx *SlotProofContext, slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey) bool {
+	if len(Proof) != LenProof || len(ProofMeg) != LenProofMeg {
+		return false
+	}
+
 	var publicKey *ecdsa.PublicKey
 	publicKey = ProofMeg[0]
 
 	publicKeyIndexes := make([]int, 0)
-	for index, value := range epochLeadersPtrPre {
+	for index, value := range ctx.EpochLeaders {
 		if uleaderselection.PublicKeyEqual(publicKey, value) {
 			publicKeyIndexes = append(publicKeyIndexes, index)
 		}
@@ -88,8 +150,8 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 
 		smaPieces := make([]*ecdsa.PublicKey, 0)
 		for i := 0; i < posconfig.EpochLeaderCount; i++ {
-			if validEpochLeadersIndex[i] {
-				smaPieces = append(smaPieces, stageTwoAlphaPKi[i][index])
+			if ctx.ValidIndexes[i] {
+				smaPieces = append(smaPieces, ctx.AlphaPKi[i][index])
 			}
 		}
 
@@ -98,17 +160,17 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 			return false
 		}
 
-		log.Debug("VerifySlotLeaderProofskGT aphaiPki", "index", index, "epochID", epochID, "slotID", slotID)
-		log.Debug("VerifySlotLeaderProofskGT", "epochID", epochID, "slotID", slotID, "slotLeaderRb", rbBytes[:])
+		log.Debug("VerifySlotLeaderProofskGT aphaiPki", "index", index, "epochID", ctx.EpochID, "slotID", slotID)
+		log.Debug("VerifySlotLeaderProofskGT", "epochID", ctx.EpochID, "slotID", slotID, "slotLeaderRb", ctx.Random)
 
 		smaPiecesHexStr := make([]string, 0)
 		for _, value := range smaPieces {
 			smaPiecesHexStr = append(smaPiecesHexStr, hex.EncodeToString(crypto.FromECDSAPub(value)))
 		}
-		log.Debug("VerifySlotLeaderProof", "epochID", epochID, "slotID", slotID, "smaPiecesHexStr", smaPiecesHexStr)
+		log.Debug("VerifySlotLeaderProof", "epochID", ctx.EpochID, "slotID", slotID, "smaPiecesHexStr", smaPiecesHexStr)
 
 		// get skGT from trans
-		skGt := s.getSkGtFromTrans(epochLeadersPtrPre, epochID, slotID, rbBytes[:], smaPieces[:])
+		skGt := getSkGtFromTrans(ctx.EpochLeaders, ctx.EpochID, slotID, ctx.Random, smaPieces[:])
 
 		if uleaderselection.PublicKeyEqual(skGt, ProofMeg[2]) {
 			skGtValid = true
@@ -117,17 +179,17 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 	}
 
 	if !skGtValid {
-		log.Warn("VerifySlotLeaderProof Fail skGt is not valid", "epochID", epochID, "slotID", slotID)
+		log.Warn("VerifySlotLeaderProof Fail skGt is not valid", "epochID", ctx.EpochID, "slotID", slotID)
 		return false
 	}
-	log.Debug("VerifySlotLeaderProof skGt is verified successfully.", "epochID", epochID, "slotID", slotID)
+	log.Debug("VerifySlotLeaderProof skGt is verified successfully.", "epochID", ctx.EpochID, "slotID", slotID)
 
 	// verify slot leader proof
-	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], epochLeadersPtrPre[:], rbBytes[:])
+	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], ctx.EpochLeaders[:], ctx.Random)
 }
 
-func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, prvKey *ecdsa.PrivateKey) ([]byte, error) {
-	proofMeg, proof, err := s.getSlotLeaderProof(prvKey, epochID, slotID)
+func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, signer possigner.Signer) ([]byte, error) {
+	proofMeg, proof, err := s.getSlotLeaderProof(signer, epochID, slotID)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +202,12 @@ func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, prvKey *ecdsa.Private
 }
 
 func (s *SLS) GetInfoFromHeadExtra(epochID uint64, input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
+	return DecodeSlotProof(epochID, input)
+}
+
+// DecodeSlotProof decodes the slot leader proof packed into a header's extra
+// data by PackSlotProof.
+func DecodeSlotProof(epochID uint64, input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
 	var info Pack
 	err := rlp.DecodeBytes(input, &info)
 	if err != nil {
@@ -175,7 +243,7 @@ func (s *SLS) GetInfoFromHeadExtra(epochID uint64, input []byte) ([]*big.Int, []
 	return proof, proofMeg, nil
 }
 
-func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID uint64,
+func (s *SLS) getSlotLeaderProofByGenesis(signer possigner.Signer, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
 
 	//1. SMA PRE
@@ -186,26 +254,25 @@ func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID
 	log.Debug("getSlotLeaderProofByGenesis", "epochID", epochID, "slotID", slotID)
 	log.Debug("getSlotLeaderProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
 		hex.EncodeToString(rbBytes[:]))
-	profMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(PrivateKey, smaPiecesPtr[:],
-		epochLeadersPtrPre[:], rbBytes[:], slotID, epochID)
+	profMeg, proof, err := signer.SlotLeaderProof(smaPiecesPtr[:], epochLeadersPtrPre[:], rbBytes[:], slotID, epochID)
 	return profMeg, proof, err
 }
 
-func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
+func (s *SLS) getSlotLeaderProof(signer possigner.Signer, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
-	if epochID <= posconfig.FirstEpochId+2 {
-		return s.getSlotLeaderProofByGenesis(PrivateKey, 0, slotID)
+	if epochID <= s.ctx.FirstEpochId()+2 {
+		return s.getSlotLeaderProofByGenesis(signer, 0, slotID)
 	}
 	epochLeadersPtrPre, isDefault := s.GetPreEpochLeadersPK(epochID)
 	if isDefault {
 		log.Warn("getSlotLeaderProof", "isDefault", isDefault)
-		return s.getSlotLeaderProofByGenesis(PrivateKey, 0, slotID)
+		return s.getSlotLeaderProofByGenesis(signer, 0, slotID)
 	}
 
 	//SMA PRE
 	smaPiecesPtr, isGenesis, _ := s.getSMAPieces(epochID)
 	if isGenesis {
-		return s.getSlotLeaderProofByGenesis(PrivateKey, 0, slotID)
+		return s.getSlotLeaderProofByGenesis(signer, 0, slotID)
 	}
 
 	//RB PRE
@@ -228,61 +295,28 @@ func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 	}
 	log.Debug("getSlotLeaderProof", "epochID", epochID, "slotID", slotID, "smaPiecesHexStr", smaPiecesHexStr)
 
-	profMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(PrivateKey, smaPiecesPtr, epochLeadersPtrPre,
-		rbBytes[:], slotID, epochID)
+	profMeg, proof, err := signer.SlotLeaderProof(smaPiecesPtr, epochLeadersPtrPre, rbBytes[:], slotID, epochID)
 
 	return profMeg, proof, err
 }
 
 func (s *SLS) verifySlotProofByGenesis(epochID uint64, slotID uint64, Proof []*big.Int,
 	ProofMeg []*ecdsa.PublicKey) bool {
+	log.Debug("verifySlotProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
+		hex.EncodeToString(s.randomGenesis.Bytes()))
 
-	var publicKey *ecdsa.PublicKey
-	publicKey = ProofMeg[0]
-
-	publicKeyIndexes := make([]int, 0)
-	for index, value := range s.epochLeadersPtrArrayGenesis {
-		if uleaderselection.PublicKeyEqual(publicKey, value) {
-			publicKeyIndexes = append(publicKeyIndexes, index)
-		}
+	ctx := &SlotProofContext{
+		EpochLeaders: s.epochLeadersPtrArrayGenesis[:],
+		Random:       s.randomGenesis.Bytes(),
+		AlphaPKi:     s.stageTwoAlphaPKiGenesis,
 	}
-
-	// Verify skGt
-	var skGtValid bool
-	skGtValid = false
-	for _, index := range publicKeyIndexes {
-
-		smaPieces := make([]*ecdsa.PublicKey, 0)
-		for i := 0; i < posconfig.EpochLeaderCount; i++ {
-			smaPieces = append(smaPieces, s.stageTwoAlphaPKiGenesis[i][index])
-		}
-
-		if len(smaPieces) == 0 {
-			return false
-		}
-
-		log.Debug("verifySlotProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
-			hex.EncodeToString(s.randomGenesis.Bytes()))
-		log.Debug("verifySlotProofByGenesis aphaiPki", "index", index, "epochID", epochID, "slotID", slotID)
-		eps := s.epochLeadersPtrArrayGenesis
-		skGt := s.getSkGtFromTrans(eps[:], 0, slotID, s.randomGenesis.Bytes()[:],
-			smaPieces[:])
-		if uleaderselection.PublicKeyEqual(skGt, ProofMeg[2]) {
-			skGtValid = true
-			break
-		}
-	}
-	if !skGtValid {
-		log.Warn("verifySlotProofByGenesis Fail skGt is not valid", "epochID", epochID, "slotID", slotID)
-		return false
+	for i := range ctx.ValidIndexes {
+		ctx.ValidIndexes[i] = true
 	}
-	log.Debug("verifySlotProofByGenesis skGt is verified successfully.", "epochID", epochID, "slotID", slotID)
-	eps := s.epochLeadersPtrArrayGenesis
-	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], eps[:],
-		s.randomGenesis.Bytes()[:])
+	return VerifySlotProofWithContext(ctx, slotID, Proof, ProofMeg)
 }
 
-func (s *SLS) getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID uint64, slotID uint64, rbBytes []byte,
+func getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID uint64, slotID uint64, rbBytes []byte,
 	smaPieces []*ecdsa.PublicKey) (skGtRet *ecdsa.PublicKey) {
 
 	var buffer bytes.Buffer
@@ -327,7 +361,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 		return validEpochLeadersIndex, stageTwoAlphaPKi, err
 	}
 
-	hash := util.GetEpochBlockHash(epochID - 1)
+	hash := s.ctx.EpochBlockHash(epochID - 1)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		if !indexesSentTran[i] {
 			validEpochLeadersIndex[i] = false
@@ -340,7 +374,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 		ckey := crypto.Keccak256Hash(bkey)
 
 		var alphaPki []*ecdsa.PublicKey
-		alphaPkiCached, ok := APkiCache.Get(ckey)
+		alphaPkiCached, ok := s.apkiCache.Get(ckey)
 		if !ok {
 			var err error
 			statedb, _ := s.getCurrentStateDb()
@@ -350,7 +384,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 				validEpochLeadersIndex[i] = false
 				continue
 			}
-			APkiCache.Add(ckey, alphaPki)
+			s.apkiCache.Add(ckey, alphaPki)
 		} else {
 			alphaPki = alphaPkiCached.([]*ecdsa.PublicKey)
 		}
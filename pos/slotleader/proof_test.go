@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 )
 
 func Wadd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
@@ -89,8 +90,8 @@ func TestVerifyDleqProof(t *testing.T) {
 }
 
 func TestGetSlotLeaderProof(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
 	pks, isGenesis, err := s.getSMAPieces(0)
 	if err != nil {
@@ -148,8 +149,8 @@ func TestGetSlotLeaderProof(t *testing.T) {
 }
 
 func TestVerifySlotProofByGenesis(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	pks, isGenesis, err := s.getSMAPieces(0)
 	if err != nil {
 		t.Error(err.Error())
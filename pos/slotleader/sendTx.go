@@ -1,7 +1,6 @@
 package slotleader
 
 import (
-	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/common/hexutil"
@@ -12,23 +11,21 @@ import (
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
-var (
-	errRCNotReady = errors.New("rc is not ready")
-)
-
+// SendTxFn sends a pos tx, it must not block the slot leader selection.
 type SendTxFn func(rc *rpc.Client, tx map[string]interface{})
 
-func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
-	if s.rc == nil {
-		return errRCNotReady
-	}
+// SetSendTxFn replaces the function sending the slot leader selection txs.
+func (s *SLS) SetSendTxFn(fn SendTxFn) {
+	s.sendTransactionFn = fn
+}
 
+func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
 	to := vm.GetSlotLeaderSCAddress()
 	data := hexutil.Bytes(payload)
 	gas := core.IntrinsicGas(data, &to, true)
 
 	arg := map[string]interface{}{}
-	arg["from"] = s.key.Address
+	arg["from"] = s.signer.Address()
 	arg["to"] = vm.GetSlotLeaderSCAddress()
 	arg["value"] = (*hexutil.Big)(big.NewInt(0))
 	arg["gas"] = (*hexutil.Big)(gas)
@@ -36,6 +33,6 @@ func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
 	arg["data"] = data
 	log.Debug("Write data of payload", "length", len(data))
 
-	go posSender(s.rc, arg)
+	posSender(s.rc, arg)
 	return nil
 }
@@ -9,12 +9,10 @@ import (
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 
 	"github.com/wanchain/go-wanchain/consensus"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
@@ -22,13 +20,14 @@ import (
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/wanchain/go-wanchain/rpc"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
@@ -52,15 +51,15 @@ const (
 	slotLeaderSelectionStageFinished = iota + 1 //5
 )
 
-var (
-	errorRetry = 3
-)
+// maxErrorRetry is the number of slots the security message generation is
+// retried in.
+const maxErrorRetry = 3
 
 type SLS struct {
 	workingEpochID uint64
 	workStage      int
 	rc             *rpc.Client
-	key            *keystore.Key
+	signer         possigner.Signer
 	stateDbTest    *state.StateDB
 
 	epochLeadersArray []string            // len(pki)=65 hex.EncodeToString
@@ -90,20 +89,26 @@ type SLS struct {
 	smaGenesis                  [posconfig.EpochLeaderCount]*ecdsa.PublicKey
 
 	sendTransactionFn SendTxFn
-}
+	errorRetry        int
 
-var slotLeaderSelection *SLS
-var APkiCache *lru.ARCCache
+	ctx           *posctx.Context
+	apkiCache     *lru.ARCCache
+	rndCache      *lru.ARCCache
+	scheduleCache *lru.ARCCache
+}
 
-var RndCache *lru.ARCCache
+const serviceName = "slotLeader"
 
 type Pack struct {
 	Proof    [][]byte
 	ProofMeg [][]byte
 }
 
-func GetSlotLeaderSelection() *SLS {
-	return slotLeaderSelection
+// GetSlotLeaderSelection returns the slot leader selection of the node, nil
+// before SlsInit.
+func GetSlotLeaderSelection(ctx *posctx.Context) *SLS {
+	s, _ := ctx.Service(serviceName).(*SLS)
+	return s
 }
 func (s *SLS) GetLocalPublicKey() (*ecdsa.PublicKey, error) {
 	return s.getLocalPublicKey()
@@ -125,7 +130,7 @@ func (s *SLS) GetSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return nil, vm.ErrSlotIDOutOfRange
 	}
 
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.ctx.FirstEpochId()+2 {
 		if s.getDefaultSlotLeader(slotID) != nil {
 			log.Info("GetSlotLeader:getDefaultSlotLeader",
 				"epochID", epochID,
@@ -164,34 +169,15 @@ func (s *SLS) getSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return nil, vm.ErrSlotIDOutOfRange
 	}
 
-	epochIDGet := epochID
-	epochLeadersPtrArray, isDefault := s.GetPreEpochLeadersPK(epochIDGet)
-	if isDefault && epochID > posconfig.FirstEpochId+2 {
-		log.Info("generateSlotLeadsGroup use default epochLeader", "epochID", epochID)
-		epochIDGet = 0
+	epochLeadersPtrArray, piecesPtr, random, err := s.slotLeaderSeed(epochID)
+	if err != nil {
+		return nil, err
 	}
 	if !s.IsLocalPkInEpochLeaders(epochLeadersPtrArray) {
 		log.Debug("Local node is not in pre epoch leaders at generateSlotLeadsGroup", "epochID", epochID)
 		return nil, uleaderselection.ErrNoInPreEPLS
 	}
 
-	piecesPtr, isGenesis, _ := s.getSMAPieces(epochIDGet)
-	if isGenesis {
-		log.Warn("Can not find pre epoch SMA or not in Pre epoch leaders, use the first epoch.", "curEpochID", epochID,
-			"preEpochID", epochID-1)
-		epochIDGet = 0
-	}
-
-	random, err := s.getRandom(nil, epochIDGet)
-	if err != nil {
-		return nil, vm.ErrInvalidRandom
-	}
-
-	if len(epochLeadersPtrArray) != posconfig.EpochLeaderCount {
-		log.Error("SLS", "Fail to get epoch leader", epochIDGet)
-		return nil, fmt.Errorf("fail to get epochLeader:%d", epochIDGet)
-	}
-
 	slotLeadersPtr, err := uleaderselection.GenerateSlotLeaderSeqOne(piecesPtr[:],
 		epochLeadersPtrArray[:], random.Bytes(), slotID, epochID)
 	if err != nil {
@@ -211,27 +197,100 @@ func (s *SLS) getSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 	return slotLeadersPtr, nil
 }
 
+// slotLeaderSeed returns the pre epoch leaders, the SMA pieces and the random
+// the slot leaders of epochID are selected with.
+func (s *SLS) slotLeaderSeed(epochID uint64) (epochLeaders []*ecdsa.PublicKey, pieces []*ecdsa.PublicKey,
+	random *big.Int, err error) {
+	epochIDGet := epochID
+	epochLeaders, isDefault := s.GetPreEpochLeadersPK(epochIDGet)
+	if isDefault && epochID > s.ctx.FirstEpochId()+2 {
+		log.Info("generateSlotLeadsGroup use default epochLeader", "epochID", epochID)
+		epochIDGet = 0
+	}
+
+	pieces, isGenesis, _ := s.getSMAPieces(epochIDGet)
+	if isGenesis {
+		log.Warn("Can not find pre epoch SMA or not in Pre epoch leaders, use the first epoch.", "curEpochID", epochID,
+			"preEpochID", epochID-1)
+		epochIDGet = 0
+	}
+
+	random, err = s.getRandom(nil, epochIDGet)
+	if err != nil {
+		return nil, nil, nil, vm.ErrInvalidRandom
+	}
+
+	if len(epochLeaders) != posconfig.EpochLeaderCount {
+		log.Error("SLS", "Fail to get epoch leader", epochIDGet)
+		return nil, nil, nil, fmt.Errorf("fail to get epochLeader:%d", epochIDGet)
+	}
+	return epochLeaders, pieces, random, nil
+}
+
+// ErrFutureEpoch is returned for the slot leaders of an epoch not begun yet.
+var ErrFutureEpoch = errors.New("slot leaders of a future epoch are not known")
+
+// GetSlotLeaders returns the slot leaders of all the slots of epochID, whether
+// or not the local node is one of the pre epoch leaders. The random of an epoch
+// isn't final before it begins, so only past and current epochs are known.
+func (s *SLS) GetSlotLeaders(epochID uint64) ([]*ecdsa.PublicKey, error) {
+	curEpochID, _ := util.CalEpochSlotID(s.ctx.Now())
+	if epochID > curEpochID {
+		return nil, ErrFutureEpoch
+	}
+	if epochID <= s.ctx.FirstEpochId()+2 {
+		return s.defaultSlotLeadersPtrArray[:], nil
+	}
+	if _, isGenesis, _ := s.getSMAPieces(epochID); isGenesis {
+		return s.defaultSlotLeadersPtrArray[:], nil
+	}
+	if leaders, ok := s.scheduleCache.Get(epochID); ok {
+		return leaders.([]*ecdsa.PublicKey), nil
+	}
+
+	epochLeaders, pieces, random, err := s.slotLeaderSeed(epochID)
+	if err != nil {
+		return nil, err
+	}
+	leaders, _, _, err := uleaderselection.GenerateSlotLeaderSeqAndIndex(pieces, epochLeaders, random.Bytes(),
+		posconfig.SlotCount, epochID)
+	if err != nil {
+		return nil, err
+	}
+	s.scheduleCache.Add(epochID, leaders)
+	return leaders, nil
+}
+
 func (s *SLS) GetSma(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, err error) {
 	return s.getSMAPieces(epochID)
 }
 
-func SlsInit() {
+// SlsInit creates the slot leader selection of the node and registers it in ctx.
+func SlsInit(ctx *posctx.Context) *SLS {
+	s := &SLS{ctx: ctx, errorRetry: maxErrorRetry}
+
 	var err error
-	APkiCache, err = lru.NewARC(1000)
-	if err != nil || APkiCache == nil {
+	s.apkiCache, err = lru.NewARC(1000)
+	if err != nil || s.apkiCache == nil {
 		log.SyslogErr("APkiCache failed")
 	}
 
-	RndCache, err = lru.NewARC(10)
-	if err != nil || RndCache == nil {
+	s.rndCache, err = lru.NewARC(10)
+	if err != nil || s.rndCache == nil {
 		log.SyslogErr("RndCache failed")
 	}
 
-	slotLeaderSelection = &SLS{}
-	slotLeaderSelection.epochLeadersMap = make(map[string][]uint64)
-	slotLeaderSelection.epochLeadersArray = make([]string, 0)
-	slotLeaderSelection.slotCreateStatus = make(map[uint64]bool)
-	slotLeaderSelection.slotCreateStatusLockCh = make(chan int, 1)
+	s.scheduleCache, err = lru.NewARC(4)
+	if err != nil || s.scheduleCache == nil {
+		log.SyslogErr("ScheduleCache failed")
+	}
+
+	s.epochLeadersMap = make(map[string][]uint64)
+	s.epochLeadersArray = make([]string, 0)
+	s.slotCreateStatus = make(map[uint64]bool)
+	s.slotCreateStatusLockCh = make(chan int, 1)
+	ctx.Register(serviceName, s)
+	return s
 }
 
 func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bool, err error) {
@@ -241,6 +300,38 @@ func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bo
 		return ret[:], err
 	}
 
+	return stage2TxIndexes(stateDb, epochID)
+}
+
+// StageTwoFromState reads the stage two data of epochID from stateDb, the
+// valid indexes mark the epoch leaders whose stage two tx is on chain.
+func StageTwoFromState(stateDb vm.StateDB, epochID uint64) (validEpochLeadersIndex [posconfig.EpochLeaderCount]bool,
+	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey, err error) {
+
+	indexesSentTran, err := stage2TxIndexes(stateDb, epochID)
+	if err != nil {
+		return validEpochLeadersIndex, stageTwoAlphaPKi, err
+	}
+
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		if !indexesSentTran[i] {
+			continue
+		}
+		alphaPki, _, err := vm.GetStage2TxAlphaPki(stateDb, epochID, uint64(i))
+		if err != nil || len(alphaPki) < posconfig.EpochLeaderCount {
+			continue
+		}
+		validEpochLeadersIndex[i] = true
+		for j := 0; j < posconfig.EpochLeaderCount; j++ {
+			stageTwoAlphaPKi[i][j] = alphaPki[j]
+		}
+	}
+	return validEpochLeadersIndex, stageTwoAlphaPKi, nil
+}
+
+func stage2TxIndexes(stateDb vm.StateDB, epochID uint64) (indexesSentTran []bool, err error) {
+	var ret [posconfig.EpochLeaderCount]bool
+
 	slotLeaderPrecompileAddr := vm.GetSlotLeaderSCAddress()
 
 	keyHash := vm.GetSlotLeaderStage2IndexesKeyHash(convert.Uint64ToBytes(epochID))
@@ -262,11 +353,11 @@ func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bo
 }
 
 func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
-	if posconfig.SelfTestMode {
+	if s.ctx.SelfTestMode() {
 		ret := big.NewInt(123)
 		return ret, nil
 	}
-	buf, err := posdb.GetDb().GetWithIndex(epochID, selfIndex, "alpha")
+	buf, err := s.ctx.LocalDb().GetWithIndex(epochID, selfIndex, "alpha")
 	if err != nil {
 		return nil, err
 	}
@@ -276,22 +367,18 @@ func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
 }
 
 func (s *SLS) getLocalPublicKey() (*ecdsa.PublicKey, error) {
-	if s.key == nil || s.key.PrivateKey == nil {
+	if s.signer == nil || s.signer.PublicKey() == nil {
 		log.SyslogErr("SLS", "getLocalPublicKey", vm.ErrInvalidLocalPublicKey.Error())
 		return nil, vm.ErrInvalidLocalPublicKey
 	}
-	return &s.key.PrivateKey.PublicKey, nil
-}
-
-func (s *SLS) getLocalPrivateKey() (*ecdsa.PrivateKey, error) {
-	return s.key.PrivateKey, nil
+	return s.signer.PublicKey(), nil
 }
 
 func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 	//test := false
-	if posconfig.SelfTestMode {
+	if s.ctx.SelfTestMode() {
 		//test: generate test publicKey
-		epochLeaderAllBytes, err := posdb.GetDb().Get(epochID, EpochLeaders)
+		epochLeaderAllBytes, err := s.ctx.LocalDb().Get(epochID, EpochLeaders)
 		if err != nil {
 			return nil
 		}
@@ -312,7 +399,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 			GetEpochLeaders(epochID uint64) [][]byte
 		}
 
-		selector := util.GetEpocherInst() //TODO:CHECK INIT
+		selector := s.ctx.Selector() //TODO:CHECK INIT
 
 		if selector == nil {
 			return nil
@@ -338,7 +425,7 @@ func (s *SLS) getEpochLeadersPK(epochID uint64) []*ecdsa.PublicKey {
 }
 
 func (s *SLS) GetPreEpochLeadersPK(epochID uint64) (pks []*ecdsa.PublicKey, isDefault bool) {
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.ctx.FirstEpochId()+2 {
 		return s.GetEpochDefaultLeadersPK(0), true
 	}
 
@@ -351,28 +438,25 @@ func (s *SLS) GetPreEpochLeadersPK(epochID uint64) (pks []*ecdsa.PublicKey, isDe
 	return pks, false
 }
 func (s *SLS) GetEpochDefaultLeadersPK(epochID uint64) []*ecdsa.PublicKey {
-	pks := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
-	if posconfig.SelfTestMode {
-		var initPksStr []*ecdsa.PublicKey
-		for _, value := range posconfig.WhiteListOrig {
+	if s.ctx.SelfTestMode() {
+		return DefaultEpochLeadersPK(posconfig.WhiteListOrig[:])
+	}
 
-			b := hexutil.MustDecode(value)
-			initPksStr = append(initPksStr, crypto.ToECDSAPub(b))
-		}
+	selector := epochLeader.GetEpocher(s.ctx)
+	initPksStr, err := selector.GetWhiteByEpochId(epochID)
+	if err != nil {
+		log.SyslogErr("GetEpochDefaultLeadersPK error", "err", err)
+	}
+	return DefaultEpochLeadersPK(initPksStr)
+}
 
-		for i := 0; i < posconfig.EpochLeaderCount; i++ {
-			pks[i] = initPksStr[i%len(initPksStr)]
-		}
-	} else {
-		selector := epochLeader.GetEpocher()
-		initPksStr, err := selector.GetWhiteByEpochId(epochID)
-		if err != nil {
-			log.SyslogErr("GetEpochDefaultLeadersPK error", "err", err)
-		}
-		for i := 0; i < posconfig.EpochLeaderCount; i++ {
-			pkBuf := common.FromHex(initPksStr[i%len(initPksStr)])
-			pks[i] = crypto.ToECDSAPub(pkBuf)
-		}
+// DefaultEpochLeadersPK fills an epoch leader group from the white list, it is
+// used until the first epoch leaders are selected from the stakers.
+func DefaultEpochLeadersPK(whiteList []string) []*ecdsa.PublicKey {
+	pks := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		pkBuf := common.FromHex(whiteList[i%len(whiteList)])
+		pks[i] = crypto.ToECDSAPub(pkBuf)
 	}
 	return pks
 }
@@ -393,7 +477,7 @@ func (s *SLS) IsLocalPkInEpochLeaders(pks []*ecdsa.PublicKey) bool {
 func (s *SLS) isLocalPkInCurrentEpochLeaders() bool {
 	selfPublicKey, _ := s.getLocalPublicKey()
 	locakPk := crypto.FromECDSAPub(selfPublicKey)
-	epochID, _ := util.GetEpochSlotID()
+	epochID, _ := util.CalEpochSlotID(s.ctx.Now())
 	pks := s.getEpochLeadersPK(epochID)
 
 	if len(pks) == 0 {
@@ -450,7 +534,7 @@ func (s *SLS) dumpPreEpochLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpPreEpochLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.ctx.FirstEpochId() {
 		return
 	}
 
@@ -465,7 +549,7 @@ func (s *SLS) dumpCurrentEpochLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpCurrentEpochLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.ctx.FirstEpochId() {
 		return
 	}
 
@@ -480,7 +564,7 @@ func (s *SLS) dumpSlotLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpSlotLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.ctx.FirstEpochId() {
 		return
 	}
 
@@ -536,7 +620,7 @@ func (s *SLS) isEpochLeaderMapReady() bool {
 
 func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err error) {
 
-	rnd, ok := RndCache.Get(epochID)
+	rnd, ok := s.rndCache.Get(epochID)
 	if ok {
 		return rnd.(*big.Int), nil
 	}
@@ -549,7 +633,7 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 			log.SyslogErr("SLS.getRandom getStateDb return error, use a default value", "epochID", epochID)
 			rb := posconfig.GetRandomGenesis()
 
-			RndCache.Add(epochID, rb)
+			s.rndCache.Add(epochID, rb)
 
 			return rb, nil
 		}
@@ -561,19 +645,19 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 			log.SyslogErr("Update stateDb error in SLS.updateToLastStateDb", "error", err.Error())
 			rb := posconfig.GetRandomGenesis()
 
-			RndCache.Add(epochID, rb)
+			s.rndCache.Add(epochID, rb)
 
 			return rb, nil
 		}
 	}
 
-	rb := vm.GetR(db, epochID)
+	rb := vm.GetR(db, epochID, s.ctx.FirstEpochId())
 	if rb == nil {
 		log.SyslogErr("vm.GetR return nil, use a default value", "epochID", epochID)
 		rb = posconfig.GetRandomGenesis()
 	}
 
-	RndCache.Add(epochID, rb)
+	s.rndCache.Add(epochID, rb)
 
 	return rb, nil
 }
@@ -582,13 +666,13 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 // It had been +1 when save into db, so do not -1 in get.
 func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, err error) {
 	piecesPtr := make([]*ecdsa.PublicKey, 0)
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.ctx.FirstEpochId()+2 {
 		return s.smaGenesis[:], true, nil
 	} else {
 		// pieces: alpha[1]*G, alpha[2]*G, .....
-		pieces, err := posdb.GetDb().Get(epochID, SecurityMsg)
+		pieces, err := s.ctx.LocalDb().Get(epochID, SecurityMsg)
 		if err != nil {
-			if epochID > posconfig.FirstEpochId+2 {
+			if epochID > s.ctx.FirstEpochId()+2 {
 				log.Warn("getSMAPieces error use the first epoch SMA", "epochID", epochID, "SecurityMsg", SecurityMsg)
 			}
 			return s.smaGenesis[:], true, nil
@@ -611,7 +695,7 @@ func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bo
 //func (s *SLS) generateSlotLeadsGroup(epochID uint64) error {
 //	epochIDGet := epochID
 //	epochLeadersPtrArray, isDefault := s.GetPreEpochLeadersPK(epochIDGet)
-//	if isDefault && epochID > posconfig.FirstEpochId+2 {
+//	if isDefault && epochID > s.ctx.FirstEpochId()+2 {
 //		log.Info("generateSlotLeadsGroup use default epochLeader", "epochID", epochID)
 //		epochIDGet = 0
 //	}
@@ -625,7 +709,7 @@ func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bo
 //	if isGenesis {
 //		log.Warn("Can not find pre epoch SMA or not in Pre epoch leaders, use the first epoch.", "curEpochID", epochID,
 //			"preEpochID", epochID-1)
-//		//epochIDGet = posconfig.FirstEpochId
+//		//epochIDGet = s.ctx.FirstEpochId()
 //		epochIDGet = 0
 //	}
 //	// get random
@@ -651,7 +735,7 @@ func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bo
 //
 //	// insert slot address to local DB
 //	for index, val := range slotLeadersPtr {
-//		_, err = posdb.GetDb().PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
+//		_, err = s.ctx.LocalDb().PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
 //		if err != nil {
 //			log.SyslogAlert("generateSlotLeadsGroup:PutWithIndex", "epochid", epochID, "error", err.Error())
 //			return err
@@ -743,10 +827,10 @@ func (s *SLS) collectStagesData(epochID uint64) (err error) {
 	return nil
 }
 
-func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey) error {
+func (s *SLS) generateSecurityMsg(epochID uint64, signer possigner.Signer) error {
 	if !s.isLocalPkInCurrentEpochLeaders() {
 		log.Debug("generateSecurityMsg", "input public key",
-			hex.EncodeToString(crypto.FromECDSAPub(&PrivateKey.PublicKey)))
+			hex.EncodeToString(crypto.FromECDSAPub(signer.PublicKey())))
 		return vm.ErrPkNotInCurrentEpochLeadersGroup
 	}
 	// collect data
@@ -765,7 +849,7 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 	smasPtr := make([]*ecdsa.PublicKey, 0)
 	var smasBytes bytes.Buffer
 
-	smasPtr, err = uleaderselection.GenerateSMA(PrivateKey, ArrayPiece)
+	smasPtr, err = signer.GenerateSMA(ArrayPiece)
 	if err != nil {
 		log.Error("generateSecurityMsg:GenerateSMA", "error", err.Error())
 		return err
@@ -775,7 +859,7 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 		log.Debug(fmt.Sprintf("epochID+1 = %d set security message is %v\n", epochID+1,
 			hex.EncodeToString(crypto.FromECDSAPub(value))))
 	}
-	_, err = posdb.GetDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
+	_, err = s.ctx.LocalDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
 	if err != nil {
 		log.SyslogCrit("generateSecurityMsg:Put", "epochid", epochID, "error", err.Error())
 		return err
@@ -810,7 +894,7 @@ func (s *SLS) buildArrayPiece(epochID uint64, selfIndex uint64) ([]*ecdsa.Public
 func (s *SLS) buildStage2TxPayload(epochID uint64, selfIndex uint64) ([]byte, error) {
 	var selfPk *ecdsa.PublicKey
 	var err error
-	if posconfig.SelfTestMode {
+	if s.ctx.SelfTestMode() {
 		selfPk = s.epochLeadersPtrArray[selfIndex]
 	} else {
 		selfPk, err = s.getLocalPublicKey()
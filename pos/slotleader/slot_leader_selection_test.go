@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"math/big"
 	"os"
-	"path"
-	"path/filepath"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -17,19 +15,20 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/crypto"
-	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
 func TestSlotLeaderSelectionGetInstance(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	SlsInit()
-	slot := GetSlotLeaderSelection()
+	slot := SlsInit(posctx.New(""))
+	defer slot.ctx.Close()
 	if slot == nil {
 		t.Fail()
 	}
@@ -226,7 +225,9 @@ func TestArraySave(t *testing.T) {
 		t.Error(err.Error())
 	}
 
-	db := posdb.NewDb("testArraySave")
+	ctx := posctx.New("")
+	defer ctx.Close()
+	db := ctx.Db("testArraySave")
 	db.Put(uint64(0), "TestArraySave", bytes)
 
 	var sendtransGet [posconfig.EpochLeaderCount]bool
@@ -239,13 +240,12 @@ func TestArraySave(t *testing.T) {
 		t.Error(err.Error())
 	}
 	fmt.Println(sendtransGet)
-	RmDB("testArraySave")
 	os.RemoveAll("sl_leader_test")
 }
 
 func TestGetSMAPieces(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
 	// getSMAPieces
 	pks, isGenesis, err := s.getSMAPieces(0)
@@ -275,8 +275,8 @@ func TestGetSMAPieces(t *testing.T) {
 }
 
 func TestDump(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	epochID := s.getWorkingEpochID()
 	s.setWorkingEpochID(2)
 
@@ -286,19 +286,19 @@ func TestDump(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
-	posconfig.SelfTestMode = true
+	s.ctx.SetSelfTestMode(true)
 	go s.dumpData()
 
 	s.setWorkingEpochID(epochID)
-	posconfig.SelfTestMode = false
+	s.ctx.SetSelfTestMode(false)
 }
 
 func TestClearData(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	epochID := s.getWorkingEpochID()
 	s.setWorkingEpochID(2)
 
@@ -308,10 +308,10 @@ func TestClearData(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
-	posconfig.SelfTestMode = true
+	s.ctx.SetSelfTestMode(true)
 	s.buildEpochLeaderGroup(1)
 
 	go s.dumpData()
@@ -319,17 +319,13 @@ func TestClearData(t *testing.T) {
 	go s.dumpData()
 
 	s.setWorkingEpochID(epochID)
-	posconfig.SelfTestMode = false
+	s.ctx.SetSelfTestMode(false)
 }
 
 func TestGetSlotLeader(t *testing.T) {
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	t.Log("Current dir path ", dir)
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		prvKey, _ := crypto.GenerateKey()
@@ -347,26 +343,48 @@ func TestGetSlotLeader(t *testing.T) {
 		t.Fail()
 	}
 
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 
 }
 
+func TestGetSlotLeaders(t *testing.T) {
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
+
+	prvKey, _ := crypto.GenerateKey()
+	s.defaultSlotLeadersPtrArray[1] = &prvKey.PublicKey
+
+	// Without the SMA of an epoch, the default slot leaders are used
+	curEpochID, _ := util.CalEpochSlotID(s.ctx.Now())
+	for _, epochID := range []uint64{0, curEpochID} {
+		leaders, err := s.GetSlotLeaders(epochID)
+		if err != nil {
+			t.Fatalf("epoch %d: failed to get the slot leaders: %v", epochID, err)
+		}
+		if len(leaders) != posconfig.SlotCount || leaders[1] != s.defaultSlotLeadersPtrArray[1] {
+			t.Errorf("epoch %d: slot leaders are not the default ones", epochID)
+		}
+	}
+
+	if _, err := s.GetSlotLeaders(curEpochID + 1); err != ErrFutureEpoch {
+		t.Errorf("future epoch error mismatch: have %v, want %v", err, ErrFutureEpoch)
+	}
+}
+
 func TestGetLocalPublicKey(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.key = &keystore.Key{}
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewLocal(&keystore.Key{PrivateKey: key})
 	// GetLocalPublicKey
 	keyGot, err := s.GetLocalPublicKey()
 	if err != nil {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot, &s.key.PrivateKey.PublicKey) {
+	if !uleaderselection.PublicKeyEqual(keyGot, &key.PublicKey) {
 		t.Fail()
 	}
 	// getLocalPublicKey
@@ -375,24 +393,14 @@ func TestGetLocalPublicKey(t *testing.T) {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot1, &s.key.PrivateKey.PublicKey) {
-		t.Fail()
-	}
-	//getLocalPrivateKey
-	prvKeyGot, err := s.getLocalPrivateKey()
-	if err != nil {
-		t.Fail()
-	}
-
-	if prvKeyGot != key {
+	if !uleaderselection.PublicKeyEqual(keyGot1, &key.PublicKey) {
 		t.Fail()
 	}
-
 }
 
 func TestGetSlotCreateStatusByEpochID(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
 	if s.GetSlotCreateStatusByEpochID(0) {
 		t.Fail()
@@ -404,22 +412,19 @@ func TestGetSlotCreateStatusByEpochID(t *testing.T) {
 }
 
 func TestGetSlotLeaderSelection(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
-	if s == nil {
+	if GetSlotLeaderSelection(s.ctx) != s {
 		t.Fail()
 	}
 }
 
 func TestGetEpochLeaders(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	posconfig.SelfTestMode = true
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
+	s.ctx.SetSelfTestMode(true)
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -427,8 +432,8 @@ func TestGetEpochLeaders(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	// getEpochLeaders
 	epochLeadersBytes := s.getEpochLeaders(uint64(1))
@@ -455,19 +460,15 @@ func TestGetEpochLeaders(t *testing.T) {
 		t.Errorf("getPreEpochLeadersPK error!")
 		t.Fail()
 	}
-	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
+	s.ctx.SetSelfTestMode(false)
 }
 
 func TestGetAlpha(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
 	alpha := big.NewInt(0).SetUint64(uint64(^uint64(0)))
-	posdb.GetDb().PutWithIndex(uint64(0), uint64(0), "alpha", alpha.Bytes())
+	s.ctx.LocalDb().PutWithIndex(uint64(0), uint64(0), "alpha", alpha.Bytes())
 
 	alphaGet, err := s.getAlpha(0, 0)
 	if err != nil {
@@ -479,7 +480,7 @@ func TestGetAlpha(t *testing.T) {
 	}
 
 	alpha = big.NewInt(0).SetUint64(0)
-	posdb.GetDb().PutWithIndex(uint64(0), uint64(1), "alpha", alpha.Bytes())
+	s.ctx.LocalDb().PutWithIndex(uint64(0), uint64(1), "alpha", alpha.Bytes())
 
 	alphaGet, err = s.getAlpha(0, 1)
 	if err != nil {
@@ -490,18 +491,13 @@ func TestGetAlpha(t *testing.T) {
 		t.Fail()
 	}
 
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.key = &keystore.Key{}
-	posconfig.SelfTestMode = true
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
+	s.ctx.SetSelfTestMode(true)
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	var prvKeyExist *ecdsa.PrivateKey
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
@@ -519,14 +515,14 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 			uint64(i))
 	}
 
-	posdb.GetDb().Put(4, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(3, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(4, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(3, EpochLeaders, epochLeaderAllBytes[:])
 
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewLocal(&keystore.Key{PrivateKey: key})
 
 	//isLocalPkInPreEpochLeaders
 	pks, _ := s.GetPreEpochLeadersPK(4)
@@ -535,7 +531,7 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 		t.Fail()
 	}
 
-	s.key.PrivateKey = prvKeyExist
+	s.signer = possigner.NewLocal(&keystore.Key{PrivateKey: prvKeyExist})
 	//isLocalPkInPreEpochLeaders
 	pks, _ = s.GetPreEpochLeadersPK(4)
 	inOrNot = s.IsLocalPkInEpochLeaders(pks)
@@ -544,20 +540,15 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 		t.Fail()
 	}
 
-	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
+	s.ctx.SetSelfTestMode(false)
 }
 
 func TestBuildEpochLeaderGroup(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.key = &keystore.Key{}
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 
-	posconfig.SelfTestMode = true
+	s.ctx.SetSelfTestMode(true)
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -566,8 +557,8 @@ func TestBuildEpochLeaderGroup(t *testing.T) {
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
 
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	// buildEpochLeaderGroup
 	s.buildEpochLeaderGroup(2)
@@ -604,8 +595,7 @@ func TestBuildEpochLeaderGroup(t *testing.T) {
 		}
 	}
 
-	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
+	s.ctx.SetSelfTestMode(false)
 }
 
 func TestGetRandom(t *testing.T) {
@@ -617,8 +607,8 @@ func TestGetRandom(t *testing.T) {
 	if stateDb == nil {
 		t.Fail()
 	}
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	vmcfg := vm.Config{}
 	gspec := core.DefaultPPOWTestingGenesisBlock()
 	gspec.MustCommit(db)
@@ -635,14 +625,10 @@ func TestGetRandom(t *testing.T) {
 }
 
 func TestBuildStage2TxPayload(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.key = &keystore.Key{}
-	posconfig.SelfTestMode = true
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
+	s.ctx.SetSelfTestMode(true)
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -651,8 +637,8 @@ func TestBuildStage2TxPayload(t *testing.T) {
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
 
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	s.buildEpochLeaderGroup(2)
 	// test below functions
@@ -665,18 +651,16 @@ func TestBuildStage2TxPayload(t *testing.T) {
 	}
 
 	fmt.Printf("bytes of stage2TxBytes is %v\n", hex.EncodeToString(stage2TxBytes))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestBuildSecurityPieces(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.key = &keystore.Key{}
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewLocal(&keystore.Key{PrivateKey: key})
 
 	// getLocalPublicKey
 	keyGot1, err := s.getLocalPublicKey()
@@ -684,7 +668,7 @@ func TestBuildSecurityPieces(t *testing.T) {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot1, &s.key.PrivateKey.PublicKey) {
+	if !uleaderselection.PublicKeyEqual(keyGot1, &key.PublicKey) {
 		t.Fail()
 	}
 
@@ -730,8 +714,8 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	if stateDb == nil {
 		t.Fail()
 	}
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := SlsInit(posctx.New(""))
+	defer s.ctx.Close()
 	s.stateDbTest = stateDb
 	// build block chain
 	vmcfg := vm.Config{}
@@ -745,16 +729,12 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 
 	// build local key
-	s.key = &keystore.Key{}
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewLocal(&keystore.Key{PrivateKey: key})
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	// build current epoch leaders s.epochLeadersMap
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -774,7 +754,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 		s.epochLeadersArray = append(s.epochLeadersArray, hex.EncodeToString(crypto.FromECDSAPub(alphaPk)))
 	}
 
-	posconfig.SelfTestMode = true
+	s.ctx.SetSelfTestMode(true)
 
 	for i := 1; i < posconfig.EpochLeaderCount; i++ {
 		key, err := crypto.GenerateKey()
@@ -793,7 +773,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(s.epochLeadersPtrArray[i])
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(epochID, EpochLeaders, epochLeaderAllBytes[:])
+	s.ctx.LocalDb().Put(epochID, EpochLeaders, epochLeaderAllBytes[:])
 
 	// build stg2 trans and input into state db
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -829,7 +809,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	// build security pieces
 	//pieces,_:= s.buildSecurityPieces(epochID)
 	// create SMA
-	err = s.generateSecurityMsg(epochID, key)
+	err = s.generateSecurityMsg(epochID, s.signer)
 	if err != nil {
 		t.Logf("generate security message error. err:%v \n", err.Error())
 		t.Fail()
@@ -845,5 +825,4 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 	// un init
 	RmDB("epochGendb")
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
@@ -2,8 +2,6 @@ package slotleader
 
 import (
 	"errors"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
-
 	"github.com/wanchain/go-wanchain/core/state"
 )
 
@@ -17,7 +15,7 @@ func (s *SLS) GetCurrentStateDb() (stateDb *state.StateDB, err error) {
 }
 
 func (s *SLS) getCurrentStateDb() (stateDb *state.StateDB, err error) {
-	if posconfig.SelfTestMode {
+	if s.ctx.SelfTestMode() {
 		return s.stateDbTest, nil
 	}
 	return s.blockChain.StateAt(s.blockChain.CurrentBlock().Root())
@@ -2,23 +2,20 @@ package slotleader
 
 import (
 	"fmt"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"testing"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
-
 	"github.com/wanchain/go-wanchain/consensus/ethash"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var s *SLS
 
-func testInitSlotleader() {
-	SlsInit()
-	s = GetSlotLeaderSelection()
+func testInitSlotleader(ctx *posctx.Context) {
+	s = SlsInit(ctx)
 
 	// Create the database in memory or in a temporary directory.
 	db, _ := ethdb.NewMemDatabase()
@@ -28,7 +25,7 @@ func testInitSlotleader() {
 	ce := ethash.NewFaker(db)
 	bc, _ := core.NewBlockChain(db, gspec.Config, ce, vm.Config{},nil)
 
-	s.Init(bc, &rpc.Client{}, &keystore.Key{})
+	s.Init(bc, &rpc.Client{}, nil)
 
 	s.sendTransactionFn = testSender
 
@@ -36,10 +33,10 @@ func testInitSlotleader() {
 
 func TestGetCurrentStateDb(t *testing.T) {
 
-	posconfig.SelfTestMode = true
-	testInitSlotleader()
+	testInitSlotleader(posctx.NewSelfTest())
+	defer s.ctx.Close()
 
-	posconfig.SelfTestMode = false
+	s.ctx.SetSelfTestMode(false)
 	stateDb, err := s.GetCurrentStateDb()
 	if err != nil || stateDb == nil {
 		t.FailNow()
@@ -54,5 +51,4 @@ func TestGetCurrentStateDb(t *testing.T) {
 
 	fmt.Println(epochID, slotID)
 	RmDB("epochGendb")
-	posconfig.SelfTestMode = false
 }
@@ -10,15 +10,14 @@ import (
 
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -44,15 +43,17 @@ func (s *SLS) GenerateDefaultSlotLeaders() error {
 }
 
 // Init use to initial slotleader module and input some params.
-func (s *SLS) Init(blockChain *core.BlockChain, rc *rpc.Client, key *keystore.Key) {
+func (s *SLS) Init(blockChain *core.BlockChain, rc *rpc.Client, signer possigner.Signer) {
 	s.blockChain = blockChain
 	s.rc = rc
-	s.key = key
+	s.signer = signer
 	if blockChain != nil {
 		log.Info("SLS init success")
 	}
 
-	s.sendTransactionFn = util.SendPosTx
+	if s.sendTransactionFn == nil {
+		s.sendTransactionFn = util.SendPosTxAsync
+	}
 	s.initSma()
 	s.GenerateDefaultSlotLeaders()
 }
@@ -67,12 +68,8 @@ func (s *SLS) initSma() {
 		s.epochLeadersPtrArrayGenesis[index] = value
 	}
 
-	alphas := make([]*big.Int, 0)
-	for _, value := range epochDefaultLeaders {
-		tempInt := new(big.Int).SetInt64(0)
-		tempInt.SetBytes(crypto.Keccak256(crypto.FromECDSAPub(value)))
-		alphas = append(alphas, tempInt)
-	}
+	alphas := genesisAlphas(epochDefaultLeaders)
+	s.stageTwoAlphaPKiGenesis = NewGenesisProofContext(epochDefaultLeaders).AlphaPKi
 
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 
@@ -93,17 +90,6 @@ func (s *SLS) initSma() {
 		smaPiece.Curve = crypto.S256()
 		smaPiece.X, smaPiece.Y = crypto.S256().ScalarMult(BasePoint.X, BasePoint.Y, alphas[i].Bytes())
 		s.smaGenesis[i] = smaPiece
-
-		for j := 0; j < posconfig.EpochLeaderCount; j++ {
-			// AlphaIPki stage2Genesis, used to verify genesis proof
-			alphaIPkj := new(ecdsa.PublicKey)
-			alphaIPkj.Curve = crypto.S256()
-			alphaIPkj.X, alphaIPkj.Y = crypto.S256().ScalarMult(s.epochLeadersPtrArrayGenesis[j].X,
-				s.epochLeadersPtrArrayGenesis[j].Y, alphas[i].Bytes())
-
-			s.stageTwoAlphaPKiGenesis[i][j] = alphaIPkj
-		}
-
 	}
 
 	epochLeadersPreHexStr := make([]string, 0)
@@ -124,9 +110,9 @@ func (s *SLS) initSma() {
 //Loop check work every Slot time. Called by backend loop.
 //It's all slotLeaderSelection's main workflow loop.
 //It does not loop at all, it is loop called by the backend.
-func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uint64) {
+func (s *SLS) Loop(rc *rpc.Client, signer possigner.Signer, epochID uint64, slotID uint64) error {
 	s.rc = rc
-	s.key = key
+	s.signer = signer
 
 	log.Info("Now epchoID and slotID:", "epochID", convert.Uint64ToString(epochID), "slotID",
 		convert.Uint64ToString(slotID))
@@ -135,7 +121,10 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 
 	//Check if epoch is new
 	s.checkNewEpochStart(epochID)
-	workStage := s.getWorkStage(epochID)
+	workStage, err := s.getWorkStage(epochID)
+	if err != nil {
+		return err
+	}
 
 	//If the gwan restart, try to recover the epoch leader and slot leader
 	if workStage != slotLeaderSelectionInit && workStage != slotLeaderSelectionStageFinished {
@@ -185,31 +174,32 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			break
 		}
 
-		go doStage2Work()
+		s.ctx.Go(s.doStage2Work)
 		s.setWorkStage(epochID, slotLeaderSelectionStage3)
 	case slotLeaderSelectionStage3:
 		if slotID < posconfig.Sma3Start {
 			break
 		}
 
-		err := s.generateSecurityMsg(epochID, s.key.PrivateKey)
+		err := s.generateSecurityMsg(epochID, s.signer)
 		if err != nil {
 			log.Warn(err.Error())
 		} else {
 			log.Info("generateSecurityMsg SMA success!")
 		}
 
-		if err != nil && errorRetry > 0 {
-			errorRetry--
+		if err != nil && s.errorRetry > 0 {
+			s.errorRetry--
 			break
 		}
 
 		s.setWorkStage(epochID, slotLeaderSelectionStageFinished)
-		errorRetry = 3
+		s.errorRetry = maxErrorRetry
 	case slotLeaderSelectionStageFinished:
 
 	default:
 	}
+	return nil
 }
 
 // doInit is used for init in each epoch
@@ -249,8 +239,7 @@ func (s *SLS) startStage1Work() error {
 	return nil
 }
 
-func doStage2Work() {
-	s := GetSlotLeaderSelection()
+func (s *SLS) doStage2Work() {
 	err := s.startStage2Work()
 	if err != nil {
 		log.Error(err.Error())
@@ -313,7 +302,7 @@ func (s *SLS) generateCommitment(publicKey *ecdsa.PublicKey,
 	buffer, err := vm.RlpPackStage1DataForTx(epochID, selfIndexInEpochLeader, commitment[1],
 		vm.GetSlotLeaderScAbiString())
 
-	posdb.GetDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
+	s.ctx.LocalDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
 
 	log.Debug(fmt.Sprintf("----Put alpha epochID:%d, selfIndex:%d, alpha:%s, mi:%s, pk:%s", epochID,
 		selfIndexInEpochLeader, alpha.String(), hex.EncodeToString(crypto.FromECDSAPub(commitment[1])),
@@ -331,10 +320,10 @@ func (s *SLS) checkNewEpochStart(epochID uint64) {
 }
 
 func (s *SLS) getWorkingEpochID() uint64 {
-	ret, err := posdb.GetDb().Get(0, "slotLeaderCurrentSlotID")
+	ret, err := s.ctx.LocalDb().Get(0, "slotLeaderCurrentSlotID")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
-			posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
+			s.ctx.LocalDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
 			return 0
 		}
 	}
@@ -343,26 +332,26 @@ func (s *SLS) getWorkingEpochID() uint64 {
 }
 
 func (s *SLS) setWorkingEpochID(workingEpochID uint64) error {
-	_, err := posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
+	_, err := s.ctx.LocalDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
 	return err
 }
 
-func (s *SLS) getWorkStage(epochID uint64) int {
-	ret, err := posdb.GetDb().Get(epochID, "slotLeaderWorkStage")
+func (s *SLS) getWorkStage(epochID uint64) (int, error) {
+	ret, err := s.ctx.LocalDb().Get(epochID, "slotLeaderWorkStage")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
 			s.setWorkStage(epochID, slotLeaderSelectionInit)
-			return slotLeaderSelectionInit
+			return slotLeaderSelectionInit, nil
 		}
 		log.Error("getWorkStage error: " + err.Error())
-		panic("getWorkStage error")
+		return 0, err
 	}
 	workStageUint64 := convert.BytesToUint64(ret)
-	return int(workStageUint64)
+	return int(workStageUint64), nil
 }
 
 func (s *SLS) setWorkStage(epochID uint64, workStage int) error {
 	workStageBig := big.NewInt(int64(workStage))
-	_, err := posdb.GetDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
+	_, err := s.ctx.LocalDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
 	return err
 }
@@ -11,14 +11,13 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
-
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
-	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -67,12 +66,15 @@ func generateTestAddrs() {
 
 
 func TestLoop(t *testing.T) {
-	posconfig.SelfTestMode = true
-	posdb.GetDb().DbInit("test")
-	SlsInit()
+	testLoop(t)
+	s.ctx.Close()
+}
+
+func testLoop(t *testing.T) {
+	ctx := posctx.NewSelfTest()
+	ctx.SetSelector(&TestSelectLead{})
 	generateTestAddrs()
-	testInitSlotleader()
-	util.SetEpocherInst(&TestSelectLead{})
+	testInitSlotleader(ctx)
 
 	key := &keystore.Key{}
 	key.PrivateKey, _ = crypto.GenerateKey()
@@ -81,20 +83,18 @@ func TestLoop(t *testing.T) {
 	epochIDStart := time.Now().Second()
 
 	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), uint64(i))
+		s.Loop(&rpc.Client{}, possigner.NewLocal(key), uint64(epochIDStart+0), uint64(i))
 	}
 
 	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), uint64(i))
+		s.Loop(&rpc.Client{}, possigner.NewLocal(key), uint64(epochIDStart+1), uint64(i))
 	}
-	RmDB("test")
-	posconfig.SelfTestMode = false
+	ctx.SetSelfTestMode(false)
 }
 
 func TestGenerateCommitmentSuccess(t *testing.T) {
-	posconfig.SelfTestMode = true
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := SlsInit(posctx.NewSelfTest())
+	defer slot.ctx.Close()
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
@@ -136,15 +136,12 @@ func TestGenerateCommitmentSuccess(t *testing.T) {
 	fmt.Println("selfIndex: ", selfIndex)
 	fmt.Println("Alpha: ", alpha)
 
-	RmDB("test")
-	posconfig.SelfTestMode = false
 }
 
 
 func TestGenerateCommitmentFailed(t *testing.T) {
-	posconfig.SelfTestMode = true
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := SlsInit(posctx.NewSelfTest())
+	defer slot.ctx.Close()
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
@@ -185,17 +182,14 @@ func TestGenerateCommitmentFailed(t *testing.T) {
 		t.Fail()
 	}
 
-	RmDB("test")
-	posconfig.SelfTestMode = false
 }
 
 func TestStartStage1Work(t *testing.T) {
-	posconfig.SelfTestMode = true
-	TestLoop(t)
+	testLoop(t)
+	defer s.ctx.Close()
 
 	err := s.startStage1Work()
 	if err != nil {
 		t.FailNow()
 	}
-	posconfig.SelfTestMode = false
 }
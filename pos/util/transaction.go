@@ -52,4 +52,9 @@ func SendPosTx(rc *rpc.Client, tx map[string]interface{})  {
 
 
 	SendTx(rc, tx)
-}
\ No newline at end of file
+}
+
+// SendPosTxAsync sends the pos tx in a new goroutine.
+func SendPosTxAsync(rc *rpc.Client, tx map[string]interface{}) {
+	go SendPosTx(rc, tx)
+}
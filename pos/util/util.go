@@ -12,7 +12,6 @@ import (
 
 	"github.com/wanchain/go-wanchain/common/hexutil"
 
-	"sync"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/accounts/abi"
@@ -80,41 +79,13 @@ type SelectLead interface {
 	//TryGetAndSaveAllStakerInfoBytes(epochId uint64) (*[][]byte, error)
 }
 
-func GetCurrentBlkEpochSlotID() (epochID, slotID uint64) {
-
-	inst :=  GetEpocherInst()
-	if inst == nil {
-		return 0,0
-	}
-
-	curheader := inst.GetCurrentHeader()
-	if curheader == nil {
-		return 0,0
-	}
-
-	return GetEpochSlotIDFromDifficulty(curheader.Difficulty)
-}
-
-
-var (
-	lastBlockEpoch     = make(map[uint64]uint64)
-	lastBlockHashEpoch = make(map[uint64]common.Hash)
-	lbe                = sync.Mutex{}
-	selecter           SelectLead
-	lastEpochId        = uint64(0)
-	selectedEpochId    = uint64(0)
-)
-
-func SetEpocherInst(sor SelectLead) {
-	selecter = sor
-}
-
-func GetEpocherInst() SelectLead {
-	// if haven't switch to pos, it could be nil
-	if selecter == nil {
-		return nil
-	}
-	return selecter
+// SealVerifier verifies the seals of pos headers that may not be part of the
+// chain.
+type SealVerifier interface {
+	// RecoverSealer checks the seal of a header against the slot leader key
+	// of its extra data, and returns the key and the hash it signed. Only the
+	// header is read, never the chain or the local pos data.
+	RecoverSealer(header *types.Header) (*ecdsa.PublicKey, common.Hash, error)
 }
 
 func CalEpSlbyTd(blkTd uint64) (epochID uint64, slotID uint64) {
@@ -123,59 +94,39 @@ func CalEpSlbyTd(blkTd uint64) (epochID uint64, slotID uint64) {
 	return epochID, slotID
 }
 
-func UpdateEpochBlock(block *types.Block) {
-	blkTd := block.Difficulty().Uint64()
-	epochID, slotID := CalEpSlbyTd(blkTd)
-	updateEpochBlock(epochID, slotID, block.Header().Number.Uint64(), block.Header().Hash())
-	posconfig.CurrentEpochId = epochID
-}
-func updateEpochBlock(epochID uint64, slotID uint64, blockNumber uint64, hash common.Hash) {
-	if epochID != lastEpochId {
-		lastEpochId = epochID
+// SearchEpochLastHeader looks up the last header of epochID on a canonical
+// chain whose head is at number head. getHeader returns the canonical header
+// of a number. It is used by nodes that don't track the epoch blocks through
+// posctx.Context.UpdateEpochBlock, and returns nil if the epoch has no block yet.
+func SearchEpochLastHeader(head uint64, epochID uint64, getHeader func(number uint64) *types.Header) *types.Header {
+	epochOf := func(number uint64) (uint64, bool) {
+		header := getHeader(number)
+		if header == nil {
+			return 0, false
+		}
+		epoch, _ := CalEpochSlotID(header.Time.Uint64())
+		return epoch, true
 	}
-	// there is 2K slot, so need not think about reorg  // selec epoch leader from the whole epoch.
-	if slotID >= 2*posconfig.K+1 && selectedEpochId != epochID+1 && epochID != posconfig.FirstEpochId {
-		go GetEpocherInst().SelectLeadersLoop(epochID + 1)
-		selectedEpochId = epochID + 1
-	}
-
-	SetEpochBlock(epochID, blockNumber, hash)
-}
-
-func SetEpochBlock(epochID uint64, blockNumber uint64, hash common.Hash) {
-	lbe.Lock()
-	lastBlockEpoch[epochID] = blockNumber
-	lastBlockHashEpoch[epochID] = hash
-	lbe.Unlock()
-}
-
-//this function only can return
-func GetEpochBlock(epochID uint64) uint64 {
 
-	lbe.Lock()
-	b := lastBlockEpoch[epochID]
-	lbe.Unlock()
-
-	if b == 0 {
-		b = selecter.GetEpochLastBlkNumber(epochID)
+	// find the first block behind the epoch, the block before is the last one
+	lo, hi := uint64(0), head+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		epoch, ok := epochOf(mid)
+		if !ok {
+			return nil
+		}
+		if epoch > epochID {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
 	}
-
-	return b
-}
-
-func GetEpochBlockHash(epochID uint64) common.Hash {
-	lbe.Lock()
-	bh := lastBlockHashEpoch[epochID]
-	lbe.Unlock()
-	return bh
-}
-func GetProposerBn256PK(epochID uint64, idx uint64, addr common.Address) []byte {
-	return GetEpocherInst().GetProposerBn256PK(epochID, idx, addr)
-}
-
-func TryGetAndSaveAllStakerInfoBytes(epochId uint64) (*[][]byte, error) {
-	//return GetEpocherInst().TryGetAndSaveAllStakerInfoBytes(epochId)
-	return nil, nil
+	if lo == 0 || lo > head {
+		// nothing reaches the epoch or the epoch isn't finished yet
+		return nil
+	}
+	return getHeader(lo - 1)
 }
 
 // CompressPk
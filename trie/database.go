@@ -0,0 +1,326 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package trie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+// LeafCallback returns the hashes of the nodes a trie leaf refers to outside
+// of its trie, like the storage trie root of an account in the state trie.
+type LeafCallback func(leaf []byte) []common.Hash
+
+// cachedNode is a trie node held in memory with its references.
+type cachedNode struct {
+	blob     []byte              // Encoded node
+	parents  int                 // Number of live nodes referencing this one
+	children map[common.Hash]int // Children referenced by this node
+}
+
+// NodeDatabase is an intermediate write layer between the tries and the disk
+// database. Committed trie nodes are kept in memory with reference counts, so
+// the nodes of stale state roots can be garbage collected with Dereference
+// before ever reaching the disk, and only flushed on Commit.
+//
+// The blobs written with Put instead of a trie commit, like contract code and
+// the preimages of secure trie keys, are kept until the next Commit and then
+// flushed whatever root is committed.
+//
+// NodeDatabase implements Database, so a trie can be opened and committed on
+// top of it. It is safe for concurrent use.
+type NodeDatabase struct {
+	diskdb ethdb.Database // Persistent storage for matured trie nodes
+	onleaf LeafCallback   // References of the trie leaves, may be nil
+
+	nodes     map[common.Hash]*cachedNode // Cached nodes, common.Hash{} is the root of the live tries
+	nodesSize common.StorageSize          // Storage size of the cached nodes
+	blobs     map[string][]byte           // Blobs written since the last commit
+	blobsSize common.StorageSize          // Storage size of the blobs
+
+	gctime  time.Duration      // Time spent on garbage collection since last commit
+	gcnodes uint64             // Nodes garbage collected since last commit
+	gcsize  common.StorageSize // Data storage garbage collected since last commit
+
+	lock sync.RWMutex
+}
+
+// NewNodeDatabase creates a node cache on top of a disk database. onleaf
+// returns the nodes of other tries a leaf keeps alive, if any.
+func NewNodeDatabase(diskdb ethdb.Database, onleaf LeafCallback) *NodeDatabase {
+	return &NodeDatabase{
+		diskdb: diskdb,
+		onleaf: onleaf,
+		nodes: map[common.Hash]*cachedNode{
+			{}: {children: make(map[common.Hash]int)},
+		},
+		blobs: make(map[string][]byte),
+	}
+}
+
+// DiskDB returns the disk database under the cache.
+func (db *NodeDatabase) DiskDB() ethdb.Database {
+	return db.diskdb
+}
+
+// Get retrieves a node or blob from memory, or from disk if not cached.
+func (db *NodeDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	blob, ok := db.blobs[string(key)]
+	if !ok && len(key) == common.HashLength {
+		if node := db.nodes[common.BytesToHash(key)]; node != nil && node.blob != nil {
+			blob, ok = node.blob, true
+		}
+	}
+	db.lock.RUnlock()
+
+	if ok {
+		return blob, nil
+	}
+	return db.diskdb.Get(key)
+}
+
+// Has reports whether a node or blob is cached or on disk.
+func (db *NodeDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	_, ok := db.blobs[string(key)]
+	if !ok && len(key) == common.HashLength {
+		node := db.nodes[common.BytesToHash(key)]
+		ok = node != nil && node.blob != nil
+	}
+	db.lock.RUnlock()
+
+	if ok {
+		return true, nil
+	}
+	return db.diskdb.Has(key)
+}
+
+// Put caches a blob that isn't a trie node until the next Commit.
+func (db *NodeDatabase) Put(key, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.blobs[string(key)]; ok {
+		return nil
+	}
+	db.blobs[string(key)] = common.CopyBytes(value)
+	db.blobsSize += common.StorageSize(len(key) + len(value))
+	return nil
+}
+
+// insertNode caches a node committed by a trie and references its children.
+func (db *NodeDatabase) insertNode(hash common.Hash, blob []byte, n node) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.nodes[hash]; !ok {
+		db.nodes[hash] = &cachedNode{
+			blob:     common.CopyBytes(blob),
+			children: make(map[common.Hash]int),
+		}
+		db.nodesSize += common.StorageSize(common.HashLength + len(blob))
+	}
+	db.gatherChildren(n, hash)
+}
+
+// gatherChildren references the children of a collapsed node from parent,
+// including those of the nodes embedded in it.
+func (db *NodeDatabase) gatherChildren(n node, parent common.Hash) {
+	switch n := n.(type) {
+	case *shortNode:
+		db.gatherChildren(n.Val, parent)
+	case *fullNode:
+		for _, child := range n.Children {
+			if child != nil {
+				db.gatherChildren(child, parent)
+			}
+		}
+	case hashNode:
+		db.reference(common.BytesToHash(n), parent)
+	case valueNode:
+		if db.onleaf != nil {
+			for _, ref := range db.onleaf(n) {
+				db.reference(ref, parent)
+			}
+		}
+	}
+}
+
+// Reference adds a reference from a parent node to a child one. Referencing
+// a root from common.Hash{} keeps its trie alive until dereferenced.
+func (db *NodeDatabase) Reference(child common.Hash, parent common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.reference(child, parent)
+}
+
+func (db *NodeDatabase) reference(child common.Hash, parent common.Hash) {
+	// Nodes not cached are on disk already, don't track them
+	node, ok := db.nodes[child]
+	if !ok {
+		return
+	}
+	// A node references a child once, the live tries root a trie once per
+	// block using its state
+	if _, ok = db.nodes[parent].children[child]; ok && parent != (common.Hash{}) {
+		return
+	}
+	node.parents++
+	db.nodes[parent].children[child]++
+}
+
+// Dereference removes a reference of a root from common.Hash{}, garbage
+// collecting the nodes no longer referenced.
+func (db *NodeDatabase) Dereference(root common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	nodes, storage, start := len(db.nodes), db.nodesSize, time.Now()
+	db.dereference(root, common.Hash{})
+
+	db.gcnodes += uint64(nodes - len(db.nodes))
+	db.gcsize += storage - db.nodesSize
+	db.gctime += time.Since(start)
+
+	log.Debug("Dereferenced trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+}
+
+func (db *NodeDatabase) dereference(child common.Hash, parent common.Hash) {
+	// Drop the reference of the parent, ignoring those never added
+	node := db.nodes[parent]
+	count, ok := node.children[child]
+	if !ok {
+		return
+	}
+	if count > 1 {
+		node.children[child]--
+	} else {
+		delete(node.children, child)
+	}
+	// Nodes flushed by a commit aren't tracked anymore
+	node, ok = db.nodes[child]
+	if !ok {
+		return
+	}
+	node.parents--
+	if node.parents == 0 {
+		for hash := range node.children {
+			db.dereference(hash, child)
+		}
+		delete(db.nodes, child)
+		db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+	}
+}
+
+// Commit writes a trie and the cached blobs to disk, removing them from
+// memory. The nodes of other tries shared with it are flushed too, and read
+// from disk from then on.
+func (db *NodeDatabase) Commit(root common.Hash, report bool) error {
+	// Hold a read lock while writing, so the tries can still be read
+	db.lock.RLock()
+
+	start := time.Now()
+	batch := db.diskdb.NewBatch()
+
+	blobs := make([]string, 0, len(db.blobs))
+	for key, blob := range db.blobs {
+		blobs = append(blobs, key)
+		if err := batch.Put([]byte(key), blob); err != nil {
+			db.lock.RUnlock()
+			return err
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				db.lock.RUnlock()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	nodes, storage := len(db.nodes), db.nodesSize
+	if err := db.commit(root, batch); err != nil {
+		log.Error("Failed to commit trie from trie database", "err", err)
+		db.lock.RUnlock()
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write trie to disk", "err", err)
+		db.lock.RUnlock()
+		return err
+	}
+	db.lock.RUnlock()
+
+	// Write successful, clear out the flushed data
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for _, key := range blobs {
+		db.blobsSize -= common.StorageSize(len(key) + len(db.blobs[key]))
+		delete(db.blobs, key)
+	}
+
+	db.uncache(root)
+
+	logger := log.Info
+	if !report {
+		logger = log.Debug
+	}
+	logger("Persisted trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+
+	// Reset the garbage collection statistics
+	db.gcnodes, db.gcsize, db.gctime = 0, 0, 0
+
+	return nil
+}
+
+// commit writes a cached node and its cached children to a batch.
+func (db *NodeDatabase) commit(hash common.Hash, batch ethdb.Batch) error {
+	node, ok := db.nodes[hash]
+	if !ok || hash == (common.Hash{}) {
+		return nil
+	}
+	for child := range node.children {
+		if err := db.commit(child, batch); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(hash[:], node.blob); err != nil {
+		return err
+	}
+	if batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+	}
+	return nil
+}
+
+// uncache removes a flushed node and its cached children from memory.
+func (db *NodeDatabase) uncache(hash common.Hash) {
+	node, ok := db.nodes[hash]
+	if !ok || hash == (common.Hash{}) {
+		return
+	}
+	delete(db.nodes, hash)
+	for child := range node.children {
+		db.uncache(child)
+	}
+	db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+}
+
+// Size returns the storage size of the cached nodes and blobs.
+func (db *NodeDatabase) Size() common.StorageSize {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.nodesSize + db.blobsSize
+}
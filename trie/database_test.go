@@ -0,0 +1,99 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+// commitTrie commits a trie of count entries into the node cache, keeping it
+// alive, with the value of the first entry set to first.
+func commitTrie(t *testing.T, db *NodeDatabase, count int, first string) common.Hash {
+	tr, _ := New(common.Hash{}, db)
+	for i := 0; i < count; i++ {
+		tr.Update([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+	}
+	tr.Update([]byte("key000"), []byte(first))
+	root, err := tr.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Reference(root, common.Hash{})
+	return root
+}
+
+func checkTrie(t *testing.T, db Database, root common.Hash, count int, first string) {
+	tr, err := New(root, db)
+	if err != nil {
+		t.Fatalf("root %x: %v", root, err)
+	}
+	if v := tr.Get([]byte("key000")); !bytes.Equal(v, []byte(first)) {
+		t.Fatalf("root %x: first value mismatch: have %q, want %q", root, v, first)
+	}
+	for i := 1; i < count; i++ {
+		if v := tr.Get([]byte(fmt.Sprintf("key%03d", i))); !bytes.Equal(v, []byte(fmt.Sprintf("value%03d", i))) {
+			t.Fatalf("root %x: value %d mismatch: have %q", root, i, v)
+		}
+	}
+}
+
+func TestNodeDatabaseGarbageCollection(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	db := NewNodeDatabase(diskdb, nil)
+
+	stale := commitTrie(t, db, 100, "stale")
+	live := commitTrie(t, db, 100, "live")
+	if len(diskdb.Keys()) != 0 {
+		t.Fatalf("nodes written to disk before commit: %d", len(diskdb.Keys()))
+	}
+	size := db.Size()
+
+	// Dropping the stale trie keeps the nodes shared with the live one.
+	db.Dereference(stale)
+	if db.Size() >= size {
+		t.Fatalf("cache not shrunk: have %v, had %v", db.Size(), size)
+	}
+	if _, err := New(stale, db); err == nil {
+		t.Fatalf("stale root still cached")
+	}
+	checkTrie(t, db, live, 100, "live")
+
+	// Committing the live trie flushes it whole to disk.
+	if err := db.Commit(live, false); err != nil {
+		t.Fatal(err)
+	}
+	if db.Size() != 0 {
+		t.Fatalf("cache not empty after commit: %v", db.Size())
+	}
+	checkTrie(t, diskdb, live, 100, "live")
+	if ok, _ := diskdb.Has(stale[:]); ok {
+		t.Fatalf("stale root written to disk")
+	}
+}
+
+func TestNodeDatabaseBlobs(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	db := NewNodeDatabase(diskdb, nil)
+
+	db.Put([]byte("code"), []byte{1, 2, 3})
+	if v, _ := db.Get([]byte("code")); !bytes.Equal(v, []byte{1, 2, 3}) {
+		t.Fatalf("cached blob mismatch: %x", v)
+	}
+	// Blobs aren't garbage collected, and are flushed by any commit.
+	root := commitTrie(t, db, 10, "value")
+	db.Dereference(root)
+	if err := db.Commit(common.Hash{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := diskdb.Get([]byte("code")); !bytes.Equal(v, []byte{1, 2, 3}) {
+		t.Fatalf("flushed blob mismatch: %x", v)
+	}
+	if len(diskdb.Keys()) != 1 {
+		t.Fatalf("disk entries mismatch: have %d, want 1", len(diskdb.Keys()))
+	}
+}
@@ -167,6 +167,11 @@ func (h *hasher) store(n node, db DatabaseWriter, force bool) (node, error) {
 		hash = hashNode(h.sha.Sum(nil))
 	}
 	if db != nil {
+		// A node cache tracks the references of the node
+		if cache, ok := db.(*NodeDatabase); ok {
+			cache.insertNode(common.BytesToHash(hash), h.tmp.Bytes(), n)
+			return hash, nil
+		}
 		return hash, db.Put(hash, h.tmp.Bytes())
 	}
 	return hash, nil
@@ -21,6 +21,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 var secureKeyPrefix = []byte("secure-key-")
@@ -167,6 +168,12 @@ func (t *SecureTrie) NodeIterator(start []byte) NodeIterator {
 	return t.trie.NodeIterator(start)
 }
 
+// Prove constructs a merkle proof for key, which is hashed before being
+// looked up in the trie like with Get. See Trie.Prove.
+func (t *SecureTrie) Prove(key []byte) []rlp.RawValue {
+	return t.trie.Prove(t.hashKey(key))
+}
+
 // CommitTo writes all nodes and the secure hash pre-images to the given database.
 // Nodes are stored with their sha3 hash as the key.
 //